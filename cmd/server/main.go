@@ -1,15 +1,20 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/ericdahl/bookshelf/internal/api"
 	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/events"
+	"github.com/ericdahl/bookshelf/internal/metrics"
+	"github.com/ericdahl/bookshelf/internal/webhook"
 )
 
 func checkWebDir(webDir string) error {
@@ -37,6 +42,10 @@ func main() {
 	webDir := flag.String("web-dir", "./web", "Directory containing static web assets (HTML, CSS, JS)")
 	verbose := flag.Bool("verbose", false, "Enable verbose logging (Debug level)")
 	logFormat := flag.String("log-format", "text", "Log format: 'json' or 'text' (default: text)")
+	accessLogFormat := flag.String("access-log-format", "structured", "Access log format: 'structured' (slog, follows --log-format) or 'combined' (Apache combined log format)")
+	dbQueryTimeout := flag.Duration("db-query-timeout", db.DefaultQueryTimeout, "Timeout for each database query (0 disables)")
+	readyCheckMetadata := flag.Bool("ready-check-metadata", false, "Make /readyz also check that the metadata provider (Open Library) is reachable")
+	trashRetentionDays := flag.Int("trash-retention-days", 30, "Permanently delete books that have been in the trash for this many days (0 keeps them forever)")
 
 	flag.Usage = func() {
 		fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", os.Args[0])
@@ -52,6 +61,16 @@ func main() {
 		os.Exit(1)
 	}
 
+	if !api.AccessLogFormat(*accessLogFormat).IsValid() {
+		fmt.Fprintf(os.Stderr, "Error: access-log-format must be either 'structured' or 'combined', got '%s'\n", *accessLogFormat)
+		os.Exit(1)
+	}
+
+	if *trashRetentionDays < 0 {
+		fmt.Fprintf(os.Stderr, "Error: trash-retention-days must not be negative, got %d\n", *trashRetentionDays)
+		os.Exit(1)
+	}
+
 	// --- Logging Setup ---
 	// Set log level based on verbose flag
 	logLevel := slog.LevelInfo
@@ -78,9 +97,13 @@ func main() {
 	slog.Info("Configuration",
 		"port", *port,
 		"dbFile", *dbFile,
+		"dbQueryTimeout", *dbQueryTimeout,
 		"webDir", *webDir,
 		"verbose", *verbose,
-		"logFormat", *logFormat)
+		"logFormat", *logFormat,
+		"accessLogFormat", *accessLogFormat,
+		"readyCheckMetadata", *readyCheckMetadata,
+		"trashRetentionDays", *trashRetentionDays)
 
 	// --- Dependency Injection ---
 	// Initialize Database
@@ -98,9 +121,31 @@ func main() {
 
 	// Create Book Store
 	bookStore := db.NewSQLiteBookStore(database)
+	bookStore.QueryTimeout = *dbQueryTimeout
+
+	// Shelf change events, published by the store and streamed to browsers via /api/events
+	eventBus := events.NewBus(events.DefaultHistorySize)
+	bookStore.Events = eventBus
+
+	// Deliver shelf events to webhook subscribers in the background
+	go webhook.NewDispatcher(bookStore).Run(context.Background(), eventBus)
+
+	// Empty the trash of books deleted more than the retention period ago
+	if *trashRetentionDays > 0 {
+		retention := time.Duration(*trashRetentionDays) * 24 * time.Hour
+		go db.RunTrashPurge(context.Background(), bookStore, retention, time.Hour)
+	}
+
+	// Expose shelf sizes on /metrics
+	metrics.Registry.MustRegister(metrics.NewBookCountCollector(bookStore.CountBooksByStatusAndType))
 
 	// Create API Handler
 	apiHandler := api.NewAPIHandler(bookStore)
+	apiHandler.Events = eventBus
+	apiHandler.CheckMetadataProvider = *readyCheckMetadata
+	apiHandler.AccessLogFormat = api.AccessLogFormat(*accessLogFormat)
+	// Suggest the next book of a series when one is finished, over the event stream
+	go apiHandler.RunNextInSeries(context.Background())
 
 	// --- Router Setup ---
 	// Ensure the web directory exists before setting up the router/server
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// ActorHeader carries the user making a request, as set by an authenticating reverse proxy.
+const ActorHeader = "X-Remote-User"
+
+// maxActorLength bounds the actor names recorded in the audit log.
+const maxActorLength = 128
+
+// Audit log page sizes for GET /api/audit.
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+// ActorMiddleware attaches the user named by the X-Remote-User header to the request context,
+// so the changes it makes are attributed to them in the audit log. Names that are too long or
+// contain control characters are ignored and the request is recorded as anonymous.
+func ActorMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if actor := strings.TrimSpace(r.Header.Get(ActorHeader)); validActor(actor) {
+			r = r.WithContext(db.WithActor(r.Context(), actor))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validActor reports whether an actor name is non-empty, reasonably short and printable.
+func validActor(actor string) bool {
+	if actor == "" || len(actor) > maxActorLength {
+		return false
+	}
+	for _, c := range actor {
+		if c < ' ' || c == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// GetAuditLogHandler handles GET /api/audit requests.
+// Returns changes to books, newest first. Optional filters: ?book_id=, ?actor=, ?action=,
+// ?from= and ?before= (dates). ?limit= sets the page size; pass the response's
+// next_before_id as ?before_id= for the next page.
+func (h *APIHandler) GetAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := db.AuditFilter{
+		Actor:  query.Get("actor"),
+		Action: model.AuditAction(query.Get("action")),
+		Limit:  defaultAuditLimit,
+	}
+	if filter.Action != "" && !filter.Action.IsValid() {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid action. Must be one of 'create', 'update', 'delete', 'restore', 'purge'")
+		return
+	}
+	for param, dst := range map[string]*int64{"book_id": &filter.BookID, "before_id": &filter.BeforeID} {
+		if value := query.Get(param); value != "" {
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || parsed < 1 {
+				respondWithError(w, r, http.StatusBadRequest, "Invalid "+param)
+				return
+			}
+			*dst = parsed
+		}
+	}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > maxAuditLimit {
+			respondWithError(w, r, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxAuditLimit))
+			return
+		}
+		filter.Limit = parsed
+	}
+	for param, dst := range map[string]**time.Time{"from": &filter.From, "before": &filter.Before} {
+		if value := query.Get(param); value != "" {
+			t, err := parseDate(value)
+			if err != nil {
+				respondWithError(w, r, http.StatusBadRequest, "Invalid "+param+": "+err.Error())
+				return
+			}
+			*dst = &t
+		}
+	}
+
+	page, err := h.Audit.GetAuditLog(r.Context(), filter)
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to retrieve audit log")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, page)
+}
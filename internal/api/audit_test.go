@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestAuditLogHandler(t *testing.T) {
+	_, _, router := newTestAPI(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/books",
+		strings.NewReader(`{"title":"Dune","author":"Frank Herbert","open_library_id":"OL-Dune","status":"Want to Read"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(ActorHeader, " alice ")
+	req.Header.Set("X-Request-ID", "req-42")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 adding a book, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/audit?actor=alice&action=create", nil))
+	var page model.AuditPage
+	if err := json.Unmarshal(rr.Body.Bytes(), &page); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Expected an audit page, got %d %s", rr.Code, rr.Body.String())
+	}
+	if len(page.Entries) != 1 || page.Entries[0].RequestID != "req-42" || page.NextBeforeID != nil {
+		t.Errorf("Expected the create by alice in request req-42, got %+v", page)
+	}
+
+	for _, query := range []string{"action=edit", "limit=0", "limit=501", "book_id=x", "before_id=-1", "from=yesterday"} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/audit?"+query, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", query, rr.Code)
+		}
+	}
+}
+
+func TestValidActor(t *testing.T) {
+	tests := map[string]bool{
+		"alice":                  true,
+		"Jane Doe":               true,
+		"":                       false,
+		"bad\nname":              false,
+		strings.Repeat("a", 129): false,
+		strings.Repeat("a", 128): true,
+	}
+	for actor, want := range tests {
+		if got := validActor(actor); got != want {
+			t.Errorf("validActor(%q) = %v, want %v", actor, got, want)
+		}
+	}
+}
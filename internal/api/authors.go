@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// parseRole reads the optional ?role= filter, responding 400 if it is not a known role.
+func parseRole(w http.ResponseWriter, r *http.Request) (model.AuthorRole, bool) {
+	role := model.AuthorRole(r.URL.Query().Get("role"))
+	if role != "" && !role.IsValid() {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid role, must be 'author', 'narrator' or 'translator'")
+		return "", false
+	}
+	return role, true
+}
+
+// GetAuthorsHandler handles GET /api/authors requests.
+// Lists the people credited on at least one book; ?role=narrator lists only narrators, etc.
+func (h *APIHandler) GetAuthorsHandler(w http.ResponseWriter, r *http.Request) {
+	role, ok := parseRole(w, r)
+	if !ok {
+		return
+	}
+	authors, err := h.Authors.GetAuthors(r.Context(), role)
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to retrieve authors")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, authors)
+}
+
+// GetAuthorBooksHandler handles GET /api/authors/{id}/books requests.
+// Returns the books crediting the author (in ?role= if given), accepting the same filter
+// and sort parameters as GET /api/books.
+func (h *APIHandler) GetAuthorBooksHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid author ID")
+		return
+	}
+	role, ok := parseRole(w, r)
+	if !ok {
+		return
+	}
+	opts, err := parseListOptions(r.URL.Query())
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	opts.AuthorID = id
+	opts.AuthorRole = role
+
+	if _, err := h.Authors.GetAuthorByID(r.Context(), id); err != nil {
+		respondWithStoreError(w, r, err, "Failed to retrieve author")
+		return
+	}
+	books, err := h.Store.ListBooks(r.Context(), opts)
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to retrieve books")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, books)
+}
+
+// SetBookAuthorsHandler handles PUT /api/books/{id}/authors requests.
+// Expects the complete list of contributors in credit order, e.g.
+// [{"name": "Ursula K. Le Guin", "open_library_key": "OL23919A"}, {"name": "Jane Doe", "role": "narrator"}].
+func (h *APIHandler) SetBookAuthorsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid book ID")
+		return
+	}
+	r, ok := withIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	var contributors []model.Contributor
+	r.Body = http.MaxBytesReader(w, r.Body, 64*1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&contributors); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	for i := range contributors {
+		contributors[i].AuthorID = 0 // Assigned by the store
+		if err := contributors[i].Validate(); err != nil {
+			var validationErr *model.ValidationError
+			if errors.As(err, &validationErr) {
+				respondWithError(w, r, http.StatusBadRequest, validationErr.Message)
+			} else {
+				respondWithError(w, r, http.StatusBadRequest, "Invalid contributor: "+err.Error())
+			}
+			return
+		}
+	}
+
+	if err := h.Authors.SetBookAuthors(r.Context(), id, contributors); err != nil {
+		respondWithStoreError(w, r, err, "Failed to update book authors")
+		return
+	}
+	book, err := h.Store.GetBookByID(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to retrieve book")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, book)
+}
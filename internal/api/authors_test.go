@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestAuthorHandlers(t *testing.T) {
+	_, store, router := newTestAPI(t)
+	ctx := context.Background()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	id, err := store.AddBook(ctx, &model.Book{Title: "Listen", Author: "Writer", OpenLibraryID: "OL1AUTH", Type: model.TypeAudiobook})
+	if err != nil {
+		t.Fatalf("Failed to add book: %v", err)
+	}
+
+	rr := do(http.MethodPut, "/api/books/"+itoa(id)+"/authors",
+		`[{"name": "Writer", "open_library_key": "OL1A"}, {"name": "Voice", "role": "narrator"}]`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var book model.Book
+	if err := json.Unmarshal(rr.Body.Bytes(), &book); err != nil {
+		t.Fatalf("Failed to decode book: %v", err)
+	}
+	if len(book.Authors) != 2 || book.Authors[1].Role != model.RoleNarrator || book.Authors[0].OpenLibraryKey == nil {
+		t.Errorf("Expected author and narrator, got %+v", book.Authors)
+	}
+
+	rr = do(http.MethodGet, "/api/authors?role=narrator", "")
+	var narrators []model.Author
+	if err := json.Unmarshal(rr.Body.Bytes(), &narrators); err != nil || len(narrators) != 1 {
+		t.Fatalf("Expected one narrator, got %s", rr.Body.String())
+	}
+
+	rr = do(http.MethodGet, "/api/authors/"+itoa(narrators[0].ID)+"/books", "")
+	var books []model.Book
+	if err := json.Unmarshal(rr.Body.Bytes(), &books); err != nil || len(books) != 1 || books[0].ID != id {
+		t.Errorf("Expected the narrated book, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	tests := []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodGet, "/api/authors?role=editor", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/authors/999/books", "", http.StatusNotFound},
+		{http.MethodPut, "/api/books/" + itoa(id) + "/authors", `[{"name": ""}]`, http.StatusBadRequest},
+		{http.MethodPut, "/api/books/999/authors", `[{"name": "Nobody"}]`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if rr := do(tt.method, tt.path, tt.body); rr.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.path, tt.want, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// BulkBooksHandler handles POST /api/books/bulk requests.
+// Expects {"ids": [1, 2, 3], "operation": "set_status", "status": "Read"}; see model.BulkOperation.
+// All books change or none do: responds 200 with the per-book results if the operation was
+// applied, or 422 with them if any book failed and nothing was changed.
+func (h *APIHandler) BulkBooksHandler(w http.ResponseWriter, r *http.Request) {
+	var op model.BulkOperation
+	r.Body = http.MaxBytesReader(w, r.Body, 64*1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&op); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if err := op.Validate(); err != nil {
+		var validationErr *model.ValidationError
+		if errors.As(err, &validationErr) {
+			respondWithError(w, r, http.StatusBadRequest, validationErr.Message)
+		} else {
+			respondWithError(w, r, http.StatusBadRequest, "Invalid bulk operation: "+err.Error())
+		}
+		return
+	}
+
+	response, err := h.Bulk.ApplyBulk(r.Context(), op)
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to apply bulk operation")
+		return
+	}
+	status := http.StatusOK
+	if !response.Applied {
+		status = http.StatusUnprocessableEntity
+	}
+	respondWithJSON(w, status, response)
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestBulkBooksHandler(t *testing.T) {
+	_, store, router := newTestAPI(t)
+	ctx := context.Background()
+
+	do := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/books/bulk", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	var ids []string
+	for _, title := range []string{"A", "B"} {
+		id, err := store.AddBook(ctx, &model.Book{Title: title, Author: "X", OpenLibraryID: "OL-" + title, Status: model.StatusWantToRead})
+		if err != nil {
+			t.Fatalf("Failed to add book: %v", err)
+		}
+		ids = append(ids, itoa(id))
+	}
+
+	rr := do(`{"ids": [` + ids[0] + `, ` + ids[1] + `], "operation": "add_tag", "tag": "imported"}`)
+	var response model.BulkResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil || rr.Code != http.StatusOK || !response.Applied || len(response.Results) != 2 {
+		t.Fatalf("Expected the tag to be applied, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = do(`{"ids": [` + ids[0] + `, 999], "operation": "delete"}`)
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil || rr.Code != http.StatusUnprocessableEntity || response.Applied || response.Results[1].OK {
+		t.Errorf("Expected 422 with book 999 failing, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = do(`{"ids": [` + ids[0] + `], "operation": "delete"}`)
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 deleting, got %d %s", rr.Code, rr.Body.String())
+	}
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/books?tag=Imported", nil))
+	var books []model.Book
+	if err := json.Unmarshal(rr.Body.Bytes(), &books); err != nil || len(books) != 1 || itoa(books[0].ID) != ids[1] {
+		t.Errorf("Expected the remaining tagged book, got %s", rr.Body.String())
+	}
+
+	for _, body := range []string{
+		`{"ids": [], "operation": "delete"}`,
+		`{"ids": [1], "operation": "archive"}`,
+		`{"ids": [1], "operation": "set_status", "status": "Lost"}`,
+		`{"ids": [1], "operation": "delete", "extra": true}`,
+	} {
+		if rr := do(body); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// GetDuplicatesHandler handles GET /api/duplicates requests.
+// Returns the groups of books that look like the same work, each with the reason they were
+// grouped and its books oldest first.
+func (h *APIHandler) GetDuplicatesHandler(w http.ResponseWriter, r *http.Request) {
+	groups, err := h.Duplicates.FindDuplicates(r.Context())
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to find duplicates")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, groups)
+}
+
+// MergeBooksHandler handles POST /api/books/merge requests.
+// Expects {"target_id": 1, "source_ids": [2, 3]}, merges the source books into the target,
+// moving them to the trash, and responds with the merged book.
+func (h *APIHandler) MergeBooksHandler(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		TargetID  int64   `json:"target_id"`
+		SourceIDs []int64 `json:"source_ids"`
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, 64*1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if payload.TargetID < 1 {
+		respondWithError(w, r, http.StatusBadRequest, "target_id is required")
+		return
+	}
+
+	book, err := h.Duplicates.MergeBooks(r.Context(), payload.TargetID, payload.SourceIDs)
+	if err != nil {
+		var validationErr *model.ValidationError
+		if errors.As(err, &validationErr) {
+			respondWithError(w, r, http.StatusBadRequest, validationErr.Message)
+		} else {
+			respondWithStoreError(w, r, err, "Failed to merge books")
+		}
+		return
+	}
+	respondWithJSON(w, http.StatusOK, book)
+}
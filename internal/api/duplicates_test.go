@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestDuplicateHandlers(t *testing.T) {
+	_, store, router := newTestAPI(t)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	var ids []int64
+	for i, book := range []model.Book{
+		{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719"},
+		{Title: "Dune", Author: "Frank Herbert", ISBN: "0441172717", Type: model.TypeEbook},
+		{Title: "Emma", Author: "Jane Austen"},
+	} {
+		book.OpenLibraryID, book.Status = "OL"+itoa(int64(i)), model.StatusRead
+		id, err := store.AddBook(context.Background(), &book)
+		if err != nil {
+			t.Fatalf("Failed to add book: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	// Adding a book that is already on the shelf is a conflict, not a server error
+	if rr := do(http.MethodPost, "/api/books", `{"title":"Dune","author":"Frank Herbert","open_library_id":"OL0"}`); rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 adding a book twice, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	var groups []model.DuplicateGroup
+	rr := do(http.MethodGet, "/api/duplicates", "")
+	if err := json.Unmarshal(rr.Body.Bytes(), &groups); err != nil || len(groups) != 1 || groups[0].Reason != model.DuplicateISBN || len(groups[0].Books) != 2 {
+		t.Fatalf("Expected the two Dunes grouped by ISBN, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	for _, tt := range []struct {
+		body string
+		want int
+	}{
+		{`{"source_ids":[2]}`, http.StatusBadRequest},
+		{`{"target_id":1,"source_ids":[]}`, http.StatusBadRequest},
+		{`{"target_id":1,"source_ids":[1]}`, http.StatusBadRequest},
+		{`{"target_id":1,"source_ids":[999]}`, http.StatusNotFound},
+		{`{"target_id":1,"sources":[2]}`, http.StatusBadRequest},
+	} {
+		if rr := do(http.MethodPost, "/api/books/merge", tt.body); rr.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d %s", tt.body, tt.want, rr.Code, rr.Body.String())
+		}
+	}
+
+	var book model.Book
+	rr = do(http.MethodPost, "/api/books/merge", `{"target_id":`+itoa(ids[0])+`,"source_ids":[`+itoa(ids[1])+`]}`)
+	if err := json.Unmarshal(rr.Body.Bytes(), &book); err != nil || rr.Code != http.StatusOK || book.ID != ids[0] || len(book.Editions) != 2 {
+		t.Fatalf("Expected the merged book with both editions, got %d %s", rr.Code, rr.Body.String())
+	}
+	rr = do(http.MethodGet, "/api/duplicates", "")
+	if err := json.Unmarshal(rr.Body.Bytes(), &groups); err != nil || len(groups) != 0 {
+		t.Errorf("Expected no duplicates left, got %s", rr.Body.String())
+	}
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// editionRequest is the body of POST and PUT /api/books/{id}/editions requests.
+type editionRequest struct {
+	Format    model.BookType `json:"format"`
+	ISBN      *string        `json:"isbn"`
+	Publisher *string        `json:"publisher"`
+	Pages     *int           `json:"pages"`
+	Duration  *int           `json:"duration_minutes"`
+	CoverURL  *string        `json:"cover_url"`
+}
+
+// editionIDs parses the {id} and {editionId} route variables, responding 400 if either is
+// malformed. editionId is 0 on routes without it.
+func editionIDs(w http.ResponseWriter, r *http.Request) (bookID, editionID int64, ok bool) {
+	vars := mux.Vars(r)
+	bookID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid book ID")
+		return 0, 0, false
+	}
+	if _, ok := vars["editionId"]; ok {
+		if editionID, err = strconv.ParseInt(vars["editionId"], 10, 64); err != nil {
+			respondWithError(w, r, http.StatusBadRequest, "Invalid edition ID")
+			return 0, 0, false
+		}
+	}
+	return bookID, editionID, true
+}
+
+// decodeEdition reads and validates an edition from the request body, responding 400 if it
+// is invalid.
+func decodeEdition(w http.ResponseWriter, r *http.Request, bookID, editionID int64) (*model.Edition, bool) {
+	var payload editionRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 64*1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return nil, false
+	}
+
+	edition := &model.Edition{
+		ID:        editionID,
+		BookID:    bookID,
+		Format:    payload.Format,
+		ISBN:      payload.ISBN,
+		Publisher: payload.Publisher,
+		Pages:     payload.Pages,
+		Duration:  payload.Duration,
+		CoverURL:  payload.CoverURL,
+	}
+	if err := edition.Validate(); err != nil {
+		var validationErr *model.ValidationError
+		if errors.As(err, &validationErr) {
+			respondWithError(w, r, http.StatusBadRequest, validationErr.Message)
+		} else {
+			respondWithError(w, r, http.StatusBadRequest, "Invalid edition: "+err.Error())
+		}
+		return nil, false
+	}
+	return edition, true
+}
+
+// GetBookEditionsHandler handles GET /api/books/{id}/editions requests.
+// Returns the book's editions in the order they were added.
+func (h *APIHandler) GetBookEditionsHandler(w http.ResponseWriter, r *http.Request) {
+	bookID, _, ok := editionIDs(w, r)
+	if !ok {
+		return
+	}
+	editions, err := h.Editions.GetEditions(r.Context(), bookID)
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to retrieve editions")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, editions)
+}
+
+// AddBookEditionHandler handles POST /api/books/{id}/editions requests.
+// Expects {"format": "audiobook", "isbn": "...", "publisher": "...", "duration_minutes": 1263,
+// "cover_url": "..."}, where only format is required (pages instead of duration_minutes for
+// printed books and ebooks), and responds 201 with the new edition.
+func (h *APIHandler) AddBookEditionHandler(w http.ResponseWriter, r *http.Request) {
+	bookID, _, ok := editionIDs(w, r)
+	if !ok {
+		return
+	}
+	r, ok = withIfMatch(w, r)
+	if !ok {
+		return
+	}
+	edition, ok := decodeEdition(w, r, bookID, 0)
+	if !ok {
+		return
+	}
+	if err := h.Editions.AddEdition(r.Context(), edition); err != nil {
+		respondWithStoreError(w, r, err, "Failed to add edition")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, edition)
+}
+
+// UpdateBookEditionHandler handles PUT /api/books/{id}/editions/{editionId} requests.
+// Expects the same body as AddBookEditionHandler and replaces the edition with it.
+func (h *APIHandler) UpdateBookEditionHandler(w http.ResponseWriter, r *http.Request) {
+	bookID, editionID, ok := editionIDs(w, r)
+	if !ok {
+		return
+	}
+	r, ok = withIfMatch(w, r)
+	if !ok {
+		return
+	}
+	edition, ok := decodeEdition(w, r, bookID, editionID)
+	if !ok {
+		return
+	}
+	if err := h.Editions.UpdateEdition(r.Context(), edition); err != nil {
+		respondWithStoreError(w, r, err, "Failed to update edition")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, edition)
+}
+
+// DeleteBookEditionHandler handles DELETE /api/books/{id}/editions/{editionId} requests.
+func (h *APIHandler) DeleteBookEditionHandler(w http.ResponseWriter, r *http.Request) {
+	bookID, editionID, ok := editionIDs(w, r)
+	if !ok {
+		return
+	}
+	r, ok = withIfMatch(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Editions.DeleteEdition(r.Context(), bookID, editionID); err != nil {
+		respondWithStoreError(w, r, err, "Failed to delete edition")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
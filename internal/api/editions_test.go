@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestEditionHandlers(t *testing.T) {
+	_, store, router := newTestAPI(t)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	id, err := store.AddBook(context.Background(), &model.Book{Title: "Dune", Author: "Frank Herbert", OpenLibraryID: "OL-Dune", Status: model.StatusRead, Type: model.TypeEbook})
+	if err != nil {
+		t.Fatalf("Failed to add book: %v", err)
+	}
+	path := "/api/books/" + itoa(id) + "/editions"
+
+	rr := do(http.MethodPost, path, `{"format":"audiobook","isbn":"978-0-593-09932-2","publisher":"Penguin Audio","duration_minutes":1263}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d %s", rr.Code, rr.Body.String())
+	}
+	var edition model.Edition
+	if err := json.Unmarshal(rr.Body.Bytes(), &edition); err != nil || edition.ID == 0 || *edition.ISBN != "9780593099322" {
+		t.Fatalf("Expected the new edition with a normalized ISBN, got %s", rr.Body.String())
+	}
+
+	var editions []model.Edition
+	rr = do(http.MethodGet, path, "")
+	if err := json.Unmarshal(rr.Body.Bytes(), &editions); err != nil || len(editions) != 2 {
+		t.Fatalf("Expected the ebook added with the book and the audiobook, got %d %s", rr.Code, rr.Body.String())
+	}
+	if editions[0].Format != model.TypeEbook || editions[1].Format != model.TypeAudiobook {
+		t.Errorf("Expected the editions in the order added, got %+v", editions)
+	}
+
+	editionPath := path + "/" + itoa(edition.ID)
+	if rr := do(http.MethodPut, editionPath, `{"format":"audiobook","duration_minutes":1290}`); rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 updating, got %d %s", rr.Code, rr.Body.String())
+	}
+	if book, _ := store.GetBookByID(context.Background(), id); book.Editions[1].ISBN != nil || *book.Editions[1].Duration != 1290 {
+		t.Errorf("Expected the edition replaced, got %+v", book.Editions[1])
+	}
+
+	for _, tt := range []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodPost, path, `{"format":"scroll"}`, http.StatusBadRequest},
+		{http.MethodPost, path, `{"format":"audiobook","pages":300}`, http.StatusBadRequest},
+		{http.MethodPost, path, `{"format":"book","isbn":"123"}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/books/999/editions", `{"format":"book"}`, http.StatusNotFound},
+		{http.MethodPut, path + "/999", `{"format":"book"}`, http.StatusNotFound},
+	} {
+		if rr := do(tt.method, tt.path, tt.body); rr.Code != tt.want {
+			t.Errorf("%s %s %s: expected %d, got %d", tt.method, tt.path, tt.body, tt.want, rr.Code)
+		}
+	}
+
+	if rr := do(http.MethodDelete, editionPath, ""); rr.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 deleting, got %d", rr.Code)
+	}
+	if rr := do(http.MethodDelete, editionPath, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 deleting twice, got %d", rr.Code)
+	}
+
+	// Ebook is a book type like the others
+	if rr := do(http.MethodPut, "/api/books/"+itoa(id)+"/type", `{"type":"audiobook"}`); rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 changing type, got %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := do(http.MethodPut, "/api/books/"+itoa(id)+"/type", `{"type":"ebook"}`); rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 changing type to ebook, got %d %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/logging"
+)
+
+// Machine-readable error codes included in every error response, so the frontend and
+// scripts can react without parsing human-readable messages.
+const (
+	CodeInvalidRequest     = "invalid_request"
+	CodeNotFound           = "not_found"
+	CodeDuplicate          = "duplicate"
+	CodeConflict           = "version_conflict"
+	CodePayloadTooLarge    = "payload_too_large"
+	CodeUpstreamError      = "upstream_error"
+	CodeServiceUnavailable = "service_unavailable"
+	CodeInternalError      = "internal_error"
+	CodeClientClosed       = "client_closed_request"
+)
+
+// StatusClientClosedRequest is the non-standard status (from nginx) recorded for requests the
+// client gave up on before the response was written.
+const StatusClientClosedRequest = 499
+
+// problemContentType is the media type for RFC 7807 problem details.
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 "problem details" error body, extended with a Code member.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// codeForStatus picks the default error code for a status code.
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeInvalidRequest
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeDuplicate
+	case http.StatusPreconditionFailed:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusBadGateway:
+		return CodeUpstreamError
+	case http.StatusServiceUnavailable:
+		return CodeServiceUnavailable
+	default:
+		return CodeInternalError
+	}
+}
+
+// respondWithProblem sends an application/problem+json error response.
+func respondWithProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	if status >= http.StatusInternalServerError {
+		logger.Error("HTTP Error", "code", status, "errorCode", code, "message", detail)
+	} else {
+		logger.Info("HTTP Client Error", "code", status, "errorCode", code, "message", detail)
+	}
+
+	title := http.StatusText(status)
+	if status == StatusClientClosedRequest {
+		title = "Client Closed Request"
+	}
+	problem := Problem{
+		Type:      "about:blank",
+		Title:     title,
+		Status:    status,
+		Detail:    detail,
+		Code:      code,
+		RequestID: logging.RequestID(ctx),
+	}
+	response, err := json.Marshal(problem)
+	if err != nil {
+		slog.Error("Error marshalling problem response", "error", err)
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(status)
+	if _, err := w.Write(response); err != nil {
+		logger.Error("Error writing problem response", "error", err)
+	}
+}
+
+// respondWithStoreError maps errors returned by the stores to the matching HTTP status:
+// db.ErrNotFound → 404, db.ErrDuplicate → 409, db.ErrConflict → 412, a cancelled request
+// → 499, a timeout → 503, anything else → 500 with message used as the detail prefix.
+func respondWithStoreError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		respondWithProblem(w, r, http.StatusNotFound, CodeNotFound, err.Error())
+	case errors.Is(err, db.ErrDuplicate):
+		respondWithProblem(w, r, http.StatusConflict, CodeDuplicate, err.Error())
+	case errors.Is(err, db.ErrConflict):
+		respondWithProblem(w, r, http.StatusPreconditionFailed, CodeConflict, err.Error()+"; reload and try again")
+	case errors.Is(err, context.Canceled):
+		// The client went away and will not read the response, but the access log and
+		// metrics should not record the request as a success
+		respondWithProblem(w, r, StatusClientClosedRequest, CodeClientClosed, message+": request cancelled by the client")
+	case errors.Is(err, context.DeadlineExceeded):
+		respondWithProblem(w, r, http.StatusServiceUnavailable, CodeServiceUnavailable, message+": database timeout")
+	default:
+		respondWithProblem(w, r, http.StatusInternalServerError, CodeInternalError, message+": "+err.Error())
+	}
+}
+
+// withIfMatch applies an If-Match header (a book version, optionally quoted as an ETag) to the
+// request context, so store mutations fail with db.ErrConflict if the book changed meanwhile.
+// Returns false (after responding 400) if the header is malformed.
+func withIfMatch(w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	header := r.Header.Get("If-Match")
+	if header == "" || header == "*" {
+		return r, true
+	}
+	version, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(header, "W/"), `"`), 10, 64)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "If-Match must be a book version number")
+		return r, false
+	}
+	return r.WithContext(db.WithExpectedVersion(r.Context(), version)), true
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// decodeProblem checks the response is an RFC 7807 problem and returns it
+func decodeProblem(t *testing.T, rr *httptest.ResponseRecorder) Problem {
+	t.Helper()
+	if ct := rr.Header().Get("Content-Type"); ct != problemContentType {
+		t.Errorf("Expected Content-Type %s, got %s", problemContentType, ct)
+	}
+	var problem Problem
+	if err := json.Unmarshal(rr.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Could not unmarshal problem response: %v", err)
+	}
+	if problem.Status != rr.Code {
+		t.Errorf("Problem status %d does not match response code %d", problem.Status, rr.Code)
+	}
+	return problem
+}
+
+// TestAddDuplicateBookConflict tests that adding the same Open Library ID twice returns 409
+func TestAddDuplicateBookConflict(t *testing.T) {
+	_, store, router := newTestAPI(t)
+	book := createTestBook(model.StatusWantToRead, "Duplicate")
+	if _, err := store.AddBook(context.Background(), book); err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+
+	jsonData, err := json.Marshal(book)
+	if err != nil {
+		t.Fatalf("Failed to marshal JSON: %v", err)
+	}
+	req, err := http.NewRequest("POST", "/api/books", bytes.NewBuffer(jsonData))
+	if err != nil {
+		t.Fatalf("Could not create request: %v", err)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusConflict {
+		t.Fatalf("Handler returned wrong status code: got %v want %v, body: %s", status, http.StatusConflict, rr.Body.String())
+	}
+	if problem := decodeProblem(t, rr); problem.Code != CodeDuplicate {
+		t.Errorf("Expected code %s, got %s", CodeDuplicate, problem.Code)
+	}
+}
+
+// TestNotFoundProblem tests that a missing book yields a not_found problem
+func TestNotFoundProblem(t *testing.T) {
+	_, _, router := newTestAPI(t)
+	req, err := http.NewRequest("PUT", "/api/books/99999/type", bytes.NewBufferString(`{"type": "audiobook"}`))
+	if err != nil {
+		t.Fatalf("Could not create request: %v", err)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Fatalf("Handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+	}
+	if problem := decodeProblem(t, rr); problem.Code != CodeNotFound {
+		t.Errorf("Expected code %s, got %s", CodeNotFound, problem.Code)
+	}
+}
+
+// TestCancelledRequestProblem tests that a request the client gave up on is not logged as a success
+func TestCancelledRequestProblem(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/api/books", nil).WithContext(ctx)
+	rr := httptest.NewRecorder()
+	respondWithStoreError(rr, req, fmt.Errorf("failed to query books: %w", ctx.Err()), "Failed to retrieve books")
+
+	if rr.Code != StatusClientClosedRequest {
+		t.Fatalf("Expected status %d, got %d", StatusClientClosedRequest, rr.Code)
+	}
+	if problem := decodeProblem(t, rr); problem.Code != CodeClientClosed || problem.Title != "Client Closed Request" {
+		t.Errorf("Unexpected problem %+v", problem)
+	}
+}
+
+// TestIfMatchPreconditionFailed tests that stale If-Match versions are rejected with 412
+func TestIfMatchPreconditionFailed(t *testing.T) {
+	_, store, router := newTestAPI(t)
+	book := createTestBook(model.StatusWantToRead, "IfMatch")
+	id, err := store.AddBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+
+	update := func(ifMatch string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("PUT", "/api/books/"+itoa(id), bytes.NewBufferString(`{"status": "Read"}`))
+		if err != nil {
+			t.Fatalf("Could not create request: %v", err)
+		}
+		req.Header.Set("If-Match", ifMatch)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := update(`"1"`); rr.Code != http.StatusOK {
+		t.Fatalf("Update with current version failed: got %v, body: %s", rr.Code, rr.Body.String())
+	}
+
+	rr := update(`"1"`) // Now stale
+	if rr.Code != http.StatusPreconditionFailed {
+		t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusPreconditionFailed)
+	}
+	if problem := decodeProblem(t, rr); problem.Code != CodeConflict {
+		t.Errorf("Expected code %s, got %s", CodeConflict, problem.Code)
+	}
+
+	if rr := update("not-a-version"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for malformed If-Match, got %v", rr.Code)
+	}
+}
+
+// TestIfMatchBookParts tests that changes to a book's editions, quotes and loans are
+// versioned changes of the book, refused when stale
+func TestIfMatchBookParts(t *testing.T) {
+	_, store, router := newTestAPI(t)
+	id, err := store.AddBook(context.Background(), createTestBook(model.StatusRead, "IfMatchParts"))
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+
+	do := func(method, path, ifMatch, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("If-Match", ifMatch)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	editions := "/api/books/" + itoa(id) + "/editions"
+	rr := do(http.MethodPost, editions, `"1"`, `{"format":"audiobook"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 adding an edition to the current version, got %d %s", rr.Code, rr.Body.String())
+	}
+	var edition model.Edition
+	if err := json.Unmarshal(rr.Body.Bytes(), &edition); err != nil {
+		t.Fatalf("Could not unmarshal edition: %v", err)
+	}
+
+	// Version 1 is now stale
+	for _, tt := range []struct{ method, path, body string }{
+		{http.MethodPut, editions + "/" + itoa(edition.ID), `{"format":"ebook"}`},
+		{http.MethodDelete, editions + "/" + itoa(edition.ID), ""},
+		{http.MethodPost, "/api/books/" + itoa(id) + "/quotes", `{"text":"Call me Ishmael."}`},
+		{http.MethodPost, "/api/loans", `{"book_id":` + itoa(id) + `,"person":"Alice","direction":"lent"}`},
+	} {
+		if rr := do(tt.method, tt.path, `"1"`, tt.body); rr.Code != http.StatusPreconditionFailed {
+			t.Errorf("Expected 412 for %s %s with a stale version, got %d %s", tt.method, tt.path, rr.Code, rr.Body.String())
+		}
+	}
+	book, err := store.GetBookByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetBookByID failed: %v", err)
+	}
+	if book.Version != 2 || len(book.Editions) != 2 || book.Editions[1].Format != model.TypeAudiobook || book.Lent {
+		t.Errorf("Expected stale changes to change nothing, got %+v", book)
+	}
+
+	rr = do(http.MethodPost, "/api/loans", `"2"`, `{"book_id":`+itoa(id)+`,"person":"Alice","direction":"lent"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 lending the current version, got %d %s", rr.Code, rr.Body.String())
+	}
+	var loan model.Loan
+	if err := json.Unmarshal(rr.Body.Bytes(), &loan); err != nil {
+		t.Fatalf("Could not unmarshal loan: %v", err)
+	}
+	if rr := do(http.MethodPost, "/api/loans/"+itoa(loan.ID)+"/return", `"2"`, ""); rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 returning a loan of a book changed since, got %d %s", rr.Code, rr.Body.String())
+	}
+}
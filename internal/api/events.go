@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/events"
+	"github.com/ericdahl/bookshelf/internal/logging"
+)
+
+// eventStreamHeartbeat is how often a comment line is sent on an idle event stream, so
+// proxies don't time the connection out and dead clients are noticed.
+const eventStreamHeartbeat = 25 * time.Second
+
+// eventStreamRetry is the reconnection delay (in milliseconds) suggested to clients.
+const eventStreamRetry = 3000
+
+// resetEventType tells the client that events were missed and it should reload the shelf.
+const resetEventType = "reset"
+
+// EventsHandler handles GET /api/events requests.
+// It streams shelf changes as Server-Sent Events. Each event's id can be sent back in the
+// Last-Event-ID header (or lastEventId query parameter) on reconnect to receive the events
+// missed in between; if they are no longer available a "reset" event is sent instead.
+func (h *APIHandler) EventsHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Events == nil {
+		respondWithError(w, r, http.StatusServiceUnavailable, "Event stream is not enabled")
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+	var lastID uint64
+	if lastEventID != "" {
+		var err error
+		lastID, err = strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			respondWithError(w, r, http.StatusBadRequest, "Last-Event-ID must be an event ID")
+			return
+		}
+	}
+
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	rc := http.NewResponseController(w)
+
+	ch, replay, complete, cancel := h.Events.Subscribe(lastID)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable proxy buffering (nginx)
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", eventStreamRetry); err != nil {
+		return
+	}
+	if !complete {
+		logger.Info("Event stream resume point no longer available, sending reset", "lastEventID", lastID)
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: {}\n\n", resetEventType); err != nil {
+			return
+		}
+		replay = nil // The client reloads everything anyway
+	}
+	for _, event := range replay {
+		if err := writeEvent(w, event); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		logger.Error("Event stream does not support flushing", "error", err)
+		return
+	}
+	logger.Info("Event stream opened", "lastEventID", lastID, "replayed", len(replay))
+
+	heartbeat := time.NewTicker(eventStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Event stream closed by client")
+			return
+		case event, ok := <-ch:
+			if !ok {
+				// We fell too far behind; the client reconnects and resumes via Last-Event-ID
+				logger.Warn("Event stream subscriber dropped for falling behind")
+				return
+			}
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent writes a single event in the text/event-stream format.
+func writeEvent(w http.ResponseWriter, event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/events"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// sseEvent is a parsed text/event-stream message.
+type sseEvent struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+// readSSEEvent reads the next event (skipping comments and retry hints) from the stream.
+func readSSEEvent(t *testing.T, reader *bufio.Reader) sseEvent {
+	t.Helper()
+	var event sseEvent
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read event stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			if event.Event != "" || event.Data != "" {
+				return event
+			}
+		case strings.HasPrefix(line, "id: "):
+			event.ID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.Event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.Data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+// openEventStream connects to /api/events on server with the given Last-Event-ID.
+func openEventStream(t *testing.T, ctx context.Context, server *httptest.Server, lastEventID string) *bufio.Reader {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/events", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("Failed to open event stream: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream, got %q", ct)
+	}
+	return bufio.NewReader(resp.Body)
+}
+
+func TestEventsHandler(t *testing.T) {
+	handler, store, router := newTestAPI(t)
+	bus := events.NewBus(10)
+	handler.Events = bus
+	store.Events = bus
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream := openEventStream(t, ctx, server, "")
+
+	book := &model.Book{Title: "Live Book", Author: "Author", OpenLibraryID: "OL1LIVE", Status: model.StatusWantToRead, Type: model.TypeBook}
+	id, err := store.AddBook(ctx, book)
+	if err != nil {
+		t.Fatalf("Failed to add book: %v", err)
+	}
+	if err := store.UpdateBookStatus(ctx, id, model.StatusRead); err != nil {
+		t.Fatalf("Failed to update book: %v", err)
+	}
+
+	created := readSSEEvent(t, stream)
+	if created.Event != string(events.BookCreated) || created.ID != "1" {
+		t.Errorf("Expected book.created with id 1, got %+v", created)
+	}
+	updated := readSSEEvent(t, stream)
+	if updated.Event != string(events.BookUpdated) || updated.ID != "2" {
+		t.Errorf("Expected book.updated with id 2, got %+v", updated)
+	}
+	var payload events.Event
+	if err := json.Unmarshal([]byte(updated.Data), &payload); err != nil {
+		t.Fatalf("Failed to decode event data: %v", err)
+	}
+	if payload.BookID != id || payload.Book == nil || payload.Book.Status != model.StatusRead {
+		t.Errorf("Unexpected event payload: %+v", payload)
+	}
+
+	// Resuming after event 1 replays event 2
+	resumed := openEventStream(t, ctx, server, "1")
+	if replayed := readSSEEvent(t, resumed); replayed.ID != "2" {
+		t.Errorf("Expected replay of event 2, got %+v", replayed)
+	}
+
+	// An unknown resume point asks the client to reload
+	reset := openEventStream(t, ctx, server, "99")
+	if event := readSSEEvent(t, reset); event.Event != resetEventType {
+		t.Errorf("Expected reset event, got %+v", event)
+	}
+}
+
+func TestEventsHandlerErrors(t *testing.T) {
+	handler, _, router := newTestAPI(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/events", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without an event bus, got %d", rr.Code)
+	}
+
+	handler.Events = events.NewBus(10)
+	req = httptest.NewRequest(http.MethodGet, "/api/events", nil)
+	req.Header.Set("Last-Event-ID", "not-a-number")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for malformed Last-Event-ID, got %d", rr.Code)
+	}
+}
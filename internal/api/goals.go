@@ -0,0 +1,122 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// goalYear parses the {year} route variable, responding 400 if it is malformed.
+func goalYear(w http.ResponseWriter, r *http.Request) (int, bool) {
+	year, err := strconv.Atoi(mux.Vars(r)["year"])
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid year")
+		return 0, false
+	}
+	return year, true
+}
+
+// goalProgress computes the progress of each goal as of now.
+func (h *APIHandler) goalProgress(r *http.Request, goals []model.Goal) ([]model.GoalProgress, error) {
+	now := time.Now().UTC()
+	progress := make([]model.GoalProgress, 0, len(goals))
+	for _, goal := range goals {
+		finished, err := h.Goals.CountFinished(r.Context(), goal.Year, goal.Type)
+		if err != nil {
+			return nil, err
+		}
+		progress = append(progress, goal.Progress(finished, now))
+	}
+	return progress, nil
+}
+
+// GetGoalsHandler handles GET /api/goals and GET /api/goals/{year} requests.
+// Returns every goal (or those of one year) with its progress.
+func (h *APIHandler) GetGoalsHandler(w http.ResponseWriter, r *http.Request) {
+	year := 0
+	if _, ok := mux.Vars(r)["year"]; ok {
+		if year, ok = goalYear(w, r); !ok {
+			return
+		}
+	}
+
+	goals, err := h.Goals.GetGoals(r.Context(), year)
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to retrieve goals")
+		return
+	}
+	progress, err := h.goalProgress(r, goals)
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to compute goal progress")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, progress)
+}
+
+// SetGoalHandler handles PUT /api/goals/{year} requests.
+// Expects {"target": 30} for all books, or {"target": 10, "type": "audiobook"} for one type.
+// Creates or replaces the goal and returns its progress.
+func (h *APIHandler) SetGoalHandler(w http.ResponseWriter, r *http.Request) {
+	year, ok := goalYear(w, r)
+	if !ok {
+		return
+	}
+
+	var payload struct {
+		Target int            `json:"target"`
+		Type   model.BookType `json:"type"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	goal := model.Goal{Year: year, Type: payload.Type, Target: payload.Target}
+	if err := goal.Validate(); err != nil {
+		var validationErr *model.ValidationError
+		if errors.As(err, &validationErr) {
+			respondWithError(w, r, http.StatusBadRequest, validationErr.Message)
+		} else {
+			respondWithError(w, r, http.StatusBadRequest, "Invalid goal: "+err.Error())
+		}
+		return
+	}
+
+	if err := h.Goals.SetGoal(r.Context(), &goal); err != nil {
+		respondWithStoreError(w, r, err, "Failed to save goal")
+		return
+	}
+	progress, err := h.goalProgress(r, []model.Goal{goal})
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to compute goal progress")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, progress[0])
+}
+
+// DeleteGoalHandler handles DELETE /api/goals/{year} requests.
+// Deletes the all-types goal, or the goal for ?type= if given.
+func (h *APIHandler) DeleteGoalHandler(w http.ResponseWriter, r *http.Request) {
+	year, ok := goalYear(w, r)
+	if !ok {
+		return
+	}
+	bookType := model.BookType(r.URL.Query().Get("type"))
+	if bookType != "" && !bookType.IsValid() {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid type, must be 'book', 'ebook' or 'audiobook'")
+		return
+	}
+
+	if err := h.Goals.DeleteGoal(r.Context(), year, bookType); err != nil {
+		respondWithStoreError(w, r, err, "Failed to delete goal")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
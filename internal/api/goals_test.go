@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestGoalHandlers(t *testing.T) {
+	_, store, router := newTestAPI(t)
+	ctx := context.Background()
+	year := strconv.Itoa(time.Now().UTC().Year())
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if _, err := store.AddBook(ctx, &model.Book{Title: "Done", Author: "A", OpenLibraryID: "OL1GOAL", Status: model.StatusRead}); err != nil {
+		t.Fatalf("Failed to add book: %v", err)
+	}
+
+	rr := do(http.MethodPut, "/api/goals/"+year, `{"target": 2}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var progress model.GoalProgress
+	if err := json.Unmarshal(rr.Body.Bytes(), &progress); err != nil {
+		t.Fatalf("Failed to decode progress: %v", err)
+	}
+	if progress.Finished != 1 || progress.Remaining != 1 || progress.PercentComplete != 50 {
+		t.Errorf("Expected 1 of 2 books finished, got %+v", progress)
+	}
+
+	if rr = do(http.MethodPut, "/api/goals/"+year, `{"target": 3, "type": "audiobook"}`); rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for audiobook goal, got %d", rr.Code)
+	}
+
+	rr = do(http.MethodGet, "/api/goals", "")
+	var all []model.GoalProgress
+	if err := json.Unmarshal(rr.Body.Bytes(), &all); err != nil {
+		t.Fatalf("Failed to decode goals: %v", err)
+	}
+	if len(all) != 2 || all[1].Type != model.TypeAudiobook || all[1].Finished != 0 {
+		t.Errorf("Expected overall and audiobook goals, got %+v", all)
+	}
+
+	for _, body := range []string{`{"target": 0}`, `{"target": 5, "type": "scroll"}`, `{"goal": 5}`} {
+		if rr := do(http.MethodPut, "/api/goals/"+year, body); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, rr.Code)
+		}
+	}
+
+	if rr = do(http.MethodDelete, "/api/goals/"+year+"?type=audiobook", ""); rr.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 on delete, got %d", rr.Code)
+	}
+	if rr = do(http.MethodDelete, "/api/goals/"+year+"?type=audiobook", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 deleting twice, got %d", rr.Code)
+	}
+	rr = do(http.MethodGet, "/api/goals/"+year, "")
+	if err := json.Unmarshal(rr.Body.Bytes(), &all); err != nil || len(all) != 1 {
+		t.Errorf("Expected one goal left, got %s", rr.Body.String())
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,31 +15,89 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/events"
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/metrics"
 	"github.com/ericdahl/bookshelf/internal/model"
 )
 
+// defaultOpenLibraryBaseURL is the metadata provider used unless overridden (e.g. in tests).
+const defaultOpenLibraryBaseURL = "https://openlibrary.org"
+
+// userAgent identifies us to Open Library. Be a good API citizen.
+const userAgent = "BookshelfApp/1.0 (github.com/ericdahl/bookshelf; contact@example.com)"
+
 // APIHandler holds dependencies for API handlers, like the database store.
 type APIHandler struct {
-	Store      db.BookStore
-	HTTPClient *http.Client // For Open Library calls
+	Store           db.BookStore
+	Webhooks        db.WebhookStore        // Webhook subscriptions and delivery log
+	Stats           db.StatsStore          // Reading statistics
+	Goals           db.GoalStore           // Yearly reading goals
+	Authors         db.AuthorStore         // Normalized authors and their credits
+	Series          db.SeriesStore         // Series entities and completion
+	Queue           db.QueueStore          // Manual shelf order and the reading queue
+	Bulk            db.BulkStore           // Bulk changes in one transaction
+	Trash           db.TrashStore          // Deleted books, until restored or purged
+	Audit           db.AuditStore          // Who changed which book, and how
+	Undo            db.UndoStore           // Undo and redo of each session's recent changes
+	Quotes          db.QuoteStore          // Quotes and highlights from books
+	Editions        db.EditionStore        // The editions owned of each book
+	Loans           db.LoanStore           // Books lent to and borrowed from other people
+	Ownership       db.OwnershipStore      // Whether books are owned, and the inventory of the collection
+	Duplicates      db.DuplicateStore      // Books entered more than once, and merging them
+	Recommendations db.RecommendationStore // Cached Open Library metadata and the recommendations made from it
+	HTTPClient      *http.Client           // For Open Library calls
+	DB              *sql.DB                // Raw connection, used by readiness checks
+	Events          *events.Bus            // Shelf change events, streamed by /api/events
+
+	// OpenLibraryBaseURL is the base URL of the Open Library API (metadata provider).
+	OpenLibraryBaseURL string
+	// CheckMetadataProvider makes /readyz also verify Open Library is reachable.
+	CheckMetadataProvider bool
+	// AccessLogFormat selects the access log format used by the router (structured by default).
+	AccessLogFormat AccessLogFormat
 }
 
 // NewAPIHandler creates a new APIHandler with dependencies.
+// A *db.SQLiteBookStore implements every store, so it also serves as all of the other stores
+// and provides the raw connection; other stores (e.g. test mocks) only set Store.
 func NewAPIHandler(store db.BookStore) *APIHandler {
-	return &APIHandler{
+	h := &APIHandler{
 		Store: store,
 		HTTPClient: &http.Client{
 			Timeout: 10 * time.Second, // Sensible timeout for external API calls
 		},
-	}
+		OpenLibraryBaseURL: defaultOpenLibraryBaseURL,
+		AccessLogFormat:    AccessLogStructured,
+	}
+	if sqlite, ok := store.(*db.SQLiteBookStore); ok {
+		h.DB = sqlite.DB
+		h.Webhooks = sqlite
+		h.Stats = sqlite
+		h.Goals = sqlite
+		h.Authors = sqlite
+		h.Series = sqlite
+		h.Queue = sqlite
+		h.Bulk = sqlite
+		h.Trash = sqlite
+		h.Audit = sqlite
+		h.Undo = sqlite
+		h.Quotes = sqlite
+		h.Editions = sqlite
+		h.Loans = sqlite
+		h.Ownership = sqlite
+		h.Duplicates = sqlite
+		h.Recommendations = sqlite
+	}
+	return h
 }
 
 // --- Helper Functions ---
 
-// respondWithError sends a JSON error response.
-func respondWithError(w http.ResponseWriter, code int, message string) {
-	slog.Error("HTTP Error", "code", code, "message", message)
-	respondWithJSON(w, code, map[string]string{"error": message})
+// respondWithError sends an RFC 7807 problem+json error response, with the error code
+// derived from the status code.
+func respondWithError(w http.ResponseWriter, r *http.Request, code int, message string) {
+	respondWithProblem(w, r, code, codeForStatus(code), message)
 }
 
 // respondWithJSON sends a JSON response.
@@ -61,10 +120,16 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 // --- Book Handlers ---
 
 // GetBooksHandler handles GET /api/books requests.
+// Optional query parameters filter and sort the list (see parseListOptions).
 func (h *APIHandler) GetBooksHandler(w http.ResponseWriter, r *http.Request) {
-	books, err := h.Store.GetBooks()
+	opts, err := parseListOptions(r.URL.Query())
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	books, err := h.Store.ListBooks(r.Context(), opts)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve books: "+err.Error())
+		respondWithStoreError(w, r, err, "Failed to retrieve books")
 		return
 	}
 	if books == nil {
@@ -73,6 +138,100 @@ func (h *APIHandler) GetBooksHandler(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, books)
 }
 
+// parseListOptions reads the filter and sort parameters of GET /api/books:
+// status, type, tag, ownership, store, room, shelf, sort, order (asc or desc), and the date
+// ranges added_from, added_before, started_from, started_before, finished_from and finished_before.
+func parseListOptions(query url.Values) (db.ListOptions, error) {
+	opts := db.ListOptions{
+		Status: model.BookStatus(query.Get("status")),
+		Type:   model.BookType(query.Get("type")),
+		SortBy: query.Get("sort"),
+		Tag:    strings.TrimSpace(query.Get("tag")),
+
+		Ownership: model.OwnershipStatus(query.Get("ownership")),
+		Store:     strings.TrimSpace(query.Get("store")),
+		Room:      strings.TrimSpace(query.Get("room")),
+		Shelf:     strings.TrimSpace(query.Get("shelf")),
+	}
+	if opts.Status != "" && !opts.Status.IsValid() {
+		return opts, fmt.Errorf("Invalid status %q", opts.Status)
+	}
+	if opts.Type != "" && !opts.Type.IsValid() {
+		return opts, fmt.Errorf("Invalid type %q", opts.Type)
+	}
+	if opts.Ownership != "" && !opts.Ownership.IsValid() {
+		return opts, fmt.Errorf("Invalid ownership %q", opts.Ownership)
+	}
+	if opts.SortBy != "" && !db.IsSortField(opts.SortBy) {
+		return opts, fmt.Errorf("Invalid sort field %q", opts.SortBy)
+	}
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		opts.Descending = true
+	default:
+		return opts, fmt.Errorf("Invalid order %q, must be 'asc' or 'desc'", query.Get("order"))
+	}
+
+	for param, dst := range map[string]**time.Time{
+		"added_from":      &opts.AddedFrom,
+		"added_before":    &opts.AddedBefore,
+		"started_from":    &opts.StartedFrom,
+		"started_before":  &opts.StartedBefore,
+		"finished_from":   &opts.FinishedFrom,
+		"finished_before": &opts.FinishedBefore,
+	} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		t, err := parseDate(value)
+		if err != nil {
+			return opts, fmt.Errorf("Invalid %s: %v", param, err)
+		}
+		*dst = &t
+	}
+	return opts, nil
+}
+
+// parseDate accepts a calendar date (2006-01-02, taken as midnight UTC) or an RFC 3339 timestamp.
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected YYYY-MM-DD or an RFC 3339 timestamp, got %q", value)
+	}
+	return t.UTC(), nil
+}
+
+// optionalDate is a date in a JSON payload that distinguishes an omitted field (Set is false)
+// from an explicit null (Set is true, Time is nil).
+type optionalDate struct {
+	Set  bool
+	Time *time.Time
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It is only called when the field is present.
+func (d *optionalDate) UnmarshalJSON(data []byte) error {
+	d.Set = true
+	if string(data) == "null" {
+		d.Time = nil
+		return nil
+	}
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("date must be a string: %w", err)
+	}
+	t, err := parseDate(value)
+	if err != nil {
+		return err
+	}
+	d.Time = &t
+	return nil
+}
+
 // AddBookHandler handles POST /api/books requests.
 // Expects JSON body based on Open Library search result selection.
 func (h *APIHandler) AddBookHandler(w http.ResponseWriter, r *http.Request) {
@@ -92,32 +251,32 @@ func (h *APIHandler) AddBookHandler(w http.ResponseWriter, r *http.Request) {
 		switch {
 		case errors.As(err, &syntaxError):
 			msg := fmt.Sprintf("Request body contains badly-formed JSON (at position %d)", syntaxError.Offset)
-			respondWithError(w, http.StatusBadRequest, msg)
+			respondWithError(w, r, http.StatusBadRequest, msg)
 		case errors.Is(err, io.ErrUnexpectedEOF):
 			msg := "Request body contains badly-formed JSON"
-			respondWithError(w, http.StatusBadRequest, msg)
+			respondWithError(w, r, http.StatusBadRequest, msg)
 		case errors.As(err, &unmarshalTypeError):
 			msg := fmt.Sprintf("Request body contains an invalid value for the %q field (at position %d)", unmarshalTypeError.Field, unmarshalTypeError.Offset)
-			respondWithError(w, http.StatusBadRequest, msg)
+			respondWithError(w, r, http.StatusBadRequest, msg)
 		case strings.HasPrefix(err.Error(), "json: unknown field "):
 			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
 			msg := fmt.Sprintf("Request body contains unknown field %s", fieldName)
-			respondWithError(w, http.StatusBadRequest, msg)
+			respondWithError(w, r, http.StatusBadRequest, msg)
 		case errors.Is(err, io.EOF):
 			msg := "Request body must not be empty"
-			respondWithError(w, http.StatusBadRequest, msg)
+			respondWithError(w, r, http.StatusBadRequest, msg)
 		case errors.As(err, &maxBytesError):
 			msg := fmt.Sprintf("Request body must not be larger than %d bytes", maxBytesError.Limit)
-			respondWithError(w, http.StatusRequestEntityTooLarge, msg)
+			respondWithError(w, r, http.StatusRequestEntityTooLarge, msg)
 		default:
-			respondWithError(w, http.StatusBadRequest, "Failed to decode request body: "+err.Error())
+			respondWithError(w, r, http.StatusBadRequest, "Failed to decode request body: "+err.Error())
 		}
 		return
 	}
 
 	// Basic validation for required fields from search result
 	if book.Title == "" || book.OpenLibraryID == "" {
-		respondWithError(w, http.StatusBadRequest, "Missing required fields: title and open_library_id")
+		respondWithError(w, r, http.StatusBadRequest, "Missing required fields: title and open_library_id")
 		return
 	}
 	// Author is highly recommended but might be missing in some OL entries
@@ -143,18 +302,17 @@ func (h *APIHandler) AddBookHandler(w http.ResponseWriter, r *http.Request) {
 	if err := book.Validate(); err != nil {
 		var validationErr *model.ValidationError
 		if errors.As(err, &validationErr) {
-			respondWithError(w, http.StatusBadRequest, validationErr.Message)
+			respondWithError(w, r, http.StatusBadRequest, validationErr.Message)
 		} else {
-			respondWithError(w, http.StatusBadRequest, "Invalid book data: "+err.Error())
+			respondWithError(w, r, http.StatusBadRequest, "Invalid book data: "+err.Error())
 		}
 		return
 	}
 
 	// Add the book to the database
-	newID, err := h.Store.AddBook(&book)
+	newID, err := h.Store.AddBook(r.Context(), &book)
 	if err != nil {
-		// TODO: Check for specific DB errors like UNIQUE constraint violation
-		respondWithError(w, http.StatusInternalServerError, "Failed to add book to database: "+err.Error())
+		respondWithStoreError(w, r, err, "Failed to add book to database")
 		return
 	}
 
@@ -167,12 +325,16 @@ func (h *APIHandler) UpdateBookStatusHandler(w http.ResponseWriter, r *http.Requ
 	vars := mux.Vars(r)
 	idStr, ok := vars["id"]
 	if !ok {
-		respondWithError(w, http.StatusBadRequest, "Missing book ID")
+		respondWithError(w, r, http.StatusBadRequest, "Missing book ID")
 		return
 	}
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		respondWithError(w, r, http.StatusBadRequest, "Invalid book ID format")
+		return
+	}
+	r, ok = withIfMatch(w, r)
+	if !ok {
 		return
 	}
 
@@ -184,23 +346,18 @@ func (h *APIHandler) UpdateBookStatusHandler(w http.ResponseWriter, r *http.Requ
 	decoder.DisallowUnknownFields()
 	if err := decoder.Decode(&payload); err != nil {
 		// Basic error handling, can be expanded like in AddBookHandler
-		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body: "+err.Error())
 		return
 	}
 
 	if !payload.Status.IsValid() {
-		respondWithError(w, http.StatusBadRequest, "Invalid status value. Must be 'Want to Read', 'Currently Reading', or 'Read'")
+		respondWithError(w, r, http.StatusBadRequest, "Invalid status value. Must be 'Want to Read', 'Currently Reading', or 'Read'")
 		return
 	}
 
-	err = h.Store.UpdateBookStatus(id, payload.Status)
+	err = h.Store.UpdateBookStatus(r.Context(), id, payload.Status)
 	if err != nil {
-		// TODO: Differentiate between Not Found (404) and other errors (500)
-		if strings.Contains(err.Error(), "not found") { // Basic check, better to use custom errors
-			respondWithError(w, http.StatusNotFound, err.Error())
-		} else {
-			respondWithError(w, http.StatusInternalServerError, "Failed to update book status: "+err.Error())
-		}
+		respondWithStoreError(w, r, err, "Failed to update book status")
 		return
 	}
 
@@ -212,12 +369,16 @@ func (h *APIHandler) UpdateBookTypeHandler(w http.ResponseWriter, r *http.Reques
 	vars := mux.Vars(r)
 	idStr, ok := vars["id"]
 	if !ok {
-		respondWithError(w, http.StatusBadRequest, "Missing book ID")
+		respondWithError(w, r, http.StatusBadRequest, "Missing book ID")
 		return
 	}
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		respondWithError(w, r, http.StatusBadRequest, "Invalid book ID format")
+		return
+	}
+	r, ok = withIfMatch(w, r)
+	if !ok {
 		return
 	}
 
@@ -228,48 +389,53 @@ func (h *APIHandler) UpdateBookTypeHandler(w http.ResponseWriter, r *http.Reques
 	decoder := json.NewDecoder(r.Body)
 	decoder.DisallowUnknownFields()
 	if err := decoder.Decode(&payload); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body: "+err.Error())
 		return
 	}
 
 	if !payload.Type.IsValid() {
-		respondWithError(w, http.StatusBadRequest, "Invalid type value. Must be 'book' or 'audiobook'")
+		respondWithError(w, r, http.StatusBadRequest, "Invalid type value. Must be 'book', 'ebook' or 'audiobook'")
 		return
 	}
 
-	err = h.Store.UpdateBookType(id, payload.Type)
+	err = h.Store.UpdateBookType(r.Context(), id, payload.Type)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			respondWithError(w, http.StatusNotFound, err.Error())
-		} else {
-			respondWithError(w, http.StatusInternalServerError, "Failed to update book type: "+err.Error())
-		}
+		respondWithStoreError(w, r, err, "Failed to update book type")
 		return
 	}
 
 	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Book type updated successfully"})
 }
 
-// UpdateBookDetailsHandler handles PUT /api/books/{id}/details requests (for rating, comments, and series info).
+// UpdateBookDetailsHandler handles PUT /api/books/{id}/details requests (for rating, comments, series info,
+// type, and the dates the book was added, started and finished).
 func (h *APIHandler) UpdateBookDetailsHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr, ok := vars["id"]
 	if !ok {
-		respondWithError(w, http.StatusBadRequest, "Missing book ID")
+		respondWithError(w, r, http.StatusBadRequest, "Missing book ID")
 		return
 	}
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid book ID format")
+		respondWithError(w, r, http.StatusBadRequest, "Invalid book ID format")
+		return
+	}
+	r, ok = withIfMatch(w, r)
+	if !ok {
 		return
 	}
 
 	// Use pointers in the payload struct to detect if a field was provided (even if null)
 	var payload struct {
-		Rating      *int    `json:"rating"`        // Pointer allows distinguishing between 0 and not provided/null
-		Comments    *string `json:"comments"`      // Pointer allows distinguishing between "" and not provided/null
-		Series      *string `json:"series"`        // Name of series
-		SeriesIndex *int    `json:"series_index"` // Position in series
+		Rating       *int            `json:"rating"`       // Pointer allows distinguishing between 0 and not provided/null
+		Comments     *string         `json:"comments"`     // Pointer allows distinguishing between "" and not provided/null
+		Series       *string         `json:"series"`       // Name of series
+		SeriesIndex  *int            `json:"series_index"` // Position in series
+		Type         *model.BookType `json:"type"`         // Nil keeps the current type
+		DateAdded    optionalDate    `json:"date_added"`   // Omitted or null keeps the current date
+		DateStarted  optionalDate    `json:"date_started"`
+		DateFinished optionalDate    `json:"date_finished"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -278,25 +444,25 @@ func (h *APIHandler) UpdateBookDetailsHandler(w http.ResponseWriter, r *http.Req
 	err = decoder.Decode(&payload)
 	if err != nil {
 		// Basic error handling
-		respondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body: "+err.Error())
 		return
 	}
 
 	// Validate rating if provided
 	if payload.Rating != nil && (*payload.Rating < 1 || *payload.Rating > 10) {
-		respondWithError(w, http.StatusBadRequest, "Rating must be between 1 and 10")
+		respondWithError(w, r, http.StatusBadRequest, "Rating must be between 1 and 10")
 		return
 	}
 	
 	// Validate series index if provided
 	if payload.SeriesIndex != nil && *payload.SeriesIndex <= 0 {
-		respondWithError(w, http.StatusBadRequest, "Series index must be greater than 0")
+		respondWithError(w, r, http.StatusBadRequest, "Series index must be greater than 0")
 		return
 	}
 	
 	// If series is null/empty but series_index is provided, return error
 	if (payload.Series == nil || *payload.Series == "") && payload.SeriesIndex != nil {
-		respondWithError(w, http.StatusBadRequest, "Cannot provide series_index without series name")
+		respondWithError(w, r, http.StatusBadRequest, "Cannot provide series_index without series name")
 		return
 	}
 
@@ -306,13 +472,9 @@ func (h *APIHandler) UpdateBookDetailsHandler(w http.ResponseWriter, r *http.Req
 	   payload.Rating == nil && payload.Comments != nil ||
 	   payload.Series != nil && (payload.SeriesIndex == nil && !(*payload.Series == "")) {
 		var err error
-		existingBook, err = h.Store.GetBookByID(id)
+		existingBook, err = h.Store.GetBookByID(r.Context(), id)
 		if err != nil {
-			if strings.Contains(err.Error(), "not found") {
-				respondWithError(w, http.StatusNotFound, err.Error())
-			} else {
-				respondWithError(w, http.StatusInternalServerError, "Failed to retrieve book: "+err.Error())
-			}
+			respondWithStoreError(w, r, err, "Failed to retrieve book")
 			return
 		}
 	}
@@ -331,14 +493,39 @@ func (h *APIHandler) UpdateBookDetailsHandler(w http.ResponseWriter, r *http.Req
 		payload.SeriesIndex = existingBook.SeriesIndex
 	}
 
+	details := model.BookDetails{
+		Rating:       payload.Rating,
+		Comments:     payload.Comments,
+		Series:       payload.Series,
+		SeriesIndex:  payload.SeriesIndex,
+		Type:         payload.Type,
+		DateAdded:    payload.DateAdded.Time,
+		DateStarted:  payload.DateStarted.Time,
+		DateFinished: payload.DateFinished.Time,
+		// Dates that were left out keep their current values; an explicit null clears them
+		ClearDateStarted:  payload.DateStarted.Set && payload.DateStarted.Time == nil,
+		ClearDateFinished: payload.DateFinished.Set && payload.DateFinished.Time == nil,
+	}
+
+	if err := details.Validate(); err != nil {
+		var validationErr *model.ValidationError
+		if errors.As(err, &validationErr) {
+			respondWithError(w, r, http.StatusBadRequest, validationErr.Message)
+		} else {
+			respondWithError(w, r, http.StatusBadRequest, "Invalid book details: "+err.Error())
+		}
+		return
+	}
+
 	// Perform the update
-	err = h.Store.UpdateBookDetails(id, payload.Rating, payload.Comments, payload.Series, payload.SeriesIndex)
+	err = h.Store.UpdateBookDetails(r.Context(), id, details)
 	if err != nil {
-		// Differentiate between Not Found (404) and other errors (500)
-		if strings.Contains(err.Error(), "not found") { // Basic check
-			respondWithError(w, http.StatusNotFound, err.Error())
+		var validationErr *model.ValidationError
+		if errors.As(err, &validationErr) {
+			// e.g. a start date after the finish date already on the book
+			respondWithError(w, r, http.StatusBadRequest, validationErr.Message)
 		} else {
-			respondWithError(w, http.StatusInternalServerError, "Failed to update book details: "+err.Error())
+			respondWithStoreError(w, r, err, "Failed to update book details")
 		}
 		return
 	}
@@ -346,24 +533,23 @@ func (h *APIHandler) UpdateBookDetailsHandler(w http.ResponseWriter, r *http.Req
 	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Book details updated successfully"})
 }
 
-// DeleteBookHandler handles the deletion of a book
+// DeleteBookHandler handles the deletion of a book. The book goes to the trash, from where
+// it can be restored until it is purged.
 func (h *APIHandler) DeleteBookHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.ParseInt(vars["id"], 10, 64)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid book ID")
+		respondWithError(w, r, http.StatusBadRequest, "Invalid book ID")
+		return
+	}
+	r, ok := withIfMatch(w, r)
+	if !ok {
 		return
 	}
 
-	err = h.Store.DeleteBook(id)
+	err = h.Store.DeleteBook(r.Context(), id)
 	if err != nil {
-		slog.Error("Error deleting book", "error", err, "id", id)
-		// Check if book not found
-		if strings.Contains(err.Error(), "not found") {
-			respondWithError(w, http.StatusNotFound, err.Error())
-		} else {
-			respondWithError(w, http.StatusInternalServerError, "Failed to delete book")
-		}
+		respondWithStoreError(w, r, err, "Failed to delete book")
 		return
 	}
 
@@ -374,86 +560,99 @@ func (h *APIHandler) DeleteBookHandler(w http.ResponseWriter, r *http.Request) {
 
 // OpenLibrarySearchResult defines the structure we want to return from our search endpoint.
 type OpenLibrarySearchResult struct {
-	OpenLibraryID string  `json:"open_library_id"` // e.g., OL7353617M
-	Title         string  `json:"title"`
-	Author        string  `json:"author"`              // Combined author names
-	ISBN          *string `json:"isbn,omitempty"`      // First available ISBN-13 or ISBN-10
-	CoverURL      *string `json:"cover_url,omitempty"` // URL for medium cover
+	OpenLibraryID string              `json:"open_library_id"` // e.g., OL7353617M
+	Title         string              `json:"title"`
+	Author        string              `json:"author"`              // Combined author names
+	Authors       []model.Contributor `json:"authors,omitempty"`   // Each author with their Open Library key
+	ISBN          *string             `json:"isbn,omitempty"`      // First available ISBN-13 or ISBN-10
+	CoverURL      *string             `json:"cover_url,omitempty"` // URL for medium cover
 	// Fields to identify if book already exists in library
-	ExistingID    *int64       `json:"existing_id,omitempty"`    // ID if book already in library
-	ExistingShelf *string      `json:"existing_shelf,omitempty"` // Shelf name if already in library
+	ExistingID    *int64  `json:"existing_id,omitempty"`    // ID if book already in library
+	ExistingShelf *string `json:"existing_shelf,omitempty"` // Shelf name if already in library
 }
 
 // openLibrarySearchResponse is the structure matching the Open Library Search API JSON response.
 // We only map the fields we need. See: https://openlibrary.org/dev/docs/api/search
 type openLibrarySearchResponse struct {
-	NumFound int `json:"numFound"`
-	Docs     []struct {
-		Key              string   `json:"key"` // e.g., "/works/OL7353617M"
-		Title            string   `json:"title"`
-		AuthorName       []string `json:"author_name"` // Array of author names
-		ISBN             []string `json:"isbn"`        // Array of ISBNs (10 and 13)
-		CoverI           int      `json:"cover_i"`     // Cover ID (integer)
-		AuthorKey        []string `json:"author_key"`  // Array of author IDs
-		FirstPublishYear int      `json:"first_publish_year"`
-	} `json:"docs"`
+	NumFound int                    `json:"numFound"`
+	Docs     []openLibrarySearchDoc `json:"docs"`
+}
+
+// openLibrarySearchDoc is one work in an Open Library search response.
+type openLibrarySearchDoc struct {
+	Key              string   `json:"key"` // e.g., "/works/OL7353617M"
+	Title            string   `json:"title"`
+	AuthorName       []string `json:"author_name"` // Array of author names
+	ISBN             []string `json:"isbn"`        // Array of ISBNs (10 and 13)
+	CoverI           int      `json:"cover_i"`     // Cover ID (integer)
+	AuthorKey        []string `json:"author_key"`  // Array of author IDs
+	FirstPublishYear int      `json:"first_publish_year"`
+	Subject          []string `json:"subject"` // Only if requested in fields
 }
 
 // SearchBooksHandler handles GET /api/search?q={query}
 func (h *APIHandler) SearchBooksHandler(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("q")
 	if query == "" {
-		respondWithError(w, http.StatusBadRequest, "Missing search query parameter 'q'")
+		respondWithError(w, r, http.StatusBadRequest, "Missing search query parameter 'q'")
 		return
 	}
 
 	// Construct Open Library API URL
 	// Using the works search endpoint as it often has better consolidated data
-	apiURL := fmt.Sprintf("https://openlibrary.org/search.json?q=%s&fields=key,title,author_name,isbn,cover_i,author_key,first_publish_year&limit=20", url.QueryEscape(query))
-	slog.Info("Querying Open Library", "url", apiURL)
+	apiURL := fmt.Sprintf("%s/search.json?q=%s&fields=key,title,author_name,isbn,cover_i,author_key,first_publish_year&limit=20", h.OpenLibraryBaseURL, url.QueryEscape(query))
+	logger := logging.FromContext(r.Context())
+	logger.Info("Querying Open Library", "url", apiURL)
 
 	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, apiURL, nil)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to create Open Library request: "+err.Error())
+		respondWithError(w, r, http.StatusInternalServerError, "Failed to create Open Library request: "+err.Error())
 		return
 	}
 	req.Header.Set("Accept", "application/json")
-	req.Header.Set("User-Agent", "BookshelfApp/1.0 (github.com/ericdahl/bookshelf; contact@example.com)") // Be a good API citizen
+	req.Header.Set("User-Agent", userAgent)
+	if requestID := logging.RequestID(r.Context()); requestID != "" {
+		req.Header.Set(logging.RequestIDHeader, requestID) // Propagate for upstream correlation
+	}
 
 	start := time.Now()
 	resp, err := h.HTTPClient.Do(req)
 	elapsed := time.Since(start)
+	metrics.OpenLibraryRequestDuration.WithLabelValues("search").Observe(elapsed.Seconds())
 	if resp != nil {
-		slog.Info("OpenLibrary API response", 
+		logger.Info("OpenLibrary API response", 
 			"url", apiURL, 
 			"status", resp.StatusCode, 
 			"responseTime", elapsed)
 	}
 
 	if err != nil {
-		respondWithError(w, http.StatusBadGateway, "Failed to contact Open Library API: "+err.Error())
+		metrics.OpenLibraryErrorsTotal.WithLabelValues("search", "transport").Inc()
+		respondWithError(w, r, http.StatusBadGateway, "Failed to contact Open Library API: "+err.Error())
 		return
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
+		metrics.OpenLibraryErrorsTotal.WithLabelValues("search", "status").Inc()
 		bodyBytes, _ := io.ReadAll(resp.Body) // Read body for context, ignore error
 		errMsg := fmt.Sprintf("Open Library API returned status %d: %s", resp.StatusCode, string(bodyBytes))
-		respondWithError(w, http.StatusBadGateway, errMsg)
+		respondWithError(w, r, http.StatusBadGateway, errMsg)
 		return
 	}
 
 	// Decode the response
 	var olResponse openLibrarySearchResponse
 	if err := json.NewDecoder(resp.Body).Decode(&olResponse); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to decode Open Library response: "+err.Error())
+		metrics.OpenLibraryErrorsTotal.WithLabelValues("search", "decode").Inc()
+		respondWithError(w, r, http.StatusInternalServerError, "Failed to decode Open Library response: "+err.Error())
 		return
 	}
 
 	// Get all existing books and create a map for quick lookup
-	existingBooks, err := h.Store.GetBooks()
+	existingBooks, err := h.Store.GetBooks(r.Context())
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve existing books: "+err.Error())
+		respondWithError(w, r, http.StatusInternalServerError, "Failed to retrieve existing books: "+err.Error())
 		return
 	}
 
@@ -464,9 +663,9 @@ func (h *APIHandler) SearchBooksHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Also check for books in the local database matching the search query
-	booksInDB, err := h.Store.GetBooks()
+	booksInDB, err := h.Store.GetBooks(r.Context())
 	if err != nil {
-		slog.Error("Error retrieving books from database for search", "error", err)
+		logger.Error("Error retrieving books from database for search", "error", err)
 		// Continue with API results only
 	} else {
 		// Filter books that match the search query in title or author
@@ -492,6 +691,7 @@ func (h *APIHandler) SearchBooksHandler(w http.ResponseWriter, r *http.Request)
 				OpenLibraryID: id,
 				Title:         book.Title,
 				Author:        book.Author,
+				Authors:       book.Authors,
 				ISBN:          &book.ISBN,
 				CoverURL:      book.CoverURL,
 				ExistingID:    &book.ID,
@@ -541,6 +741,7 @@ func (h *APIHandler) SearchBooksHandler(w http.ResponseWriter, r *http.Request)
 			OpenLibraryID: olid,
 			Title:         doc.Title,
 			Author:        strings.Join(doc.AuthorName, ", "), // Combine authors
+			Authors:       model.OpenLibraryContributors(doc.AuthorName, doc.AuthorKey),
 			ISBN:          isbn,
 			CoverURL:      coverURL,
 		}
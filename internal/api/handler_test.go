@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"bytes"
 	"database/sql"
 	"encoding/json"
@@ -9,13 +10,18 @@ import (
 	"net/http/httptest"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/metrics"
 	"github.com/ericdahl/bookshelf/internal/model"
 	"github.com/gorilla/mux"
 	"github.com/klauspost/compress/gzip"
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 var (
@@ -48,6 +54,7 @@ func setupTestAPI() error {
 	// Create the test store and handler
 	testStore = db.NewSQLiteBookStore(testDB)
 	testHandler = NewAPIHandler(testStore)
+	testHandler.DB = testDB
 
 	// Set up the router
 	testRouter = mux.NewRouter()
@@ -59,10 +66,32 @@ func setupTestAPI() error {
 	testRouter.HandleFunc("/api/books/{id:[0-9]+}/details", testHandler.UpdateBookDetailsHandler).Methods(http.MethodPut)
 	testRouter.HandleFunc("/api/books/{id:[0-9]+}", testHandler.DeleteBookHandler).Methods(http.MethodDelete)
 	testRouter.HandleFunc("/api/books/search", testHandler.SearchBooksHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/healthz", testHandler.HealthzHandler).Methods(http.MethodGet)
+	testRouter.HandleFunc("/readyz", testHandler.ReadyzHandler).Methods(http.MethodGet)
 
 	return nil
 }
 
+// newTestAPI creates an APIHandler backed by its own in-memory database and wired into the
+// full application router, for tests that must not share books with other tests.
+func newTestAPI(t *testing.T) (*APIHandler, *db.SQLiteBookStore, http.Handler) {
+	t.Helper()
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open in-memory database: %v", err)
+	}
+	database.SetMaxOpenConns(1) // Every connection to :memory: is a separate database
+	t.Cleanup(func() { database.Close() })
+
+	if err := db.CreateSchema(database); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+
+	store := db.NewSQLiteBookStore(database)
+	handler := NewAPIHandler(store)
+	return handler, store, SetupRouter(handler, t.TempDir())
+}
+
 // teardownTestAPI cleans up after tests
 func teardownTestAPI() {
 	if testDB != nil {
@@ -108,7 +137,7 @@ func createTestBook(status model.BookStatus, suffix string) *model.Book {
 func TestGetBooksHandler(t *testing.T) {
 	// Add test books
 	book1 := createTestBook(model.StatusWantToRead, "1")
-	_, err := testStore.AddBook(book1)
+	_, err := testStore.AddBook(context.Background(), book1)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
@@ -193,7 +222,7 @@ func TestAddBookHandler(t *testing.T) {
 func TestUpdateBookStatusHandler(t *testing.T) {
 	// Add test book
 	book := createTestBook(model.StatusWantToRead, "3")
-	id, err := testStore.AddBook(book)
+	id, err := testStore.AddBook(context.Background(), book)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
@@ -226,7 +255,7 @@ func TestUpdateBookStatusHandler(t *testing.T) {
 	}
 
 	// Verify the status was updated in the database
-	updatedBook, err := testStore.GetBookByID(id)
+	updatedBook, err := testStore.GetBookByID(context.Background(), id)
 	if err != nil {
 		t.Fatalf("Failed to retrieve updated book: %v", err)
 	}
@@ -240,7 +269,7 @@ func TestUpdateBookStatusHandler(t *testing.T) {
 func TestUpdateBookDetailsHandler(t *testing.T) {
 	// Add test book
 	book := createTestBook(model.StatusWantToRead, "4")
-	id, err := testStore.AddBook(book)
+	id, err := testStore.AddBook(context.Background(), book)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
@@ -276,7 +305,7 @@ func TestUpdateBookDetailsHandler(t *testing.T) {
 	}
 
 	// Verify the details were updated in the database
-	updatedBook, err := testStore.GetBookByID(id)
+	updatedBook, err := testStore.GetBookByID(context.Background(), id)
 	if err != nil {
 		t.Fatalf("Failed to retrieve updated book: %v", err)
 	}
@@ -294,7 +323,7 @@ func TestUpdateBookDetailsHandler(t *testing.T) {
 func TestDeleteBookHandler(t *testing.T) {
 	// Add test book
 	book := createTestBook(model.StatusWantToRead, "5")
-	id, err := testStore.AddBook(book)
+	id, err := testStore.AddBook(context.Background(), book)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
@@ -317,7 +346,7 @@ func TestDeleteBookHandler(t *testing.T) {
 	}
 
 	// Verify the book was deleted from the database
-	_, err = testStore.GetBookByID(id)
+	_, err = testStore.GetBookByID(context.Background(), id)
 	if err == nil {
 		t.Errorf("Book was not deleted from the database")
 	}
@@ -329,7 +358,7 @@ func TestSearchBooksHandler(t *testing.T) {
 	book1 := createTestBook(model.StatusWantToRead, "Search1")
 	book1.Title = "The Great Gatsby"
 	book1.Author = "F. Scott Fitzgerald"
-	_, err := testStore.AddBook(book1)
+	_, err := testStore.AddBook(context.Background(), book1)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
@@ -337,7 +366,7 @@ func TestSearchBooksHandler(t *testing.T) {
 	book2 := createTestBook(model.StatusCurrentlyReading, "Search2")
 	book2.Title = "The Great Adventure"
 	book2.Author = "John Smith"
-	_, err = testStore.AddBook(book2)
+	_, err = testStore.AddBook(context.Background(), book2)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
@@ -409,7 +438,7 @@ func TestAddBookHandlerInvalidInput(t *testing.T) {
 func TestUpdateBookTypeHandler(t *testing.T) {
 	// Add test book
 	book := createTestBook(model.StatusWantToRead, "TypeTest")
-	id, err := testStore.AddBook(book)
+	id, err := testStore.AddBook(context.Background(), book)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
@@ -442,7 +471,7 @@ func TestUpdateBookTypeHandler(t *testing.T) {
 	}
 
 	// Verify the type was updated in the database
-	updatedBook, err := testStore.GetBookByID(id)
+	updatedBook, err := testStore.GetBookByID(context.Background(), id)
 	if err != nil {
 		t.Fatalf("Failed to retrieve updated book: %v", err)
 	}
@@ -456,7 +485,7 @@ func TestUpdateBookTypeHandler(t *testing.T) {
 func TestUpdateBookTypeHandlerInvalidType(t *testing.T) {
 	// Add test book
 	book := createTestBook(model.StatusWantToRead, "InvalidType")
-	id, err := testStore.AddBook(book)
+	id, err := testStore.AddBook(context.Background(), book)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
@@ -488,7 +517,7 @@ func TestUpdateBookTypeHandlerInvalidType(t *testing.T) {
 func TestUpdateBookStatusHandlerInvalidStatus(t *testing.T) {
 	// Add test book
 	book := createTestBook(model.StatusWantToRead, "InvalidStatus")
-	id, err := testStore.AddBook(book)
+	id, err := testStore.AddBook(context.Background(), book)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
@@ -578,7 +607,7 @@ func TestMalformedJSON(t *testing.T) {
 func TestUpdateBookDetailsHandlerPartialUpdate(t *testing.T) {
 	// Add test book
 	book := createTestBook(model.StatusWantToRead, "PartialUpdate")
-	id, err := testStore.AddBook(book)
+	id, err := testStore.AddBook(context.Background(), book)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
@@ -606,7 +635,7 @@ func TestUpdateBookDetailsHandlerPartialUpdate(t *testing.T) {
 	}
 
 	// Verify only rating was updated
-	updatedBook, err := testStore.GetBookByID(id)
+	updatedBook, err := testStore.GetBookByID(context.Background(), id)
 	if err != nil {
 		t.Fatalf("Failed to retrieve updated book: %v", err)
 	}
@@ -631,7 +660,7 @@ func TestUpdateBookDetailsHandlerPartialUpdate(t *testing.T) {
 func TestGzipCompression(t *testing.T) {
 	// Add test books with a unique OpenLibraryID to avoid conflicts with other tests
 	book1 := createTestBook(model.StatusWantToRead, "Gzip")
-	_, err := testStore.AddBook(book1)
+	_, err := testStore.AddBook(context.Background(), book1)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
@@ -732,3 +761,201 @@ func TestGzipCompression(t *testing.T) {
 		})
 	}
 }
+
+// TestMetricsMiddleware tests that requests are counted by route template and status code
+func TestMetricsMiddleware(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(MetricsMiddleware)
+	router.HandleFunc("/api/books/{id:[0-9]+}", testHandler.DeleteBookHandler).Methods(http.MethodDelete)
+
+	counter := metrics.HTTPRequestsTotal.WithLabelValues("/api/books/{id:[0-9]+}", http.MethodDelete, "404")
+	before := testutil.ToFloat64(counter)
+
+	req, err := http.NewRequest("DELETE", "/api/books/424242", nil)
+	if err != nil {
+		t.Fatalf("Could not create request: %v", err)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Fatalf("Handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+	}
+	if after := testutil.ToFloat64(counter); after != before+1 {
+		t.Errorf("Expected request counter to be incremented, got %v want %v", after, before+1)
+	}
+}
+
+// TestLoggingMiddlewareRequestID tests that request IDs are propagated or generated and exposed to handlers
+func TestLoggingMiddlewareRequestID(t *testing.T) {
+	var seenID string
+	handler := LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenID = logging.RequestID(r.Context())
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	// Propagated from the client
+	req := httptest.NewRequest("GET", "/api/books", nil)
+	req.Header.Set(logging.RequestIDHeader, "client-id-123")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if seenID != "client-id-123" {
+		t.Errorf("Expected propagated request ID in context, got %q", seenID)
+	}
+	if got := rr.Header().Get(logging.RequestIDHeader); got != "client-id-123" {
+		t.Errorf("Expected request ID echoed in response, got %q", got)
+	}
+
+	// Generated when missing
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/books", nil))
+	if seenID == "" || seenID == "client-id-123" {
+		t.Errorf("Expected a freshly generated request ID, got %q", seenID)
+	}
+	if got := rr.Header().Get(logging.RequestIDHeader); got != seenID {
+		t.Errorf("Expected generated request ID %q in response, got %q", seenID, got)
+	}
+}
+
+// TestLoggingMiddlewareCombinedFormat tests the Apache combined log format output
+func TestLoggingMiddlewareCombinedFormat(t *testing.T) {
+	var out bytes.Buffer
+	handler := NewLoggingMiddleware(AccessLogCombined, &out)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not here"))
+	}))
+
+	req := httptest.NewRequest("GET", "/api/books/7", nil)
+	req.RemoteAddr = "192.0.2.10:54321"
+	req.Header.Set("Referer", "http://localhost/")
+	req.Header.Set("User-Agent", "test-agent")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	line := out.String()
+	if !strings.HasPrefix(line, "192.0.2.10 - - [") {
+		t.Errorf("Unexpected host/ident/user prefix in log line: %q", line)
+	}
+	if !strings.Contains(line, `] "GET /api/books/7 HTTP/1.1" 404 8 "http://localhost/" "test-agent"`) {
+		t.Errorf("Unexpected combined log line: %q", line)
+	}
+}
+
+// TestGetBooksHandlerFilters tests the filter and sort query parameters of GET /api/books
+func TestGetBooksHandlerFilters(t *testing.T) {
+	_, store, router := newTestAPI(t)
+	ctx := context.Background()
+
+	finished := time.Date(2024, time.May, 3, 0, 0, 0, 0, time.UTC)
+	for _, b := range []*model.Book{
+		{Title: "Old", Author: "A", OpenLibraryID: "OL-OLD", Status: model.StatusRead, DateFinished: &finished},
+		{Title: "New", Author: "B", OpenLibraryID: "OL-NEW", Status: model.StatusRead},
+		{Title: "Next", Author: "C", OpenLibraryID: "OL-NEXT", Status: model.StatusWantToRead},
+	} {
+		if _, err := store.AddBook(ctx, b); err != nil {
+			t.Fatalf("Failed to add %s: %v", b.Title, err)
+		}
+	}
+
+	list := func(query string) (int, []string) {
+		req := httptest.NewRequest(http.MethodGet, "/api/books?"+query, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var books []model.Book
+		json.Unmarshal(rr.Body.Bytes(), &books)
+		var titles []string
+		for _, b := range books {
+			titles = append(titles, b.Title)
+		}
+		return rr.Code, titles
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "New,Next,Old"},
+		{"status=Read&sort=date_finished&order=desc", "New,Old"},
+		{"finished_from=2024-01-01&finished_before=2025-01-01", "Old"},
+		{"finished_before=2024-05-03T00:00:00Z", ""},
+	}
+	for _, tt := range tests {
+		code, titles := list(tt.query)
+		if code != http.StatusOK || strings.Join(titles, ",") != tt.want {
+			t.Errorf("GET /api/books?%s: expected 200 %q, got %d %v", tt.query, tt.want, code, titles)
+		}
+	}
+
+	for _, query := range []string{"sort=pages", "order=up", "status=Lost", "added_from=yesterday"} {
+		if code, _ := list(query); code != http.StatusBadRequest {
+			t.Errorf("GET /api/books?%s: expected 400, got %d", query, code)
+		}
+	}
+}
+
+// TestUpdateBookDetailsHandlerDates tests editing the type and dates through the details endpoint
+func TestUpdateBookDetailsHandlerDates(t *testing.T) {
+	_, store, router := newTestAPI(t)
+	ctx := context.Background()
+
+	id, err := store.AddBook(ctx, &model.Book{Title: "Backfill", Author: "A", OpenLibraryID: "OL-BF", Status: model.StatusRead})
+	if err != nil {
+		t.Fatalf("Failed to add book: %v", err)
+	}
+
+	put := func(body string) int {
+		req := httptest.NewRequest(http.MethodPut, "/api/books/"+itoa(id)+"/details", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := put(`{"date_started": "2019-06-01", "date_finished": "2019-06-20T18:30:00Z", "type": "audiobook"}`); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	book, _ := store.GetBookByID(ctx, id)
+	if book.DateStarted == nil || book.DateStarted.Format(time.DateOnly) != "2019-06-01" ||
+		book.DateFinished == nil || book.DateFinished.Format(time.RFC3339) != "2019-06-20T18:30:00Z" ||
+		book.Type != model.TypeAudiobook {
+		t.Fatalf("Expected backfilled audiobook, got %+v", book)
+	}
+
+	// Omitted dates are kept; null clears them
+	if code := put(`{"rating": 7, "date_started": null}`); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	book, _ = store.GetBookByID(ctx, id)
+	if book.DateStarted != nil || book.DateFinished == nil || book.Type != model.TypeAudiobook {
+		t.Errorf("Expected cleared start and kept finish, got %v %v", book.DateStarted, book.DateFinished)
+	}
+
+	for _, body := range []string{
+		`{"date_started": "2019-07-01"}`, // After the finish date
+		`{"date_finished": "June 20th"}`,
+		`{"type": "scroll"}`,
+	} {
+		if code := put(body); code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, code)
+		}
+	}
+}
+
+// TestNewAPIHandlerStores tests that a SQLite store is used for every store of the handler
+func TestNewAPIHandlerStores(t *testing.T) {
+	handler, _, _ := newTestAPI(t)
+	stores := map[string]interface{}{
+		"Webhooks": handler.Webhooks, "Stats": handler.Stats, "Goals": handler.Goals, "Authors": handler.Authors,
+		"Series": handler.Series, "Queue": handler.Queue, "Bulk": handler.Bulk, "Trash": handler.Trash,
+		"Audit": handler.Audit, "Undo": handler.Undo, "Quotes": handler.Quotes, "Editions": handler.Editions,
+		"Loans": handler.Loans, "Ownership": handler.Ownership, "Duplicates": handler.Duplicates,
+		"Recommendations": handler.Recommendations,
+	}
+	for name, store := range stores {
+		if store == nil {
+			t.Errorf("Expected %s to be set", name)
+		}
+	}
+	if handler.DB == nil {
+		t.Error("Expected DB to be set")
+	}
+}
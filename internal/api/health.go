@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/metrics"
+)
+
+// readinessCheckTimeout bounds how long a single readiness check may take, so a hung
+// dependency makes the probe fail rather than hang.
+const readinessCheckTimeout = 3 * time.Second
+
+// CheckResult is the outcome of a single readiness check.
+type CheckResult struct {
+	Status     string `json:"status"` // "ok" or "fail"
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// HealthResponse is the JSON body returned by /healthz and /readyz.
+type HealthResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// HealthzHandler handles GET /healthz requests.
+// It is a liveness check: if the process can serve HTTP, it is alive.
+func (h *APIHandler) HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, HealthResponse{Status: "ok"})
+}
+
+// ReadyzHandler handles GET /readyz requests.
+// It verifies the database is reachable and on the expected schema version and, if enabled,
+// that the metadata provider (Open Library) is reachable. Responds 503 if any check fails.
+func (h *APIHandler) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]func(ctx context.Context) error{
+		"database": h.checkDatabase,
+		"schema":   h.checkSchemaVersion,
+	}
+	if h.CheckMetadataProvider {
+		checks["metadata_provider"] = h.checkMetadataProvider
+	}
+
+	response := HealthResponse{Status: "ready", Checks: make(map[string]CheckResult, len(checks))}
+	code := http.StatusOK
+	for name, check := range checks {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessCheckTimeout)
+		start := time.Now()
+		err := check(ctx)
+		cancel()
+
+		result := CheckResult{Status: "ok", DurationMS: time.Since(start).Milliseconds()}
+		if err != nil {
+			result.Status = "fail"
+			result.Error = err.Error()
+			response.Status = "not ready"
+			code = http.StatusServiceUnavailable
+		}
+		response.Checks[name] = result
+	}
+
+	respondWithJSON(w, code, response)
+}
+
+// checkDatabase pings the database.
+func (h *APIHandler) checkDatabase(ctx context.Context) error {
+	if h.DB == nil {
+		return errors.New("database not configured")
+	}
+	return h.DB.PingContext(ctx)
+}
+
+// checkSchemaVersion verifies all migrations have been applied.
+func (h *APIHandler) checkSchemaVersion(ctx context.Context) error {
+	if h.DB == nil {
+		return errors.New("database not configured")
+	}
+	version, err := db.SchemaVersion(ctx, h.DB)
+	if err != nil {
+		return err
+	}
+	if expected := db.ExpectedSchemaVersion(); version != expected {
+		return fmt.Errorf("schema version is %d, expected %d", version, expected)
+	}
+	return nil
+}
+
+// checkMetadataProvider verifies Open Library responds to a HEAD request.
+func (h *APIHandler) checkMetadataProvider(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, h.OpenLibraryBaseURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	start := time.Now()
+	resp, err := h.HTTPClient.Do(req)
+	metrics.OpenLibraryRequestDuration.WithLabelValues("readiness").Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.OpenLibraryErrorsTotal.WithLabelValues("readiness", "transport").Inc()
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		metrics.OpenLibraryErrorsTotal.WithLabelValues("readiness", "status").Inc()
+		return fmt.Errorf("metadata provider returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestHealthzHandler tests the GET /healthz liveness endpoint
+func TestHealthzHandler(t *testing.T) {
+	req, err := http.NewRequest("GET", "/healthz", nil)
+	if err != nil {
+		t.Fatalf("Could not create request: %v", err)
+	}
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	var resp HealthResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Could not unmarshal response: %v", err)
+	}
+	if resp.Status != "ok" {
+		t.Errorf("Expected status ok, got %s", resp.Status)
+	}
+}
+
+// TestReadyzHandler tests the GET /readyz readiness endpoint
+func TestReadyzHandler(t *testing.T) {
+	req, err := http.NewRequest("GET", "/readyz", nil)
+	if err != nil {
+		t.Fatalf("Could not create request: %v", err)
+	}
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Handler returned wrong status code: got %v want %v, body: %s", status, http.StatusOK, rr.Body.String())
+	}
+	var resp HealthResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Could not unmarshal response: %v", err)
+	}
+	for _, name := range []string{"database", "schema"} {
+		if resp.Checks[name].Status != "ok" {
+			t.Errorf("Expected %s check to be ok, got %+v", name, resp.Checks[name])
+		}
+	}
+	if _, ok := resp.Checks["metadata_provider"]; ok {
+		t.Error("Metadata provider should not be checked unless enabled")
+	}
+}
+
+// TestReadyzHandlerFailures tests that /readyz reports 503 when a dependency is unavailable
+func TestReadyzHandlerFailures(t *testing.T) {
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer provider.Close()
+
+	handler := NewAPIHandler(&MockBookStore{}) // No DB configured
+	handler.CheckMetadataProvider = true
+	handler.OpenLibraryBaseURL = provider.URL
+
+	rr := httptest.NewRecorder()
+	handler.ReadyzHandler(rr, httptest.NewRequest("GET", "/readyz", nil))
+
+	if status := rr.Code; status != http.StatusServiceUnavailable {
+		t.Errorf("Handler returned wrong status code: got %v want %v", status, http.StatusServiceUnavailable)
+	}
+	var resp HealthResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Could not unmarshal response: %v", err)
+	}
+	if resp.Status != "not ready" {
+		t.Errorf("Expected status 'not ready', got %s", resp.Status)
+	}
+	for _, name := range []string{"database", "schema", "metadata_provider"} {
+		if resp.Checks[name].Status != "fail" {
+			t.Errorf("Expected %s check to fail, got %+v", name, resp.Checks[name])
+		}
+	}
+}
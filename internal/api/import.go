@@ -0,0 +1,141 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/kindle"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// maxClippingsSize bounds uploaded clippings files; years of highlights take a few megabytes.
+const maxClippingsSize = 20 * 1024 * 1024
+
+// ImportKindleHandler handles POST /api/import/kindle requests.
+// Expects a Kindle "My Clippings.txt" file, either as the request body or as the "file" field
+// of a multipart form. Each book in the file is matched to a book on the shelf by title and
+// author, or added to the shelf (with ?status=, Read by default). Highlights, with the notes
+// attached to them, are stored as the book's quotes; quotes imported before are skipped, so
+// the same file can be imported again as it grows. Responds with a report of the import.
+func (h *APIHandler) ImportKindleHandler(w http.ResponseWriter, r *http.Request) {
+	status := model.StatusRead
+	if value := r.URL.Query().Get("status"); value != "" {
+		status = model.BookStatus(value)
+		if !status.IsValid() {
+			respondWithError(w, r, http.StatusBadRequest, "Invalid status")
+			return
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxClippingsSize)
+	var file io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		upload, _, err := r.FormFile("file")
+		if err != nil {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				respondWithError(w, r, http.StatusRequestEntityTooLarge, "Clippings file is too large")
+			} else {
+				respondWithError(w, r, http.StatusBadRequest, "Expected the clippings in a \"file\" form field")
+			}
+			return
+		}
+		defer upload.Close()
+		file = upload
+	}
+	clippings, unreadable, err := kindle.Parse(file)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			respondWithError(w, r, http.StatusRequestEntityTooLarge, "Clippings file is too large")
+		} else {
+			respondWithError(w, r, http.StatusBadRequest, "Failed to read clippings: "+err.Error())
+		}
+		return
+	}
+	if len(clippings) == 0 {
+		respondWithError(w, r, http.StatusBadRequest, "No clippings found; expected a Kindle \"My Clippings.txt\" file")
+		return
+	}
+
+	shelf, err := h.Store.ListBooks(r.Context(), db.ListOptions{})
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to retrieve books")
+		return
+	}
+
+	report := model.ImportReport{Clippings: len(clippings), Unreadable: unreadable, Books: []model.ImportedBook{}}
+	for _, group := range kindle.Group(clippings) {
+		report.Bookmarks += group.Bookmarks
+		if len(group.Clippings) == 0 {
+			continue
+		}
+		result := model.ImportedBook{Title: group.Title, Author: group.Author}
+
+		if book := kindle.Match(group.Title, group.Author, shelf); book != nil {
+			result.BookID = book.ID
+		} else {
+			book := model.Book{
+				Title:         group.Title,
+				Author:        group.Author,
+				OpenLibraryID: kindle.SourceID(group.Title, group.Author),
+				Status:        status,
+				Type:          model.TypeEbook,
+			}
+			if book.Author == "" {
+				book.Author = "Unknown Author"
+			}
+			id, err := h.Store.AddBook(r.Context(), &book)
+			if errors.Is(err, db.ErrDuplicate) {
+				// Added by an earlier import and since renamed or moved to the trash
+				result.Error = err.Error()
+				report.Books = append(report.Books, result)
+				continue
+			} else if err != nil {
+				respondWithStoreError(w, r, err, "Failed to add book "+group.Title)
+				return
+			}
+			book.ID = id
+			shelf = append(shelf, book)
+			result.BookID, result.Created = id, true
+		}
+
+		quotes := make([]model.Quote, 0, len(group.Clippings))
+		for _, c := range group.Clippings {
+			quote := clippingQuote(c)
+			if quote.Validate() != nil {
+				report.Unreadable++
+				continue
+			}
+			quotes = append(quotes, quote)
+		}
+		added, err := h.Quotes.ImportQuotes(r.Context(), result.BookID, quotes)
+		if err != nil {
+			respondWithStoreError(w, r, err, "Failed to import quotes of "+group.Title)
+			return
+		}
+		result.Added, result.Duplicates = added, len(quotes)-added
+		report.Added += result.Added
+		report.Duplicates += result.Duplicates
+		report.Books = append(report.Books, result)
+	}
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+// clippingQuote turns a highlight (or a note that annotates no highlight) into a quote.
+func clippingQuote(c kindle.Clipping) model.Quote {
+	quote := model.Quote{Text: c.Text, Page: c.Page, ImportHash: c.Hash()}
+	if c.Location != "" {
+		quote.Location = &c.Location
+	}
+	if c.Note != "" {
+		quote.Note = &c.Note
+	}
+	if c.AddedAt != nil {
+		quote.CreatedAt = *c.AddedAt
+	}
+	return quote
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+const testClippings = "\ufeffDune (Herbert, Frank)\r\n" +
+	"- Your Highlight on page 8 | Location 120-122 | Added on Saturday, March 4, 2023 9:05:00 PM\r\n" +
+	"\r\n" +
+	"Fear is the mind-killer.\r\n" +
+	"==========\r\n" +
+	"\ufeffDune (Herbert, Frank)\r\n" +
+	"- Your Note on page 8 | Location 122 | Added on Saturday, March 4, 2023 9:06:00 PM\r\n" +
+	"\r\n" +
+	"Litany against fear\r\n" +
+	"==========\r\n" +
+	"\ufeffThe Left Hand of Darkness (Le Guin, Ursula K.)\r\n" +
+	"- Your Highlight at location 300-301 | Added on Sunday, 5 March 2023 10:15:30\r\n" +
+	"\r\n" +
+	"Light is the left hand of darkness.\r\n" +
+	"==========\r\n"
+
+func TestImportKindleHandler(t *testing.T) {
+	_, store, router := newTestAPI(t)
+
+	importFile := func(req *http.Request) (*httptest.ResponseRecorder, model.ImportReport) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var report model.ImportReport
+		if rr.Code == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+				t.Fatalf("Failed to decode report: %v", err)
+			}
+		}
+		return rr, report
+	}
+
+	duneID, err := store.AddBook(context.Background(), &model.Book{Title: "Dune", Author: "Frank Herbert", OpenLibraryID: "OL-Dune", Status: model.StatusRead})
+	if err != nil {
+		t.Fatalf("Failed to add book: %v", err)
+	}
+
+	rr, report := importFile(httptest.NewRequest(http.MethodPost, "/api/import/kindle?status=Want+to+Read", strings.NewReader(testClippings)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rr.Code, rr.Body.String())
+	}
+	if report.Clippings != 3 || report.Added != 2 || report.Duplicates != 0 || len(report.Books) != 2 {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+	if dune := report.Books[0]; dune.BookID != duneID || dune.Created {
+		t.Errorf("Expected Dune matched to the book on the shelf, got %+v", dune)
+	}
+	created := report.Books[1]
+	if !created.Created || created.Added != 1 {
+		t.Fatalf("Expected The Left Hand of Darkness added to the shelf, got %+v", created)
+	}
+	book, err := store.GetBookByID(context.Background(), created.BookID)
+	if err != nil || book.Author != "Ursula K. Le Guin" || book.Status != model.StatusWantToRead || book.Type != model.TypeEbook {
+		t.Errorf("Unexpected created book: %+v, %v", book, err)
+	}
+
+	quotes, err := store.GetQuotes(context.Background(), duneID)
+	if err != nil || len(quotes) != 1 {
+		t.Fatalf("Expected 1 quote of Dune, got %d, %v", len(quotes), err)
+	}
+	if quotes[0].Note == nil || *quotes[0].Note != "Litany against fear" || quotes[0].Location == nil || *quotes[0].Location != "120-122" {
+		t.Errorf("Expected the highlight with its note and location, got %+v", quotes[0])
+	}
+
+	// Importing the same file again, as a form upload, adds nothing
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", "My Clippings.txt")
+	part.Write([]byte(testClippings))
+	form.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/import/kindle", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rr, report = importFile(req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 re-importing, got %d %s", rr.Code, rr.Body.String())
+	}
+	if report.Added != 0 || report.Duplicates != 2 || report.Books[1].Created || report.Books[1].BookID != created.BookID {
+		t.Errorf("Expected everything recognized on re-import, got %+v", report)
+	}
+
+	for _, tt := range []struct{ path, body string }{
+		{"/api/import/kindle", "not a clippings file"},
+		{"/api/import/kindle?status=Lost", testClippings},
+	} {
+		if rr, _ := importFile(httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", tt.path, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// loanRequest is the body of PUT /api/loans/{id} requests. Dates are YYYY-MM-DD.
+type loanRequest struct {
+	Person     string              `json:"person"`
+	Direction  model.LoanDirection `json:"direction"`
+	DateOut    optionalDate        `json:"date_out"` // Omitted or null: today for new loans, unchanged otherwise
+	DueDate    optionalDate        `json:"due_date"`
+	ReturnedAt optionalDate        `json:"returned_at"`
+}
+
+// newLoanRequest is the body of POST /api/loans requests.
+type newLoanRequest struct {
+	BookID int64 `json:"book_id"`
+	loanRequest
+}
+
+// loanID parses the {id} route variable, responding 400 if it is malformed.
+func loanID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid loan ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// decodeLoanBody decodes a JSON request body into payload, responding 400 if it is invalid.
+func decodeLoanBody(w http.ResponseWriter, r *http.Request, payload interface{}) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, 64*1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(payload); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// loan builds a validated loan from the payload, responding 400 if it is invalid.
+func (p *loanRequest) loan(w http.ResponseWriter, r *http.Request, id, bookID int64) (*model.Loan, bool) {
+	loan := &model.Loan{
+		ID:         id,
+		BookID:     bookID,
+		Person:     p.Person,
+		Direction:  p.Direction,
+		DueDate:    p.DueDate.Time,
+		ReturnedAt: p.ReturnedAt.Time,
+	}
+	if p.DateOut.Time != nil {
+		loan.DateOut = *p.DateOut.Time
+	}
+	if !respondToLoanError(w, r, loan.Validate(), "Invalid loan") {
+		return nil, false
+	}
+	return loan, true
+}
+
+// respondToLoanError responds to a loan store error: 400 for validation errors, as
+// respondWithStoreError otherwise. It returns true if err is nil.
+func respondToLoanError(w http.ResponseWriter, r *http.Request, err error, message string) bool {
+	if err == nil {
+		return true
+	}
+	var validationErr *model.ValidationError
+	if errors.As(err, &validationErr) {
+		respondWithError(w, r, http.StatusBadRequest, validationErr.Message)
+	} else {
+		respondWithStoreError(w, r, err, message)
+	}
+	return false
+}
+
+// GetLoansHandler handles GET /api/loans requests.
+// Returns loans, most recent first, filtered by ?book_id=, ?person=, ?direction=lent|borrowed
+// and ?open=true for the loans not returned yet.
+func (h *APIHandler) GetLoansHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := db.LoanFilter{Person: query.Get("person"), Direction: model.LoanDirection(query.Get("direction"))}
+	if filter.Direction != "" && !filter.Direction.IsValid() {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid direction. Must be 'lent' or 'borrowed'")
+		return
+	}
+	if value := query.Get("book_id"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 1 {
+			respondWithError(w, r, http.StatusBadRequest, "Invalid book_id")
+			return
+		}
+		filter.BookID = parsed
+	}
+	if value := query.Get("open"); value != "" {
+		open, err := strconv.ParseBool(value)
+		if err != nil {
+			respondWithError(w, r, http.StatusBadRequest, "open must be true or false")
+			return
+		}
+		filter.Open = open
+	}
+	h.respondWithLoans(w, r, filter)
+}
+
+// GetOverdueLoansHandler handles GET /api/loans/overdue requests.
+// Returns the loans not returned by their due date, soonest due first, optionally only
+// ?direction=lent (books to ask back) or borrowed (books to give back).
+func (h *APIHandler) GetOverdueLoansHandler(w http.ResponseWriter, r *http.Request) {
+	filter := db.LoanFilter{Overdue: true, Direction: model.LoanDirection(r.URL.Query().Get("direction"))}
+	if filter.Direction != "" && !filter.Direction.IsValid() {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid direction. Must be 'lent' or 'borrowed'")
+		return
+	}
+	h.respondWithLoans(w, r, filter)
+}
+
+// GetBookLoansHandler handles GET /api/books/{id}/loans requests.
+// Returns the loan history of a book, most recent first.
+func (h *APIHandler) GetBookLoansHandler(w http.ResponseWriter, r *http.Request) {
+	bookID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid book ID")
+		return
+	}
+	h.respondWithLoans(w, r, db.LoanFilter{BookID: bookID})
+}
+
+// respondWithLoans responds with the loans matching filter.
+func (h *APIHandler) respondWithLoans(w http.ResponseWriter, r *http.Request, filter db.LoanFilter) {
+	loans, err := h.Loans.GetLoans(r.Context(), filter)
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to retrieve loans")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, loans)
+}
+
+// GetLoanHandler handles GET /api/loans/{id} requests.
+func (h *APIHandler) GetLoanHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := loanID(w, r)
+	if !ok {
+		return
+	}
+	loan, err := h.Loans.GetLoan(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to retrieve loan")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, loan)
+}
+
+// AddLoanHandler handles POST /api/loans requests.
+// Expects {"book_id": 1, "person": "Alice", "direction": "lent", "date_out": "2024-05-01",
+// "due_date": "2024-06-01"}, where date_out defaults to today and due_date is optional, and
+// responds 201 with the loan. Responds 409 if the book is already on loan.
+func (h *APIHandler) AddLoanHandler(w http.ResponseWriter, r *http.Request) {
+	r, ok := withIfMatch(w, r)
+	if !ok {
+		return
+	}
+	var payload newLoanRequest
+	if !decodeLoanBody(w, r, &payload) {
+		return
+	}
+	if payload.BookID < 1 {
+		respondWithError(w, r, http.StatusBadRequest, "book_id is required")
+		return
+	}
+	loan, ok := payload.loan(w, r, 0, payload.BookID)
+	if !ok {
+		return
+	}
+	if !respondToLoanError(w, r, h.Loans.AddLoan(r.Context(), loan), "Failed to add loan") {
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, loan)
+}
+
+// UpdateLoanHandler handles PUT /api/loans/{id} requests.
+// Expects the body of AddLoanHandler without book_id and replaces the loan with it, except
+// that an omitted date_out is kept.
+func (h *APIHandler) UpdateLoanHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := loanID(w, r)
+	if !ok {
+		return
+	}
+	r, ok = withIfMatch(w, r)
+	if !ok {
+		return
+	}
+	var payload loanRequest
+	if !decodeLoanBody(w, r, &payload) {
+		return
+	}
+	loan, ok := payload.loan(w, r, id, 0)
+	if !ok {
+		return
+	}
+	if !respondToLoanError(w, r, h.Loans.UpdateLoan(r.Context(), loan), "Failed to update loan") {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, loan)
+}
+
+// ReturnLoanHandler handles POST /api/loans/{id}/return requests.
+// Records that the book came back, today or on {"returned_at": "2024-06-03"}, and responds
+// with the loan.
+func (h *APIHandler) ReturnLoanHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := loanID(w, r)
+	if !ok {
+		return
+	}
+	r, ok = withIfMatch(w, r)
+	if !ok {
+		return
+	}
+	var payload struct {
+		ReturnedAt optionalDate `json:"returned_at"`
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, 64*1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	returnedAt := model.Today(time.Now())
+	if payload.ReturnedAt.Time != nil {
+		returnedAt = *payload.ReturnedAt.Time
+	}
+
+	loan, err := h.Loans.ReturnLoan(r.Context(), id, returnedAt)
+	if !respondToLoanError(w, r, err, "Failed to return loan") {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, loan)
+}
+
+// DeleteLoanHandler handles DELETE /api/loans/{id} requests.
+func (h *APIHandler) DeleteLoanHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := loanID(w, r)
+	if !ok {
+		return
+	}
+	r, ok = withIfMatch(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Loans.DeleteLoan(r.Context(), id); err != nil {
+		respondWithStoreError(w, r, err, "Failed to delete loan")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
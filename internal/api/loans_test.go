@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestLoanHandlers(t *testing.T) {
+	_, store, router := newTestAPI(t)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	id, err := store.AddBook(context.Background(), &model.Book{Title: "Dune", Author: "Frank Herbert", OpenLibraryID: "OL-Dune", Status: model.StatusRead})
+	if err != nil {
+		t.Fatalf("Failed to add book: %v", err)
+	}
+	today := model.Today(time.Now())
+	dateOut, due := today.AddDate(0, -1, 0).Format("2006-01-02"), today.AddDate(0, 0, -3).Format("2006-01-02")
+
+	rr := do(http.MethodPost, "/api/loans", `{"book_id":`+itoa(id)+`,"person":"Alice","direction":"lent","date_out":"`+dateOut+`","due_date":"`+due+`"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d %s", rr.Code, rr.Body.String())
+	}
+	var loan model.Loan
+	if err := json.Unmarshal(rr.Body.Bytes(), &loan); err != nil || loan.ID == 0 || !loan.Overdue || loan.BookTitle != "Dune" {
+		t.Fatalf("Expected the new loan, overdue, got %s", rr.Body.String())
+	}
+	loanPath := "/api/loans/" + itoa(loan.ID)
+
+	for _, tt := range []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodPost, "/api/loans", `{"book_id":` + itoa(id) + `,"person":"Bob","direction":"lent"}`, http.StatusConflict},
+		{http.MethodPost, "/api/loans", `{"person":"Bob","direction":"lent"}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/loans", `{"book_id":999,"person":"Bob","direction":"lent"}`, http.StatusNotFound},
+		{http.MethodPost, "/api/loans", `{"book_id":` + itoa(id) + `,"person":"Bob","direction":"given"}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/loans", `{"book_id":` + itoa(id) + `,"person":"Bob","direction":"lent","due_date":"tomorrow"}`, http.StatusBadRequest},
+		{http.MethodPut, loanPath, `{"person":"Alice","direction":"lent","due_date":"2000-01-01"}`, http.StatusBadRequest},
+		{http.MethodGet, "/api/loans/999", "", http.StatusNotFound},
+		{http.MethodGet, "/api/loans?direction=given", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/loans/overdue?direction=given", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/books/999/loans", "", http.StatusNotFound},
+	} {
+		if rr := do(tt.method, tt.path, tt.body); rr.Code != tt.want {
+			t.Errorf("%s %s %s: expected %d, got %d %s", tt.method, tt.path, tt.body, tt.want, rr.Code, rr.Body.String())
+		}
+	}
+
+	var books []model.Book
+	rr = do(http.MethodGet, "/api/books", "")
+	if err := json.Unmarshal(rr.Body.Bytes(), &books); err != nil || len(books) != 1 || !books[0].Lent {
+		t.Errorf("Expected the book listed as lent, got %s", rr.Body.String())
+	}
+
+	var loans []model.Loan
+	rr = do(http.MethodGet, "/api/loans/overdue?direction=lent", "")
+	if err := json.Unmarshal(rr.Body.Bytes(), &loans); err != nil || len(loans) != 1 || loans[0].ID != loan.ID {
+		t.Errorf("Expected the loan overdue, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = do(http.MethodPost, loanPath+"/return", "")
+	if err := json.Unmarshal(rr.Body.Bytes(), &loan); err != nil || rr.Code != http.StatusOK || loan.ReturnedAt == nil || loan.Overdue {
+		t.Fatalf("Expected the loan returned today, got %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := do(http.MethodPost, loanPath+"/return", `{"returned_at":"`+dateOut+`"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 returning twice, got %d", rr.Code)
+	}
+
+	rr = do(http.MethodGet, "/api/loans/overdue", "")
+	if err := json.Unmarshal(rr.Body.Bytes(), &loans); err != nil || len(loans) != 0 {
+		t.Errorf("Expected no overdue loans, got %s", rr.Body.String())
+	}
+	rr = do(http.MethodGet, "/api/books/"+itoa(id)+"/loans", "")
+	if err := json.Unmarshal(rr.Body.Bytes(), &loans); err != nil || len(loans) != 1 {
+		t.Errorf("Expected the book's loan history, got %s", rr.Body.String())
+	}
+
+	if rr := do(http.MethodDelete, loanPath, ""); rr.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 deleting, got %d", rr.Code)
+	}
+	if rr := do(http.MethodGet, loanPath, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 after deleting, got %d", rr.Code)
+	}
+}
@@ -1,10 +1,12 @@
 package api
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/ericdahl/bookshelf/internal/db"
 	"github.com/ericdahl/bookshelf/internal/model"
 )
 
@@ -19,11 +21,24 @@ type MockBookStore struct {
 	DeleteErr   error
 }
 
-func (m *MockBookStore) GetBooks() ([]model.Book, error) {
+func (m *MockBookStore) GetBooks(ctx context.Context) ([]model.Book, error) {
 	return m.Books, m.GetBooksErr
 }
 
-func (m *MockBookStore) AddBook(book *model.Book) (int64, error) {
+func (m *MockBookStore) ListBooks(ctx context.Context, opts db.ListOptions) ([]model.Book, error) {
+	if m.GetBooksErr != nil {
+		return nil, m.GetBooksErr
+	}
+	var books []model.Book
+	for _, book := range m.Books {
+		if (opts.Status == "" || book.Status == opts.Status) && (opts.Type == "" || book.Type == opts.Type) {
+			books = append(books, book)
+		}
+	}
+	return books, nil
+}
+
+func (m *MockBookStore) AddBook(ctx context.Context, book *model.Book) (int64, error) {
 	if m.AddBookErr != nil {
 		return 0, m.AddBookErr
 	}
@@ -33,7 +48,7 @@ func (m *MockBookStore) AddBook(book *model.Book) (int64, error) {
 	return book.ID, nil
 }
 
-func (m *MockBookStore) GetBookByID(id int64) (*model.Book, error) {
+func (m *MockBookStore) GetBookByID(ctx context.Context, id int64) (*model.Book, error) {
 	if m.GetBookErr != nil {
 		return nil, m.GetBookErr
 	}
@@ -45,7 +60,7 @@ func (m *MockBookStore) GetBookByID(id int64) (*model.Book, error) {
 	return nil, m.GetBookErr
 }
 
-func (m *MockBookStore) UpdateBookStatus(id int64, status model.BookStatus) error {
+func (m *MockBookStore) UpdateBookStatus(ctx context.Context, id int64, status model.BookStatus) error {
 	if m.UpdateErr != nil {
 		return m.UpdateErr
 	}
@@ -58,7 +73,7 @@ func (m *MockBookStore) UpdateBookStatus(id int64, status model.BookStatus) erro
 	return nil
 }
 
-func (m *MockBookStore) UpdateBookType(id int64, bookType model.BookType) error {
+func (m *MockBookStore) UpdateBookType(ctx context.Context, id int64, bookType model.BookType) error {
 	if m.UpdateErr != nil {
 		return m.UpdateErr
 	}
@@ -71,23 +86,35 @@ func (m *MockBookStore) UpdateBookType(id int64, bookType model.BookType) error
 	return nil
 }
 
-func (m *MockBookStore) UpdateBookDetails(id int64, rating *int, comments *string, series *string, seriesIndex *int) error {
+func (m *MockBookStore) UpdateBookDetails(ctx context.Context, id int64, details model.BookDetails) error {
 	if m.UpdateErr != nil {
 		return m.UpdateErr
 	}
 	for i, book := range m.Books {
 		if book.ID == id {
-			m.Books[i].Rating = rating
-			m.Books[i].Comments = comments
-			m.Books[i].Series = series
-			m.Books[i].SeriesIndex = seriesIndex
+			m.Books[i].Rating = details.Rating
+			m.Books[i].Comments = details.Comments
+			m.Books[i].Series = details.Series
+			m.Books[i].SeriesIndex = details.SeriesIndex
+			if details.Type != nil {
+				m.Books[i].Type = *details.Type
+			}
+			if details.DateAdded != nil {
+				m.Books[i].DateAdded = details.DateAdded
+			}
+			if details.DateStarted != nil || details.ClearDateStarted {
+				m.Books[i].DateStarted = details.DateStarted
+			}
+			if details.DateFinished != nil || details.ClearDateFinished {
+				m.Books[i].DateFinished = details.DateFinished
+			}
 			return nil
 		}
 	}
 	return nil
 }
 
-func (m *MockBookStore) DeleteBook(id int64) error {
+func (m *MockBookStore) DeleteBook(ctx context.Context, id int64) error {
 	if m.DeleteErr != nil {
 		return m.DeleteErr
 	}
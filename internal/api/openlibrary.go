@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/metrics"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// openLibrarySearchLimit is the number of works asked for in each Open Library search.
+const openLibrarySearchLimit = 20
+
+// openLibraryWork is the structure matching the Open Library Works API JSON response.
+// See: https://openlibrary.org/dev/docs/api/books
+type openLibraryWork struct {
+	Title    string   `json:"title"`
+	Subjects []string `json:"subjects"`
+	Covers   []int    `json:"covers"` // Cover IDs, the first being the main one
+}
+
+// getOpenLibrary fetches path from the metadata provider with query and decodes its JSON
+// response into v. The call's latency and failures are recorded under operation.
+func (h *APIHandler) getOpenLibrary(ctx context.Context, operation, path string, query url.Values, v interface{}) error {
+	apiURL := h.OpenLibraryBaseURL + path
+	if len(query) > 0 {
+		apiURL += "?" + query.Encode()
+	}
+	logger := logging.FromContext(ctx)
+	logger.Info("Querying Open Library", "url", apiURL)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create Open Library request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	if requestID := logging.RequestID(ctx); requestID != "" {
+		req.Header.Set(logging.RequestIDHeader, requestID) // Propagate for upstream correlation
+	}
+
+	start := time.Now()
+	resp, err := h.HTTPClient.Do(req)
+	metrics.OpenLibraryRequestDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.OpenLibraryErrorsTotal.WithLabelValues(operation, "transport").Inc()
+		return fmt.Errorf("failed to contact Open Library API: %w", err)
+	}
+	defer resp.Body.Close()
+	logger.Info("OpenLibrary API response", "url", apiURL, "status", resp.StatusCode, "responseTime", time.Since(start))
+
+	if resp.StatusCode != http.StatusOK {
+		metrics.OpenLibraryErrorsTotal.WithLabelValues(operation, "status").Inc()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512)) // For context only
+		return fmt.Errorf("Open Library API returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		metrics.OpenLibraryErrorsTotal.WithLabelValues(operation, "decode").Inc()
+		return fmt.Errorf("failed to decode Open Library response: %w", err)
+	}
+	return nil
+}
+
+// fetchWorkMetadata fetches a work, by its Open Library ID, from the metadata provider. The
+// Works API only has author keys, so the author is left empty.
+func (h *APIHandler) fetchWorkMetadata(ctx context.Context, id string) (model.WorkMetadata, error) {
+	var work openLibraryWork
+	if err := h.getOpenLibrary(ctx, "work", "/works/"+url.PathEscape(id)+".json", nil, &work); err != nil {
+		return model.WorkMetadata{}, err
+	}
+	metadata := model.WorkMetadata{OpenLibraryID: id, Title: work.Title, Subjects: work.Subjects}
+	if len(work.Covers) > 0 && work.Covers[0] > 0 {
+		metadata.CoverURL = coverURL(work.Covers[0])
+	}
+	return metadata, nil
+}
+
+// searchWorkMetadata searches the metadata provider with query (e.g. author= or subject=) and
+// returns the works found, with their subjects.
+func (h *APIHandler) searchWorkMetadata(ctx context.Context, query url.Values) ([]model.WorkMetadata, error) {
+	query.Set("fields", "key,title,author_name,isbn,cover_i,subject")
+	query.Set("limit", fmt.Sprint(openLibrarySearchLimit))
+	var response openLibrarySearchResponse
+	if err := h.getOpenLibrary(ctx, "search", "/search.json", query, &response); err != nil {
+		return nil, err
+	}
+	works := []model.WorkMetadata{}
+	for _, doc := range response.Docs {
+		id := doc.Key[strings.LastIndex(doc.Key, "/")+1:]
+		if id == "" || doc.Title == "" {
+			continue
+		}
+		work := model.WorkMetadata{OpenLibraryID: id, Title: doc.Title, Author: strings.Join(doc.AuthorName, ", "), Subjects: doc.Subject}
+		for _, code := range doc.ISBN {
+			if work.ISBN == nil || (len(code) == 13 && len(*work.ISBN) != 13) {
+				work.ISBN = &code // Prefer ISBN-13
+			}
+		}
+		if doc.CoverI > 0 {
+			work.CoverURL = coverURL(doc.CoverI)
+		}
+		works = append(works, work)
+	}
+	return works, nil
+}
+
+// coverURL returns the URL of the medium size of an Open Library cover.
+func coverURL(coverID int) *string {
+	link := fmt.Sprintf("https://covers.openlibrary.org/b/id/%d-M.jpg", coverID)
+	return &link
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// ownershipRequest is the body of PUT /api/books/{id}/ownership requests.
+type ownershipRequest struct {
+	Status       model.OwnershipStatus `json:"status"`
+	PurchaseDate optionalDate          `json:"purchase_date"` // YYYY-MM-DD or an RFC 3339 timestamp
+	Price        *float64              `json:"price"`
+	Currency     *string               `json:"currency"`
+	Store        *string               `json:"store"`
+	Room         *string               `json:"room"`
+	Shelf        *string               `json:"shelf"`
+}
+
+// ownershipBookID parses the {id} route variable and applies If-Match, responding 400 if
+// either is malformed.
+func ownershipBookID(w http.ResponseWriter, r *http.Request) (int64, *http.Request, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid book ID")
+		return 0, r, false
+	}
+	r, ok := withIfMatch(w, r)
+	return id, r, ok
+}
+
+// respondToOwnershipError responds to a store error: 400 for validation errors, as
+// respondWithStoreError otherwise.
+func respondToOwnershipError(w http.ResponseWriter, r *http.Request, err error) {
+	var validationErr *model.ValidationError
+	if errors.As(err, &validationErr) {
+		respondWithError(w, r, http.StatusBadRequest, validationErr.Message)
+	} else {
+		respondWithStoreError(w, r, err, "Failed to update ownership")
+	}
+}
+
+// SetOwnershipHandler handles PUT /api/books/{id}/ownership requests.
+// Expects {"status": "owned", "purchase_date": "2024-05-01", "price": 18.99, "currency": "USD",
+// "store": "City Books", "room": "Study", "shelf": "B2"}, where only status is required, and
+// replaces the book's ownership with it. Responds with the ownership as stored.
+func (h *APIHandler) SetOwnershipHandler(w http.ResponseWriter, r *http.Request) {
+	id, r, ok := ownershipBookID(w, r)
+	if !ok {
+		return
+	}
+	var payload ownershipRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 64*1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	ownership := &model.Ownership{
+		Status:       payload.Status,
+		PurchaseDate: payload.PurchaseDate.Time,
+		Price:        payload.Price,
+		Currency:     payload.Currency,
+		Store:        payload.Store,
+		Room:         payload.Room,
+		Shelf:        payload.Shelf,
+	}
+	if err := h.Ownership.SetOwnership(r.Context(), id, ownership); err != nil {
+		respondToOwnershipError(w, r, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, ownership)
+}
+
+// ClearOwnershipHandler handles DELETE /api/books/{id}/ownership requests.
+// Forgets whether the book is owned, along with its purchase and location.
+func (h *APIHandler) ClearOwnershipHandler(w http.ResponseWriter, r *http.Request) {
+	id, r, ok := ownershipBookID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Ownership.SetOwnership(r.Context(), id, nil); err != nil {
+		respondToOwnershipError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetInventoryHandler handles GET /api/inventory requests.
+// Counts the books owned, and what was paid for them, in total and per room and shelf.
+// ?ownership= counts books with another ownership status instead, or every book with one
+// recorded if it is "any"; ?type= only counts books of that type.
+func (h *APIHandler) GetInventoryHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := db.InventoryFilter{Ownership: model.OwnershipOwned, Type: model.BookType(query.Get("type"))}
+	switch ownership := model.OwnershipStatus(query.Get("ownership")); {
+	case ownership == "":
+	case ownership == "any":
+		filter.Ownership = ""
+	case ownership.IsValid():
+		filter.Ownership = ownership
+	default:
+		respondWithError(w, r, http.StatusBadRequest, "Invalid ownership. Must be 'owned', 'wishlist', 'library', 'sold', 'donated' or 'any'")
+		return
+	}
+	if filter.Type != "" && !filter.Type.IsValid() {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid type. Must be 'book', 'ebook' or 'audiobook'")
+		return
+	}
+
+	report, err := h.Ownership.GetInventory(r.Context(), filter)
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to compute inventory")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestOwnershipHandlers(t *testing.T) {
+	_, store, router := newTestAPI(t)
+
+	do := func(method, path, body string, headers ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	id, err := store.AddBook(context.Background(), &model.Book{Title: "Dune", Author: "Frank Herbert", OpenLibraryID: "OL-Dune", Status: model.StatusRead})
+	if err != nil {
+		t.Fatalf("Failed to add book: %v", err)
+	}
+	path := "/api/books/" + itoa(id) + "/ownership"
+
+	rr := do(http.MethodPut, path, `{"status":"owned","purchase_date":"2024-05-01","price":18.99,"currency":"usd","store":"City Books","room":"Study","shelf":"B2"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rr.Code, rr.Body.String())
+	}
+	var ownership model.Ownership
+	if err := json.Unmarshal(rr.Body.Bytes(), &ownership); err != nil || *ownership.Currency != "USD" || ownership.PurchaseDate == nil {
+		t.Fatalf("Expected the ownership as stored, got %s", rr.Body.String())
+	}
+
+	for _, tt := range []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodPut, path, `{"status":"borrowed"}`, http.StatusBadRequest},
+		{http.MethodPut, path, `{"status":"owned","price":5}`, http.StatusBadRequest},
+		{http.MethodPut, path, `{"status":"owned","purchase_date":"yesterday"}`, http.StatusBadRequest},
+		{http.MethodPut, "/api/books/999/ownership", `{"status":"owned"}`, http.StatusNotFound},
+		{http.MethodGet, "/api/books?ownership=stolen", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/inventory?ownership=stolen", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/inventory?type=scroll", "", http.StatusBadRequest},
+	} {
+		if rr := do(tt.method, tt.path, tt.body); rr.Code != tt.want {
+			t.Errorf("%s %s %s: expected %d, got %d %s", tt.method, tt.path, tt.body, tt.want, rr.Code, rr.Body.String())
+		}
+	}
+	if rr := do(http.MethodPut, path, `{"status":"owned"}`, "If-Match", "1"); rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for a stale version, got %d", rr.Code)
+	}
+
+	var books []model.Book
+	rr = do(http.MethodGet, "/api/books?ownership=owned&room=study", "")
+	if err := json.Unmarshal(rr.Body.Bytes(), &books); err != nil || len(books) != 1 || books[0].Ownership.Store == nil {
+		t.Errorf("Expected the book listed with its ownership, got %s", rr.Body.String())
+	}
+
+	var report model.InventoryReport
+	rr = do(http.MethodGet, "/api/inventory", "")
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil || report.Count != 1 || len(report.Locations) != 1 ||
+		report.Locations[0].Room != "Study" || report.Value[0] != (model.Amount{Currency: "USD", Amount: 18.99}) {
+		t.Errorf("Expected the book in the inventory, got %s", rr.Body.String())
+	}
+	rr = do(http.MethodGet, "/api/inventory?ownership=wishlist", "")
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil || report.Count != 0 || report.Locations == nil {
+		t.Errorf("Expected an empty wishlist inventory, got %s", rr.Body.String())
+	}
+
+	if rr := do(http.MethodDelete, path, ""); rr.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 clearing ownership, got %d", rr.Code)
+	}
+	rr = do(http.MethodGet, "/api/inventory?ownership=any", "")
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil || report.Count != 0 {
+		t.Errorf("Expected nothing in the inventory, got %s", rr.Body.String())
+	}
+}
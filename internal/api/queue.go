@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// MoveBookHandler handles PUT /api/books/{id}/position requests.
+// Expects exactly one of {"before": <book ID>} or {"after": <book ID>}; both books must be on
+// the same shelf. Responds with the moved book.
+func (h *APIHandler) MoveBookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid book ID")
+		return
+	}
+	r, ok := withIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	var payload struct {
+		Before *int64 `json:"before"`
+		After  *int64 `json:"after"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if (payload.Before == nil) == (payload.After == nil) {
+		respondWithError(w, r, http.StatusBadRequest, "Exactly one of before or after must be given")
+		return
+	}
+	targetID, after := payload.Before, false
+	if payload.After != nil {
+		targetID, after = payload.After, true
+	}
+
+	if err := h.Queue.MoveBook(r.Context(), id, *targetID, after); err != nil {
+		var validationErr *model.ValidationError
+		if errors.As(err, &validationErr) {
+			respondWithError(w, r, http.StatusBadRequest, validationErr.Message)
+		} else {
+			respondWithStoreError(w, r, err, "Failed to move book")
+		}
+		return
+	}
+	book, err := h.Store.GetBookByID(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to retrieve book")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, book)
+}
+
+// GetNextBookHandler handles GET /api/books/next requests.
+// Returns the top of the Want to Read queue, or an earlier unread book of the same series.
+func (h *APIHandler) GetNextBookHandler(w http.ResponseWriter, r *http.Request) {
+	book, err := h.Queue.NextBook(r.Context())
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to retrieve next book")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, book)
+}
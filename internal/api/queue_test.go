@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestQueueHandlers(t *testing.T) {
+	_, store, router := newTestAPI(t)
+	ctx := context.Background()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := do(http.MethodGet, "/api/books/next", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an empty queue, got %d", rr.Code)
+	}
+
+	var ids []int64
+	for _, title := range []string{"First", "Second", "Third"} {
+		id, err := store.AddBook(ctx, &model.Book{Title: title, Author: "A", OpenLibraryID: "OL-" + title, Status: model.StatusWantToRead})
+		if err != nil {
+			t.Fatalf("Failed to add book: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	read, err := store.AddBook(ctx, &model.Book{Title: "Done", Author: "A", OpenLibraryID: "OL-Done", Status: model.StatusRead})
+	if err != nil {
+		t.Fatalf("Failed to add book: %v", err)
+	}
+
+	rr := do(http.MethodPut, "/api/books/"+itoa(ids[2])+"/position", `{"before": `+itoa(ids[0])+`}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 moving a book, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var moved model.Book
+	if err := json.Unmarshal(rr.Body.Bytes(), &moved); err != nil || moved.Position != 1 {
+		t.Errorf("Expected the moved book at position 1, got %s", rr.Body.String())
+	}
+
+	rr = do(http.MethodGet, "/api/books/next", "")
+	var next model.Book
+	if err := json.Unmarshal(rr.Body.Bytes(), &next); err != nil || next.ID != ids[2] {
+		t.Errorf("Expected the moved book next, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = do(http.MethodGet, "/api/books?status=Want%20to%20Read&sort=position", "")
+	var books []model.Book
+	if err := json.Unmarshal(rr.Body.Bytes(), &books); err != nil || len(books) != 3 || books[0].ID != ids[2] || books[1].ID != ids[0] {
+		t.Errorf("Expected the shelf in manual order, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	tests := []struct {
+		name, path, body string
+		want             int
+	}{
+		{"neither", "/api/books/" + itoa(ids[0]) + "/position", `{}`, http.StatusBadRequest},
+		{"both", "/api/books/" + itoa(ids[0]) + "/position", `{"before": ` + itoa(ids[1]) + `, "after": ` + itoa(ids[2]) + `}`, http.StatusBadRequest},
+		{"itself", "/api/books/" + itoa(ids[0]) + "/position", `{"after": ` + itoa(ids[0]) + `}`, http.StatusBadRequest},
+		{"other shelf", "/api/books/" + itoa(ids[0]) + "/position", `{"after": ` + itoa(read) + `}`, http.StatusBadRequest},
+		{"missing book", "/api/books/999/position", `{"after": ` + itoa(ids[0]) + `}`, http.StatusNotFound},
+		{"missing target", "/api/books/" + itoa(ids[0]) + "/position", `{"after": 999}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rr := do(http.MethodPut, tt.path, tt.body); rr.Code != tt.want {
+				t.Errorf("Expected %d, got %d: %s", tt.want, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// Search result sizes for GET /api/quotes.
+const (
+	defaultQuoteLimit = 50
+	maxQuoteLimit     = 500
+)
+
+// quoteRequest is the body of POST and PUT /api/books/{id}/quotes requests.
+type quoteRequest struct {
+	Text      string  `json:"text"`
+	Page      *int    `json:"page"`
+	Location  *string `json:"location"`
+	Timestamp *int    `json:"timestamp"`
+	Note      *string `json:"note"`
+}
+
+// quoteIDs parses the {id} and {quoteId} route variables, responding 400 if either is malformed.
+// quoteId is 0 on routes without it.
+func quoteIDs(w http.ResponseWriter, r *http.Request) (bookID, quoteID int64, ok bool) {
+	vars := mux.Vars(r)
+	bookID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid book ID")
+		return 0, 0, false
+	}
+	if _, ok := vars["quoteId"]; ok {
+		if quoteID, err = strconv.ParseInt(vars["quoteId"], 10, 64); err != nil {
+			respondWithError(w, r, http.StatusBadRequest, "Invalid quote ID")
+			return 0, 0, false
+		}
+	}
+	return bookID, quoteID, true
+}
+
+// decodeQuote reads and validates a quote from the request body, responding 400 if it is invalid.
+func decodeQuote(w http.ResponseWriter, r *http.Request, bookID, quoteID int64) (*model.Quote, bool) {
+	var payload quoteRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 64*1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return nil, false
+	}
+
+	quote := &model.Quote{
+		ID:        quoteID,
+		BookID:    bookID,
+		Text:      payload.Text,
+		Page:      payload.Page,
+		Location:  payload.Location,
+		Timestamp: payload.Timestamp,
+		Note:      payload.Note,
+	}
+	if err := quote.Validate(); err != nil {
+		var validationErr *model.ValidationError
+		if errors.As(err, &validationErr) {
+			respondWithError(w, r, http.StatusBadRequest, validationErr.Message)
+		} else {
+			respondWithError(w, r, http.StatusBadRequest, "Invalid quote: "+err.Error())
+		}
+		return nil, false
+	}
+	return quote, true
+}
+
+// GetBookQuotesHandler handles GET /api/books/{id}/quotes requests.
+// Returns the book's quotes in reading order.
+func (h *APIHandler) GetBookQuotesHandler(w http.ResponseWriter, r *http.Request) {
+	bookID, _, ok := quoteIDs(w, r)
+	if !ok {
+		return
+	}
+	quotes, err := h.Quotes.GetQuotes(r.Context(), bookID)
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to retrieve quotes")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, quotes)
+}
+
+// GetBookQuoteHandler handles GET /api/books/{id}/quotes/{quoteId} requests.
+func (h *APIHandler) GetBookQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	bookID, quoteID, ok := quoteIDs(w, r)
+	if !ok {
+		return
+	}
+	quote, err := h.Quotes.GetQuote(r.Context(), bookID, quoteID)
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to retrieve quote")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, quote)
+}
+
+// AddBookQuoteHandler handles POST /api/books/{id}/quotes requests.
+// Expects {"text": "...", "page": 12, "location": "170-172", "timestamp": 3600, "note": "..."},
+// where only text is required, and responds 201 with the new quote.
+func (h *APIHandler) AddBookQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	bookID, _, ok := quoteIDs(w, r)
+	if !ok {
+		return
+	}
+	r, ok = withIfMatch(w, r)
+	if !ok {
+		return
+	}
+	quote, ok := decodeQuote(w, r, bookID, 0)
+	if !ok {
+		return
+	}
+	if err := h.Quotes.AddQuote(r.Context(), quote); err != nil {
+		respondWithStoreError(w, r, err, "Failed to add quote")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, quote)
+}
+
+// UpdateBookQuoteHandler handles PUT /api/books/{id}/quotes/{quoteId} requests.
+// Expects the same body as AddBookQuoteHandler and replaces the quote with it.
+func (h *APIHandler) UpdateBookQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	bookID, quoteID, ok := quoteIDs(w, r)
+	if !ok {
+		return
+	}
+	r, ok = withIfMatch(w, r)
+	if !ok {
+		return
+	}
+	quote, ok := decodeQuote(w, r, bookID, quoteID)
+	if !ok {
+		return
+	}
+	if err := h.Quotes.UpdateQuote(r.Context(), quote); err != nil {
+		respondWithStoreError(w, r, err, "Failed to update quote")
+		return
+	}
+	updated, err := h.Quotes.GetQuote(r.Context(), bookID, quoteID)
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to retrieve quote")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, updated)
+}
+
+// DeleteBookQuoteHandler handles DELETE /api/books/{id}/quotes/{quoteId} requests.
+func (h *APIHandler) DeleteBookQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	bookID, quoteID, ok := quoteIDs(w, r)
+	if !ok {
+		return
+	}
+	r, ok = withIfMatch(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Quotes.DeleteQuote(r.Context(), bookID, quoteID); err != nil {
+		respondWithStoreError(w, r, err, "Failed to delete quote")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SearchQuotesHandler handles GET /api/quotes requests.
+// Returns the quotes (across all books) whose text or note contains ?q=, newest first, or
+// the newest quotes without ?q=. ?limit= sets the number of results.
+func (h *APIHandler) SearchQuotesHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := defaultQuoteLimit
+	if limitStr := query.Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > maxQuoteLimit {
+			respondWithError(w, r, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxQuoteLimit))
+			return
+		}
+		limit = parsed
+	}
+	quotes, err := h.Quotes.SearchQuotes(r.Context(), query.Get("q"), limit)
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to search quotes")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, quotes)
+}
+
+// RandomQuoteHandler handles GET /api/quotes/random requests.
+// Returns a quote picked at random, with its book's title and author.
+func (h *APIHandler) RandomQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	quote, err := h.Quotes.RandomQuote(r.Context())
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to retrieve a quote")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, quote)
+}
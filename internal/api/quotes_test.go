@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestQuoteHandlers(t *testing.T) {
+	_, store, router := newTestAPI(t)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	id, err := store.AddBook(context.Background(), &model.Book{Title: "Dune", Author: "Frank Herbert", OpenLibraryID: "OL-Dune", Status: model.StatusRead})
+	if err != nil {
+		t.Fatalf("Failed to add book: %v", err)
+	}
+	path := "/api/books/" + itoa(id) + "/quotes"
+
+	rr := do(http.MethodPost, path, `{"text": "Fear is the mind-killer.", "page": 8, "note": "Litany"}`)
+	var quote model.Quote
+	if err := json.Unmarshal(rr.Body.Bytes(), &quote); err != nil || rr.Code != http.StatusCreated || quote.ID == 0 {
+		t.Fatalf("Expected 201 with the new quote, got %d %s", rr.Code, rr.Body.String())
+	}
+	quotePath := path + "/" + itoa(quote.ID)
+
+	for _, body := range []string{`{"text": ""}`, `{"text": "x", "page": 0}`, `{"text": "x", "chapter": 2}`} {
+		if rr := do(http.MethodPost, path, body); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, rr.Code)
+		}
+	}
+	if rr := do(http.MethodPost, "/api/books/999/quotes", `{"text": "x"}`); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 quoting a missing book, got %d", rr.Code)
+	}
+
+	rr = do(http.MethodPut, quotePath, `{"text": "Fear is the mind-killer.", "timestamp": 754}`)
+	quote = model.Quote{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &quote); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 with the updated quote, got %d %s", rr.Code, rr.Body.String())
+	}
+	if quote.Page != nil || quote.Timestamp == nil || *quote.Timestamp != 754 || quote.Note != nil {
+		t.Errorf("Expected the quote replaced, got %+v", quote)
+	}
+
+	rr = do(http.MethodGet, "/api/quotes?q=mind", "")
+	var quotes []model.Quote
+	if err := json.Unmarshal(rr.Body.Bytes(), &quotes); err != nil || len(quotes) != 1 || quotes[0].BookTitle != "Dune" {
+		t.Errorf("Expected the quote with its book, got %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := do(http.MethodGet, "/api/quotes?limit=0", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for limit=0, got %d", rr.Code)
+	}
+	if rr := do(http.MethodGet, "/api/quotes/random", ""); rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 for a random quote, got %d", rr.Code)
+	}
+
+	if rr := do(http.MethodDelete, quotePath, ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204 deleting, got %d", rr.Code)
+	}
+	if rr := do(http.MethodGet, quotePath, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a deleted quote, got %d", rr.Code)
+	}
+	if rr := do(http.MethodGet, "/api/quotes/random", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without quotes, got %d", rr.Code)
+	}
+}
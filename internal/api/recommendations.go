@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/recommend"
+)
+
+const (
+	defaultRecommendationLimit = 20
+	maxRecommendationLimit     = 100
+
+	// maxWorkFetches caps the works fetched from Open Library per refresh; refreshing again
+	// fetches the next ones.
+	maxWorkFetches = 20
+	// searchesPerSignal is the number of top authors, and of top subjects, searched for books
+	// not on the shelf on each refresh.
+	searchesPerSignal = 3
+)
+
+// recommendationsResponse is the body of GET /api/recommendations responses.
+type recommendationsResponse struct {
+	Recommendations []model.Recommendation `json:"recommendations"`
+	// Warning is set if a refresh was asked for and failed, so the cached metadata was used.
+	Warning string `json:"warning,omitempty"`
+}
+
+// GetRecommendationsHandler handles GET /api/recommendations requests.
+// Ranks the Want to Read books and books found on Open Library by how much they resemble the
+// books rated highly, each with the reasons it was recommended. It only uses the metadata
+// cached from Open Library, so it works offline; ?refresh=true first updates the cache,
+// falling back to what is cached if Open Library cannot be reached. ?source= only returns
+// "shelf" or "open_library" books and ?limit= sets the number of results.
+func (h *APIHandler) GetRecommendationsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := db.RecommendationFilter{Source: model.RecommendationSource(query.Get("source")), Limit: defaultRecommendationLimit}
+	if filter.Source != "" && !filter.Source.IsValid() {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid source. Must be 'shelf' or 'open_library'")
+		return
+	}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > maxRecommendationLimit {
+			respondWithError(w, r, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxRecommendationLimit))
+			return
+		}
+		filter.Limit = parsed
+	}
+	refresh := false
+	if value := query.Get("refresh"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			respondWithError(w, r, http.StatusBadRequest, "refresh must be true or false")
+			return
+		}
+		refresh = parsed
+	}
+
+	response := recommendationsResponse{}
+	if refresh {
+		if err := h.refreshWorkMetadata(r.Context()); err != nil {
+			if r.Context().Err() != nil {
+				respondWithStoreError(w, r, r.Context().Err(), "Failed to refresh recommendations")
+				return
+			}
+			logging.FromContext(r.Context()).Warn("Refreshing work metadata failed, using the cache", "error", err)
+			response.Warning = "Could not refresh from Open Library, using cached metadata: " + err.Error()
+		}
+	}
+
+	recommendations, err := h.Recommendations.GetRecommendations(r.Context(), filter)
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to compute recommendations")
+		return
+	}
+	response.Recommendations = recommendations
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// refreshWorkMetadata updates the metadata cache from Open Library: it fetches the subjects
+// of the liked and Want to Read books not cached yet, then searches for books by the authors
+// and about the subjects liked most. What was fetched before an error is still cached.
+func (h *APIHandler) refreshWorkMetadata(ctx context.Context) error {
+	books, err := h.Store.ListBooks(ctx, db.ListOptions{})
+	if err != nil {
+		return err
+	}
+	cache, err := h.Recommendations.ListWorkMetadata(ctx)
+	if err != nil {
+		return err
+	}
+
+	var works []model.WorkMetadata
+	for _, id := range recommend.MissingWorks(books, cache, maxWorkFetches) {
+		work, err := h.fetchWorkMetadata(ctx, id)
+		if err != nil {
+			return errors.Join(err, h.Recommendations.SaveWorkMetadata(ctx, works))
+		}
+		for _, book := range books {
+			if book.OpenLibraryID == id {
+				// The Works API has no author names, and our title is the one people know
+				work.Title, work.Author = book.Title, book.Author
+				break
+			}
+		}
+		works = append(works, work)
+	}
+	if err := h.Recommendations.SaveWorkMetadata(ctx, works); err != nil {
+		return err
+	}
+
+	cache = append(cache, works...)
+	var searches []url.Values
+	for _, author := range recommend.TopAuthors(books, searchesPerSignal) {
+		searches = append(searches, url.Values{"author": {author}})
+	}
+	for _, subject := range recommend.TopSubjects(books, cache, searchesPerSignal) {
+		searches = append(searches, url.Values{"subject": {subject}})
+	}
+	onShelf := map[string]bool{}
+	for _, book := range books {
+		onShelf[book.OpenLibraryID] = true
+	}
+	works = nil
+	for _, search := range searches {
+		found, err := h.searchWorkMetadata(ctx, search)
+		if err != nil {
+			return errors.Join(err, h.Recommendations.SaveWorkMetadata(ctx, works))
+		}
+		for _, work := range found {
+			// Search results have fewer subjects than the Works API gives for shelf books
+			if !onShelf[work.OpenLibraryID] {
+				works = append(works, work)
+			}
+		}
+	}
+	return h.Recommendations.SaveWorkMetadata(ctx, works)
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestRecommendationsHandler(t *testing.T) {
+	handler, store, router := newTestAPI(t)
+
+	calls := 0
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch {
+		case r.URL.Path == "/works/OL59801W.json":
+			w.Write([]byte(`{"title":"The Left Hand of Darkness","subjects":["Fiction","Science fiction"],"covers":[123]}`))
+		case r.URL.Path == "/works/OL59803W.json":
+			w.Write([]byte(`{"title":"The Lathe of Heaven","subjects":["Dreams"]}`))
+		case r.URL.Path == "/search.json" && r.URL.Query().Get("author") == "Ursula K. Le Guin":
+			w.Write([]byte(`{"docs":[{"key":"/works/OL59801W","title":"The Left Hand of Darkness","author_name":["Ursula K. Le Guin"]},
+				{"key":"/works/OL2W","title":"The Dispossessed","author_name":["Ursula K. Le Guin"],"isbn":["0061054887","9780061054884"],"subject":["Anarchism"]}]}`))
+		case r.URL.Path == "/search.json" && r.URL.Query().Get("subject") == "Science fiction":
+			w.Write([]byte(`{"docs":[{"key":"/works/OL3W","title":"Hyperion","author_name":["Dan Simmons"],"cover_i":7,"subject":["Science fiction"]}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer provider.Close()
+	handler.OpenLibraryBaseURL = provider.URL
+
+	rating := 10
+	for _, book := range []model.Book{
+		{Title: "The Left Hand of Darkness", Author: "Ursula K. Le Guin", OpenLibraryID: "OL59801W", Status: model.StatusRead, Rating: &rating},
+		{Title: "The Lathe of Heaven", Author: "Ursula K. Le Guin", OpenLibraryID: "OL59803W", Status: model.StatusWantToRead},
+	} {
+		if _, err := store.AddBook(context.Background(), &book); err != nil {
+			t.Fatalf("Failed to add book: %v", err)
+		}
+	}
+
+	get := func(path string) (*httptest.ResponseRecorder, recommendationsResponse) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		var response recommendationsResponse
+		if rr.Code == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not unmarshal response: %v", err)
+			}
+		}
+		return rr, response
+	}
+
+	// Without a refresh only the cache is used, and the provider is not called
+	rr, response := get("/api/recommendations")
+	if rr.Code != http.StatusOK || len(response.Recommendations) != 1 || response.Recommendations[0].Title != "The Lathe of Heaven" || calls != 0 {
+		t.Fatalf("Expected only the Want to Read book by a liked author, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr, response = get("/api/recommendations?refresh=true")
+	if rr.Code != http.StatusOK || response.Warning != "" {
+		t.Fatalf("Expected a refresh, got %d %s", rr.Code, rr.Body.String())
+	}
+	var titles []string
+	for _, rec := range response.Recommendations {
+		titles = append(titles, rec.Title)
+	}
+	if strings.Join(titles, ", ") != "The Lathe of Heaven, The Dispossessed, Hyperion" {
+		t.Fatalf("Unexpected recommendations %v", titles)
+	}
+	dispossessed, hyperion := response.Recommendations[1], response.Recommendations[2]
+	if dispossessed.Source != model.SourceOpenLibrary || dispossessed.ISBN == nil || *dispossessed.ISBN != "9780061054884" {
+		t.Errorf("Expected an Open Library book with its ISBN-13, got %+v", dispossessed)
+	}
+	if len(hyperion.Reasons) != 1 || hyperion.Reasons[0] != "About Science fiction, like The Left Hand of Darkness" {
+		t.Errorf("Unexpected reasons %q", hyperion.Reasons)
+	}
+
+	// Offline, the cache still gives the same recommendations
+	provider.Close()
+	rr, response = get("/api/recommendations?refresh=true&source=open_library&limit=1")
+	if rr.Code != http.StatusOK || !strings.HasPrefix(response.Warning, "Could not refresh from Open Library") {
+		t.Fatalf("Expected a warning, got %d %s", rr.Code, rr.Body.String())
+	}
+	if len(response.Recommendations) != 1 || response.Recommendations[0].Title != "The Dispossessed" {
+		t.Errorf("Expected the cached Open Library book, got %+v", response.Recommendations)
+	}
+
+	for _, path := range []string{"/api/recommendations?source=library", "/api/recommendations?limit=0", "/api/recommendations?refresh=maybe"} {
+		if rr, _ := get(path); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", path, rr.Code)
+		}
+	}
+}
@@ -1,37 +1,180 @@
 package api
 
 import (
+	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
+	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/metrics"
 	"github.com/gorilla/mux"
 	"github.com/klauspost/compress/gzip"
 )
 
-// LoggingMiddleware logs incoming HTTP requests.
-func LoggingMiddleware(next http.Handler) http.Handler {
+// AccessLogFormat selects how LoggingMiddleware writes access log entries.
+type AccessLogFormat string
+
+const (
+	// AccessLogStructured logs each request through slog, using the configured handler (text or JSON).
+	AccessLogStructured AccessLogFormat = "structured"
+	// AccessLogCombined writes Apache/NCSA combined log format lines.
+	AccessLogCombined AccessLogFormat = "combined"
+)
+
+// IsValid checks if the format is one of the supported access log formats.
+func (f AccessLogFormat) IsValid() bool {
+	switch f {
+	case AccessLogStructured, AccessLogCombined:
+		return true
+	default:
+		return false
+	}
+}
+
+// responseRecorder wraps an http.ResponseWriter to remember the status code and
+// number of body bytes written by the handler.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK // Implicit 200 on first write
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Status returns the status code sent to the client, defaulting to 200 if the handler wrote nothing.
+func (r *responseRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController (e.g. for flushing).
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// LoggingMiddleware logs incoming HTTP requests as structured slog entries.
+// See NewLoggingMiddleware for details.
+var LoggingMiddleware = NewLoggingMiddleware(AccessLogStructured, nil)
+
+// NewLoggingMiddleware returns a middleware that assigns each request an ID, stores a
+// request-scoped logger in the request context and writes an access log entry once the
+// request completes, including status code, response size and latency.
+//
+// An incoming X-Request-ID header is propagated if it looks sane; otherwise a new ID is
+// generated. Either way it is echoed back in the response.
+//
+// With AccessLogCombined, entries are written to out in Apache combined log format
+// instead of going through slog.
+func NewLoggingMiddleware(format AccessLogFormat, out io.Writer) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			requestID := r.Header.Get(logging.RequestIDHeader)
+			if !logging.ValidRequestID(requestID) {
+				requestID = logging.NewRequestID()
+			}
+			w.Header().Set(logging.RequestIDHeader, requestID)
+
+			logger := slog.Default().With("requestID", requestID)
+			ctx := logging.WithRequestID(r.Context(), requestID)
+			ctx = logging.WithLogger(ctx, logger)
+			r = r.WithContext(ctx)
+
+			logger.Debug("HTTP Request started",
+				"method", r.Method,
+				"uri", r.RequestURI,
+				"remoteAddr", r.RemoteAddr)
+
+			rec := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r) // Call the next handler
+
+			if format == AccessLogCombined {
+				writeCombinedLogLine(out, r, rec, start)
+				return
+			}
+			logger.Info("HTTP Request completed",
+				"method", r.Method,
+				"uri", r.RequestURI,
+				"status", rec.Status(),
+				"bytes", rec.bytes,
+				"duration", time.Since(start))
+		})
+	}
+}
+
+// writeCombinedLogLine writes one access log entry in Apache combined log format:
+// host ident authuser [date] "request" status bytes "referer" "user-agent"
+func writeCombinedLogLine(out io.Writer, r *http.Request, rec *responseRecorder, start time.Time) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	user := "-"
+	if r.URL.User != nil && r.URL.User.Username() != "" {
+		user = r.URL.User.Username()
+	}
+	size := "-"
+	if rec.bytes > 0 {
+		size = strconv.FormatInt(rec.bytes, 10)
+	}
+
+	line := fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %s %q %q\n",
+		host,
+		user,
+		start.Format("02/Jan/2006:15:04:05 -0700"),
+		r.Method, r.RequestURI, r.Proto,
+		rec.Status(),
+		size,
+		r.Referer(),
+		r.UserAgent())
+	if _, err := io.WriteString(out, line); err != nil {
+		slog.Error("Error writing access log", "error", err)
+	}
+}
+
+// MetricsMiddleware records request counts and latencies per route template, method and status.
+// Route templates (e.g. /api/books/{id}) are used instead of raw paths to keep label cardinality bounded.
+func MetricsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		// Use a response writer wrapper if you need to capture status code
-		// For simple logging, this is often sufficient.
-		slog.Debug("HTTP Request started",
-			"method", r.Method,
-			"uri", r.RequestURI,
-			"remoteAddr", r.RemoteAddr)
-
-		next.ServeHTTP(w, r) // Call the next handler
-
-		// Log after the request is handled
-		// Note: Status code logging requires a response writer wrapper.
-		// For now, just log duration.
-		slog.Info("HTTP Request completed",
-			"method", r.Method,
-			"uri", r.RequestURI,
-			"duration", time.Since(start))
+		rec := &responseRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r)
+
+		route := "unmatched"
+		if current := mux.CurrentRoute(r); current != nil {
+			if tpl, err := current.GetPathTemplate(); err == nil {
+				route = tpl
+			}
+		}
+		status := strconv.Itoa(rec.Status())
+
+		metrics.HTTPRequestsTotal.WithLabelValues(route, r.Method, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(route, r.Method, status).Observe(time.Since(start).Seconds())
 	})
 }
 
@@ -70,14 +213,39 @@ func (w gzipResponseWriter) Write(b []byte) (int, error) {
 	return w.Writer.Write(b)
 }
 
+// Flush flushes buffered compressed data to the client, so streaming responses
+// (e.g. Server-Sent Events) work through the gzip middleware.
+func (w gzipResponseWriter) Flush() {
+	if gz, ok := w.Writer.(*gzip.Writer); ok {
+		gz.Flush()
+	}
+	// The wrapped writer may itself be a middleware recorder, so flush through Unwrap chains
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying ResponseWriter, for http.ResponseController.
+func (w gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // SetupRouter configures the routes for the application.
 func SetupRouter(apiHandler *APIHandler, webDir string) *mux.Router {
 	r := mux.NewRouter()
 
 	// Apply middlewares to all routes
-	r.Use(LoggingMiddleware)
+	r.Use(MetricsMiddleware)
+	r.Use(NewLoggingMiddleware(apiHandler.AccessLogFormat, os.Stdout))
+	r.Use(ActorMiddleware)
+	r.Use(SessionMiddleware)
 	r.Use(GzipMiddleware)
 
+	// Prometheus metrics
+	r.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
+
+	// Liveness and readiness probes
+	r.HandleFunc("/healthz", apiHandler.HealthzHandler).Methods(http.MethodGet)
+	r.HandleFunc("/readyz", apiHandler.ReadyzHandler).Methods(http.MethodGet)
+
 	// API Routes (prefixed with /api)
 	apiRouter := r.PathPrefix("/api").Subrouter()
 	apiRouter.HandleFunc("/books", apiHandler.GetBooksHandler).Methods(http.MethodGet)
@@ -87,6 +255,89 @@ func SetupRouter(apiHandler *APIHandler, webDir string) *mux.Router {
 	apiRouter.HandleFunc("/books/{id:[0-9]+}/details", apiHandler.UpdateBookDetailsHandler).Methods(http.MethodPut) // For rating/comments
 	apiRouter.HandleFunc("/books/search", apiHandler.SearchBooksHandler).Methods(http.MethodGet)                    // Expects ?q=query
 	apiRouter.HandleFunc("/books/{id:[0-9]+}", apiHandler.DeleteBookHandler).Methods(http.MethodDelete)             // Delete a book
+	apiRouter.HandleFunc("/stats", apiHandler.StatsHandler).Methods(http.MethodGet)                                 // Reading statistics
+	apiRouter.HandleFunc("/events", apiHandler.EventsHandler).Methods(http.MethodGet)                               // Server-Sent Events stream
+
+	// Yearly reading goals
+	apiRouter.HandleFunc("/goals", apiHandler.GetGoalsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/goals/{year:[0-9]{4}}", apiHandler.GetGoalsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/goals/{year:[0-9]{4}}", apiHandler.SetGoalHandler).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/goals/{year:[0-9]{4}}", apiHandler.DeleteGoalHandler).Methods(http.MethodDelete)
+
+	// Authors, narrators and translators
+	apiRouter.HandleFunc("/authors", apiHandler.GetAuthorsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/authors/{id:[0-9]+}/books", apiHandler.GetAuthorBooksHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/authors", apiHandler.SetBookAuthorsHandler).Methods(http.MethodPut)
+
+	// Quotes and highlights
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/quotes", apiHandler.GetBookQuotesHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/quotes", apiHandler.AddBookQuoteHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/quotes/{quoteId:[0-9]+}", apiHandler.GetBookQuoteHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/quotes/{quoteId:[0-9]+}", apiHandler.UpdateBookQuoteHandler).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/quotes/{quoteId:[0-9]+}", apiHandler.DeleteBookQuoteHandler).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/quotes", apiHandler.SearchQuotesHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/quotes/random", apiHandler.RandomQuoteHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/import/kindle", apiHandler.ImportKindleHandler).Methods(http.MethodPost)
+
+	// Editions
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/editions", apiHandler.GetBookEditionsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/editions", apiHandler.AddBookEditionHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/editions/{editionId:[0-9]+}", apiHandler.UpdateBookEditionHandler).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/editions/{editionId:[0-9]+}", apiHandler.DeleteBookEditionHandler).Methods(http.MethodDelete)
+
+	// Loans of books to and from other people
+	apiRouter.HandleFunc("/loans", apiHandler.GetLoansHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/loans", apiHandler.AddLoanHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/loans/overdue", apiHandler.GetOverdueLoansHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/loans/{id:[0-9]+}", apiHandler.GetLoanHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/loans/{id:[0-9]+}", apiHandler.UpdateLoanHandler).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/loans/{id:[0-9]+}", apiHandler.DeleteLoanHandler).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/loans/{id:[0-9]+}/return", apiHandler.ReturnLoanHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/loans", apiHandler.GetBookLoansHandler).Methods(http.MethodGet)
+
+	// Ownership of books and the inventory of the collection
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/ownership", apiHandler.SetOwnershipHandler).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/ownership", apiHandler.ClearOwnershipHandler).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/inventory", apiHandler.GetInventoryHandler).Methods(http.MethodGet)
+
+	// Duplicate books and merging them
+	apiRouter.HandleFunc("/duplicates", apiHandler.GetDuplicatesHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/books/merge", apiHandler.MergeBooksHandler).Methods(http.MethodPost)
+
+	// Recommendations from cached Open Library metadata
+	apiRouter.HandleFunc("/recommendations", apiHandler.GetRecommendationsHandler).Methods(http.MethodGet)
+
+	// Undoing the session's recent changes
+	apiRouter.HandleFunc("/undo", apiHandler.UndoHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/redo", apiHandler.RedoHandler).Methods(http.MethodPost)
+
+	// Who changed what
+	apiRouter.HandleFunc("/audit", apiHandler.GetAuditLogHandler).Methods(http.MethodGet)
+
+	// Deleted books
+	apiRouter.HandleFunc("/trash", apiHandler.GetTrashHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/restore", apiHandler.RestoreBookHandler).Methods(http.MethodPost)
+
+	// Bulk changes to many books in one transaction
+	apiRouter.HandleFunc("/books/bulk", apiHandler.BulkBooksHandler).Methods(http.MethodPost)
+
+	// Manual shelf order; the Want to Read shelf is the reading queue
+	apiRouter.HandleFunc("/books/next", apiHandler.GetNextBookHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/books/{id:[0-9]+}/position", apiHandler.MoveBookHandler).Methods(http.MethodPut)
+
+	// Series, with ordering, gaps and completion
+	apiRouter.HandleFunc("/series", apiHandler.GetSeriesHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/series/next", apiHandler.GetNextInSeriesHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/series/{id:[0-9]+}", apiHandler.GetSeriesByIDHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/series/{id:[0-9]+}", apiHandler.UpdateSeriesHandler).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/series/{id:[0-9]+}/merge", apiHandler.MergeSeriesHandler).Methods(http.MethodPost)
+
+	// Webhook subscriptions
+	apiRouter.HandleFunc("/webhooks", apiHandler.GetWebhooksHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/webhooks", apiHandler.AddWebhookHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/webhooks/{id:[0-9]+}", apiHandler.GetWebhookHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/webhooks/{id:[0-9]+}", apiHandler.DeleteWebhookHandler).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/webhooks/{id:[0-9]+}/deliveries", apiHandler.GetWebhookDeliveriesHandler).Methods(http.MethodGet) // Delivery log
 
 	// Static File Server for Frontend
 	// Serve files from the web directory.
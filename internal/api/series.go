@@ -0,0 +1,128 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// seriesID parses the {id} route variable, responding 400 if it is malformed.
+func seriesID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid series ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// GetSeriesHandler handles GET /api/series requests.
+// Returns every series with its books in order, gaps, missing books and completion.
+func (h *APIHandler) GetSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	series, err := h.Series.GetSeries(r.Context())
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to retrieve series")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, series)
+}
+
+// GetSeriesByIDHandler handles GET /api/series/{id} requests.
+func (h *APIHandler) GetSeriesByIDHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := seriesID(w, r)
+	if !ok {
+		return
+	}
+	series, err := h.Series.GetSeriesByID(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to retrieve series")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, series)
+}
+
+// UpdateSeriesHandler handles PUT /api/series/{id} requests.
+// Expects {"name": "...", "total_books": 7}; total_books is optional (null if unknown).
+// Renaming to the name of another series responds 409; merge them instead.
+func (h *APIHandler) UpdateSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := seriesID(w, r)
+	if !ok {
+		return
+	}
+
+	var payload struct {
+		Name       string `json:"name"`
+		TotalBooks *int   `json:"total_books"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	series := model.Series{ID: id, Name: payload.Name, TotalBooks: payload.TotalBooks}
+	if err := series.Validate(); err != nil {
+		var validationErr *model.ValidationError
+		if errors.As(err, &validationErr) {
+			respondWithError(w, r, http.StatusBadRequest, validationErr.Message)
+		} else {
+			respondWithError(w, r, http.StatusBadRequest, "Invalid series: "+err.Error())
+		}
+		return
+	}
+
+	if err := h.Series.UpdateSeries(r.Context(), &series); err != nil {
+		respondWithStoreError(w, r, err, "Failed to update series")
+		return
+	}
+	h.respondWithSeries(w, r, id)
+}
+
+// MergeSeriesHandler handles POST /api/series/{id}/merge requests.
+// Expects {"into": <target series ID>}. Moves the books of series {id} into the target,
+// deletes series {id} and returns the target.
+func (h *APIHandler) MergeSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := seriesID(w, r)
+	if !ok {
+		return
+	}
+
+	var payload struct {
+		Into int64 `json:"into"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if payload.Into <= 0 {
+		respondWithError(w, r, http.StatusBadRequest, "into must be the ID of the series to merge into")
+		return
+	}
+	if payload.Into == id {
+		respondWithError(w, r, http.StatusBadRequest, "Cannot merge a series into itself")
+		return
+	}
+
+	if err := h.Series.MergeSeries(r.Context(), id, payload.Into); err != nil {
+		respondWithStoreError(w, r, err, "Failed to merge series")
+		return
+	}
+	h.respondWithSeries(w, r, payload.Into)
+}
+
+// respondWithSeries responds with the current state of a series after a change.
+func (h *APIHandler) respondWithSeries(w http.ResponseWriter, r *http.Request, id int64) {
+	series, err := h.Series.GetSeriesByID(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to retrieve series")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, series)
+}
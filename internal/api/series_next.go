@@ -0,0 +1,198 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/events"
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/textmatch"
+)
+
+// maxSeriesLookups caps the next books looked up with Open Library per request.
+const maxSeriesLookups = 10
+
+// seriesNextResponse is the body of GET /api/series/next responses.
+type seriesNextResponse struct {
+	Next []model.NextInSeries `json:"next"`
+	// Warning is set if Open Library could not be reached, so books not on the shelf have no
+	// candidate.
+	Warning string `json:"warning,omitempty"`
+}
+
+// GetNextInSeriesHandler handles GET /api/series/next requests.
+// Returns the next book to read of every series, after the highest position read, or only
+// the one after ?book_id=. The next book is the one on the shelf if there is one; otherwise
+// Open Library is searched for it and the best match is returned as a candidate to add to
+// Want to Read.
+func (h *APIHandler) GetNextInSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	var next []model.NextInSeries
+	if value := r.URL.Query().Get("book_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id < 1 {
+			respondWithError(w, r, http.StatusBadRequest, "Invalid book_id")
+			return
+		}
+		one, err := h.Series.GetNextInSeries(r.Context(), id)
+		if err != nil {
+			var validationErr *model.ValidationError
+			if errors.As(err, &validationErr) {
+				respondWithError(w, r, http.StatusBadRequest, validationErr.Message)
+			} else {
+				respondWithStoreError(w, r, err, "Failed to find the next book in the series")
+			}
+			return
+		}
+		next = []model.NextInSeries{}
+		if one != nil {
+			next = append(next, *one)
+		}
+	} else {
+		var err error
+		if next, err = h.Series.ListNextInSeries(r.Context()); err != nil {
+			respondWithStoreError(w, r, err, "Failed to find the next books in series")
+			return
+		}
+	}
+
+	response := seriesNextResponse{Next: next}
+	if err := h.findSeriesCandidates(r.Context(), next); err != nil {
+		if r.Context().Err() != nil {
+			respondWithStoreError(w, r, r.Context().Err(), "Failed to find the next books in series")
+			return
+		}
+		logging.FromContext(r.Context()).Warn("Looking up next books in series failed", "error", err)
+		response.Warning = "Could not search Open Library for books not on the shelf: " + err.Error()
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// findSeriesCandidates looks up with Open Library the next books that are not on the shelf,
+// setting their Candidate if one is found. At most maxSeriesLookups are looked up.
+func (h *APIHandler) findSeriesCandidates(ctx context.Context, next []model.NextInSeries) error {
+	var books []model.Book
+	lookups := 0
+	for i := range next {
+		if next[i].Book != nil || lookups == maxSeriesLookups {
+			continue
+		}
+		lookups++
+		if books == nil {
+			var err error
+			if books, err = h.Store.ListBooks(ctx, db.ListOptions{}); err != nil {
+				return err
+			}
+		}
+		candidate, err := h.findSeriesCandidate(ctx, next[i], books)
+		if err != nil {
+			return err
+		}
+		next[i].Candidate = candidate
+	}
+	return nil
+}
+
+// findSeriesCandidate searches Open Library for the next book of a series by the author of the
+// book read, and returns the first work found that is not on the shelf, ready to add, or nil
+// if there is none. The search is by series name and position, so the match is a best guess.
+func (h *APIHandler) findSeriesCandidate(ctx context.Context, next model.NextInSeries, books []model.Book) (*model.Book, error) {
+	query := url.Values{"q": {fmt.Sprintf("%s %d", next.Series, next.SeriesIndex)}}
+	for _, book := range books {
+		if book.ID == next.AfterBookID {
+			if author, _, _ := strings.Cut(book.Author, ","); strings.TrimSpace(author) != "" {
+				query.Set("author", strings.TrimSpace(author))
+			}
+		}
+	}
+	works, err := h.searchWorkMetadata(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+works:
+	for _, work := range works {
+		title := textmatch.NormalizeTitle(work.Title)
+		for _, book := range books {
+			if book.OpenLibraryID == work.OpenLibraryID ||
+				(textmatch.NormalizeTitle(book.Title) == title && textmatch.SameAuthor(book.Author, work.Author)) {
+				continue works
+			}
+		}
+		series, index := next.Series, next.SeriesIndex
+		candidate := &model.Book{Title: work.Title, Author: work.Author, OpenLibraryID: work.OpenLibraryID,
+			CoverURL: work.CoverURL, Status: model.StatusWantToRead, Type: model.TypeBook, Series: &series, SeriesIndex: &index}
+		if work.ISBN != nil {
+			candidate.ISBN = *work.ISBN
+		}
+		return candidate, nil
+	}
+	return nil, nil
+}
+
+// RunNextInSeries publishes a series.next event whenever a book numbered in a series moves
+// to Read, suggesting the next book, until ctx is cancelled. The next book is looked up with
+// Open Library if it is not on the shelf, so events may follow the status change by a few
+// seconds. Does nothing without an event bus.
+func (h *APIHandler) RunNextInSeries(ctx context.Context) {
+	if h.Events == nil {
+		return
+	}
+	var lastID uint64
+	for {
+		ch, replay, complete, cancel := h.Events.Subscribe(lastID)
+		if !complete {
+			slog.Warn("Next in series suggestions missed events", "lastEventID", lastID)
+		}
+		for _, event := range replay {
+			h.suggestNextInSeries(ctx, event)
+			lastID = event.ID
+		}
+
+	consume:
+		for {
+			select {
+			case <-ctx.Done():
+				cancel()
+				return
+			case event, ok := <-ch:
+				if !ok {
+					break consume
+				}
+				h.suggestNextInSeries(ctx, event)
+				lastID = event.ID
+			}
+		}
+		cancel()
+		slog.Warn("Next in series suggestions fell behind, resubscribing", "lastEventID", lastID)
+	}
+}
+
+// suggestNextInSeries publishes the next book of the series if event moved a numbered book
+// of a series to Read.
+func (h *APIHandler) suggestNextInSeries(ctx context.Context, event events.Event) {
+	if event.Type != events.BookUpdated || event.Book == nil || event.Previous == nil ||
+		event.Book.Status != model.StatusRead || event.Previous.Status == model.StatusRead || event.Book.SeriesIndex == nil {
+		return
+	}
+	next, err := h.Series.GetNextInSeries(ctx, event.BookID)
+	if err != nil {
+		slog.Error("Failed to find the next book in series", "bookID", event.BookID, "error", err)
+		return
+	}
+	if next == nil {
+		return // Read to the end of the series
+	}
+	found := []model.NextInSeries{*next}
+	if err := h.findSeriesCandidates(ctx, found); err != nil {
+		slog.Warn("Looking up the next book in series failed", "bookID", event.BookID, "error", err)
+	}
+	h.Events.Publish(events.Event{Type: events.SeriesNext, BookID: event.BookID, Next: &found[0]})
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/events"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestNextInSeriesHandler(t *testing.T) {
+	handler, store, router := newTestAPI(t)
+
+	calls := 0
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/search.json" && r.URL.Query().Get("q") == "Earthsea 2" && r.URL.Query().Get("author") == "Ursula K. Le Guin" {
+			w.Write([]byte(`{"docs":[{"key":"/works/OL59800W","title":"A Wizard of Earthsea","author_name":["Ursula K. Le Guin"]},
+				{"key":"/works/OL59802W","title":"The Tombs of Atuan","author_name":["Ursula K. Le Guin"],"isbn":["9780689845369"]}]}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer provider.Close()
+	handler.OpenLibraryBaseURL = provider.URL
+
+	earthsea, hyperion := "Earthsea", "Hyperion Cantos"
+	one, two := 1, 2
+	var ids []int64
+	for _, book := range []model.Book{
+		{Title: "A Wizard of Earthsea", Author: "Ursula K. Le Guin", OpenLibraryID: "OL59800W", Status: model.StatusRead, Series: &earthsea, SeriesIndex: &one},
+		{Title: "Hyperion", Author: "Dan Simmons", OpenLibraryID: "OL2794726W", Status: model.StatusCurrentlyReading, Series: &hyperion, SeriesIndex: &one},
+		{Title: "The Fall of Hyperion", Author: "Dan Simmons", OpenLibraryID: "OL2794727W", Status: model.StatusWantToRead, Series: &hyperion, SeriesIndex: &two},
+		{Title: "Dune", Author: "Frank Herbert", OpenLibraryID: "OL893415W", Status: model.StatusRead},
+	} {
+		id, err := store.AddBook(context.Background(), &book)
+		if err != nil {
+			t.Fatalf("Failed to add book: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	get := func(path string) (*httptest.ResponseRecorder, seriesNextResponse) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		var response seriesNextResponse
+		if rr.Code == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not unmarshal response: %v", err)
+			}
+		}
+		return rr, response
+	}
+
+	// Only Earthsea has a book read, and its next book is found with the provider
+	rr, response := get("/api/series/next")
+	if rr.Code != http.StatusOK || len(response.Next) != 1 || response.Warning != "" {
+		t.Fatalf("Expected the next Earthsea book, got %d %s", rr.Code, rr.Body.String())
+	}
+	next := response.Next[0]
+	if next.Series != "Earthsea" || next.AfterBookID != ids[0] || next.SeriesIndex != 2 || next.Book != nil {
+		t.Errorf("Unexpected suggestion %+v", next)
+	}
+	candidate := next.Candidate
+	if candidate == nil || candidate.Title != "The Tombs of Atuan" || candidate.ISBN != "9780689845369" ||
+		candidate.Status != model.StatusWantToRead || candidate.SeriesIndex == nil || *candidate.SeriesIndex != 2 {
+		t.Fatalf("Expected the book not on the shelf as a candidate, got %+v", candidate)
+	}
+	if err := candidate.Validate(); err != nil {
+		t.Errorf("Expected a candidate that can be added, got %v", err)
+	}
+
+	// The next book on the shelf needs no lookup
+	calls = 0
+	rr, response = get("/api/series/next?book_id=" + itoa(ids[1]))
+	if rr.Code != http.StatusOK || len(response.Next) != 1 || response.Next[0].Book == nil || response.Next[0].Book.ID != ids[2] || calls != 0 {
+		t.Fatalf("Expected The Fall of Hyperion from the shelf, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	provider.Close()
+	rr, response = get("/api/series/next")
+	if rr.Code != http.StatusOK || len(response.Next) != 1 || response.Next[0].Candidate != nil ||
+		!strings.HasPrefix(response.Warning, "Could not search Open Library") {
+		t.Errorf("Expected a warning without the provider, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	for path, want := range map[string]int{
+		"/api/series/next?book_id=abc":             http.StatusBadRequest,
+		"/api/series/next?book_id=" + itoa(ids[3]): http.StatusBadRequest,
+		"/api/series/next?book_id=999":             http.StatusNotFound,
+	} {
+		if rr, _ := get(path); rr.Code != want {
+			t.Errorf("Expected %d for %s, got %d", want, path, rr.Code)
+		}
+	}
+}
+
+func TestRunNextInSeries(t *testing.T) {
+	handler, store, _ := newTestAPI(t)
+	bus := events.NewBus(10)
+	handler.Events = bus
+	store.Events = bus
+
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/search.json" && r.URL.Query().Get("q") == "Hyperion Cantos 2" {
+			w.Write([]byte(`{"docs":[{"key":"/works/OL2794727W","title":"The Fall of Hyperion","author_name":["Dan Simmons"]}]}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer provider.Close()
+	handler.OpenLibraryBaseURL = provider.URL
+
+	// The next book is not on the shelf, so it is suggested as a candidate from Open Library
+	hyperion := "Hyperion Cantos"
+	one := 1
+	var ids []int64
+	for _, book := range []model.Book{
+		{Title: "Hyperion", Author: "Dan Simmons", OpenLibraryID: "OL2794726W", Status: model.StatusCurrentlyReading, Series: &hyperion, SeriesIndex: &one},
+	} {
+		id, err := store.AddBook(context.Background(), &book)
+		if err != nil {
+			t.Fatalf("Failed to add book: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := store.UpdateBookStatus(context.Background(), ids[0], model.StatusRead); err != nil {
+		t.Fatalf("Failed to update status: %v", err)
+	}
+	finished, err := store.GetBookByID(context.Background(), ids[0])
+	if err != nil {
+		t.Fatalf("Failed to get book: %v", err)
+	}
+	reading := *finished
+	reading.Status = model.StatusCurrentlyReading
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, _, _, unsubscribe := bus.Subscribe(bus.LastID())
+	defer unsubscribe()
+	go handler.RunNextInSeries(ctx)
+
+	// Publish the status change until the watcher has subscribed and suggests the next book
+	deadline := time.After(2 * time.Second)
+	tick := time.NewTicker(10 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case <-deadline:
+			t.Fatal("Timed out waiting for a series.next event")
+		case <-tick.C:
+			bus.Publish(events.Event{Type: events.BookUpdated, BookID: ids[0], Book: finished, Previous: &reading})
+		case event := <-ch:
+			if event.Type != events.SeriesNext {
+				continue
+			}
+			if event.BookID != ids[0] || event.Next == nil || event.Next.Book != nil ||
+				event.Next.Candidate == nil || event.Next.Candidate.OpenLibraryID != "OL2794727W" {
+				t.Fatalf("Expected The Fall of Hyperion to be suggested, got %+v", event)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestSeriesHandlers(t *testing.T) {
+	_, store, router := newTestAPI(t)
+	ctx := context.Background()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for i, name := range []string{"Expanse", "The Expanse"} {
+		id, err := store.AddBook(ctx, &model.Book{Title: name + " book", Author: "A", OpenLibraryID: "OL-S" + itoa(int64(i)), Status: model.StatusRead})
+		if err != nil {
+			t.Fatalf("Failed to add book: %v", err)
+		}
+		index := i + 1
+		if err := store.UpdateBookDetails(ctx, id, model.BookDetails{Series: &name, SeriesIndex: &index}); err != nil {
+			t.Fatalf("Failed to set series: %v", err)
+		}
+	}
+
+	rr := do(http.MethodGet, "/api/series", "")
+	var all []model.Series
+	if err := json.Unmarshal(rr.Body.Bytes(), &all); err != nil || len(all) != 2 {
+		t.Fatalf("Expected two series, got %d %s", rr.Code, rr.Body.String())
+	}
+	source, target := all[0], all[1]
+
+	if rr = do(http.MethodPut, "/api/series/"+itoa(source.ID), `{"name": "the expanse"}`); rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 renaming onto an existing series, got %d", rr.Code)
+	}
+
+	rr = do(http.MethodPost, "/api/series/"+itoa(source.ID)+"/merge", `{"into": `+itoa(target.ID)+`}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 merging, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var merged model.Series
+	if err := json.Unmarshal(rr.Body.Bytes(), &merged); err != nil {
+		t.Fatalf("Failed to decode series: %v", err)
+	}
+	if merged.Name != "The Expanse" || len(merged.Books) != 2 || merged.Status != model.SeriesComplete {
+		t.Errorf("Expected a complete series of 2, got %+v", merged)
+	}
+
+	rr = do(http.MethodPut, "/api/series/"+itoa(target.ID), `{"name": "The Expanse", "total_books": 9}`)
+	if err := json.Unmarshal(rr.Body.Bytes(), &merged); err != nil || merged.Status != model.SeriesInProgress || len(merged.Missing) != 7 {
+		t.Errorf("Expected 7 missing books once the length is known, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	tests := []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodGet, "/api/series/" + itoa(source.ID), "", http.StatusNotFound},
+		{http.MethodPost, "/api/series/" + itoa(target.ID) + "/merge", `{"into": ` + itoa(target.ID) + `}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/series/" + itoa(target.ID) + "/merge", `{"into": 999}`, http.StatusNotFound},
+		{http.MethodPut, "/api/series/" + itoa(target.ID), `{"name": " "}`, http.StatusBadRequest},
+		{http.MethodPut, "/api/series/" + itoa(target.ID), `{"name": "X", "total_books": 0}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rr := do(tt.method, tt.path, tt.body); rr.Code != tt.want {
+			t.Errorf("%s %s %s: expected %d, got %d", tt.method, tt.path, tt.body, tt.want, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package api
+
+import (
+	"net/http"
+)
+
+// StatsHandler handles GET /api/stats requests.
+// Returns aggregate reading statistics (books finished per period, ratings, top authors and
+// series, type split, average time to finish and reading streaks).
+func (h *APIHandler) StatsHandler(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.Stats.GetStats(r.Context())
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to compute statistics")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, stats)
+}
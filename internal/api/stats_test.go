@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestStatsHandler(t *testing.T) {
+	_, store, router := newTestAPI(t)
+	ctx := context.Background()
+
+	rating := 7
+	book := &model.Book{Title: "Stats Book", Author: "Author", OpenLibraryID: "OL1STATS", Status: model.StatusWantToRead, Type: model.TypeAudiobook}
+	id, err := store.AddBook(ctx, book)
+	if err != nil {
+		t.Fatalf("Failed to add book: %v", err)
+	}
+	if err := store.UpdateBookStatus(ctx, id, model.StatusRead); err != nil {
+		t.Fatalf("Failed to update status: %v", err)
+	}
+	if err := store.UpdateBookDetails(ctx, id, model.BookDetails{Rating: &rating}); err != nil {
+		t.Fatalf("Failed to rate book: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/stats", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var stats model.Stats
+	if err := json.Unmarshal(rr.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Failed to decode stats: %v", err)
+	}
+	if stats.FinishedBooks != 1 || len(stats.FinishedPerMonth) != 1 || stats.FinishedPerMonth[0].Count != 1 {
+		t.Errorf("Expected one book finished this month, got %+v", stats)
+	}
+	if stats.AverageRating == nil || *stats.AverageRating != 7 {
+		t.Errorf("Expected average rating 7, got %v", stats.AverageRating)
+	}
+	if stats.CurrentStreakMonths != 1 {
+		t.Errorf("Expected a current streak of 1 month, got %d", stats.CurrentStreakMonths)
+	}
+	if len(stats.ByType) != 1 || stats.ByType[0].Type != model.TypeAudiobook {
+		t.Errorf("Expected only audiobooks in type split, got %+v", stats.ByType)
+	}
+}
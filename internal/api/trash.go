@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// GetTrashHandler handles GET /api/trash requests.
+// Returns the deleted books, most recently deleted first.
+func (h *APIHandler) GetTrashHandler(w http.ResponseWriter, r *http.Request) {
+	books, err := h.Trash.GetTrash(r.Context())
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to retrieve trash")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, books)
+}
+
+// RestoreBookHandler handles POST /api/books/{id}/restore requests.
+// Puts a deleted book back at the end of its shelf and responds with it.
+func (h *APIHandler) RestoreBookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid book ID")
+		return
+	}
+	r, ok := withIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Trash.RestoreBook(r.Context(), id); err != nil {
+		respondWithStoreError(w, r, err, "Failed to restore book")
+		return
+	}
+	book, err := h.Store.GetBookByID(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to retrieve book")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, book)
+}
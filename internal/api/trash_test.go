@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestTrashHandlers(t *testing.T) {
+	_, store, router := newTestAPI(t)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+		return rr
+	}
+
+	id, err := store.AddBook(context.Background(), &model.Book{Title: "Dune", Author: "Frank Herbert", OpenLibraryID: "OL-Dune", Status: model.StatusRead})
+	if err != nil {
+		t.Fatalf("Failed to add book: %v", err)
+	}
+	path := "/api/books/" + itoa(id)
+
+	if rr := do(http.MethodDelete, path); rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204 deleting, got %d", rr.Code)
+	}
+	if rr := do(http.MethodGet, path); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a trashed book, got %d", rr.Code)
+	}
+
+	rr := do(http.MethodGet, "/api/trash")
+	var trash []model.Book
+	if err := json.Unmarshal(rr.Body.Bytes(), &trash); err != nil || len(trash) != 1 || trash[0].ID != id || trash[0].DeletedAt == nil {
+		t.Fatalf("Expected the book in the trash, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = do(http.MethodPost, path+"/restore")
+	var book model.Book
+	if err := json.Unmarshal(rr.Body.Bytes(), &book); err != nil || rr.Code != http.StatusOK || book.ID != id || book.DeletedAt != nil {
+		t.Fatalf("Expected the restored book, got %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := do(http.MethodPost, path+"/restore"); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 restoring a book not in the trash, got %d", rr.Code)
+	}
+	if rr := do(http.MethodGet, "/api/trash"); rr.Body.String() != "[]\n" && rr.Body.String() != "[]" {
+		t.Errorf("Expected an empty trash, got %s", rr.Body.String())
+	}
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/logging"
+)
+
+// SessionHeader identifies the client session (a browser tab) whose changes undo and redo apply to.
+const SessionHeader = "X-Session-ID"
+
+// SessionMiddleware attaches the session named by the X-Session-ID header to the request
+// context, so that the changes it makes can be undone by the same session. Session IDs follow
+// the same rules as request IDs; others are ignored.
+func SessionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if session := r.Header.Get(SessionHeader); logging.ValidRequestID(session) {
+			r = r.WithContext(db.WithSession(r.Context(), session))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// UndoHandler handles POST /api/undo requests.
+// Reverses the session's most recent change and responds with what was undone.
+func (h *APIHandler) UndoHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireSession(w, r) {
+		return
+	}
+	step, err := h.Undo.Undo(r.Context())
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to undo")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, step)
+}
+
+// RedoHandler handles POST /api/redo requests.
+// Makes the session's most recently undone change again and responds with what was redone.
+func (h *APIHandler) RedoHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireSession(w, r) {
+		return
+	}
+	step, err := h.Undo.Redo(r.Context())
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to redo")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, step)
+}
+
+// requireSession responds 400 and returns false if the request has no valid session.
+func requireSession(w http.ResponseWriter, r *http.Request) bool {
+	if !logging.ValidRequestID(r.Header.Get(SessionHeader)) {
+		respondWithError(w, r, http.StatusBadRequest, "A valid "+SessionHeader+" header is required")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestUndoHandlers(t *testing.T) {
+	_, store, router := newTestAPI(t)
+
+	do := func(method, path, session, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if session != "" {
+			req.Header.Set(SessionHeader, session)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	id, err := store.AddBook(context.Background(), &model.Book{Title: "Dune", Author: "Frank Herbert", OpenLibraryID: "OL-Dune", Status: model.StatusWantToRead})
+	if err != nil {
+		t.Fatalf("Failed to add book: %v", err)
+	}
+	if rr := do(http.MethodPut, "/api/books/"+itoa(id), "tab-1", `{"status":"Read"}`); rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 updating status, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	if rr := do(http.MethodPost, "/api/undo", "", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a session, got %d", rr.Code)
+	}
+	if rr := do(http.MethodPost, "/api/undo", "tab-2", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 with nothing to undo, got %d", rr.Code)
+	}
+
+	rr := do(http.MethodPost, "/api/undo", "tab-1", "")
+	var step model.UndoStep
+	if err := json.Unmarshal(rr.Body.Bytes(), &step); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Expected the undone step, got %d %s", rr.Code, rr.Body.String())
+	}
+	if step.Action != model.UndoSetStatus || len(step.BookIDs) != 1 || step.BookIDs[0] != id {
+		t.Errorf("Expected the status change undone, got %+v", step)
+	}
+	if book, _ := store.GetBookByID(context.Background(), id); book.Status != model.StatusWantToRead {
+		t.Errorf("Expected the book back in Want to Read, got %q", book.Status)
+	}
+
+	if rr := do(http.MethodPost, "/api/redo", "tab-1", ""); rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 redoing, got %d %s", rr.Code, rr.Body.String())
+	}
+	if book, _ := store.GetBookByID(context.Background(), id); book.Status != model.StatusRead {
+		t.Errorf("Expected the book read again, got %q", book.Status)
+	}
+}
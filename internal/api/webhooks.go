@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/webhook"
+	"github.com/gorilla/mux"
+)
+
+// Delivery log page sizes for GET /api/webhooks/{id}/deliveries.
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 500
+)
+
+// webhookID parses the {id} route variable, responding 400 if it is malformed.
+func webhookID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid webhook ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// GetWebhooksHandler handles GET /api/webhooks requests.
+// Secrets are never included in the listing.
+func (h *APIHandler) GetWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := h.Webhooks.GetWebhooks(r.Context())
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to retrieve webhooks")
+		return
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	respondWithJSON(w, http.StatusOK, webhooks)
+}
+
+// AddWebhookHandler handles POST /api/webhooks requests.
+// Expects {"url": ..., "events": [...], "secret": ...}; events and secret are optional.
+// A random secret is generated if none is given. The response is the only time the
+// secret is returned.
+func (h *APIHandler) AddWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		URL    string               `json:"url"`
+		Events []model.WebhookEvent `json:"events"`
+		Secret string               `json:"secret"`
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 64*1024)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	hook := model.Webhook{URL: payload.URL, Events: payload.Events, Secret: payload.Secret}
+	if hook.Events == nil {
+		hook.Events = []model.WebhookEvent{}
+	}
+	if err := hook.Validate(); err != nil {
+		var validationErr *model.ValidationError
+		if errors.As(err, &validationErr) {
+			respondWithError(w, r, http.StatusBadRequest, validationErr.Message)
+		} else {
+			respondWithError(w, r, http.StatusBadRequest, "Invalid webhook: "+err.Error())
+		}
+		return
+	}
+	if hook.Secret == "" {
+		secret, err := webhook.NewSecret()
+		if err != nil {
+			respondWithError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		hook.Secret = secret
+	}
+
+	if _, err := h.Webhooks.AddWebhook(r.Context(), &hook); err != nil {
+		respondWithStoreError(w, r, err, "Failed to add webhook")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, hook)
+}
+
+// GetWebhookHandler handles GET /api/webhooks/{id} requests.
+func (h *APIHandler) GetWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+	hook, err := h.Webhooks.GetWebhookByID(r.Context(), id)
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to retrieve webhook")
+		return
+	}
+	hook.Secret = ""
+	respondWithJSON(w, http.StatusOK, hook)
+}
+
+// DeleteWebhookHandler handles DELETE /api/webhooks/{id} requests.
+func (h *APIHandler) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Webhooks.DeleteWebhook(r.Context(), id); err != nil {
+		respondWithStoreError(w, r, err, "Failed to delete webhook")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetWebhookDeliveriesHandler handles GET /api/webhooks/{id}/deliveries requests.
+// Returns the most recent delivery attempts, newest first; ?limit= caps the count.
+func (h *APIHandler) GetWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+	limit := defaultDeliveryLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > maxDeliveryLimit {
+			respondWithError(w, r, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxDeliveryLimit))
+			return
+		}
+		limit = parsed
+	}
+
+	deliveries, err := h.Webhooks.GetDeliveries(r.Context(), id, limit)
+	if err != nil {
+		respondWithStoreError(w, r, err, "Failed to retrieve webhook deliveries")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, deliveries)
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestWebhookHandlers(t *testing.T) {
+	_, _, router := newTestAPI(t)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Create with a generated secret
+	rr := do(http.MethodPost, "/api/webhooks", `{"url":"https://chat.example.com/hook","events":["book.added"]}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var created model.Webhook
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Failed to decode webhook: %v", err)
+	}
+	if created.ID == 0 || len(created.Secret) != 64 {
+		t.Errorf("Expected ID and generated secret, got %+v", created)
+	}
+
+	// Secrets are not listed
+	rr = do(http.MethodGet, "/api/webhooks", "")
+	var listed []model.Webhook
+	if err := json.Unmarshal(rr.Body.Bytes(), &listed); err != nil {
+		t.Fatalf("Failed to decode webhooks: %v", err)
+	}
+	if len(listed) != 1 || listed[0].Secret != "" {
+		t.Errorf("Expected one webhook without secret, got %+v", listed)
+	}
+
+	// Delivery log starts empty
+	rr = do(http.MethodGet, "/api/webhooks/1/deliveries", "")
+	if rr.Code != http.StatusOK || strings.TrimSpace(rr.Body.String()) != "[]" {
+		t.Errorf("Expected empty delivery log, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr = do(http.MethodGet, "/api/webhooks/1/deliveries?limit=0", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for limit=0, got %d", rr.Code)
+	}
+
+	// Validation
+	for _, body := range []string{
+		`{"url":"not a url"}`,
+		`{"url":"https://example.com","events":["book.exploded"]}`,
+		`{"url":"https://example.com","unknown":true}`,
+	} {
+		if rr := do(http.MethodPost, "/api/webhooks", body); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, rr.Code)
+		}
+	}
+
+	if rr = do(http.MethodDelete, "/api/webhooks/1", ""); rr.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 on delete, got %d", rr.Code)
+	}
+	if rr = do(http.MethodGet, "/api/webhooks/1", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 after delete, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,185 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/metrics"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// AuditStore defines the interface for reading the audit log. Entries are written by the
+// book store mutations themselves, in the same transaction as the change.
+type AuditStore interface {
+	GetAuditLog(ctx context.Context, filter AuditFilter) (*model.AuditPage, error)
+}
+
+// AuditFilter selects audit entries. Zero values match everything.
+type AuditFilter struct {
+	BookID       int64
+	Actor        string
+	Action       model.AuditAction
+	From, Before *time.Time // Entry time range; From is inclusive, Before exclusive
+
+	// BeforeID continues from a previous page (its NextBeforeID).
+	BeforeID int64
+	Limit    int
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying the user making changes (typically from an
+// authenticating proxy). Mutations made with this context record them in the audit log.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// actorFromContext returns the actor set by WithActor, or "" if there is none.
+func actorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// readBook reads a book through q, including a book in the trash, for the audit log.
+// It returns nil if the book does not exist.
+func readBook(ctx context.Context, q dbtx, id int64) (*model.Book, error) {
+	book, err := scanBook(q.QueryRowContext(ctx, `SELECT `+bookColumns+` FROM books WHERE id = ?;`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read book %d: %w", id, err)
+	}
+
+	rows, err := q.QueryContext(ctx, `
+        SELECT t.name FROM book_tags bt JOIN tags t ON t.id = bt.tag_id
+        WHERE bt.book_id = ? ORDER BY t.name;`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tags of book %d: %w", id, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		book.Tags = append(book.Tags, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	editions, err := editionsOf(ctx, q, []int64{id})
+	if err != nil {
+		return nil, err
+	}
+	book.Editions = editions[id]
+
+	err = q.QueryRowContext(ctx, `
+        SELECT EXISTS (SELECT 1 FROM loans WHERE book_id = ? AND direction = 'lent' AND returned_at IS NULL);`, id).Scan(&book.Lent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read loans of book %d: %w", id, err)
+	}
+	return book, nil
+}
+
+// recordAudit writes an audit entry for a change to book id, given the book as it was before
+// the change (nil if it was just created). The book after the change is read through q, which
+// must be the transaction making the change. Nothing is recorded if nothing changed.
+func recordAudit(ctx context.Context, q dbtx, action model.AuditAction, id int64, before *model.Book) error {
+	after, err := readBook(ctx, q, id)
+	if err != nil {
+		return err
+	}
+	beforeJSON, afterJSON, err := model.DiffBooks(before, after)
+	if err != nil {
+		return fmt.Errorf("failed to diff book %d: %w", id, err)
+	}
+	if beforeJSON == nil && afterJSON == nil {
+		return nil
+	}
+	_, err = q.ExecContext(ctx, `
+        INSERT INTO audit_log (actor, request_id, action, book_id, before, after) VALUES (?, ?, ?, ?, ?, ?);`,
+		actorFromContext(ctx), logging.RequestID(ctx), action, id, string(beforeJSON), string(afterJSON))
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return nil
+}
+
+// GetAuditLog retrieves a page of audit entries matching filter, newest first.
+func (s *SQLiteBookStore) GetAuditLog(ctx context.Context, filter AuditFilter) (*model.AuditPage, error) {
+	defer metrics.ObserveDBQuery("GetAuditLog", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	var where []string
+	var args []interface{}
+	if filter.BookID != 0 {
+		where = append(where, "book_id = ?")
+		args = append(args, filter.BookID)
+	}
+	if filter.Actor != "" {
+		where = append(where, "actor = ?")
+		args = append(args, filter.Actor)
+	}
+	if filter.Action != "" {
+		where = append(where, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.From != nil {
+		where = append(where, "created_at >= ?")
+		args = append(args, sqlTimestamp(filter.From))
+	}
+	if filter.Before != nil {
+		where = append(where, "created_at < ?")
+		args = append(args, sqlTimestamp(filter.Before))
+	}
+	if filter.BeforeID != 0 {
+		where = append(where, "id < ?")
+		args = append(args, filter.BeforeID)
+	}
+	query := `SELECT id, created_at, actor, request_id, action, book_id, before, after FROM audit_log`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	// One extra row tells whether there is another page
+	query += ` ORDER BY id DESC LIMIT ?;`
+	args = append(args, filter.Limit+1)
+	logger.Info("SQL: Executing GetAuditLog query", "query", query)
+
+	page := &model.AuditPage{Entries: []model.AuditEntry{}}
+	err := s.queryRows(ctx, query, args, func(rows *sql.Rows) error {
+		var e model.AuditEntry
+		var before, after sql.NullString
+		if err := rows.Scan(&e.ID, &e.Time, &e.Actor, &e.RequestID, &e.Action, &e.BookID, &before, &after); err != nil {
+			return err
+		}
+		e.Before = rawJSON(before)
+		e.After = rawJSON(after)
+		page.Entries = append(page.Entries, e)
+		return nil
+	})
+	if err != nil {
+		logger.Error("SQL Error: Querying audit log failed", "error", err)
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	if len(page.Entries) > filter.Limit {
+		page.Entries = page.Entries[:filter.Limit]
+		page.NextBeforeID = &page.Entries[filter.Limit-1].ID
+	}
+	return page, nil
+}
+
+// rawJSON converts a nullable JSON column to a raw message, JSON null if NULL.
+func rawJSON(value sql.NullString) []byte {
+	if !value.Valid || value.String == "" {
+		return []byte("null")
+	}
+	return []byte(value.String)
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestAuditLog(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := WithActor(logging.WithRequestID(context.Background(), "req-1"), "alice")
+
+	id, err := store.AddBook(ctx, &model.Book{Title: "Dune", Author: "Frank Herbert", OpenLibraryID: "OL-Dune", Status: model.StatusWantToRead})
+	if err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	other, err := store.AddBook(context.Background(), &model.Book{Title: "Emma", Author: "Jane Austen", OpenLibraryID: "OL-Emma", Status: model.StatusRead})
+	if err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	if err := store.UpdateBookStatus(ctx, id, model.StatusCurrentlyReading); err != nil {
+		t.Fatalf("UpdateBookStatus failed: %v", err)
+	}
+	if err := store.DeleteBook(ctx, id); err != nil {
+		t.Fatalf("DeleteBook failed: %v", err)
+	}
+	if err := store.RestoreBook(ctx, id); err != nil {
+		t.Fatalf("RestoreBook failed: %v", err)
+	}
+	if err := store.DeleteBook(ctx, other); err != nil {
+		t.Fatalf("DeleteBook failed: %v", err)
+	}
+	if _, err := store.PurgeTrash(context.Background(), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("PurgeTrash failed: %v", err)
+	}
+
+	page, err := store.GetAuditLog(context.Background(), AuditFilter{BookID: id, Limit: 10})
+	if err != nil {
+		t.Fatalf("GetAuditLog failed: %v", err)
+	}
+	var actions []string
+	for _, e := range page.Entries {
+		actions = append(actions, string(e.Action))
+	}
+	if got := strings.Join(actions, ","); got != "restore,delete,update,create" {
+		t.Fatalf("Expected newest-first restore,delete,update,create, got %s", got)
+	}
+	update := page.Entries[2]
+	if update.Actor != "alice" || update.RequestID != "req-1" {
+		t.Errorf("Expected actor and request ID recorded, got %q %q", update.Actor, update.RequestID)
+	}
+	if !strings.Contains(string(update.Before), `"status":"Want to Read"`) || !strings.Contains(string(update.After), `"status":"Currently Reading"`) {
+		t.Errorf("Expected the status change in the diff, got %s -> %s", update.Before, update.After)
+	}
+	if strings.Contains(string(update.After), `"title"`) {
+		t.Errorf("Expected unchanged fields left out of the diff, got %s", update.After)
+	}
+	if create := page.Entries[3]; string(create.Before) != "null" || !strings.Contains(string(create.After), `"title":"Dune"`) {
+		t.Errorf("Expected the whole book for the create, got %s -> %s", create.Before, create.After)
+	}
+
+	page, err = store.GetAuditLog(context.Background(), AuditFilter{Action: model.AuditPurge, Limit: 10})
+	if err != nil {
+		t.Fatalf("GetAuditLog failed: %v", err)
+	}
+	if len(page.Entries) != 1 || page.Entries[0].BookID != other || string(page.Entries[0].After) != "null" || page.Entries[0].Actor != "" {
+		t.Errorf("Expected an anonymous purge of the other book, got %+v", page.Entries)
+	}
+
+	page, err = store.GetAuditLog(context.Background(), AuditFilter{Actor: "alice", Limit: 2})
+	if err != nil {
+		t.Fatalf("GetAuditLog failed: %v", err)
+	}
+	if len(page.Entries) != 2 || page.NextBeforeID == nil {
+		t.Fatalf("Expected a first page of 2 with more to come, got %+v", page)
+	}
+	seen := len(page.Entries)
+	for page.NextBeforeID != nil {
+		page, err = store.GetAuditLog(context.Background(), AuditFilter{Actor: "alice", Limit: 2, BeforeID: *page.NextBeforeID})
+		if err != nil {
+			t.Fatalf("GetAuditLog failed: %v", err)
+		}
+		seen += len(page.Entries)
+	}
+	if seen != 5 {
+		t.Errorf("Expected 5 entries by alice across pages, got %d", seen)
+	}
+
+	future := time.Now().Add(time.Hour)
+	if page, _ := store.GetAuditLog(context.Background(), AuditFilter{From: &future, Limit: 10}); len(page.Entries) != 0 {
+		t.Errorf("Expected no entries from the future, got %d", len(page.Entries))
+	}
+}
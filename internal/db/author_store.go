@@ -0,0 +1,247 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/events"
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/metrics"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// AuthorStore defines the interface for database operations on authors and their credits.
+// The books of an author are listed with BookStore.ListBooks and ListOptions.AuthorID.
+type AuthorStore interface {
+	GetAuthors(ctx context.Context, role model.AuthorRole) ([]model.Author, error)
+	GetAuthorByID(ctx context.Context, id int64) (*model.Author, error)
+	SetBookAuthors(ctx context.Context, bookID int64, contributors []model.Contributor) error
+}
+
+// authorColumns selects an author with the number of books crediting them (a is authors).
+const authorColumns = `a.id, a.name, a.open_library_key,
+    (SELECT COUNT(DISTINCT ba.book_id) FROM book_authors ba JOIN books b ON b.id = ba.book_id WHERE ba.author_id = a.id AND b.deleted_at IS NULL)`
+
+// scanAuthor scans a row selected with authorColumns.
+func scanAuthor(row rowScanner) (*model.Author, error) {
+	var author model.Author
+	var key sql.NullString
+	if err := row.Scan(&author.ID, &author.Name, &key, &author.BookCount); err != nil {
+		return nil, err
+	}
+	if key.Valid {
+		author.OpenLibraryKey = &key.String
+	}
+	return &author, nil
+}
+
+// findOrCreateAuthor resolves a contributor to an author row and sets its AuthorID.
+// Authors are matched by Open Library key, then by name among authors whose key is unknown
+// or not given, so that a key learned later is attached to the existing author.
+func findOrCreateAuthor(ctx context.Context, q dbtx, c *model.Contributor) error {
+	if c.OpenLibraryKey != nil {
+		err := q.QueryRowContext(ctx, `SELECT id FROM authors WHERE open_library_key = ?;`, *c.OpenLibraryKey).Scan(&c.AuthorID)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to look up author by key: %w", err)
+		}
+	}
+
+	var key sql.NullString
+	err := q.QueryRowContext(ctx, `
+        SELECT id, open_library_key FROM authors
+        WHERE name = ? AND (open_library_key IS NULL OR ? IS NULL)
+        ORDER BY open_library_key IS NULL, id LIMIT 1;`, c.Name, c.OpenLibraryKey).Scan(&c.AuthorID, &key)
+	switch {
+	case err == nil:
+		if !key.Valid && c.OpenLibraryKey != nil {
+			if _, err := q.ExecContext(ctx, `UPDATE authors SET open_library_key = ? WHERE id = ?;`, *c.OpenLibraryKey, c.AuthorID); err != nil {
+				return fmt.Errorf("failed to set author key: %w", err)
+			}
+		}
+		return nil
+	case !errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("failed to look up author by name: %w", err)
+	}
+
+	res, err := q.ExecContext(ctx, `INSERT INTO authors (name, open_library_key) VALUES (?, ?);`, c.Name, c.OpenLibraryKey)
+	if err != nil {
+		return fmt.Errorf("failed to insert author: %w", err)
+	}
+	if c.AuthorID, err = res.LastInsertId(); err != nil {
+		return fmt.Errorf("failed to retrieve author ID: %w", err)
+	}
+	return nil
+}
+
+// setContributors replaces the credits of a book, in order. The same author may be credited
+// in several roles; repeated credits in the same role are ignored.
+func setContributors(ctx context.Context, q dbtx, bookID int64, contributors []model.Contributor) error {
+	if _, err := q.ExecContext(ctx, `DELETE FROM book_authors WHERE book_id = ?;`, bookID); err != nil {
+		return fmt.Errorf("failed to clear book authors: %w", err)
+	}
+	for i := range contributors {
+		if err := findOrCreateAuthor(ctx, q, &contributors[i]); err != nil {
+			return err
+		}
+		_, err := q.ExecContext(ctx, `
+            INSERT OR IGNORE INTO book_authors (book_id, author_id, role, position) VALUES (?, ?, ?, ?);`,
+			bookID, contributors[i].AuthorID, contributors[i].Role, i+1)
+		if err != nil {
+			return fmt.Errorf("failed to credit author: %w", err)
+		}
+	}
+	return nil
+}
+
+// loadContributors fills in the Authors of each book with a single query.
+func (s *SQLiteBookStore) loadContributors(ctx context.Context, books []model.Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+	index := make(map[int64]int, len(books))
+	placeholders := make([]string, len(books))
+	args := make([]interface{}, len(books))
+	for i, book := range books {
+		index[book.ID] = i
+		placeholders[i] = "?"
+		args[i] = book.ID
+	}
+
+	return s.queryRows(ctx, `
+        SELECT ba.book_id, a.id, a.name, a.open_library_key, ba.role
+        FROM book_authors ba JOIN authors a ON a.id = ba.author_id
+        WHERE ba.book_id IN (`+strings.Join(placeholders, ", ")+`)
+        ORDER BY ba.book_id, ba.position, ba.role;`, args, func(rows *sql.Rows) error {
+		var bookID int64
+		var c model.Contributor
+		var key sql.NullString
+		if err := rows.Scan(&bookID, &c.AuthorID, &c.Name, &key, &c.Role); err != nil {
+			return err
+		}
+		if key.Valid {
+			c.OpenLibraryKey = &key.String
+		}
+		i := index[bookID]
+		books[i].Authors = append(books[i].Authors, c)
+		return nil
+	})
+}
+
+// GetAuthors retrieves the authors credited on at least one book, ordered by name.
+// If role is not empty, only authors credited in that role are returned.
+func (s *SQLiteBookStore) GetAuthors(ctx context.Context, role model.AuthorRole) ([]model.Author, error) {
+	defer metrics.ObserveDBQuery("GetAuthors", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	logger.Info("SQL: Executing GetAuthors query", "role", role)
+	authors := []model.Author{}
+	err := s.queryRows(ctx, `
+        SELECT `+authorColumns+` FROM authors a
+        WHERE EXISTS (
+            SELECT 1 FROM book_authors ba JOIN books b ON b.id = ba.book_id
+            WHERE ba.author_id = a.id AND b.deleted_at IS NULL AND (? = '' OR ba.role = ?)
+        )
+        ORDER BY a.name, a.id;`, []interface{}{role, role}, func(rows *sql.Rows) error {
+		author, err := scanAuthor(rows)
+		if err != nil {
+			return err
+		}
+		authors = append(authors, *author)
+		return nil
+	})
+	if err != nil {
+		logger.Error("SQL Error: Executing GetAuthors query failed", "error", err)
+		return nil, fmt.Errorf("failed to query authors: %w", err)
+	}
+	return authors, nil
+}
+
+// GetAuthorByID retrieves a single author by ID.
+func (s *SQLiteBookStore) GetAuthorByID(ctx context.Context, id int64) (*model.Author, error) {
+	defer metrics.ObserveDBQuery("GetAuthorByID", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	logger.Info("SQL: Executing GetAuthorByID query", "id", id)
+	author, err := scanAuthor(s.DB.QueryRowContext(ctx, `SELECT `+authorColumns+` FROM authors a WHERE a.id = ?;`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("author with ID %d: %w", id, ErrNotFound)
+		}
+		logger.Error("SQL Error: Scanning author row failed", "id", id, "error", err)
+		return nil, fmt.Errorf("failed to scan author row for ID %d: %w", id, err)
+	}
+	return author, nil
+}
+
+// SetBookAuthors replaces the authors, narrators and translators credited on a book.
+// The book's display author is rebuilt from the contributors credited as authors, if any.
+func (s *SQLiteBookStore) SetBookAuthors(ctx context.Context, bookID int64, contributors []model.Contributor) error {
+	defer metrics.ObserveDBQuery("SetBookAuthors", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	for i := range contributors {
+		if err := contributors[i].Validate(); err != nil {
+			return fmt.Errorf("validation failed: %w", err)
+		}
+	}
+
+	logger.Info("SQL: Executing SetBookAuthors", "id", bookID, "count", len(contributors))
+	previous := s.snapshot(ctx, bookID)
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := readBook(ctx, tx, bookID)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `
+        UPDATE books SET author = COALESCE(NULLIF(?, ''), author), version = version + 1
+        WHERE id = ? AND deleted_at IS NULL AND (? IS NULL OR version = ?);`,
+		model.AuthorNames(contributors), bookID, expectedVersion(ctx), expectedVersion(ctx))
+	if err != nil {
+		logger.Error("SQL Error: Updating book author failed", "error", err)
+		return fmt.Errorf("failed to update book author: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return s.notFoundOrConflict(ctx, tx, bookID)
+	}
+
+	if err := setContributors(ctx, tx, bookID, contributors); err != nil {
+		logger.Error("SQL Error: Setting book authors failed", "error", err)
+		return err
+	}
+	if err := recordAudit(ctx, tx, model.AuditUpdate, bookID, before); err != nil {
+		logger.Error("SQL Error: Recording audit entry failed", "error", err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("SQL Error: Committing SetBookAuthors transaction failed", "error", err)
+		return fmt.Errorf("failed to commit book authors: %w", err)
+	}
+
+	logger.Info("SQL: Successfully set book authors", "id", bookID)
+	s.publish(events.BookUpdated, bookID, previous, s.snapshot(ctx, bookID))
+	return nil
+}
@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestAddBookCreditsAuthors(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	key := "OL23919A"
+	first := &model.Book{Title: "Earthsea", Author: "Ursula K. Le Guin", OpenLibraryID: "OL-E",
+		Authors: []model.Contributor{{Name: "Ursula K. Le Guin", OpenLibraryKey: &key}}}
+	if _, err := store.AddBook(ctx, first); err != nil {
+		t.Fatalf("Failed to add book: %v", err)
+	}
+	// Without contributors the display author is split, and matched to the known author by name
+	second := &model.Book{Title: "Good Omens", Author: "Neil Gaiman, Ursula K. Le Guin", OpenLibraryID: "OL-G"}
+	id, err := store.AddBook(ctx, second)
+	if err != nil {
+		t.Fatalf("Failed to add book: %v", err)
+	}
+
+	book, err := store.GetBookByID(ctx, id)
+	if err != nil {
+		t.Fatalf("GetBookByID failed: %v", err)
+	}
+	if len(book.Authors) != 2 || book.Authors[0].Name != "Neil Gaiman" || book.Authors[1].Role != model.RoleAuthor {
+		t.Fatalf("Expected two credited authors, got %+v", book.Authors)
+	}
+	if book.Authors[1].AuthorID != first.Authors[0].AuthorID || book.Authors[1].OpenLibraryKey == nil {
+		t.Errorf("Expected Le Guin to resolve to the keyed author, got %+v", book.Authors[1])
+	}
+
+	authors, err := store.GetAuthors(ctx, "")
+	if err != nil {
+		t.Fatalf("GetAuthors failed: %v", err)
+	}
+	if len(authors) != 2 || authors[0].Name != "Neil Gaiman" || authors[1].BookCount != 2 {
+		t.Errorf("Unexpected authors: %+v", authors)
+	}
+}
+
+func TestSetBookAuthors(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	id, err := store.AddBook(ctx, &model.Book{Title: "Audio", Author: "Writer", OpenLibraryID: "OL-AU", Type: model.TypeAudiobook})
+	if err != nil {
+		t.Fatalf("Failed to add book: %v", err)
+	}
+	err = store.SetBookAuthors(ctx, id, []model.Contributor{
+		{Name: "Writer"},
+		{Name: "Co-Writer"},
+		{Name: "Voice", Role: model.RoleNarrator},
+	})
+	if err != nil {
+		t.Fatalf("SetBookAuthors failed: %v", err)
+	}
+
+	book, _ := store.GetBookByID(ctx, id)
+	if book.Author != "Writer, Co-Writer" || len(book.Authors) != 3 || book.Authors[2].Role != model.RoleNarrator || book.Version != 2 {
+		t.Errorf("Unexpected book after setting authors: %+v", book)
+	}
+
+	narrators, err := store.GetAuthors(ctx, model.RoleNarrator)
+	if err != nil || len(narrators) != 1 || narrators[0].Name != "Voice" {
+		t.Fatalf("Expected one narrator, got %+v (%v)", narrators, err)
+	}
+	books, err := store.ListBooks(ctx, ListOptions{AuthorID: narrators[0].ID})
+	if err != nil || len(books) != 1 || books[0].ID != id {
+		t.Errorf("Expected the narrated book, got %+v (%v)", books, err)
+	}
+	if books, _ := store.ListBooks(ctx, ListOptions{AuthorID: narrators[0].ID, AuthorRole: model.RoleAuthor}); len(books) != 0 {
+		t.Errorf("Expected no books written by the narrator, got %+v", books)
+	}
+
+	if err := store.SetBookAuthors(ctx, 999, []model.Contributor{{Name: "Nobody"}}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a missing book, got %v", err)
+	}
+	if err := store.SetBookAuthors(ctx, id, []model.Contributor{{Name: "X", Role: "editor"}}); err == nil {
+		t.Error("Expected validation error for an unknown role")
+	}
+	if _, err := store.GetAuthorByID(ctx, 999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a missing author, got %v", err)
+	}
+}
+
+func TestAuthorsMigrationBackfill(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open in-memory database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1) // Every connection to :memory: is a separate database
+
+	// Build the schema as it was before authors existed
+	const authorsMigration = 7
+	for i, m := range migrations[:authorsMigration-1] {
+		if err := applyMigration(db, i+1, m); err != nil {
+			t.Fatalf("Migration %d failed: %v", i+1, err)
+		}
+	}
+	_, err = db.Exec(`INSERT INTO books (title, author, open_library_id, status) VALUES
+        ('One', 'Ann Smith, Bo Lee', 'OL-1', 'Read'),
+        ('Two', 'bo lee', 'OL-2', 'Read'),
+        ('Three', '', 'OL-3', 'Read');`)
+	if err != nil {
+		t.Fatalf("Failed to insert books: %v", err)
+	}
+	if err := CreateSchema(db); err != nil {
+		t.Fatalf("CreateSchema failed: %v", err)
+	}
+
+	store := NewSQLiteBookStore(db)
+	authors, err := store.GetAuthors(context.Background(), "")
+	if err != nil {
+		t.Fatalf("GetAuthors failed: %v", err)
+	}
+	if len(authors) != 2 || authors[0].Name != "Ann Smith" || authors[1].Name != "Bo Lee" || authors[1].BookCount != 2 {
+		t.Errorf("Unexpected backfilled authors: %+v", authors)
+	}
+	books, _ := store.GetBooks(context.Background())
+	for _, book := range books {
+		if book.Title == "One" && (len(book.Authors) != 2 || book.Authors[1].Name != "Bo Lee") {
+			t.Errorf("Expected One to credit both authors in order, got %+v", book.Authors)
+		}
+	}
+}
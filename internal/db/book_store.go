@@ -1,37 +1,351 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log/slog"
+	"strings"
+	"time"
 
+	"github.com/ericdahl/bookshelf/internal/events"
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/metrics"
 	"github.com/ericdahl/bookshelf/internal/model"
 )
 
 // BookStore defines the interface for database operations on books.
+// All methods honour ctx cancellation and deadlines.
 type BookStore interface {
-	AddBook(book *model.Book) (int64, error)
-	GetBooks() ([]model.Book, error)
-	GetBookByID(id int64) (*model.Book, error)
-	UpdateBookStatus(id int64, status model.BookStatus) error
-	UpdateBookType(id int64, bookType model.BookType) error
-	UpdateBookDetails(id int64, rating *int, comments *string, series *string, seriesIndex *int) error
-	DeleteBook(id int64) error
+	AddBook(ctx context.Context, book *model.Book) (int64, error)
+	GetBooks(ctx context.Context) ([]model.Book, error)
+	ListBooks(ctx context.Context, opts ListOptions) ([]model.Book, error)
+	GetBookByID(ctx context.Context, id int64) (*model.Book, error)
+	UpdateBookStatus(ctx context.Context, id int64, status model.BookStatus) error
+	UpdateBookType(ctx context.Context, id int64, bookType model.BookType) error
+	UpdateBookDetails(ctx context.Context, id int64, details model.BookDetails) error
+	DeleteBook(ctx context.Context, id int64) error
 }
 
+// DefaultQueryTimeout is the per-query timeout used by NewSQLiteBookStore.
+const DefaultQueryTimeout = 5 * time.Second
+
 // SQLiteBookStore implements the BookStore interface using SQLite.
 type SQLiteBookStore struct {
 	DB *sql.DB
+	// QueryTimeout bounds each store method call, on top of any deadline already set on the
+	// caller's context. Zero disables the per-query timeout.
+	QueryTimeout time.Duration
+	// Events, if set, receives an event after every successful mutation.
+	Events *events.Bus
 }
 
 // NewSQLiteBookStore creates a new SQLiteBookStore.
 func NewSQLiteBookStore(db *sql.DB) *SQLiteBookStore {
-	return &SQLiteBookStore{DB: db}
+	return &SQLiteBookStore{DB: db, QueryTimeout: DefaultQueryTimeout}
+}
+
+// queryContext derives the context for a single store method call, applying QueryTimeout.
+func (s *SQLiteBookStore) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.QueryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.QueryTimeout)
+}
+
+// bookColumns lists the books columns read by scanBook, in order.
+const bookColumns = `id, title, author, open_library_id, isbn, status, type, rating, comments, cover_url, series, series_index, version,
+    date_added, date_started, date_finished, series_id, position, deleted_at,
+    ownership, purchase_date, price, currency, store, location_room, location_shelf`
+
+// timestampFormat is how times are stored, matching SQLite's CURRENT_TIMESTAMP so that
+// stored values compare and sort correctly as text.
+const timestampFormat = "2006-01-02 15:04:05"
+
+// sqlTimestamp converts an optional time to a query argument (NULL if nil), in UTC.
+func sqlTimestamp(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(timestampFormat)
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanBook reads a row selected with bookColumns into a Book, converting NULL columns to nil pointers.
+func scanBook(row rowScanner) (*model.Book, error) {
+	var book model.Book
+	// Ensure pointers are used for nullable fields
+	var rating sql.NullInt64
+	var comments sql.NullString
+	var coverURL sql.NullString
+	var isbn sql.NullString
+	var series sql.NullString
+	var seriesIndex sql.NullInt64
+	var bookType sql.NullString
+	var dateAdded, dateStarted, dateFinished, deletedAt sql.NullTime
+	var seriesID sql.NullInt64
+	var ownership, currency, store, room, shelf sql.NullString
+	var purchaseDate sql.NullTime
+	var price sql.NullFloat64
+
+	if err := row.Scan(&book.ID, &book.Title, &book.Author, &book.OpenLibraryID, &isbn,
+		&book.Status, &bookType, &rating, &comments, &coverURL, &series, &seriesIndex, &book.Version,
+		&dateAdded, &dateStarted, &dateFinished, &seriesID, &book.Position, &deletedAt,
+		&ownership, &purchaseDate, &price, &currency, &store, &room, &shelf); err != nil {
+		return nil, err
+	}
+
+	// Set type, defaulting to "book" if NULL or invalid
+	if bookType.Valid {
+		book.Type = model.BookType(bookType.String)
+	}
+	if !book.Type.IsValid() {
+		book.Type = model.TypeBook
+	}
+
+	// Convert sql.Null types to pointers
+	if isbn.Valid {
+		book.ISBN = isbn.String
+	}
+	if rating.Valid {
+		r := int(rating.Int64)
+		book.Rating = &r
+	}
+	if comments.Valid {
+		book.Comments = &comments.String
+	}
+	if coverURL.Valid {
+		book.CoverURL = &coverURL.String
+	}
+	if series.Valid {
+		book.Series = &series.String
+	}
+	if seriesIndex.Valid {
+		si := int(seriesIndex.Int64)
+		book.SeriesIndex = &si
+	}
+	if seriesID.Valid {
+		book.SeriesID = &seriesID.Int64
+	}
+	if deletedAt.Valid {
+		book.DeletedAt = &deletedAt.Time
+	}
+	if dateAdded.Valid {
+		book.DateAdded = &dateAdded.Time
+	}
+	if dateStarted.Valid {
+		book.DateStarted = &dateStarted.Time
+	}
+	if dateFinished.Valid {
+		book.DateFinished = &dateFinished.Time
+	}
+	// Ownership is only recorded along with its status
+	if ownership.Valid {
+		book.Ownership = &model.Ownership{Status: model.OwnershipStatus(ownership.String)}
+		if purchaseDate.Valid {
+			book.Ownership.PurchaseDate = &purchaseDate.Time
+		}
+		if price.Valid {
+			book.Ownership.Price = &price.Float64
+		}
+		if currency.Valid {
+			book.Ownership.Currency = &currency.String
+		}
+		if store.Valid {
+			book.Ownership.Store = &store.String
+		}
+		if room.Valid {
+			book.Ownership.Room = &room.String
+		}
+		if shelf.Valid {
+			book.Ownership.Shelf = &shelf.String
+		}
+	}
+	return &book, nil
+}
+
+// dbtx is the subset of *sql.DB and *sql.Tx used by queries that may run inside a transaction.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// recordStatusChange appends an entry to the status history of a book. from is empty
+// for a newly added book.
+func recordStatusChange(ctx context.Context, q dbtx, bookID int64, from, to model.BookStatus) error {
+	var fromStatus interface{}
+	if from != "" {
+		fromStatus = from
+	}
+	_, err := q.ExecContext(ctx, `INSERT INTO book_status_history (book_id, from_status, to_status) VALUES (?, ?, ?);`,
+		bookID, fromStatus, to)
+	if err != nil {
+		return fmt.Errorf("failed to record status change: %w", err)
+	}
+	return nil
+}
+
+// updateStatusDates stamps the date a book was started or finished when it moves into
+// Currently Reading or Read. Starting a book again clears its finish date.
+func updateStatusDates(ctx context.Context, q dbtx, id int64, status model.BookStatus) error {
+	now := sqlTimestamp(ptrTo(time.Now()))
+	var err error
+	switch status {
+	case model.StatusCurrentlyReading:
+		_, err = q.ExecContext(ctx, `UPDATE books SET date_started = ?, date_finished = NULL WHERE id = ?;`, now, id)
+	case model.StatusRead:
+		_, err = q.ExecContext(ctx, `UPDATE books SET date_finished = ? WHERE id = ?;`, now, id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update status dates: %w", err)
+	}
+	return nil
+}
+
+// changeShelf does the bookkeeping for a book whose status just changed from one shelf to
+// another: its status history, its started/finished dates and its position at the end of the
+// new shelf.
+func changeShelf(ctx context.Context, q dbtx, id int64, from, to model.BookStatus) error {
+	if err := recordStatusChange(ctx, q, id, from, to); err != nil {
+		return err
+	}
+	if err := updateStatusDates(ctx, q, id, to); err != nil {
+		return err
+	}
+	_, err := q.ExecContext(ctx, `
+        UPDATE books SET position = (SELECT COALESCE(MAX(position), 0) + 1 FROM books WHERE status = ? AND id != ? AND deleted_at IS NULL)
+        WHERE id = ?;`, to, id, id)
+	if err != nil {
+		return fmt.Errorf("failed to update position: %w", err)
+	}
+	return nil
+}
+
+// ptrTo returns a pointer to v.
+func ptrTo[T any](v T) *T {
+	return &v
+}
+
+// notFoundOrConflict explains why a guarded UPDATE/DELETE affected no rows: either the book
+// does not exist (ErrNotFound) or its version did not match the expected one (ErrConflict).
+func (s *SQLiteBookStore) notFoundOrConflict(ctx context.Context, q dbtx, id int64) error {
+	var exists bool
+	err := q.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM books WHERE id = ? AND deleted_at IS NULL);`, id).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check book existence: %w", err)
+	}
+	if exists {
+		return fmt.Errorf("book with ID %d: %w", id, ErrConflict)
+	}
+	return fmt.Errorf("book with ID %d: %w", id, ErrNotFound)
+}
+
+// snapshot reads the current state of a book for a change event. It returns nil when no
+// event bus is configured, the book is in the trash or it cannot be read; events are
+// best-effort and must not fail the mutation they describe.
+func (s *SQLiteBookStore) snapshot(ctx context.Context, id int64) *model.Book {
+	if s.Events == nil {
+		return nil
+	}
+	book, err := scanBook(s.DB.QueryRowContext(ctx, `SELECT `+bookColumns+` FROM books WHERE id = ? AND deleted_at IS NULL;`, id))
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			logging.FromContext(ctx).Warn("Failed to read book for change event", "id", id, "error", err)
+		}
+		return nil
+	}
+	books := []model.Book{*book}
+	if err := s.loadLent(ctx, books); err != nil {
+		logging.FromContext(ctx).Warn("Failed to read loans for change event", "id", id, "error", err)
+	}
+	return &books[0]
+}
+
+// publish sends a change event for book id to the event bus, if one is configured.
+// Changes to books in the trash (no snapshot before or after) are not published.
+func (s *SQLiteBookStore) publish(eventType events.Type, id int64, previous, book *model.Book) {
+	if previous == nil && book == nil {
+		return
+	}
+	s.Events.Publish(events.Event{Type: eventType, BookID: id, Book: book, Previous: previous})
+}
+
+// errUnchanged is returned by a changeBook change that turned out to change nothing, so that
+// the book keeps its version and nothing is recorded or published.
+var errUnchanged = errors.New("unchanged")
+
+// changeBook runs change, which edits rows belonging to book bookID (e.g. its editions or
+// loans), in a transaction as a new version of the book. The book must exist and not be in
+// the trash, and have the version set by WithExpectedVersion if any (ErrConflict otherwise,
+// changing nothing). The change is recorded in the audit log and published.
+func (s *SQLiteBookStore) changeBook(ctx context.Context, name string, bookID int64, change func(tx *sql.Tx) error) error {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	logger.Info("SQL: Executing "+name, "bookID", bookID)
+	previous := s.snapshot(ctx, bookID)
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := bookExists(ctx, tx, bookID); err != nil {
+		return err
+	}
+	before, err := readBook(ctx, tx, bookID)
+	if err != nil {
+		return err
+	}
+	if err := change(tx); errors.Is(err, errUnchanged) {
+		logger.Info("SQL: "+name+" changed nothing", "bookID", bookID)
+		return nil
+	} else if err != nil {
+		var validationErr *model.ValidationError
+		if !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrDuplicate) && !errors.As(err, &validationErr) {
+			logger.Error("SQL Error: "+name+" failed", "error", err)
+		}
+		return err
+	}
+	res, err := tx.ExecContext(ctx, `UPDATE books SET version = version + 1 WHERE id = ? AND (? IS NULL OR version = ?);`,
+		bookID, expectedVersion(ctx), expectedVersion(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to update version of book %d: %w", bookID, err)
+	}
+	if rowsAffected, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	} else if rowsAffected == 0 {
+		return s.notFoundOrConflict(ctx, tx, bookID)
+	}
+	if err := recordAudit(ctx, tx, model.AuditUpdate, bookID, before); err != nil {
+		logger.Error("SQL Error: Recording audit entry failed", "error", err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("SQL Error: Committing "+name+" transaction failed", "error", err)
+		return fmt.Errorf("failed to commit change to book %d: %w", bookID, err)
+	}
+
+	logger.Info("SQL: Successfully completed "+name, "bookID", bookID)
+	s.publish(events.BookUpdated, bookID, previous, s.snapshot(ctx, bookID))
+	return nil
 }
 
 // AddBook inserts a new book into the database.
 // It sets the book's ID after successful insertion.
-func (s *SQLiteBookStore) AddBook(book *model.Book) (int64, error) {
+func (s *SQLiteBookStore) AddBook(ctx context.Context, book *model.Book) (int64, error) {
+	defer metrics.ObserveDBQuery("AddBook", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
 	// Default status if not provided (though handler should ensure it)
 	if book.Status == "" {
 		book.Status = model.StatusWantToRead // Or Currently Reading as per initial request? Let's stick to Want to Read for now.
@@ -43,11 +357,33 @@ func (s *SQLiteBookStore) AddBook(book *model.Book) (int64, error) {
 		return 0, fmt.Errorf("validation failed: %w", err)
 	}
 
+	// Books added without contributors are credited from their display author and vice versa
+	if len(book.Authors) == 0 {
+		book.Authors = model.ContributorsFromNames(book.Author)
+	} else if book.Author == "" {
+		book.Author = model.AuthorNames(book.Authors)
+	}
+
+	// Dates default to now, as if the book had moved through the statuses as it was added
+	now := time.Now().UTC().Truncate(time.Second)
+	if book.DateAdded == nil {
+		book.DateAdded = &now
+	}
+	if book.DateStarted == nil && book.Status == model.StatusCurrentlyReading {
+		book.DateStarted = &now
+	}
+	if book.DateFinished == nil && book.Status == model.StatusRead {
+		book.DateFinished = &now
+	}
+
 	query := `
-        INSERT INTO books (title, author, open_library_id, isbn, status, type, rating, comments, cover_url)
-        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);
+        INSERT INTO books (title, author, open_library_id, isbn, status, type, rating, comments, cover_url,
+                           date_added, date_started, date_finished, series, series_id, series_index,
+                           ownership, purchase_date, price, currency, store, location_room, location_shelf, position)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?,
+                (SELECT COALESCE(MAX(position), 0) + 1 FROM books WHERE status = ? AND deleted_at IS NULL));
     `
-	slog.Info("SQL: Executing AddBook query",
+	logger.Info("SQL: Executing AddBook query",
 		"title", book.Title,
 		"author", book.Author,
 		"openLibraryID", book.OpenLibraryID,
@@ -57,322 +393,656 @@ func (s *SQLiteBookStore) AddBook(book *model.Book) (int64, error) {
 		"rating", book.Rating,
 		"comments", book.Comments,
 		"coverURL", book.CoverURL)
-	stmt, err := s.DB.Prepare(query)
+	tx, err := s.DB.BeginTx(ctx, nil)
 	if err != nil {
-		slog.Error("SQL Error: Preparing AddBook statement failed", "error", err)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// A book added with a series name joins that series, as if set through UpdateBookDetails
+	if book.Series, book.SeriesID, err = resolveSeries(ctx, tx, book.Series); err != nil {
+		return 0, err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		logger.Error("SQL Error: Preparing AddBook statement failed", "error", err)
 		return 0, fmt.Errorf("failed to prepare insert statement: %w", err)
 	}
 	defer stmt.Close()
 
-	res, err := stmt.Exec(book.Title, book.Author, book.OpenLibraryID, book.ISBN, book.Status, book.Type, book.Rating, book.Comments, book.CoverURL)
+	args := []interface{}{book.Title, book.Author, book.OpenLibraryID, book.ISBN, book.Status, book.Type, book.Rating, book.Comments, book.CoverURL,
+		sqlTimestamp(book.DateAdded), sqlTimestamp(book.DateStarted), sqlTimestamp(book.DateFinished),
+		book.Series, book.SeriesID, book.SeriesIndex}
+	args = append(args, ownershipArgs(book.Ownership)...)
+	res, err := stmt.ExecContext(ctx, append(args, book.Status)...)
 	if err != nil {
-		slog.Error("SQL Error: Executing AddBook statement failed", "error", err)
-		// Consider checking for UNIQUE constraint violation specifically
+		if isUniqueViolation(err) {
+			logger.Info("SQL: Book already exists", "openLibraryID", book.OpenLibraryID)
+			var trashed bool
+			err := tx.QueryRowContext(ctx, `SELECT deleted_at IS NOT NULL FROM books WHERE open_library_id = ?;`, book.OpenLibraryID).Scan(&trashed)
+			if err != nil {
+				logger.Error("SQL Error: Checking the trash for the existing book failed", "openLibraryID", book.OpenLibraryID, "error", err)
+				return 0, fmt.Errorf("failed to check the trash for book with Open Library ID %s: %w", book.OpenLibraryID, err)
+			}
+			if trashed {
+				return 0, fmt.Errorf("book with Open Library ID %s %w in the trash; restore it instead", book.OpenLibraryID, ErrDuplicate)
+			}
+			return 0, fmt.Errorf("book with Open Library ID %s %w", book.OpenLibraryID, ErrDuplicate)
+		}
+		logger.Error("SQL Error: Executing AddBook statement failed", "error", err)
 		return 0, fmt.Errorf("failed to execute insert statement: %w", err)
 	}
 
 	id, err := res.LastInsertId()
 	if err != nil {
-		slog.Error("SQL Error: Failed to get last insert ID", "error", err)
+		logger.Error("SQL Error: Failed to get last insert ID", "error", err)
 		return 0, fmt.Errorf("failed to retrieve last insert ID: %w", err)
 	}
+	if err := recordStatusChange(ctx, tx, id, "", book.Status); err != nil {
+		logger.Error("SQL Error: Recording initial status failed", "error", err)
+		return 0, err
+	}
+	if err := setContributors(ctx, tx, id, book.Authors); err != nil {
+		logger.Error("SQL Error: Crediting authors failed", "error", err)
+		return 0, err
+	}
+	// The copy being added is the book's first edition
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO editions (book_id, format, isbn, cover_url) VALUES (?, ?, NULLIF(TRIM(?), ''), NULLIF(TRIM(?), ''));`,
+		id, book.Type, book.ISBN, book.CoverURL)
+	if err != nil {
+		logger.Error("SQL Error: Adding first edition failed", "error", err)
+		return 0, fmt.Errorf("failed to add edition: %w", err)
+	}
+	if err := recordAudit(ctx, tx, model.AuditCreate, id, nil); err != nil {
+		logger.Error("SQL Error: Recording audit entry failed", "error", err)
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("SQL Error: Committing AddBook transaction failed", "error", err)
+		return 0, fmt.Errorf("failed to commit insert: %w", err)
+	}
 	book.ID = id // Set the ID on the original struct
-	slog.Info("SQL: Successfully added book", "id", id)
+	book.Version = 1
+	logger.Info("SQL: Successfully added book", "id", id)
+	s.publish(events.BookCreated, id, nil, s.snapshot(ctx, id))
 	return id, nil
 }
 
-// GetBooks retrieves all books from the database.
-func (s *SQLiteBookStore) GetBooks() ([]model.Book, error) {
-	query := `SELECT id, title, author, open_library_id, isbn, status, type, rating, comments, cover_url, series, series_index FROM books ORDER BY title;`
-	slog.Info("SQL: Executing GetBooks query")
+// GetBooks retrieves all books from the database, ordered by title.
+func (s *SQLiteBookStore) GetBooks(ctx context.Context) ([]model.Book, error) {
+	return s.ListBooks(ctx, ListOptions{})
+}
+
+// ListOptions filters and orders the books returned by ListBooks. Zero values apply no filter.
+// Date ranges include their From bound and exclude their Before bound.
+type ListOptions struct {
+	Status model.BookStatus
+	Type   model.BookType
+
+	// SortBy is one of SortFields; empty sorts by title. Books without a value sort last.
+	SortBy     string
+	Descending bool
+
+	AddedFrom, AddedBefore       *time.Time
+	StartedFrom, StartedBefore   *time.Time
+	FinishedFrom, FinishedBefore *time.Time
+
+	// AuthorID only returns books crediting that author, in AuthorRole if it is not empty.
+	AuthorID   int64
+	AuthorRole model.AuthorRole
+
+	// SeriesID only returns the books of that series.
+	SeriesID int64
 
-	rows, err := s.DB.Query(query)
+	// Tag only returns books with that tag (ignoring case).
+	Tag string
+
+	// Ownership only returns books with that ownership status; Store, Room and Shelf only those
+	// bought at that store or kept in that room and shelf (ignoring case).
+	Ownership          model.OwnershipStatus
+	Store, Room, Shelf string
+
+	// Trashed lists the books in the trash instead of those on the shelves.
+	Trashed bool
+}
+
+// sortColumns maps the sort fields accepted by ListBooks to their SQL expressions.
+var sortColumns = map[string]string{
+	"title":         "title",
+	"author":        "author COLLATE NOCASE",
+	"rating":        "rating",
+	"series_index":  "series_index",
+	"position":      "position",
+	"deleted_at":    "deleted_at",
+	"date_added":    "date_added",
+	"date_started":  "date_started",
+	"date_finished": "date_finished",
+	"purchase_date": "purchase_date",
+	"price":         "price",
+}
+
+// IsSortField reports whether field can be used as ListOptions.SortBy.
+func IsSortField(field string) bool {
+	_, ok := sortColumns[field]
+	return ok
+}
+
+// ListBooks retrieves the books matching opts, in the order it asks for.
+func (s *SQLiteBookStore) ListBooks(ctx context.Context, opts ListOptions) ([]model.Book, error) {
+	defer metrics.ObserveDBQuery("ListBooks", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	where := []string{"deleted_at IS NULL"}
+	if opts.Trashed {
+		where[0] = "deleted_at IS NOT NULL"
+	}
+	var args []interface{}
+	if opts.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, opts.Status)
+	}
+	if opts.Type != "" {
+		where = append(where, "type = ?")
+		args = append(args, opts.Type)
+	}
+	if opts.SeriesID != 0 {
+		where = append(where, "series_id = ?")
+		args = append(args, opts.SeriesID)
+	}
+	if opts.Tag != "" {
+		where = append(where, "id IN (SELECT bt.book_id FROM book_tags bt JOIN tags t ON t.id = bt.tag_id WHERE t.name = ?)")
+		args = append(args, opts.Tag)
+	}
+	if opts.Ownership != "" {
+		where = append(where, "ownership = ?")
+		args = append(args, opts.Ownership)
+	}
+	for _, f := range []struct{ column, value string }{
+		{"store", opts.Store}, {"location_room", opts.Room}, {"location_shelf", opts.Shelf},
+	} {
+		if f.value != "" {
+			where = append(where, f.column+" = ? COLLATE NOCASE")
+			args = append(args, f.value)
+		}
+	}
+	if opts.AuthorID != 0 {
+		where = append(where, "id IN (SELECT book_id FROM book_authors WHERE author_id = ? AND (? = '' OR role = ?))")
+		args = append(args, opts.AuthorID, opts.AuthorRole, opts.AuthorRole)
+	}
+	for _, r := range []struct {
+		column       string
+		from, before *time.Time
+	}{
+		{"date_added", opts.AddedFrom, opts.AddedBefore},
+		{"date_started", opts.StartedFrom, opts.StartedBefore},
+		{"date_finished", opts.FinishedFrom, opts.FinishedBefore},
+	} {
+		if r.from != nil {
+			where = append(where, r.column+" >= ?")
+			args = append(args, sqlTimestamp(r.from))
+		}
+		if r.before != nil {
+			where = append(where, r.column+" < ?")
+			args = append(args, sqlTimestamp(r.before))
+		}
+	}
+
+	query := `SELECT ` + bookColumns + ` FROM books`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	sortBy := opts.SortBy
+	if sortBy == "" {
+		sortBy = "title"
+	}
+	column, ok := sortColumns[sortBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort field %q", opts.SortBy)
+	}
+	direction := "ASC"
+	if opts.Descending {
+		direction = "DESC"
+	}
+	query += fmt.Sprintf(` ORDER BY %s %s NULLS LAST, title, id;`, column, direction)
+	logger.Info("SQL: Executing ListBooks query", "query", query)
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
 	if err != nil {
-		slog.Error("SQL Error: Executing GetBooks query failed", "error", err)
+		logger.Error("SQL Error: Executing ListBooks query failed", "error", err)
 		return nil, fmt.Errorf("failed to query books: %w", err)
 	}
 	defer rows.Close()
 
 	books := []model.Book{}
 	for rows.Next() {
-		var book model.Book
-		// Ensure pointers are used for nullable fields
-		var rating sql.NullInt64
-		var comments sql.NullString
-		var coverURL sql.NullString
-		var isbn sql.NullString
-		var series sql.NullString
-		var seriesIndex sql.NullInt64
-		var bookType sql.NullString
-
-		if err := rows.Scan(&book.ID, &book.Title, &book.Author, &book.OpenLibraryID, &isbn, 
-			&book.Status, &bookType, &rating, &comments, &coverURL, &series, &seriesIndex); err != nil {
-			slog.Error("SQL Error: Scanning book row failed", "error", err)
+		book, err := scanBook(rows)
+		if err != nil {
+			logger.Error("SQL Error: Scanning book row failed", "error", err)
 			return nil, fmt.Errorf("failed to scan book row: %w", err)
 		}
-		
-		// Set type, defaulting to "book" if NULL or invalid
-		if bookType.Valid {
-			book.Type = model.BookType(bookType.String)
-		}
-		if !book.Type.IsValid() {
-			book.Type = model.TypeBook
-		}
-
-		// Convert sql.Null types to pointers
-		if isbn.Valid {
-			book.ISBN = isbn.String
-		}
-		if rating.Valid {
-			r := int(rating.Int64)
-			book.Rating = &r
-		}
-		if comments.Valid {
-			book.Comments = &comments.String
-		}
-		if coverURL.Valid {
-			book.CoverURL = &coverURL.String
-		}
-		if series.Valid {
-			book.Series = &series.String
-		}
-		if seriesIndex.Valid {
-			si := int(seriesIndex.Int64)
-			book.SeriesIndex = &si
-		}
-
-		books = append(books, book)
+		books = append(books, *book)
 	}
 
 	if err = rows.Err(); err != nil {
-		slog.Error("SQL Error: Error during row iteration", "error", err)
+		logger.Error("SQL Error: Error during row iteration", "error", err)
 		return nil, fmt.Errorf("error iterating book rows: %w", err)
 	}
+	if err := s.loadContributors(ctx, books); err != nil {
+		logger.Error("SQL Error: Loading book authors failed", "error", err)
+		return nil, fmt.Errorf("failed to load book authors: %w", err)
+	}
+	if err := s.loadTags(ctx, books); err != nil {
+		logger.Error("SQL Error: Loading book tags failed", "error", err)
+		return nil, fmt.Errorf("failed to load book tags: %w", err)
+	}
+	if err := s.loadEditions(ctx, books); err != nil {
+		logger.Error("SQL Error: Loading book editions failed", "error", err)
+		return nil, fmt.Errorf("failed to load book editions: %w", err)
+	}
+	if err := s.loadLent(ctx, books); err != nil {
+		logger.Error("SQL Error: Loading lent books failed", "error", err)
+		return nil, fmt.Errorf("failed to load lent books: %w", err)
+	}
 
-	slog.Info("SQL: Retrieved books", "count", len(books))
+	logger.Info("SQL: Retrieved books", "count", len(books))
 	return books, nil
 }
 
 // GetBookByID retrieves a single book by its ID.
-func (s *SQLiteBookStore) GetBookByID(id int64) (*model.Book, error) {
-	query := `SELECT id, title, author, open_library_id, isbn, status, type, rating, comments, cover_url, series, series_index FROM books WHERE id = ?;`
-	slog.Info("SQL: Executing GetBookByID query", "id", id)
+func (s *SQLiteBookStore) GetBookByID(ctx context.Context, id int64) (*model.Book, error) {
+	defer metrics.ObserveDBQuery("GetBookByID", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
 
-	row := s.DB.QueryRow(query, id)
+	query := `SELECT ` + bookColumns + ` FROM books WHERE id = ? AND deleted_at IS NULL;`
+	logger.Info("SQL: Executing GetBookByID query", "id", id)
 
-	var book model.Book
-	var rating sql.NullInt64
-	var comments sql.NullString
-	var coverURL sql.NullString
-	var isbn sql.NullString
-	var series sql.NullString
-	var seriesIndex sql.NullInt64
-	var bookType sql.NullString
-
-	err := row.Scan(&book.ID, &book.Title, &book.Author, &book.OpenLibraryID, &isbn, 
-		&book.Status, &bookType, &rating, &comments, &coverURL, &series, &seriesIndex)
+	book, err := scanBook(s.DB.QueryRowContext(ctx, query, id))
 	if err != nil {
-		if err == sql.ErrNoRows {
-			slog.Info("SQL: No book found", "id", id)
-			return nil, fmt.Errorf("book with ID %d not found", id) // Consider a specific error type (e.g., ErrNotFound)
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Info("SQL: No book found", "id", id)
+			return nil, fmt.Errorf("book with ID %d: %w", id, ErrNotFound)
 		}
-		slog.Error("SQL Error: Scanning book row failed", "id", id, "error", err)
+		logger.Error("SQL Error: Scanning book row failed", "id", id, "error", err)
 		return nil, fmt.Errorf("failed to scan book row for ID %d: %w", id, err)
 	}
-	
-	// Set type, defaulting to "book" if NULL or invalid
-	if bookType.Valid {
-		book.Type = model.BookType(bookType.String)
-	}
-	if !book.Type.IsValid() {
-		book.Type = model.TypeBook
-	}
 
-	// Convert sql.Null types to pointers
-	if isbn.Valid {
-		book.ISBN = isbn.String
-	}
-	if rating.Valid {
-		r := int(rating.Int64)
-		book.Rating = &r
-	}
-	if comments.Valid {
-		book.Comments = &comments.String
+	books := []model.Book{*book}
+	if err := s.loadContributors(ctx, books); err != nil {
+		logger.Error("SQL Error: Loading book authors failed", "id", id, "error", err)
+		return nil, fmt.Errorf("failed to load authors for book ID %d: %w", id, err)
 	}
-	if coverURL.Valid {
-		book.CoverURL = &coverURL.String
+	if err := s.loadTags(ctx, books); err != nil {
+		logger.Error("SQL Error: Loading book tags failed", "id", id, "error", err)
+		return nil, fmt.Errorf("failed to load tags for book ID %d: %w", id, err)
 	}
-	if series.Valid {
-		book.Series = &series.String
+	if err := s.loadEditions(ctx, books); err != nil {
+		logger.Error("SQL Error: Loading book editions failed", "id", id, "error", err)
+		return nil, fmt.Errorf("failed to load editions for book ID %d: %w", id, err)
 	}
-	if seriesIndex.Valid {
-		si := int(seriesIndex.Int64)
-		book.SeriesIndex = &si
+	if err := s.loadLent(ctx, books); err != nil {
+		logger.Error("SQL Error: Loading loans failed", "id", id, "error", err)
+		return nil, fmt.Errorf("failed to load loans for book ID %d: %w", id, err)
 	}
 
-	slog.Info("SQL: Retrieved book", "id", id)
-	return &book, nil
+	logger.Info("SQL: Retrieved book", "id", id)
+	return &books[0], nil
 }
 
 // UpdateBookStatus updates the status of a specific book.
-func (s *SQLiteBookStore) UpdateBookStatus(id int64, status model.BookStatus) error {
+func (s *SQLiteBookStore) UpdateBookStatus(ctx context.Context, id int64, status model.BookStatus) error {
+	defer metrics.ObserveDBQuery("UpdateBookStatus", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
 	if !status.IsValid() {
 		return fmt.Errorf("invalid status provided: %s", status)
 	}
 
-	query := `UPDATE books SET status = ? WHERE id = ?;`
-	slog.Info("SQL: Executing UpdateBookStatus query", "status", status, "id", id)
+	query := `UPDATE books SET status = ?, version = version + 1 WHERE id = ? AND deleted_at IS NULL AND (? IS NULL OR version = ?);`
+	logger.Info("SQL: Executing UpdateBookStatus query", "status", status, "id", id)
+
+	previous := s.snapshot(ctx, id)
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-	stmt, err := s.DB.Prepare(query)
+	before, err := readBook(ctx, tx, id)
 	if err != nil {
-		slog.Error("SQL Error: Preparing UpdateBookStatus statement failed", "error", err)
+		return err
+	}
+
+	// The previous status is recorded in the status history; read it in the same transaction
+	var fromStatus model.BookStatus
+	err = tx.QueryRowContext(ctx, `SELECT status FROM books WHERE id = ?;`, id).Scan(&fromStatus)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logger.Error("SQL Error: Reading current status failed", "id", id, "error", err)
+		return fmt.Errorf("failed to read current status: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		logger.Error("SQL Error: Preparing UpdateBookStatus statement failed", "error", err)
 		return fmt.Errorf("failed to prepare update status statement: %w", err)
 	}
 	defer stmt.Close()
 
-	res, err := stmt.Exec(status, id)
+	res, err := stmt.ExecContext(ctx, status, id, expectedVersion(ctx), expectedVersion(ctx))
 	if err != nil {
-		slog.Error("SQL Error: Executing UpdateBookStatus statement failed", "error", err)
+		logger.Error("SQL Error: Executing UpdateBookStatus statement failed", "error", err)
 		return fmt.Errorf("failed to execute update status statement: %w", err)
 	}
 
 	rowsAffected, err := res.RowsAffected()
 	if err != nil {
-		slog.Error("SQL Error: Failed to get rows affected for UpdateBookStatus", "error", err)
+		logger.Error("SQL Error: Failed to get rows affected for UpdateBookStatus", "error", err)
 		return fmt.Errorf("failed to get rows affected: %w", err)
 	}
 
 	if rowsAffected == 0 {
-		slog.Info("SQL: No book found to update status", "id", id)
-		return fmt.Errorf("book with ID %d not found", id) // Consider ErrNotFound
+		logger.Info("SQL: No book found to update status", "id", id)
+		return s.notFoundOrConflict(ctx, tx, id)
 	}
 
-	slog.Info("SQL: Successfully updated status for book", "id", id)
+	if fromStatus != status {
+		if err := changeShelf(ctx, tx, id, fromStatus, status); err != nil {
+			logger.Error("SQL Error: Moving book to its new shelf failed", "error", err)
+			return err
+		}
+	}
+	if err := recordAudit(ctx, tx, model.AuditUpdate, id, before); err != nil {
+		logger.Error("SQL Error: Recording audit entry failed", "error", err)
+		return err
+	}
+	if err := recordUndo(ctx, tx, model.UndoSetStatus, before); err != nil {
+		logger.Error("SQL Error: Recording undo step failed", "error", err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("SQL Error: Committing UpdateBookStatus transaction failed", "error", err)
+		return fmt.Errorf("failed to commit status update: %w", err)
+	}
+
+	logger.Info("SQL: Successfully updated status for book", "id", id)
+	s.publish(events.BookUpdated, id, previous, s.snapshot(ctx, id))
 	return nil
 }
 
 // UpdateBookType updates the type of a specific book.
-func (s *SQLiteBookStore) UpdateBookType(id int64, bookType model.BookType) error {
+func (s *SQLiteBookStore) UpdateBookType(ctx context.Context, id int64, bookType model.BookType) error {
+	defer metrics.ObserveDBQuery("UpdateBookType", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
 	if !bookType.IsValid() {
 		return fmt.Errorf("invalid book type provided: %s", bookType)
 	}
 
-	query := `UPDATE books SET type = ? WHERE id = ?;`
-	slog.Info("SQL: Executing UpdateBookType query", "type", bookType, "id", id)
+	query := `UPDATE books SET type = ?, version = version + 1 WHERE id = ? AND deleted_at IS NULL AND (? IS NULL OR version = ?);`
+	logger.Info("SQL: Executing UpdateBookType query", "type", bookType, "id", id)
+
+	previous := s.snapshot(ctx, id)
 
-	stmt, err := s.DB.Prepare(query)
+	tx, err := s.DB.BeginTx(ctx, nil)
 	if err != nil {
-		slog.Error("SQL Error: Preparing UpdateBookType statement failed", "error", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := readBook(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		logger.Error("SQL Error: Preparing UpdateBookType statement failed", "error", err)
 		return fmt.Errorf("failed to prepare update type statement: %w", err)
 	}
 	defer stmt.Close()
 
-	res, err := stmt.Exec(bookType, id)
+	res, err := stmt.ExecContext(ctx, bookType, id, expectedVersion(ctx), expectedVersion(ctx))
 	if err != nil {
-		slog.Error("SQL Error: Executing UpdateBookType statement failed", "error", err)
+		logger.Error("SQL Error: Executing UpdateBookType statement failed", "error", err)
 		return fmt.Errorf("failed to execute update type statement: %w", err)
 	}
 
 	rowsAffected, err := res.RowsAffected()
 	if err != nil {
-		slog.Error("SQL Error: Failed to get rows affected for UpdateBookType", "error", err)
+		logger.Error("SQL Error: Failed to get rows affected for UpdateBookType", "error", err)
 		return fmt.Errorf("failed to get rows affected: %w", err)
 	}
 
 	if rowsAffected == 0 {
-		slog.Info("SQL: No book found to update type", "id", id)
-		return fmt.Errorf("book with ID %d not found", id)
+		logger.Info("SQL: No book found to update type", "id", id)
+		return s.notFoundOrConflict(ctx, tx, id)
+	}
+	if err := recordAudit(ctx, tx, model.AuditUpdate, id, before); err != nil {
+		logger.Error("SQL Error: Recording audit entry failed", "error", err)
+		return err
+	}
+	if err := recordUndo(ctx, tx, model.UndoSetType, before); err != nil {
+		logger.Error("SQL Error: Recording undo step failed", "error", err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("SQL Error: Committing UpdateBookType transaction failed", "error", err)
+		return fmt.Errorf("failed to commit type update: %w", err)
 	}
 
-	slog.Info("SQL: Successfully updated type for book", "id", id)
+	logger.Info("SQL: Successfully updated type for book", "id", id)
+	s.publish(events.BookUpdated, id, previous, s.snapshot(ctx, id))
 	return nil
 }
 
-// UpdateBookDetails updates the rating, comments, series info of a specific book.
+// UpdateBookDetails updates the user-editable details of a specific book (see model.BookDetails).
 // It handles NULL values correctly.
-func (s *SQLiteBookStore) UpdateBookDetails(id int64, rating *int, comments *string, series *string, seriesIndex *int) error {
-	// Validate rating if provided
-	if rating != nil && (*rating < 1 || *rating > 10) {
-		return fmt.Errorf("rating must be between 1 and 10")
+func (s *SQLiteBookStore) UpdateBookDetails(ctx context.Context, id int64, details model.BookDetails) error {
+	defer metrics.ObserveDBQuery("UpdateBookDetails", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	if err := details.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
 	}
 
-	query := `UPDATE books SET rating = ?, comments = ?, series = ?, series_index = ? WHERE id = ?;`
-	slog.Info("SQL: Executing UpdateBookDetails query", "rating", rating, "comments", comments, "series", series, "seriesIndex", seriesIndex, "id", id)
+	query := `
+        UPDATE books SET rating = ?, comments = ?, series = ?, series_id = ?, series_index = ?, type = COALESCE(?, type),
+            date_added = COALESCE(?, date_added),
+            date_started = CASE WHEN ? THEN NULL ELSE COALESCE(?, date_started) END,
+            date_finished = CASE WHEN ? THEN NULL ELSE COALESCE(?, date_finished) END, version = version + 1
+        WHERE id = ? AND deleted_at IS NULL AND (? IS NULL OR version = ?);`
+	logger.Info("SQL: Executing UpdateBookDetails query", "rating", details.Rating, "comments", details.Comments,
+		"series", details.Series, "seriesIndex", details.SeriesIndex, "type", details.Type,
+		"dateAdded", details.DateAdded, "dateStarted", details.DateStarted, "dateFinished", details.DateFinished, "id", id)
+
+	previous := s.snapshot(ctx, id)
 
-	stmt, err := s.DB.Prepare(query)
+	tx, err := s.DB.BeginTx(ctx, nil)
 	if err != nil {
-		slog.Error("SQL Error: Preparing UpdateBookDetails statement failed", "error", err)
-		return fmt.Errorf("failed to prepare update details statement: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	defer stmt.Close()
+	defer tx.Rollback()
 
-	// Handle potential nil values for parameters when passing to Exec
-	var sqlRating interface{}
-	if rating != nil {
-		sqlRating = *rating
-	} else {
-		sqlRating = nil // This will be translated to NULL by the driver
+	before, err := readBook(ctx, tx, id)
+	if err != nil {
+		return err
 	}
-
-	var sqlComments interface{}
-	if comments != nil {
-		sqlComments = *comments
-	} else {
-		sqlComments = nil // This will be translated to NULL by the driver
+	if before != nil {
+		// The dates kept from the book must still be in order with the ones given
+		dates := model.BookDetails{DateStarted: details.DateStarted, DateFinished: details.DateFinished}
+		if dates.DateStarted == nil && !details.ClearDateStarted {
+			dates.DateStarted = before.DateStarted
+		}
+		if dates.DateFinished == nil && !details.ClearDateFinished {
+			dates.DateFinished = before.DateFinished
+		}
+		if err := dates.Validate(); err != nil {
+			return fmt.Errorf("validation failed: %w", err)
+		}
 	}
-	
-	var sqlSeries interface{}
-	if series != nil {
-		sqlSeries = *series
-	} else {
-		sqlSeries = nil
+
+	// The series name links the book to a series entity, created on first use
+	series, seriesID, err := resolveSeries(ctx, tx, details.Series)
+	if err != nil {
+		logger.Error("SQL Error: Resolving series failed", "series", details.Series, "error", err)
+		return err
 	}
-	
-	var sqlSeriesIndex interface{}
-	if seriesIndex != nil {
-		sqlSeriesIndex = *seriesIndex
-	} else {
-		sqlSeriesIndex = nil
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		logger.Error("SQL Error: Preparing UpdateBookDetails statement failed", "error", err)
+		return fmt.Errorf("failed to prepare update details statement: %w", err)
 	}
+	defer stmt.Close()
 
-	res, err := stmt.Exec(sqlRating, sqlComments, sqlSeries, sqlSeriesIndex, id)
+	// Nil pointers are passed as untyped nil, which the driver translates to NULL
+	var sqlType interface{}
+	if details.Type != nil {
+		sqlType = string(*details.Type)
+	}
+	res, err := stmt.ExecContext(ctx, details.Rating, details.Comments, series, seriesID, details.SeriesIndex, sqlType,
+		sqlTimestamp(details.DateAdded), details.ClearDateStarted, sqlTimestamp(details.DateStarted),
+		details.ClearDateFinished, sqlTimestamp(details.DateFinished),
+		id, expectedVersion(ctx), expectedVersion(ctx))
 	if err != nil {
-		slog.Error("SQL Error: Executing UpdateBookDetails statement failed", "error", err)
+		logger.Error("SQL Error: Executing UpdateBookDetails statement failed", "error", err)
 		return fmt.Errorf("failed to execute update details statement: %w", err)
 	}
 
 	rowsAffected, err := res.RowsAffected()
 	if err != nil {
-		slog.Error("SQL Error: Failed to get rows affected for UpdateBookDetails", "error", err)
+		logger.Error("SQL Error: Failed to get rows affected for UpdateBookDetails", "error", err)
 		return fmt.Errorf("failed to get rows affected: %w", err)
 	}
 
 	if rowsAffected == 0 {
-		slog.Info("SQL: No book found to update details", "id", id)
-		return fmt.Errorf("book with ID %d not found", id) // Consider ErrNotFound
+		logger.Info("SQL: No book found to update details", "id", id)
+		return s.notFoundOrConflict(ctx, tx, id)
+	}
+	if err := recordAudit(ctx, tx, model.AuditUpdate, id, before); err != nil {
+		logger.Error("SQL Error: Recording audit entry failed", "error", err)
+		return err
+	}
+	if err := recordUndo(ctx, tx, model.UndoEditDetails, before); err != nil {
+		logger.Error("SQL Error: Recording undo step failed", "error", err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("SQL Error: Committing UpdateBookDetails transaction failed", "error", err)
+		return fmt.Errorf("failed to commit details update: %w", err)
 	}
 
-	slog.Info("SQL: Successfully updated details for book", "id", id)
+	logger.Info("SQL: Successfully updated details for book", "id", id)
+	s.publish(events.BookUpdated, id, previous, s.snapshot(ctx, id))
 	return nil
 }
 
-// DeleteBook removes a book from the database by its ID.
-func (s *SQLiteBookStore) DeleteBook(id int64) error {
-	query := `DELETE FROM books WHERE id = ?;`
+// DeleteBook moves a book to the trash. It disappears from every listing and statistic until
+// it is restored with RestoreBook, or purged with PurgeTrash.
+func (s *SQLiteBookStore) DeleteBook(ctx context.Context, id int64) error {
+	defer metrics.ObserveDBQuery("DeleteBook", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	query := `UPDATE books SET deleted_at = ?, version = version + 1 WHERE id = ? AND deleted_at IS NULL AND (? IS NULL OR version = ?);`
+	logger.Info("SQL: Executing DeleteBook query", "id", id)
+
+	previous := s.snapshot(ctx, id)
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := readBook(ctx, tx, id)
+	if err != nil {
+		return err
+	}
 
-	result, err := s.DB.Exec(query, id)
+	result, err := tx.ExecContext(ctx, query, sqlTimestamp(ptrTo(time.Now())), id, expectedVersion(ctx), expectedVersion(ctx))
 	if err != nil {
+		logger.Error("SQL Error: Executing DeleteBook statement failed", "error", err)
 		return fmt.Errorf("failed to delete book: %w", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
+		logger.Error("SQL Error: Failed to get rows affected for DeleteBook", "error", err)
 		return fmt.Errorf("failed to get rows affected: %w", err)
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("book with ID %d not found", id)
+		logger.Info("SQL: No book found to delete", "id", id)
+		return s.notFoundOrConflict(ctx, tx, id)
+	}
+	if err := recordAudit(ctx, tx, model.AuditDelete, id, before); err != nil {
+		logger.Error("SQL Error: Recording audit entry failed", "error", err)
+		return err
+	}
+	if err := recordUndo(ctx, tx, model.UndoDelete, before); err != nil {
+		logger.Error("SQL Error: Recording undo step failed", "error", err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("SQL Error: Committing DeleteBook transaction failed", "error", err)
+		return fmt.Errorf("failed to commit delete: %w", err)
 	}
 
+	logger.Info("SQL: Successfully moved book to the trash", "id", id)
+	s.publish(events.BookDeleted, id, previous, nil)
 	return nil
 }
+
+// CountBooksByStatusAndType returns the number of books grouped by status and type.
+// Used to expose shelf size gauges on the metrics endpoint.
+func (s *SQLiteBookStore) CountBooksByStatusAndType(ctx context.Context) (map[model.BookStatus]map[model.BookType]int, error) {
+	defer metrics.ObserveDBQuery("CountBooksByStatusAndType", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	query := `SELECT status, type, COUNT(*) FROM books WHERE deleted_at IS NULL GROUP BY status, type;`
+	logger.Debug("SQL: Executing CountBooksByStatusAndType query")
+
+	rows, err := s.DB.QueryContext(ctx, query)
+	if err != nil {
+		logger.Error("SQL Error: Executing CountBooksByStatusAndType query failed", "error", err)
+		return nil, fmt.Errorf("failed to count books: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[model.BookStatus]map[model.BookType]int)
+	for rows.Next() {
+		var status model.BookStatus
+		var bookType model.BookType
+		var count int
+		if err := rows.Scan(&status, &bookType, &count); err != nil {
+			logger.Error("SQL Error: Scanning book count row failed", "error", err)
+			return nil, fmt.Errorf("failed to scan book count row: %w", err)
+		}
+		if counts[status] == nil {
+			counts[status] = make(map[model.BookType]int)
+		}
+		counts[status][bookType] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		logger.Error("SQL Error: Error during book count iteration", "error", err)
+		return nil, fmt.Errorf("error iterating book count rows: %w", err)
+	}
+	return counts, nil
+}
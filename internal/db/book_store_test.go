@@ -1,11 +1,15 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"reflect"
 	"testing"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/ericdahl/bookshelf/internal/events"
 	"github.com/ericdahl/bookshelf/internal/model"
 )
 
@@ -55,7 +59,7 @@ func TestAddBook(t *testing.T) {
 	defer teardownTestDB(db)
 
 	book := createTestBook()
-	id, err := store.AddBook(book)
+	id, err := store.AddBook(context.Background(), book)
 	if err != nil {
 		t.Fatalf("AddBook failed: %v", err)
 	}
@@ -71,7 +75,7 @@ func TestAddBook(t *testing.T) {
 	// Test adding book with invalid status
 	invalidBook := createTestBook()
 	invalidBook.Status = "Invalid Status"
-	_, err = store.AddBook(invalidBook)
+	_, err = store.AddBook(context.Background(), invalidBook)
 	if err == nil {
 		t.Errorf("Expected error when adding book with invalid status")
 	}
@@ -81,14 +85,14 @@ func TestAddBook(t *testing.T) {
 	invalidBook = createTestBook()
 	invalidBook.OpenLibraryID = "OL67890M" // Different ID to avoid uniqueness constraint
 	invalidBook.Rating = &invalidRating
-	_, err = store.AddBook(invalidBook)
+	_, err = store.AddBook(context.Background(), invalidBook)
 	if err == nil {
 		t.Errorf("Expected error when adding book with invalid rating")
 	}
 
 	// Test uniqueness constraint
 	duplicateBook := createTestBook()
-	_, err = store.AddBook(duplicateBook)
+	_, err = store.AddBook(context.Background(), duplicateBook)
 	if err == nil {
 		t.Errorf("Expected error when adding book with duplicate OpenLibraryID")
 	}
@@ -101,7 +105,7 @@ func TestGetBooks(t *testing.T) {
 
 	// Add test books
 	book1 := createTestBook()
-	_, err := store.AddBook(book1)
+	_, err := store.AddBook(context.Background(), book1)
 	if err != nil {
 		t.Fatalf("Failed to add test book 1: %v", err)
 	}
@@ -110,13 +114,13 @@ func TestGetBooks(t *testing.T) {
 	book2.Title = "Test Book 2"
 	book2.OpenLibraryID = "OL67890M"
 	book2.Status = model.StatusCurrentlyReading
-	_, err = store.AddBook(book2)
+	_, err = store.AddBook(context.Background(), book2)
 	if err != nil {
 		t.Fatalf("Failed to add test book 2: %v", err)
 	}
 
 	// Test GetBooks
-	books, err := store.GetBooks()
+	books, err := store.GetBooks(context.Background())
 	if err != nil {
 		t.Fatalf("GetBooks failed: %v", err)
 	}
@@ -133,13 +137,13 @@ func TestGetBookByID(t *testing.T) {
 
 	// Add a test book
 	book := createTestBook()
-	id, err := store.AddBook(book)
+	id, err := store.AddBook(context.Background(), book)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
 
 	// Test getting the book by ID
-	retrievedBook, err := store.GetBookByID(id)
+	retrievedBook, err := store.GetBookByID(context.Background(), id)
 	if err != nil {
 		t.Fatalf("GetBookByID failed: %v", err)
 	}
@@ -173,7 +177,7 @@ func TestGetBookByID(t *testing.T) {
 	}
 
 	// Test getting non-existent book
-	_, err = store.GetBookByID(999)
+	_, err = store.GetBookByID(context.Background(), 999)
 	if err == nil {
 		t.Errorf("Expected error when getting non-existent book")
 	}
@@ -186,19 +190,19 @@ func TestUpdateBookStatus(t *testing.T) {
 
 	// Add a test book
 	book := createTestBook()
-	id, err := store.AddBook(book)
+	id, err := store.AddBook(context.Background(), book)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
 
 	// Test updating status
-	err = store.UpdateBookStatus(id, model.StatusCurrentlyReading)
+	err = store.UpdateBookStatus(context.Background(), id, model.StatusCurrentlyReading)
 	if err != nil {
 		t.Fatalf("UpdateBookStatus failed: %v", err)
 	}
 
 	// Verify the update
-	updatedBook, err := store.GetBookByID(id)
+	updatedBook, err := store.GetBookByID(context.Background(), id)
 	if err != nil {
 		t.Fatalf("Failed to get book after update: %v", err)
 	}
@@ -208,13 +212,13 @@ func TestUpdateBookStatus(t *testing.T) {
 	}
 
 	// Test updating with invalid status
-	err = store.UpdateBookStatus(id, "Invalid Status")
+	err = store.UpdateBookStatus(context.Background(), id, "Invalid Status")
 	if err == nil {
 		t.Errorf("Expected error when updating with invalid status")
 	}
 
 	// Test updating non-existent book
-	err = store.UpdateBookStatus(999, model.StatusRead)
+	err = store.UpdateBookStatus(context.Background(), 999, model.StatusRead)
 	if err == nil {
 		t.Errorf("Expected error when updating non-existent book")
 	}
@@ -227,7 +231,7 @@ func TestUpdateBookDetails(t *testing.T) {
 
 	// Add a test book
 	book := createTestBook()
-	id, err := store.AddBook(book)
+	id, err := store.AddBook(context.Background(), book)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
@@ -237,13 +241,13 @@ func TestUpdateBookDetails(t *testing.T) {
 	newComments := "Updated comments"
 	var series *string
 	var seriesIndex *int
-	err = store.UpdateBookDetails(id, &newRating, &newComments, series, seriesIndex)
+	err = store.UpdateBookDetails(context.Background(), id, model.BookDetails{Rating: &newRating, Comments: &newComments, Series: series, SeriesIndex: seriesIndex})
 	if err != nil {
 		t.Fatalf("UpdateBookDetails failed: %v", err)
 	}
 
 	// Verify the update
-	updatedBook, err := store.GetBookByID(id)
+	updatedBook, err := store.GetBookByID(context.Background(), id)
 	if err != nil {
 		t.Fatalf("Failed to get book after update: %v", err)
 	}
@@ -256,13 +260,13 @@ func TestUpdateBookDetails(t *testing.T) {
 	}
 
 	// Test clearing details (setting to null)
-	err = store.UpdateBookDetails(id, nil, nil, nil, nil)
+	err = store.UpdateBookDetails(context.Background(), id, model.BookDetails{})
 	if err != nil {
 		t.Fatalf("UpdateBookDetails with nil values failed: %v", err)
 	}
 
 	// Verify nulls were set
-	updatedBook, err = store.GetBookByID(id)
+	updatedBook, err = store.GetBookByID(context.Background(), id)
 	if err != nil {
 		t.Fatalf("Failed to get book after update: %v", err)
 	}
@@ -276,13 +280,13 @@ func TestUpdateBookDetails(t *testing.T) {
 
 	// Test with invalid rating
 	invalidRating := 11
-	err = store.UpdateBookDetails(id, &invalidRating, nil, nil, nil)
+	err = store.UpdateBookDetails(context.Background(), id, model.BookDetails{Rating: &invalidRating})
 	if err == nil {
 		t.Errorf("Expected error when updating with invalid rating")
 	}
 
 	// Test updating non-existent book
-	err = store.UpdateBookDetails(999, &newRating, &newComments, nil, nil)
+	err = store.UpdateBookDetails(context.Background(), 999, model.BookDetails{Rating: &newRating, Comments: &newComments})
 	if err == nil {
 		t.Errorf("Expected error when updating non-existent book")
 	}
@@ -295,26 +299,282 @@ func TestDeleteBook(t *testing.T) {
 
 	// Add a test book
 	book := createTestBook()
-	id, err := store.AddBook(book)
+	id, err := store.AddBook(context.Background(), book)
 	if err != nil {
 		t.Fatalf("Failed to add test book: %v", err)
 	}
 
 	// Test deleting the book
-	err = store.DeleteBook(id)
+	err = store.DeleteBook(context.Background(), id)
 	if err != nil {
 		t.Fatalf("DeleteBook failed: %v", err)
 	}
 
 	// Verify the book was deleted
-	_, err = store.GetBookByID(id)
+	_, err = store.GetBookByID(context.Background(), id)
 	if err == nil {
 		t.Errorf("Expected error when getting deleted book")
 	}
 
 	// Test deleting non-existent book
-	err = store.DeleteBook(999)
+	err = store.DeleteBook(context.Background(), 999)
 	if err == nil {
 		t.Errorf("Expected error when deleting non-existent book")
 	}
-}
\ No newline at end of file
+}
+// TestContextCancellation tests that store methods honour cancelled contexts
+func TestContextCancellation(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	book := createTestBook()
+	id, err := store.AddBook(context.Background(), book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := store.GetBooks(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled from GetBooks, got %v", err)
+	}
+	if err := store.UpdateBookStatus(ctx, id, model.StatusRead); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled from UpdateBookStatus, got %v", err)
+	}
+
+	// The book must be untouched
+	unchanged, err := store.GetBookByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Failed to get book: %v", err)
+	}
+	if unchanged.Status != model.StatusWantToRead {
+		t.Errorf("Expected status %s, got %s", model.StatusWantToRead, unchanged.Status)
+	}
+}
+
+// TestSentinelErrors tests that store errors can be identified with errors.Is
+func TestSentinelErrors(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	book := createTestBook()
+	id, err := store.AddBook(ctx, book)
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+
+	if _, err := store.AddBook(ctx, createTestBook()); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Expected ErrDuplicate for duplicate OpenLibraryID, got %v", err)
+	}
+	if _, err := store.GetBookByID(ctx, 999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound from GetBookByID, got %v", err)
+	}
+	if err := store.UpdateBookStatus(ctx, 999, model.StatusRead); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound from UpdateBookStatus, got %v", err)
+	}
+	if err := store.DeleteBook(ctx, 999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound from DeleteBook, got %v", err)
+	}
+
+	// Versions increment on every change and guard against lost updates
+	if err := store.UpdateBookStatus(WithExpectedVersion(ctx, 1), id, model.StatusCurrentlyReading); err != nil {
+		t.Fatalf("UpdateBookStatus with matching version failed: %v", err)
+	}
+	updated, err := store.GetBookByID(ctx, id)
+	if err != nil {
+		t.Fatalf("Failed to get book: %v", err)
+	}
+	if updated.Version != 2 {
+		t.Errorf("Expected version 2 after update, got %d", updated.Version)
+	}
+	if err := store.UpdateBookType(WithExpectedVersion(ctx, 1), id, model.TypeAudiobook); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict for stale version, got %v", err)
+	}
+	if err := store.DeleteBook(WithExpectedVersion(ctx, 1), id); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict deleting with stale version, got %v", err)
+	}
+}
+
+func TestMutationEvents(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	store.Events = events.NewBus(10)
+	ch, _, _, cancel := store.Events.Subscribe(0)
+	defer cancel()
+
+	id, err := store.AddBook(ctx, createTestBook())
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	if err := store.UpdateBookStatus(ctx, id, model.StatusRead); err != nil {
+		t.Fatalf("Failed to update status: %v", err)
+	}
+	// Failed mutations publish nothing
+	if err := store.UpdateBookStatus(ctx, 999, model.StatusRead); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+	if err := store.DeleteBook(ctx, id); err != nil {
+		t.Fatalf("Failed to delete book: %v", err)
+	}
+
+	created := <-ch
+	if created.Type != events.BookCreated || created.BookID != id || created.Book == nil || created.Previous != nil {
+		t.Errorf("Unexpected created event: %+v", created)
+	}
+	updated := <-ch
+	if updated.Type != events.BookUpdated || updated.Previous == nil || updated.Book == nil {
+		t.Fatalf("Unexpected updated event: %+v", updated)
+	}
+	if updated.Previous.Status != model.StatusWantToRead || updated.Book.Status != model.StatusRead {
+		t.Errorf("Expected status change want_to_read -> read, got %s -> %s", updated.Previous.Status, updated.Book.Status)
+	}
+	deleted := <-ch
+	if deleted.Type != events.BookDeleted || deleted.Book != nil || deleted.Previous == nil || deleted.Previous.ID != id {
+		t.Errorf("Unexpected deleted event: %+v", deleted)
+	}
+	select {
+	case e := <-ch:
+		t.Errorf("Unexpected extra event: %+v", e)
+	default:
+	}
+}
+
+// TestBookDates tests that status changes stamp the dates and that they can be edited
+func TestBookDates(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	id, err := store.AddBook(ctx, createTestBook())
+	if err != nil {
+		t.Fatalf("Failed to add book: %v", err)
+	}
+	book, _ := store.GetBookByID(ctx, id)
+	if book.DateAdded == nil || book.DateStarted != nil || book.DateFinished != nil {
+		t.Fatalf("Expected only date_added on a new book, got %v %v %v", book.DateAdded, book.DateStarted, book.DateFinished)
+	}
+
+	if err := store.UpdateBookStatus(ctx, id, model.StatusCurrentlyReading); err != nil {
+		t.Fatalf("Failed to start book: %v", err)
+	}
+	if err := store.UpdateBookStatus(ctx, id, model.StatusRead); err != nil {
+		t.Fatalf("Failed to finish book: %v", err)
+	}
+	book, _ = store.GetBookByID(ctx, id)
+	if book.DateStarted == nil || book.DateFinished == nil {
+		t.Fatalf("Expected start and finish dates, got %v %v", book.DateStarted, book.DateFinished)
+	}
+
+	// Starting again clears the finish date
+	if err := store.UpdateBookStatus(ctx, id, model.StatusCurrentlyReading); err != nil {
+		t.Fatalf("Failed to restart book: %v", err)
+	}
+	book, _ = store.GetBookByID(ctx, id)
+	if book.DateFinished != nil {
+		t.Errorf("Expected finish date to be cleared, got %v", book.DateFinished)
+	}
+
+	// Backfill dates through the details
+	started := time.Date(2020, time.March, 1, 0, 0, 0, 0, time.UTC)
+	finished := time.Date(2020, time.March, 15, 0, 0, 0, 0, time.UTC)
+	err = store.UpdateBookDetails(ctx, id, model.BookDetails{DateStarted: &started, DateFinished: &finished})
+	if err != nil {
+		t.Fatalf("Failed to update dates: %v", err)
+	}
+	book, _ = store.GetBookByID(ctx, id)
+	if !book.DateStarted.Equal(started) || !book.DateFinished.Equal(finished) || book.DateAdded == nil {
+		t.Errorf("Expected backfilled dates %v to %v, got %v to %v (added %v)", started, finished, book.DateStarted, book.DateFinished, book.DateAdded)
+	}
+
+	err = store.UpdateBookDetails(ctx, id, model.BookDetails{DateStarted: &finished, DateFinished: &started})
+	if err == nil {
+		t.Error("Expected an error finishing a book before starting it")
+	}
+
+	// Dates left out are kept, and checked against the ones given
+	rating := 8
+	if err := store.UpdateBookDetails(ctx, id, model.BookDetails{Rating: &rating}); err != nil {
+		t.Fatalf("Failed to rate book: %v", err)
+	}
+	book, _ = store.GetBookByID(ctx, id)
+	if book.DateStarted == nil || !book.DateStarted.Equal(started) || book.DateFinished == nil || !book.DateFinished.Equal(finished) {
+		t.Errorf("Expected the dates to be kept, got %v to %v", book.DateStarted, book.DateFinished)
+	}
+	late := finished.AddDate(0, 0, 1)
+	var validationErr *model.ValidationError
+	if err := store.UpdateBookDetails(ctx, id, model.BookDetails{DateStarted: &late}); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a validation error starting after the kept finish date, got %v", err)
+	}
+	if err := store.UpdateBookDetails(ctx, id, model.BookDetails{ClearDateFinished: true}); err != nil {
+		t.Fatalf("Failed to clear finish date: %v", err)
+	}
+	book, _ = store.GetBookByID(ctx, id)
+	if book.DateStarted == nil || book.DateFinished != nil {
+		t.Errorf("Expected only the finish date to be cleared, got %v to %v", book.DateStarted, book.DateFinished)
+	}
+}
+
+// TestListBooks tests filtering and sorting the book list
+func TestListBooks(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	day := func(d int) *time.Time {
+		t := time.Date(2024, time.January, d, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+	for _, b := range []*model.Book{
+		{Title: "Alpha", Author: "Zed", OpenLibraryID: "OL-A", Status: model.StatusRead, DateStarted: day(1), DateFinished: day(10)},
+		{Title: "Beta", Author: "Yan", OpenLibraryID: "OL-B", Status: model.StatusRead, Type: model.TypeAudiobook, DateStarted: day(2), DateFinished: day(5)},
+		{Title: "Gamma", Author: "Xia", OpenLibraryID: "OL-C", Status: model.StatusCurrentlyReading, DateStarted: day(20)},
+		{Title: "Delta", Author: "Wu", OpenLibraryID: "OL-D", Status: model.StatusWantToRead},
+	} {
+		if _, err := store.AddBook(ctx, b); err != nil {
+			t.Fatalf("Failed to add %s: %v", b.Title, err)
+		}
+	}
+
+	titles := func(opts ListOptions) []string {
+		t.Helper()
+		books, err := store.ListBooks(ctx, opts)
+		if err != nil {
+			t.Fatalf("ListBooks(%+v) failed: %v", opts, err)
+		}
+		var got []string
+		for _, b := range books {
+			got = append(got, b.Title)
+		}
+		return got
+	}
+
+	tests := []struct {
+		name string
+		opts ListOptions
+		want []string
+	}{
+		{"default", ListOptions{}, []string{"Alpha", "Beta", "Delta", "Gamma"}},
+		{"status", ListOptions{Status: model.StatusRead}, []string{"Alpha", "Beta"}},
+		{"type", ListOptions{Type: model.TypeAudiobook}, []string{"Beta"}},
+		{"author", ListOptions{SortBy: "author"}, []string{"Delta", "Gamma", "Beta", "Alpha"}},
+		{"finished nulls last", ListOptions{SortBy: "date_finished"}, []string{"Beta", "Alpha", "Delta", "Gamma"}},
+		{"started descending", ListOptions{SortBy: "date_started", Descending: true}, []string{"Gamma", "Beta", "Alpha", "Delta"}},
+		{"finished range", ListOptions{FinishedFrom: day(5), FinishedBefore: day(10)}, []string{"Beta"}},
+		{"started from", ListOptions{StartedFrom: day(2)}, []string{"Beta", "Gamma"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := titles(tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := store.ListBooks(ctx, ListOptions{SortBy: "pages"}); err == nil {
+		t.Error("Expected an error for an unknown sort field")
+	}
+}
@@ -0,0 +1,206 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/events"
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/metrics"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// BulkStore defines the interface for changing many books at once.
+type BulkStore interface {
+	ApplyBulk(ctx context.Context, op model.BulkOperation) (*model.BulkResponse, error)
+}
+
+// ApplyBulk applies op to each of its books in a single transaction. If any book fails
+// (typically because it does not exist) the transaction is rolled back and the response is
+// not Applied; the results then say which books failed. The returned error is for database
+// failures only. Books that were not actually changed (already on the shelf, already tagged)
+// succeed without a new version or change event. Deleted books go to the trash.
+func (s *SQLiteBookStore) ApplyBulk(ctx context.Context, op model.BulkOperation) (*model.BulkResponse, error) {
+	defer metrics.ObserveDBQuery("ApplyBulk", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	if err := op.Validate(); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	logger.Info("SQL: Executing ApplyBulk", "operation", op.Operation, "count", len(op.IDs))
+	previous := make(map[int64]*model.Book, len(op.IDs))
+	for _, id := range op.IDs {
+		previous[id] = s.snapshot(ctx, id)
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var tagID int64
+	if op.Operation == model.BulkAddTag {
+		if tagID, err = findOrCreateTag(ctx, tx, op.Tag); err != nil {
+			logger.Error("SQL Error: Creating tag failed", "tag", op.Tag, "error", err)
+			return nil, err
+		}
+	}
+
+	response := &model.BulkResponse{Applied: true, Results: make([]model.BulkResult, len(op.IDs))}
+	changed := make([]bool, len(op.IDs))
+	befores := make([]*model.Book, len(op.IDs))
+	action := model.AuditUpdate
+	if op.Operation == model.BulkDelete {
+		action = model.AuditDelete
+	}
+	for i, id := range op.IDs {
+		response.Results[i].ID = id
+		before, err := readBook(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		befores[i] = before
+		changed[i], err = applyBulkItem(ctx, tx, op, id, tagID)
+		if err == nil && changed[i] {
+			err = recordAudit(ctx, tx, action, id, before)
+		}
+		switch {
+		case errors.Is(err, ErrNotFound):
+			response.Applied = false
+			response.Results[i].Error = err.Error()
+		case err != nil:
+			logger.Error("SQL Error: Bulk operation failed", "id", id, "error", err)
+			return nil, err
+		default:
+			response.Results[i].OK = true
+		}
+	}
+	if !response.Applied {
+		logger.Info("SQL: Bulk operation rolled back", "operation", op.Operation)
+		return response, nil
+	}
+	if err := recordUndo(ctx, tx, model.UndoAction(op.Operation), befores...); err != nil {
+		logger.Error("SQL Error: Recording undo step failed", "error", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error("SQL Error: Committing ApplyBulk transaction failed", "error", err)
+		return nil, fmt.Errorf("failed to commit bulk operation: %w", err)
+	}
+
+	logger.Info("SQL: Successfully applied bulk operation", "operation", op.Operation, "count", len(op.IDs))
+	for i, id := range op.IDs {
+		switch {
+		case !changed[i]:
+		case op.Operation == model.BulkDelete:
+			s.publish(events.BookDeleted, id, previous[id], nil)
+		default:
+			s.publish(events.BookUpdated, id, previous[id], s.snapshot(ctx, id))
+		}
+	}
+	return response, nil
+}
+
+// applyBulkItem applies a bulk operation to one book and reports whether it changed.
+// tagID is the tag to add for add_tag.
+func applyBulkItem(ctx context.Context, tx *sql.Tx, op model.BulkOperation, id, tagID int64) (bool, error) {
+	var status model.BookStatus
+	err := tx.QueryRowContext(ctx, `SELECT status FROM books WHERE id = ? AND deleted_at IS NULL;`, id).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, fmt.Errorf("book with ID %d: %w", id, ErrNotFound)
+	} else if err != nil {
+		return false, fmt.Errorf("failed to read book %d: %w", id, err)
+	}
+
+	var res sql.Result
+	switch op.Operation {
+	case model.BulkSetStatus:
+		if status == op.Status {
+			return false, nil
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE books SET status = ?, version = version + 1 WHERE id = ?;`, op.Status, id); err != nil {
+			return false, fmt.Errorf("failed to update status of book %d: %w", id, err)
+		}
+		return true, changeShelf(ctx, tx, id, status, op.Status)
+	case model.BulkSetType:
+		res, err = tx.ExecContext(ctx, `UPDATE books SET type = ?, version = version + 1 WHERE id = ? AND type != ?;`, op.Type, id, op.Type)
+	case model.BulkAddTag:
+		res, err = tx.ExecContext(ctx, `INSERT OR IGNORE INTO book_tags (book_id, tag_id) VALUES (?, ?);`, id, tagID)
+	case model.BulkRemoveTag:
+		res, err = tx.ExecContext(ctx, `
+            DELETE FROM book_tags WHERE book_id = ? AND tag_id IN (SELECT id FROM tags WHERE name = ?);`, id, op.Tag)
+	case model.BulkDelete:
+		res, err = tx.ExecContext(ctx, `UPDATE books SET deleted_at = ?, version = version + 1 WHERE id = ?;`,
+			sqlTimestamp(ptrTo(time.Now())), id)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to %s book %d: %w", strings.ReplaceAll(string(op.Operation), "_", " "), id, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	if op.Operation == model.BulkAddTag || op.Operation == model.BulkRemoveTag {
+		// Tags are part of the book, so changing them is a new version
+		if _, err := tx.ExecContext(ctx, `UPDATE books SET version = version + 1 WHERE id = ?;`, id); err != nil {
+			return false, fmt.Errorf("failed to update version of book %d: %w", id, err)
+		}
+	}
+	return true, nil
+}
+
+// findOrCreateTag returns the ID of the tag with the given name (case-insensitively),
+// creating it if needed.
+func findOrCreateTag(ctx context.Context, q dbtx, name string) (int64, error) {
+	if _, err := q.ExecContext(ctx, `INSERT OR IGNORE INTO tags (name) VALUES (?);`, name); err != nil {
+		return 0, fmt.Errorf("failed to create tag: %w", err)
+	}
+	var id int64
+	if err := q.QueryRowContext(ctx, `SELECT id FROM tags WHERE name = ?;`, name).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to look up tag: %w", err)
+	}
+	return id, nil
+}
+
+// loadTags fills in the Tags of each book with a single query.
+func (s *SQLiteBookStore) loadTags(ctx context.Context, books []model.Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+	index := make(map[int64]int, len(books))
+	placeholders := make([]string, len(books))
+	args := make([]interface{}, len(books))
+	for i, book := range books {
+		index[book.ID] = i
+		placeholders[i] = "?"
+		args[i] = book.ID
+	}
+
+	return s.queryRows(ctx, `
+        SELECT bt.book_id, t.name
+        FROM book_tags bt JOIN tags t ON t.id = bt.tag_id
+        WHERE bt.book_id IN (`+strings.Join(placeholders, ", ")+`)
+        ORDER BY bt.book_id, t.name;`, args, func(rows *sql.Rows) error {
+		var bookID int64
+		var name string
+		if err := rows.Scan(&bookID, &name); err != nil {
+			return err
+		}
+		i := index[bookID]
+		books[i].Tags = append(books[i].Tags, name)
+		return nil
+	})
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestApplyBulk(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	var ids []int64
+	for _, title := range []string{"A", "B", "C"} {
+		id, err := store.AddBook(ctx, &model.Book{Title: title, Author: "X", OpenLibraryID: "OL-" + title, Status: model.StatusWantToRead})
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", title, err)
+		}
+		ids = append(ids, id)
+	}
+
+	apply := func(op model.BulkOperation) *model.BulkResponse {
+		t.Helper()
+		response, err := store.ApplyBulk(ctx, op)
+		if err != nil {
+			t.Fatalf("ApplyBulk(%s) failed: %v", op.Operation, err)
+		}
+		return response
+	}
+
+	// One missing book rolls back the whole operation
+	response := apply(model.BulkOperation{IDs: []int64{ids[0], 999}, Operation: model.BulkSetStatus, Status: model.StatusRead})
+	if response.Applied || !response.Results[0].OK || response.Results[1].OK || response.Results[1].Error == "" {
+		t.Errorf("Expected the operation to fail on book 999 only, got %+v", response)
+	}
+	if book, _ := store.GetBookByID(ctx, ids[0]); book.Status != model.StatusWantToRead {
+		t.Errorf("Expected the rolled back book to stay on its shelf, got %s", book.Status)
+	}
+
+	response = apply(model.BulkOperation{IDs: ids[:2], Operation: model.BulkSetStatus, Status: model.StatusRead})
+	if !response.Applied {
+		t.Fatalf("Expected the operation to be applied, got %+v", response)
+	}
+	book, _ := store.GetBookByID(ctx, ids[1])
+	if book.Status != model.StatusRead || book.DateFinished == nil || book.Position != 2 || book.Version != 2 {
+		t.Errorf("Expected the book finished and at the end of Read, got %+v", book)
+	}
+
+	apply(model.BulkOperation{IDs: ids, Operation: model.BulkAddTag, Tag: "Imported"})
+	apply(model.BulkOperation{IDs: ids[:1], Operation: model.BulkAddTag, Tag: "imported"})
+	apply(model.BulkOperation{IDs: ids[:1], Operation: model.BulkAddTag, Tag: "2024"})
+	book, _ = store.GetBookByID(ctx, ids[0])
+	if len(book.Tags) != 2 || book.Tags[0] != "2024" || book.Tags[1] != "Imported" || book.Version != 4 {
+		t.Errorf("Expected two tags and one version per change, got %v (version %d)", book.Tags, book.Version)
+	}
+
+	apply(model.BulkOperation{IDs: ids[1:], Operation: model.BulkRemoveTag, Tag: "IMPORTED"})
+	tagged, err := store.ListBooks(ctx, ListOptions{Tag: "imported"})
+	if err != nil || len(tagged) != 1 || tagged[0].ID != ids[0] {
+		t.Errorf("Expected only the first book tagged, got %+v (%v)", tagged, err)
+	}
+
+	apply(model.BulkOperation{IDs: ids[1:], Operation: model.BulkSetType, Type: model.TypeAudiobook})
+	if book, _ := store.GetBookByID(ctx, ids[2]); book.Type != model.TypeAudiobook {
+		t.Errorf("Expected an audiobook, got %s", book.Type)
+	}
+
+	apply(model.BulkOperation{IDs: ids[1:], Operation: model.BulkDelete})
+	if _, err := store.GetBookByID(ctx, ids[2]); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the book to be deleted, got %v", err)
+	}
+
+	var validationErr *model.ValidationError
+	if _, err := store.ApplyBulk(ctx, model.BulkOperation{Operation: model.BulkDelete}); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a validation error without IDs, got %v", err)
+	}
+}
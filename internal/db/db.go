@@ -1,7 +1,9 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
@@ -48,13 +50,27 @@ func InitDB(dataSourceName string) (*sql.DB, error) {
 	return db, nil
 }
 
-// CreateSchema defines and executes the SQL statements to create the database tables.
-// Exported for testing purposes.
-func CreateSchema(db *sql.DB) error {
-	// Use TEXT for status, INTEGER for rating (nullable), TEXT for comments (nullable)
-	// Use TEXT for OpenLibraryID and ISBN
-	// Add UNIQUE constraint on OpenLibraryID to prevent duplicates? Or handle in application logic.
-	schema := `
+// migration is a single, ordered step in the evolution of the database schema.
+// Migrations are applied in order and the number applied is stored in PRAGMA user_version,
+// so each one runs exactly once per database.
+type migration struct {
+	description string
+	statements  string
+	// foreignKeysOff runs the migration with foreign key enforcement off, as SQLite requires
+	// to rebuild a table that other tables reference; dropping the old table would otherwise
+	// cascade. Foreign keys are checked before the migration commits.
+	foreignKeysOff bool
+}
+
+// migrations lists every schema change. Only ever append to this list; never edit or reorder
+// entries that have shipped, as existing databases have already applied them.
+var migrations = []migration{
+	{
+		description: "create books table",
+		// Use TEXT for status, INTEGER for rating (nullable), TEXT for comments (nullable)
+		// Use TEXT for OpenLibraryID and ISBN
+		// IF NOT EXISTS keeps this safe for databases created before schema versioning existed.
+		statements: `
     CREATE TABLE IF NOT EXISTS books (
         id INTEGER PRIMARY KEY AUTOINCREMENT,
         title TEXT NOT NULL,
@@ -69,13 +85,429 @@ func CreateSchema(db *sql.DB) error {
         series TEXT,
         series_index INTEGER
     );
-    `
-	slog.Info("Executing schema creation SQL")
-	_, err := db.Exec(schema)
+    `,
+	},
+	{
+		description: "add books.version for optimistic concurrency",
+		statements:  `ALTER TABLE books ADD COLUMN version INTEGER NOT NULL DEFAULT 1;`,
+	},
+	{
+		description: "create webhooks and webhook_deliveries tables",
+		// events is a comma-separated filter; empty means every event.
+		// webhook_deliveries has one row per delivery attempt.
+		statements: `
+    CREATE TABLE webhooks (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        url TEXT NOT NULL,
+        events TEXT NOT NULL DEFAULT '',
+        secret TEXT NOT NULL,
+        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );
+    CREATE TABLE webhook_deliveries (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        webhook_id INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+        delivery_id TEXT NOT NULL,
+        event TEXT NOT NULL,
+        attempt INTEGER NOT NULL,
+        status_code INTEGER,
+        error TEXT,
+        success INTEGER NOT NULL,
+        duration_ms INTEGER NOT NULL,
+        delivered_at TIMESTAMP NOT NULL
+    );
+    CREATE INDEX idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id, id);
+    `,
+	},
+	{
+		description: "create book_status_history table",
+		// One row per status transition; from_status is NULL when the book was added.
+		// Books that existed before this migration have no history.
+		statements: `
+    CREATE TABLE book_status_history (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        book_id INTEGER NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+        from_status TEXT,
+        to_status TEXT NOT NULL,
+        changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );
+    CREATE INDEX idx_book_status_history_book_id ON book_status_history(book_id, changed_at);
+    CREATE INDEX idx_book_status_history_to_status ON book_status_history(to_status, changed_at);
+    `,
+	},
+	{
+		description: "create goals table",
+		// book_type is '' for a goal covering every type (NULLs would not be UNIQUE)
+		statements: `
+    CREATE TABLE goals (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        year INTEGER NOT NULL,
+        book_type TEXT NOT NULL DEFAULT '',
+        target INTEGER NOT NULL CHECK(target > 0),
+        UNIQUE(year, book_type)
+    );
+    `,
+	},
+	{
+		description: "add books.date_added, date_started and date_finished",
+		// Backfilled from the status history where it exists; older books keep NULL dates
+		// until they are edited.
+		statements: `
+    ALTER TABLE books ADD COLUMN date_added TIMESTAMP;
+    ALTER TABLE books ADD COLUMN date_started TIMESTAMP;
+    ALTER TABLE books ADD COLUMN date_finished TIMESTAMP;
+    UPDATE books SET
+        date_added = (SELECT MIN(h.changed_at) FROM book_status_history h WHERE h.book_id = books.id),
+        date_started = (SELECT MAX(h.changed_at) FROM book_status_history h
+                        WHERE h.book_id = books.id AND h.to_status = 'Currently Reading'),
+        date_finished = CASE WHEN status = 'Read' THEN
+                            (SELECT MAX(h.changed_at) FROM book_status_history h
+                             WHERE h.book_id = books.id AND h.to_status = 'Read')
+                        END;
+    CREATE INDEX idx_books_date_finished ON books(date_finished);
+    `,
+	},
+	{
+		description: "create authors and book_authors tables",
+		// Existing books are credited from their comma-joined author string, without Open Library keys.
+		// Names written with a comma ("Le Guin, Ursula K.", "King, Jr.") are split into two authors;
+		// such credits can be corrected through PUT /api/books/{id}/authors.
+		statements: `
+    CREATE TABLE authors (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        name TEXT NOT NULL COLLATE NOCASE,
+        open_library_key TEXT UNIQUE
+    );
+    CREATE INDEX idx_authors_name ON authors(name);
+    CREATE TABLE book_authors (
+        book_id INTEGER NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+        author_id INTEGER NOT NULL REFERENCES authors(id),
+        role TEXT NOT NULL CHECK(role IN ('author', 'narrator', 'translator')),
+        position INTEGER NOT NULL,
+        PRIMARY KEY (book_id, author_id, role)
+    );
+    CREATE INDEX idx_book_authors_author ON book_authors(author_id, role);
+    CREATE TEMP TABLE split_authors AS
+    WITH RECURSIVE split(book_id, position, name, rest) AS (
+        SELECT id, 0, '', author || ',' FROM books
+        UNION ALL
+        SELECT book_id, position + 1, TRIM(substr(rest, 1, instr(rest, ',') - 1)), substr(rest, instr(rest, ',') + 1)
+        FROM split WHERE rest <> ''
+    )
+    SELECT book_id, position, name FROM split WHERE position > 0 AND name <> '';
+    INSERT INTO authors (name) SELECT name FROM split_authors GROUP BY name COLLATE NOCASE ORDER BY MIN(book_id);
+    INSERT OR IGNORE INTO book_authors (book_id, author_id, role, position)
+    SELECT s.book_id, a.id, 'author', s.position FROM split_authors s JOIN authors a ON a.name = s.name;
+    DROP TABLE split_authors;
+    `,
+	},
+	{
+		description: "create series table and books.series_id",
+		// books.series keeps the series name for display; it is kept equal to series.name.
+		statements: `
+    CREATE TABLE series (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+        total_books INTEGER CHECK(total_books > 0)
+    );
+    ALTER TABLE books ADD COLUMN series_id INTEGER REFERENCES series(id) ON DELETE SET NULL;
+    CREATE INDEX idx_books_series_id ON books(series_id);
+    UPDATE books SET series = NULLIF(TRIM(series), '');
+    INSERT INTO series (name)
+    SELECT series FROM books WHERE series IS NOT NULL GROUP BY series COLLATE NOCASE ORDER BY MIN(id);
+    UPDATE books SET series_id = (SELECT s.id FROM series s WHERE s.name = books.series) WHERE series IS NOT NULL;
+    UPDATE books SET series = (SELECT s.name FROM series s WHERE s.id = books.series_id) WHERE series_id IS NOT NULL;
+    `,
+	},
+	{
+		description: "add books.position for manual ordering within a shelf",
+		// Existing shelves keep the order they were shown in: by title.
+		statements: `
+    ALTER TABLE books ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+    UPDATE books SET position = (
+        SELECT COUNT(*) FROM books b2
+        WHERE b2.status = books.status AND (b2.title < books.title OR (b2.title = books.title AND b2.id <= books.id))
+    );
+    CREATE INDEX idx_books_status_position ON books(status, position);
+    `,
+	},
+	{
+		description: "create tags and book_tags tables",
+		statements: `
+    CREATE TABLE tags (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        name TEXT NOT NULL UNIQUE COLLATE NOCASE
+    );
+    CREATE TABLE book_tags (
+        book_id INTEGER NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+        tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+        PRIMARY KEY (book_id, tag_id)
+    );
+    CREATE INDEX idx_book_tags_tag ON book_tags(tag_id);
+    `,
+	},
+	{
+		description: "add books.deleted_at for the trash",
+		statements: `
+    ALTER TABLE books ADD COLUMN deleted_at TIMESTAMP;
+    CREATE INDEX idx_books_deleted_at ON books(deleted_at);
+    `,
+	},
+	{
+		description: "create audit_log table",
+		// book_id has no foreign key so that the history of purged books is kept
+		statements: `
+    CREATE TABLE audit_log (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        actor TEXT NOT NULL DEFAULT '',
+        request_id TEXT NOT NULL DEFAULT '',
+        action TEXT NOT NULL CHECK(action IN ('create', 'update', 'delete', 'restore', 'purge')),
+        book_id INTEGER NOT NULL,
+        before TEXT,
+        after TEXT
+    );
+    CREATE INDEX idx_audit_log_book_id ON audit_log(book_id, id);
+    CREATE INDEX idx_audit_log_actor ON audit_log(actor, id);
+    `,
+	},
+	{
+		description: "create undo_steps and undo_changes tables",
+		// Like the audit log, changes have no foreign key to books: undoing a change to a
+		// purged book is refused rather than silently dropped
+		statements: `
+    CREATE TABLE undo_steps (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        session_id TEXT NOT NULL,
+        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        action TEXT NOT NULL,
+        undone INTEGER NOT NULL DEFAULT 0
+    );
+    CREATE INDEX idx_undo_steps_session ON undo_steps(session_id, id);
+    CREATE INDEX idx_undo_steps_created_at ON undo_steps(created_at);
+    CREATE TABLE undo_changes (
+        step_id INTEGER NOT NULL REFERENCES undo_steps(id) ON DELETE CASCADE,
+        seq INTEGER NOT NULL,
+        book_id INTEGER NOT NULL,
+        before TEXT NOT NULL,
+        after TEXT NOT NULL,
+        PRIMARY KEY (step_id, seq)
+    );
+    `,
+	},
+	{
+		description: "create quotes table",
+		statements: `
+    CREATE TABLE quotes (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        book_id INTEGER NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+        text TEXT NOT NULL,
+        page INTEGER,
+        location TEXT,
+        timestamp_seconds INTEGER,
+        note TEXT,
+        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );
+    CREATE INDEX idx_quotes_book_id ON quotes(book_id);
+    `,
+	},
+	{
+		description: "add quotes.import_hash to de-duplicate imported quotes",
+		statements: `
+    ALTER TABLE quotes ADD COLUMN import_hash TEXT;
+    CREATE UNIQUE INDEX idx_quotes_import_hash ON quotes(book_id, import_hash) WHERE import_hash IS NOT NULL;
+    `,
+	},
+	{
+		description: "allow ebooks and create editions table",
+		// SQLite cannot change the CHECK constraint on books.type, so the table is rebuilt. Its
+		// AUTOINCREMENT counter is carried over so that IDs of purged books, which the audit log
+		// refers to, are not reused. Every book gets an edition of its type, ISBN and cover.
+		foreignKeysOff: true,
+		statements: `
+    CREATE TABLE books_new (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        title TEXT NOT NULL,
+        author TEXT NOT NULL,
+        open_library_id TEXT NOT NULL UNIQUE,
+        isbn TEXT,
+        status TEXT NOT NULL CHECK(status IN ('Want to Read', 'Currently Reading', 'Read')),
+        type TEXT NOT NULL DEFAULT 'book' CHECK(type IN ('book', 'ebook', 'audiobook')),
+        rating INTEGER CHECK(rating IS NULL OR (rating >= 1 AND rating <= 10)),
+        comments TEXT,
+        cover_url TEXT,
+        series TEXT,
+        series_index INTEGER,
+        version INTEGER NOT NULL DEFAULT 1,
+        date_added TIMESTAMP,
+        date_started TIMESTAMP,
+        date_finished TIMESTAMP,
+        series_id INTEGER REFERENCES series(id) ON DELETE SET NULL,
+        position INTEGER NOT NULL DEFAULT 0,
+        deleted_at TIMESTAMP
+    );
+    INSERT INTO books_new (id, title, author, open_library_id, isbn, status, type, rating, comments, cover_url,
+                           series, series_index, version, date_added, date_started, date_finished, series_id,
+                           position, deleted_at)
+    SELECT id, title, author, open_library_id, isbn, status, type, rating, comments, cover_url,
+           series, series_index, version, date_added, date_started, date_finished, series_id,
+           position, deleted_at
+    FROM books;
+    UPDATE sqlite_sequence SET seq = MAX(seq, (SELECT seq FROM sqlite_sequence WHERE name = 'books'))
+    WHERE name = 'books_new';
+    DROP TABLE books;
+    ALTER TABLE books_new RENAME TO books;
+    CREATE INDEX idx_books_date_finished ON books(date_finished);
+    CREATE INDEX idx_books_series_id ON books(series_id);
+    CREATE INDEX idx_books_status_position ON books(status, position);
+    CREATE INDEX idx_books_deleted_at ON books(deleted_at);
+
+    CREATE TABLE editions (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        book_id INTEGER NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+        format TEXT NOT NULL CHECK(format IN ('book', 'ebook', 'audiobook')),
+        isbn TEXT,
+        publisher TEXT,
+        pages INTEGER CHECK(pages > 0),
+        duration_minutes INTEGER CHECK(duration_minutes > 0),
+        cover_url TEXT
+    );
+    CREATE INDEX idx_editions_book_id ON editions(book_id);
+    CREATE UNIQUE INDEX idx_editions_isbn ON editions(book_id, isbn) WHERE isbn IS NOT NULL;
+    INSERT INTO editions (book_id, format, isbn, cover_url)
+    SELECT id, type, NULLIF(TRIM(isbn), ''), NULLIF(TRIM(cover_url), '') FROM books ORDER BY id;
+    `,
+	},
+	{
+		description: "create loans table",
+		// A book is lent or borrowed by one person at a time
+		statements: `
+    CREATE TABLE loans (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        book_id INTEGER NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+        person TEXT NOT NULL,
+        direction TEXT NOT NULL CHECK(direction IN ('lent', 'borrowed')),
+        date_out TIMESTAMP NOT NULL,
+        due_date TIMESTAMP,
+        returned_at TIMESTAMP
+    );
+    CREATE INDEX idx_loans_book_id ON loans(book_id);
+    CREATE UNIQUE INDEX idx_loans_open ON loans(book_id) WHERE returned_at IS NULL;
+    `,
+	},
+	{
+		description: "add ownership and purchase columns to books",
+		// Existing books have no ownership recorded; it is not the same as their reading status
+		statements: `
+    ALTER TABLE books ADD COLUMN ownership TEXT CHECK(ownership IN ('owned', 'wishlist', 'library', 'sold', 'donated'));
+    ALTER TABLE books ADD COLUMN purchase_date TIMESTAMP;
+    ALTER TABLE books ADD COLUMN price REAL CHECK(price >= 0);
+    ALTER TABLE books ADD COLUMN currency TEXT;
+    ALTER TABLE books ADD COLUMN store TEXT;
+    ALTER TABLE books ADD COLUMN location_room TEXT;
+    ALTER TABLE books ADD COLUMN location_shelf TEXT;
+    CREATE INDEX idx_books_ownership ON books(ownership);
+    `,
+	},
+	{
+		description: "create work metadata cache",
+		// What Open Library knows of works, on the shelf or not, for recommendations offline
+		statements: `
+    CREATE TABLE work_metadata (
+        open_library_id TEXT PRIMARY KEY,
+        title TEXT NOT NULL,
+        author TEXT NOT NULL DEFAULT '',
+        subjects TEXT NOT NULL DEFAULT '[]', -- JSON array of strings
+        isbn TEXT,
+        cover_url TEXT,
+        fetched_at TIMESTAMP NOT NULL
+    );
+    `,
+	},
+}
+
+// ExpectedSchemaVersion returns the schema version this build of the application requires.
+func ExpectedSchemaVersion() int {
+	return len(migrations)
+}
+
+// SchemaVersion returns the schema version currently recorded in the database.
+func SchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version;").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// CreateSchema brings the database schema up to date by applying any pending migrations.
+// Exported for testing purposes.
+func CreateSchema(db *sql.DB) error {
+	current, err := SchemaVersion(context.Background(), db)
 	if err != nil {
-		slog.Error("Error executing schema SQL", "error", err)
-		return fmt.Errorf("failed to execute schema creation: %w", err)
+		return err
 	}
-	slog.Info("Schema execution successful")
+	if current > len(migrations) {
+		return fmt.Errorf("database schema version %d is newer than supported version %d", current, len(migrations))
+	}
+
+	for i := current; i < len(migrations); i++ {
+		version := i + 1
+		slog.Info("Applying schema migration", "version", version, "description", migrations[i].description)
+		if err := applyMigration(db, version, migrations[i]); err != nil {
+			slog.Error("Error applying schema migration", "version", version, "error", err)
+			return fmt.Errorf("failed to apply migration %d (%s): %w", version, migrations[i].description, err)
+		}
+	}
+	slog.Info("Schema is up to date", "version", len(migrations))
 	return nil
-}
\ No newline at end of file
+}
+
+// applyMigration runs a migration and records its version in a single transaction,
+// so a failed migration leaves the database untouched.
+func applyMigration(db *sql.DB, version int, m migration) error {
+	ctx := context.Background()
+	// PRAGMA foreign_keys is per connection, so the migration needs one to itself
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if m.foreignKeysOff {
+		var enabled bool
+		if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys;").Scan(&enabled); err != nil {
+			return fmt.Errorf("failed to read foreign key setting: %w", err)
+		}
+		// PRAGMA foreign_keys has no effect inside a transaction
+		if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF;"); err != nil {
+			return fmt.Errorf("failed to disable foreign keys: %w", err)
+		}
+		defer conn.ExecContext(ctx, fmt.Sprintf("PRAGMA foreign_keys = %t;", enabled))
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(m.statements); err != nil {
+		return err
+	}
+	if m.foreignKeysOff {
+		var table, parent string
+		var rowID sql.NullInt64
+		var foreignKey int
+		err := tx.QueryRow("PRAGMA foreign_key_check;").Scan(&table, &rowID, &parent, &foreignKey)
+		if err == nil {
+			return fmt.Errorf("migration leaves rows of %s with broken foreign keys", table)
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to check foreign keys: %w", err)
+		}
+	}
+	// PRAGMA does not accept bound parameters
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d;", version)); err != nil {
+		return fmt.Errorf("failed to record schema version: %w", err)
+	}
+	return tx.Commit()
+}
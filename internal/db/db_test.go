@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// TestCreateSchemaVersioning tests that migrations are applied once and recorded
+func TestCreateSchemaVersioning(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open in-memory database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1) // Every connection to :memory: is a separate database
+
+	if err := CreateSchema(db); err != nil {
+		t.Fatalf("CreateSchema failed: %v", err)
+	}
+	version, err := SchemaVersion(context.Background(), db)
+	if err != nil {
+		t.Fatalf("SchemaVersion failed: %v", err)
+	}
+	if version != ExpectedSchemaVersion() {
+		t.Errorf("Expected schema version %d, got %d", ExpectedSchemaVersion(), version)
+	}
+
+	// Running again must be a no-op
+	if err := CreateSchema(db); err != nil {
+		t.Fatalf("Second CreateSchema failed: %v", err)
+	}
+
+	// A database from a newer build must be rejected
+	if _, err := db.Exec("PRAGMA user_version = 9999;"); err != nil {
+		t.Fatalf("Failed to bump schema version: %v", err)
+	}
+	if err := CreateSchema(db); err == nil {
+		t.Error("Expected error for a schema version newer than supported")
+	}
+}
+
+// TestEditionsMigration tests that rebuilding the books table keeps books and the rows that
+// refer to them, with foreign keys enforced as in production
+func TestEditionsMigration(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "bookshelf.db")+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// Bring the database to the version before editions
+	rebuild := slices.IndexFunc(migrations, func(m migration) bool { return m.foreignKeysOff })
+	for i, m := range migrations[:rebuild] {
+		if err := applyMigration(db, i+1, m); err != nil {
+			t.Fatalf("Migration %d failed: %v", i+1, err)
+		}
+	}
+	_, err = db.Exec(`
+        INSERT INTO books (id, title, author, open_library_id, isbn, status, type, cover_url)
+        VALUES (7, 'Dune', 'Frank Herbert', 'OL-Dune', '9780441172719', 'Read', 'audiobook', 'https://covers.example/dune.jpg'),
+               (9, 'Emma', 'Jane Austen', 'OL-Emma', '', 'Want to Read', 'book', NULL);
+        DELETE FROM books WHERE id = 9;
+        INSERT INTO tags (name) VALUES ('classics');
+        INSERT INTO book_tags (book_id, tag_id) VALUES (7, 1);
+        INSERT INTO quotes (book_id, text) VALUES (7, 'Fear is the mind-killer.');`)
+	if err != nil {
+		t.Fatalf("Failed to insert data: %v", err)
+	}
+
+	if err := CreateSchema(db); err != nil {
+		t.Fatalf("CreateSchema failed: %v", err)
+	}
+	var foreignKeys bool
+	if err := db.QueryRow("PRAGMA foreign_keys;").Scan(&foreignKeys); err != nil || !foreignKeys {
+		t.Errorf("Expected foreign keys enforced again, got %v (%v)", foreignKeys, err)
+	}
+
+	store := NewSQLiteBookStore(db)
+	book, err := store.GetBookByID(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("GetBookByID failed: %v", err)
+	}
+	if len(book.Tags) != 1 || len(book.Editions) != 1 {
+		t.Fatalf("Expected the tag kept and an edition added, got %+v", book)
+	}
+	if e := book.Editions[0]; e.Format != model.TypeAudiobook || e.ISBN == nil || *e.ISBN != "9780441172719" || e.CoverURL == nil {
+		t.Errorf("Expected an audiobook edition with the book's ISBN and cover, got %+v", e)
+	}
+	if quotes, err := store.GetQuotes(context.Background(), 7); err != nil || len(quotes) != 1 {
+		t.Errorf("Expected the quote kept, got %d, %v", len(quotes), err)
+	}
+
+	// IDs are not reused, and foreign keys still cascade to the rebuilt table
+	id, err := store.AddBook(context.Background(), &model.Book{Title: "Neuromancer", Author: "William Gibson", OpenLibraryID: "OL-Neuromancer", Type: model.TypeEbook})
+	if err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	if id != 10 {
+		t.Errorf("Expected the ID after the highest ever used, got %d", id)
+	}
+	if _, err := db.Exec(`DELETE FROM books WHERE id = 7;`); err != nil {
+		t.Fatalf("Failed to delete book: %v", err)
+	}
+	var remaining int
+	db.QueryRow(`SELECT (SELECT COUNT(*) FROM editions WHERE book_id = 7) + (SELECT COUNT(*) FROM book_tags WHERE book_id = 7);`).Scan(&remaining)
+	if remaining != 0 {
+		t.Errorf("Expected editions and tags deleted with their book, got %d rows", remaining)
+	}
+}
@@ -0,0 +1,208 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/dedupe"
+	"github.com/ericdahl/bookshelf/internal/events"
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/metrics"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// DuplicateStore defines the interface for finding books entered more than once and merging
+// them into one.
+type DuplicateStore interface {
+	FindDuplicates(ctx context.Context) ([]model.DuplicateGroup, error)
+	MergeBooks(ctx context.Context, targetID int64, sourceIDs []int64) (*model.Book, error)
+}
+
+// FindDuplicates groups the books on the shelves that look like the same work (see dedupe.Find).
+func (s *SQLiteBookStore) FindDuplicates(ctx context.Context) ([]model.DuplicateGroup, error) {
+	defer metrics.ObserveDBQuery("FindDuplicates", time.Now())
+	books, err := s.ListBooks(ctx, ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return dedupe.Find(books), nil
+}
+
+// MergeBooks merges the source books into the target book, which survives, and moves the
+// sources to the trash. The target gets their status history, tags, quotes, editions and loans,
+// and keeps its own details, except that those it lacks (rating, series, ISBN, cover, dates
+// started and finished, ownership) are taken from the first source that has them. Comments are
+// joined, and the earliest date added is kept. The sources' moves to Read stay with them unless
+// the target is Read, so that a finished duplicate does not count as a finish of an unread
+// target. The merge can be undone like a deletion. Returns the merged book, ErrNotFound if any
+// of the books does not exist or is in the trash, or a validation error if more than one of
+// them is on loan.
+func (s *SQLiteBookStore) MergeBooks(ctx context.Context, targetID int64, sourceIDs []int64) (*model.Book, error) {
+	defer metrics.ObserveDBQuery("MergeBooks", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	if len(sourceIDs) == 0 {
+		return nil, &model.ValidationError{Message: "at least one book to merge is required"}
+	}
+	for i, id := range sourceIDs {
+		if id == targetID || slices.Contains(sourceIDs[:i], id) {
+			return nil, &model.ValidationError{Message: fmt.Sprintf("book %d is given more than once", id)}
+		}
+	}
+
+	logger.Info("SQL: Executing MergeBooks", "targetID", targetID, "sourceIDs", sourceIDs)
+	previous := make(map[int64]*model.Book, len(sourceIDs)+1)
+	for _, id := range append([]int64{targetID}, sourceIDs...) {
+		previous[id] = s.snapshot(ctx, id)
+	}
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := bookExists(ctx, tx, targetID); err != nil {
+		return nil, err
+	}
+	target, err := readBook(ctx, tx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	sources := make([]*model.Book, len(sourceIDs))
+	for i, id := range sourceIDs {
+		if err := bookExists(ctx, tx, id); err != nil {
+			return nil, err
+		}
+		if sources[i], err = readBook(ctx, tx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	merged := mergeDetails(target, sources)
+	series, seriesID, err := resolveSeries(ctx, tx, merged.Series)
+	if err != nil {
+		return nil, err
+	}
+	args := []interface{}{merged.Rating, merged.Comments, series, seriesID, merged.SeriesIndex, merged.ISBN, merged.CoverURL,
+		sqlTimestamp(merged.DateAdded), sqlTimestamp(merged.DateStarted), sqlTimestamp(merged.DateFinished)}
+	args = append(args, ownershipArgs(merged.Ownership)...)
+	_, err = tx.ExecContext(ctx, `
+        UPDATE books SET rating = ?, comments = ?, series = ?, series_id = ?, series_index = ?, isbn = ?, cover_url = ?,
+            date_added = ?, date_started = ?, date_finished = ?,
+            ownership = ?, purchase_date = ?, price = ?, currency = ?, store = ?, location_room = ?, location_shelf = ?,
+            version = version + 1
+        WHERE id = ?;`, append(args, targetID)...)
+	if err != nil {
+		logger.Error("SQL Error: Updating merged book failed", "error", err)
+		return nil, fmt.Errorf("failed to update book %d: %w", targetID, err)
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(sourceIDs)), ", ")
+	idArgs := []interface{}{targetID}
+	for _, id := range sourceIDs {
+		idArgs = append(idArgs, id)
+	}
+	// Rows the target already has (the same tag, an edition with the same ISBN, a quote
+	// imported twice) are left with the sources, in the trash
+	history := `UPDATE book_status_history SET book_id = ? WHERE book_id IN (` + placeholders + `)`
+	if target.Status != model.StatusRead {
+		history += ` AND to_status != 'Read'`
+	}
+	for _, statement := range []string{
+		history + `;`,
+		`INSERT OR IGNORE INTO book_tags (book_id, tag_id) SELECT ?, tag_id FROM book_tags WHERE book_id IN (` + placeholders + `);`,
+		`UPDATE OR IGNORE quotes SET book_id = ? WHERE book_id IN (` + placeholders + `);`,
+		`UPDATE OR IGNORE editions SET book_id = ? WHERE book_id IN (` + placeholders + `);`,
+	} {
+		if _, err := tx.ExecContext(ctx, statement, idArgs...); err != nil {
+			logger.Error("SQL Error: Moving rows to merged book failed", "error", err)
+			return nil, fmt.Errorf("failed to merge books: %w", err)
+		}
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE loans SET book_id = ? WHERE book_id IN (`+placeholders+`);`, idArgs...)
+	if isUniqueViolation(err) {
+		return nil, &model.ValidationError{Message: "more than one of the books is on loan; return all but one first"}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to merge loans: %w", err)
+	}
+
+	deletedAt := sqlTimestamp(ptrTo(time.Now()))
+	for i, id := range sourceIDs {
+		if _, err := tx.ExecContext(ctx, `UPDATE books SET deleted_at = ?, version = version + 1 WHERE id = ?;`, deletedAt, id); err != nil {
+			logger.Error("SQL Error: Moving merged book to the trash failed", "id", id, "error", err)
+			return nil, fmt.Errorf("failed to delete book %d: %w", id, err)
+		}
+		if err := recordAudit(ctx, tx, model.AuditDelete, id, sources[i]); err != nil {
+			logger.Error("SQL Error: Recording audit entry failed", "error", err)
+			return nil, err
+		}
+	}
+	if err := recordAudit(ctx, tx, model.AuditUpdate, targetID, target); err != nil {
+		logger.Error("SQL Error: Recording audit entry failed", "error", err)
+		return nil, err
+	}
+	if err := recordUndo(ctx, tx, model.UndoMerge, append([]*model.Book{target}, sources...)...); err != nil {
+		logger.Error("SQL Error: Recording undo step failed", "error", err)
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("SQL Error: Committing MergeBooks transaction failed", "error", err)
+		return nil, fmt.Errorf("failed to commit merge: %w", err)
+	}
+
+	logger.Info("SQL: Successfully merged books", "targetID", targetID, "sourceIDs", sourceIDs)
+	for _, id := range sourceIDs {
+		s.publish(events.BookDeleted, id, previous[id], nil)
+	}
+	s.publish(events.BookUpdated, targetID, previous[targetID], s.snapshot(ctx, targetID))
+	return s.GetBookByID(ctx, targetID)
+}
+
+// mergeDetails returns a copy of target with the details it lacks taken from the first of
+// sources that has them, its comments joined with theirs and the earliest date added.
+func mergeDetails(target *model.Book, sources []*model.Book) *model.Book {
+	merged := *target
+	comments := []string{}
+	if target.Comments != nil {
+		comments = append(comments, *target.Comments)
+	}
+	for _, source := range sources {
+		if merged.Rating == nil {
+			merged.Rating = source.Rating
+		}
+		if merged.Series == nil && source.Series != nil {
+			merged.Series, merged.SeriesIndex = source.Series, source.SeriesIndex
+		}
+		if merged.ISBN == "" {
+			merged.ISBN = source.ISBN
+		}
+		if merged.CoverURL == nil {
+			merged.CoverURL = source.CoverURL
+		}
+		if merged.DateStarted == nil {
+			merged.DateStarted = source.DateStarted
+		}
+		if merged.DateFinished == nil {
+			merged.DateFinished = source.DateFinished
+		}
+		if merged.Ownership == nil {
+			merged.Ownership = source.Ownership
+		}
+		if source.DateAdded != nil && (merged.DateAdded == nil || source.DateAdded.Before(*merged.DateAdded)) {
+			merged.DateAdded = source.DateAdded
+		}
+		if source.Comments != nil && !slices.Contains(comments, *source.Comments) {
+			comments = append(comments, *source.Comments)
+		}
+	}
+	if len(comments) > 0 {
+		joined := strings.Join(comments, "\n\n")
+		merged.Comments = &joined
+	}
+	return &merged
+}
@@ -0,0 +1,187 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	add := func(book model.Book) int64 {
+		id, err := store.AddBook(ctx, &book)
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", book.Title, err)
+		}
+		return id
+	}
+	dune := add(model.Book{Title: "Dune", Author: "Frank Herbert", OpenLibraryID: "OL1", ISBN: "0441172717", Status: model.StatusRead})
+	audio := add(model.Book{Title: "Dune (Unabridged)", Author: "Frank Herbert", OpenLibraryID: "OL2", Status: model.StatusRead, Type: model.TypeAudiobook})
+	add(model.Book{Title: "Emma", Author: "Jane Austen", OpenLibraryID: "OL3", Status: model.StatusRead})
+	ebook := add(model.Book{Title: "Arrakis", Author: "Someone", OpenLibraryID: "OL4", Status: model.StatusRead})
+	if err := store.AddEdition(ctx, &model.Edition{BookID: ebook, Format: model.TypeEbook, ISBN: ptrTo("978-0-441-17271-9")}); err != nil {
+		t.Fatalf("AddEdition failed: %v", err)
+	}
+	trashed := add(model.Book{Title: "Dune", Author: "Frank Herbert", OpenLibraryID: "OL5", Status: model.StatusRead})
+	if err := store.DeleteBook(ctx, trashed); err != nil {
+		t.Fatalf("DeleteBook failed: %v", err)
+	}
+
+	groups, err := store.FindDuplicates(ctx)
+	if err != nil {
+		t.Fatalf("FindDuplicates failed: %v", err)
+	}
+	if len(groups) != 1 || groups[0].Reason != model.DuplicateISBN || len(groups[0].Books) != 3 {
+		t.Fatalf("Expected one group of the three Dunes on the shelves, got %+v", groups)
+	}
+	for i, want := range []int64{dune, audio, ebook} {
+		if groups[0].Books[i].ID != want {
+			t.Errorf("Expected book %d at %d, got %d", want, i, groups[0].Books[i].ID)
+		}
+	}
+}
+
+func TestMergeBooks(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	longAgo := time.Date(2010, 1, 2, 0, 0, 0, 0, time.UTC)
+	target, err := store.AddBook(ctx, &model.Book{Title: "Dune", Author: "Frank Herbert", OpenLibraryID: "OL1", Status: model.StatusWantToRead})
+	if err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	source, err := store.AddBook(ctx, &model.Book{Title: "Dune", Author: "Frank Herbert", OpenLibraryID: "OL2", ISBN: "0441172717",
+		Status: model.StatusRead, Type: model.TypeAudiobook, DateAdded: &longAgo, Ownership: &model.Ownership{Status: model.OwnershipOwned}})
+	if err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	other, err := store.AddBook(ctx, &model.Book{Title: "Dune", Author: "Frank Herbert", OpenLibraryID: "OL3", Status: model.StatusRead})
+	if err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	if err := store.UpdateBookDetails(ctx, target, model.BookDetails{Comments: ptrTo("Reread soon"), Series: ptrTo("Dune"), SeriesIndex: ptrTo(1)}); err != nil {
+		t.Fatalf("UpdateBookDetails failed: %v", err)
+	}
+	if err := store.UpdateBookDetails(ctx, source, model.BookDetails{Rating: ptrTo(9), Comments: ptrTo("A classic"), DateAdded: &longAgo, DateFinished: ptrTo(longAgo.AddDate(0, 1, 0))}); err != nil {
+		t.Fatalf("UpdateBookDetails failed: %v", err)
+	}
+	if _, err := store.ApplyBulk(ctx, model.BulkOperation{IDs: []int64{target, source}, Operation: model.BulkAddTag, Tag: "sci-fi"}); err != nil {
+		t.Fatalf("ApplyBulk failed: %v", err)
+	}
+	if _, err := store.ApplyBulk(ctx, model.BulkOperation{IDs: []int64{source}, Operation: model.BulkAddTag, Tag: "favorites"}); err != nil {
+		t.Fatalf("ApplyBulk failed: %v", err)
+	}
+	if err := store.AddQuote(ctx, &model.Quote{BookID: source, Text: "Fear is the mind-killer."}); err != nil {
+		t.Fatalf("AddQuote failed: %v", err)
+	}
+	if err := store.AddLoan(ctx, &model.Loan{BookID: source, Person: "Alice", Direction: model.LoanLent}); err != nil {
+		t.Fatalf("AddLoan failed: %v", err)
+	}
+	if err := store.AddLoan(ctx, &model.Loan{BookID: other, Person: "Bob", Direction: model.LoanLent}); err != nil {
+		t.Fatalf("AddLoan failed: %v", err)
+	}
+
+	var validationErr *model.ValidationError
+	for _, sources := range [][]int64{nil, {target}, {source, source}} {
+		if _, err := store.MergeBooks(ctx, target, sources); !errors.As(err, &validationErr) {
+			t.Errorf("Expected a validation error merging %v, got %v", sources, err)
+		}
+	}
+	if _, err := store.MergeBooks(ctx, target, []int64{source, 999}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound merging a missing book, got %v", err)
+	}
+	if _, err := store.MergeBooks(ctx, target, []int64{source, other}); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a validation error merging two books on loan, got %v", err)
+	}
+
+	session := WithSession(ctx, "tab-1")
+	book, err := store.MergeBooks(session, target, []int64{source})
+	if err != nil {
+		t.Fatalf("MergeBooks failed: %v", err)
+	}
+	if book.Status != model.StatusWantToRead || book.Type != model.TypeBook || book.OpenLibraryID != "OL1" {
+		t.Errorf("Expected the target to keep its status, type and Open Library ID, got %+v", book)
+	}
+	if book.Rating == nil || *book.Rating != 9 || book.ISBN != "0441172717" || book.Ownership == nil || book.DateFinished == nil {
+		t.Errorf("Expected the details the target lacked taken from the source, got %+v", book)
+	}
+	if *book.Series != "Dune" || *book.Comments != "Reread soon\n\nA classic" || !book.DateAdded.Equal(longAgo) {
+		t.Errorf("Expected the target's series, joined comments and the earliest date added, got %+v", book)
+	}
+	if len(book.Tags) != 2 || book.Tags[0] != "favorites" || book.Tags[1] != "sci-fi" {
+		t.Errorf("Expected the tags combined, got %v", book.Tags)
+	}
+	if len(book.Editions) != 2 || book.Editions[1].Format != model.TypeAudiobook || !book.Lent {
+		t.Errorf("Expected the source's edition and loan moved, got %+v, lent=%v", book.Editions, book.Lent)
+	}
+	if quotes, err := store.GetQuotes(ctx, target); err != nil || len(quotes) != 1 {
+		t.Errorf("Expected the quote moved, got %+v, %v", quotes, err)
+	}
+	// The source was finished, but the target is not: its move to Read stays with it
+	var history int
+	db.QueryRow(`SELECT COUNT(*) FROM book_status_history WHERE book_id = ?;`, target).Scan(&history)
+	if history != 1 {
+		t.Errorf("Expected the source's move to Read left out of the target's history, got %d entries", history)
+	}
+	if n, err := store.CountFinished(ctx, time.Now().Year(), ""); err != nil || n != 1 {
+		t.Errorf("Expected only the other book finished this year, got %d (%v)", n, err)
+	}
+
+	if _, err := store.GetBookByID(ctx, source); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the source deleted, got %v", err)
+	}
+	if trash, err := store.GetTrash(ctx); err != nil || len(trash) != 1 || trash[0].ID != source {
+		t.Errorf("Expected the source in the trash, got %+v, %v", trash, err)
+	}
+	page, err := store.GetAuditLog(ctx, AuditFilter{BookID: source, Limit: 50})
+	if err != nil || page.Entries[0].Action != model.AuditDelete {
+		t.Errorf("Expected the source's deletion audited, got %+v, %v", page, err)
+	}
+
+	// Undo brings the source back and restores the target's details
+	step, err := store.Undo(session)
+	if err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if step.Action != model.UndoMerge || len(step.BookIDs) != 2 {
+		t.Errorf("Expected the merge of both books undone, got %+v", step)
+	}
+	if restored, err := store.GetBookByID(ctx, source); err != nil || restored.Rating == nil || *restored.Rating != 9 {
+		t.Errorf("Expected the source restored with its rating, got %+v, %v", restored, err)
+	}
+	if book, err := store.GetBookByID(ctx, target); err != nil || book.Rating != nil || len(book.Tags) != 1 {
+		t.Errorf("Expected the target's own rating and tags restored, got %+v, %v", book, err)
+	}
+}
+
+func TestMergeBooksIntoRead(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	var ids []int64
+	for _, olid := range []string{"OL1", "OL2"} {
+		id, err := store.AddBook(ctx, &model.Book{Title: "Dune", Author: "Frank Herbert", OpenLibraryID: olid, Status: model.StatusRead})
+		if err != nil {
+			t.Fatalf("AddBook failed: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if _, err := store.MergeBooks(ctx, ids[0], ids[1:]); err != nil {
+		t.Fatalf("MergeBooks failed: %v", err)
+	}
+
+	// Both books were finished, and so is the target: the source's finish moves with it
+	var history int
+	db.QueryRow(`SELECT COUNT(*) FROM book_status_history WHERE book_id = ? AND to_status = 'Read';`, ids[0]).Scan(&history)
+	if history != 2 {
+		t.Errorf("Expected both moves to Read on the target, got %d", history)
+	}
+}
@@ -0,0 +1,184 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/metrics"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// EditionStore defines the interface for the editions of books. Editions are part of their
+// book, so changing them is a new version of it.
+type EditionStore interface {
+	GetEditions(ctx context.Context, bookID int64) ([]model.Edition, error)
+	AddEdition(ctx context.Context, edition *model.Edition) error
+	UpdateEdition(ctx context.Context, edition *model.Edition) error
+	DeleteEdition(ctx context.Context, bookID, editionID int64) error
+}
+
+// editionColumns lists the columns read by scanEdition.
+const editionColumns = `id, book_id, format, isbn, publisher, pages, duration_minutes, cover_url`
+
+// scanEdition reads an edition selected with editionColumns.
+func scanEdition(row rowScanner) (*model.Edition, error) {
+	var edition model.Edition
+	var isbn, publisher, coverURL sql.NullString
+	var pages, duration sql.NullInt64
+	if err := row.Scan(&edition.ID, &edition.BookID, &edition.Format, &isbn, &publisher, &pages, &duration, &coverURL); err != nil {
+		return nil, err
+	}
+	if isbn.Valid {
+		edition.ISBN = &isbn.String
+	}
+	if publisher.Valid {
+		edition.Publisher = &publisher.String
+	}
+	if pages.Valid {
+		edition.Pages = ptrTo(int(pages.Int64))
+	}
+	if duration.Valid {
+		edition.Duration = ptrTo(int(duration.Int64))
+	}
+	if coverURL.Valid {
+		edition.CoverURL = &coverURL.String
+	}
+	return &edition, nil
+}
+
+// editionsOf reads the editions of the given books through q, in the order they were added.
+func editionsOf(ctx context.Context, q dbtx, bookIDs []int64) (map[int64][]model.Edition, error) {
+	editions := make(map[int64][]model.Edition, len(bookIDs))
+	if len(bookIDs) == 0 {
+		return editions, nil
+	}
+	placeholders := make([]string, len(bookIDs))
+	args := make([]interface{}, len(bookIDs))
+	for i, id := range bookIDs {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+	rows, err := q.QueryContext(ctx, `
+        SELECT `+editionColumns+` FROM editions
+        WHERE book_id IN (`+strings.Join(placeholders, ", ")+`)
+        ORDER BY book_id, id;`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query editions: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		edition, err := scanEdition(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan edition: %w", err)
+		}
+		editions[edition.BookID] = append(editions[edition.BookID], *edition)
+	}
+	return editions, rows.Err()
+}
+
+// loadEditions fills in the Editions of each book with a single query.
+func (s *SQLiteBookStore) loadEditions(ctx context.Context, books []model.Book) error {
+	ids := make([]int64, len(books))
+	for i, book := range books {
+		ids[i] = book.ID
+	}
+	editions, err := editionsOf(ctx, s.DB, ids)
+	if err != nil {
+		return err
+	}
+	for i := range books {
+		books[i].Editions = editions[books[i].ID]
+	}
+	return nil
+}
+
+// GetEditions retrieves the editions of a book in the order they were added.
+// Returns ErrNotFound if the book does not exist.
+func (s *SQLiteBookStore) GetEditions(ctx context.Context, bookID int64) ([]model.Edition, error) {
+	defer metrics.ObserveDBQuery("GetEditions", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	logger.Info("SQL: Executing GetEditions query", "bookID", bookID)
+	if err := bookExists(ctx, s.DB, bookID); err != nil {
+		return nil, err
+	}
+	editions, err := editionsOf(ctx, s.DB, []int64{bookID})
+	if err != nil {
+		logger.Error("SQL Error: Querying editions failed", "error", err)
+		return nil, err
+	}
+	if editions[bookID] == nil {
+		return []model.Edition{}, nil
+	}
+	return editions[bookID], nil
+}
+
+// AddEdition adds an edition to the book edition.BookID and sets its ID. Returns ErrNotFound
+// if the book does not exist, or ErrDuplicate if it already has an edition with the ISBN.
+func (s *SQLiteBookStore) AddEdition(ctx context.Context, edition *model.Edition) error {
+	defer metrics.ObserveDBQuery("AddEdition", time.Now())
+	if err := edition.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+	return s.changeBook(ctx, "AddEdition", edition.BookID, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+            INSERT INTO editions (book_id, format, isbn, publisher, pages, duration_minutes, cover_url)
+            VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id;`,
+			edition.BookID, edition.Format, edition.ISBN, edition.Publisher, edition.Pages, edition.Duration, edition.CoverURL).Scan(&edition.ID)
+		if isUniqueViolation(err) {
+			return fmt.Errorf("edition with ISBN %s of book %d %w", *edition.ISBN, edition.BookID, ErrDuplicate)
+		} else if err != nil {
+			return fmt.Errorf("failed to add edition: %w", err)
+		}
+		return nil
+	})
+}
+
+// UpdateEdition replaces edition.ID, which must belong to the book edition.BookID. Returns
+// ErrNotFound otherwise, or ErrDuplicate if the book has another edition with the ISBN.
+func (s *SQLiteBookStore) UpdateEdition(ctx context.Context, edition *model.Edition) error {
+	defer metrics.ObserveDBQuery("UpdateEdition", time.Now())
+	if err := edition.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+	return s.changeBook(ctx, "UpdateEdition", edition.BookID, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `
+            UPDATE editions SET format = ?, isbn = ?, publisher = ?, pages = ?, duration_minutes = ?, cover_url = ?
+            WHERE id = ? AND book_id = ?;`,
+			edition.Format, edition.ISBN, edition.Publisher, edition.Pages, edition.Duration, edition.CoverURL, edition.ID, edition.BookID)
+		if isUniqueViolation(err) {
+			return fmt.Errorf("edition with ISBN %s of book %d %w", *edition.ISBN, edition.BookID, ErrDuplicate)
+		}
+		return editionChanged(res, err, edition.BookID, edition.ID)
+	})
+}
+
+// DeleteEdition deletes an edition of a book. Returns ErrNotFound if either does not exist.
+func (s *SQLiteBookStore) DeleteEdition(ctx context.Context, bookID, editionID int64) error {
+	defer metrics.ObserveDBQuery("DeleteEdition", time.Now())
+	return s.changeBook(ctx, "DeleteEdition", bookID, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `DELETE FROM editions WHERE id = ? AND book_id = ?;`, editionID, bookID)
+		return editionChanged(res, err, bookID, editionID)
+	})
+}
+
+// editionChanged returns ErrNotFound if an update or delete of an edition matched no row.
+func editionChanged(res sql.Result, err error, bookID, editionID int64) error {
+	if err != nil {
+		return fmt.Errorf("failed to change edition: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("edition %d of book %d: %w", editionID, bookID, ErrNotFound)
+	}
+	return nil
+}
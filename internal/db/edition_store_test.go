@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestEditionStore(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	cover := "https://covers.example/dune.jpg"
+	bookID, err := store.AddBook(ctx, &model.Book{Title: "Dune", Author: "Frank Herbert", OpenLibraryID: "OL-Dune", ISBN: "9780441172719", CoverURL: &cover, Status: model.StatusRead})
+	if err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+
+	// The copy added is the first edition
+	editions, err := store.GetEditions(ctx, bookID)
+	if err != nil || len(editions) != 1 {
+		t.Fatalf("Expected 1 edition, got %d, %v", len(editions), err)
+	}
+	if e := editions[0]; e.Format != model.TypeBook || e.ISBN == nil || *e.ISBN != "9780441172719" || e.CoverURL == nil || *e.CoverURL != cover {
+		t.Errorf("Expected the book's type, ISBN and cover, got %+v", e)
+	}
+
+	isbn := "978-0-593-09932-2"
+	audiobook := &model.Edition{BookID: bookID, Format: model.TypeAudiobook, ISBN: &isbn, Duration: ptrTo(1263)}
+	if err := store.AddEdition(ctx, audiobook); err != nil {
+		t.Fatalf("AddEdition failed: %v", err)
+	}
+	if audiobook.ID == 0 || *audiobook.ISBN != "9780593099322" {
+		t.Errorf("Expected the ID set and the ISBN normalized, got %+v", audiobook)
+	}
+	duplicate := &model.Edition{BookID: bookID, Format: model.TypeEbook, ISBN: &isbn}
+	if err := store.AddEdition(ctx, duplicate); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Expected ErrDuplicate adding an ISBN twice, got %v", err)
+	}
+	if err := store.AddEdition(ctx, &model.Edition{BookID: 999, Format: model.TypeEbook}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a missing book, got %v", err)
+	}
+
+	// Editions are part of the book: changing them is a new version, and audited
+	book, err := store.GetBookByID(ctx, bookID)
+	if err != nil {
+		t.Fatalf("GetBookByID failed: %v", err)
+	}
+	if len(book.Editions) != 2 || book.Editions[1].ID != audiobook.ID || book.Version != 2 {
+		t.Errorf("Expected the new edition and version 2, got %+v", book)
+	}
+	page, err := store.GetAuditLog(ctx, AuditFilter{BookID: bookID, Limit: 50})
+	if err != nil || len(page.Entries) != 2 {
+		t.Errorf("Expected the edition audited, got %+v, %v", page, err)
+	}
+
+	publisher := "Penguin Audio"
+	audiobook.Publisher = &publisher
+	if err := store.UpdateEdition(ctx, audiobook); err != nil {
+		t.Fatalf("UpdateEdition failed: %v", err)
+	}
+	if err := store.UpdateEdition(ctx, &model.Edition{ID: audiobook.ID, BookID: bookID + 1, Format: model.TypeBook}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound updating the edition of another book, got %v", err)
+	}
+	editions, _ = store.GetEditions(ctx, bookID)
+	if len(editions) != 2 || editions[1].Publisher == nil || *editions[1].Publisher != publisher {
+		t.Errorf("Expected the publisher updated, got %+v", editions)
+	}
+
+	if err := store.DeleteEdition(ctx, bookID, editions[0].ID); err != nil {
+		t.Fatalf("DeleteEdition failed: %v", err)
+	}
+	if err := store.DeleteEdition(ctx, bookID, editions[0].ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+	}
+
+	// Editions of books in the trash are hidden with them
+	if err := store.DeleteBook(ctx, bookID); err != nil {
+		t.Fatalf("DeleteBook failed: %v", err)
+	}
+	if _, err := store.GetEditions(ctx, bookID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a book in the trash, got %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Sentinel errors returned (wrapped) by the stores. Use errors.Is to check for them.
+var (
+	// ErrNotFound means the requested record does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate means the record would violate a uniqueness constraint (e.g. the same Open Library ID twice).
+	ErrDuplicate = errors.New("already exists")
+	// ErrConflict means the record was modified since the version the caller based its change on.
+	ErrConflict = errors.New("version conflict")
+)
+
+// isUniqueViolation reports whether err is an SQLite UNIQUE or PRIMARY KEY constraint failure.
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique ||
+		sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+}
+
+type expectedVersionKey struct{}
+
+// WithExpectedVersion returns a copy of ctx carrying the book version the caller expects
+// to modify (typically from an If-Match header). Mutations made with this context fail
+// with ErrConflict if the stored version differs.
+func WithExpectedVersion(ctx context.Context, version int64) context.Context {
+	return context.WithValue(ctx, expectedVersionKey{}, version)
+}
+
+// expectedVersion returns the version set by WithExpectedVersion, or nil if there is none.
+// The nil interface maps to SQL NULL, which the update queries treat as "any version".
+func expectedVersion(ctx context.Context) interface{} {
+	if version, ok := ctx.Value(expectedVersionKey{}).(int64); ok {
+		return version
+	}
+	return nil
+}
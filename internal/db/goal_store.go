@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/metrics"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// GoalStore defines the interface for database operations on yearly reading goals.
+type GoalStore interface {
+	SetGoal(ctx context.Context, goal *model.Goal) error
+	GetGoals(ctx context.Context, year int) ([]model.Goal, error)
+	DeleteGoal(ctx context.Context, year int, bookType model.BookType) error
+	CountFinished(ctx context.Context, year int, bookType model.BookType) (int, error)
+}
+
+// SetGoal creates the goal for its year and type, or replaces the target of an existing one.
+// It sets the goal's ID.
+func (s *SQLiteBookStore) SetGoal(ctx context.Context, goal *model.Goal) error {
+	defer metrics.ObserveDBQuery("SetGoal", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	if err := goal.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	logger.Info("SQL: Executing SetGoal query", "year", goal.Year, "type", goal.Type, "target", goal.Target)
+	err := s.DB.QueryRowContext(ctx, `
+        INSERT INTO goals (year, book_type, target) VALUES (?, ?, ?)
+        ON CONFLICT(year, book_type) DO UPDATE SET target = excluded.target
+        RETURNING id;`, goal.Year, goal.Type, goal.Target).Scan(&goal.ID)
+	if err != nil {
+		logger.Error("SQL Error: Executing SetGoal statement failed", "error", err)
+		return fmt.Errorf("failed to save goal: %w", err)
+	}
+	return nil
+}
+
+// GetGoals retrieves goals ordered by year and type. A year of 0 returns every year.
+func (s *SQLiteBookStore) GetGoals(ctx context.Context, year int) ([]model.Goal, error) {
+	defer metrics.ObserveDBQuery("GetGoals", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	logger.Info("SQL: Executing GetGoals query", "year", year)
+	rows, err := s.DB.QueryContext(ctx, `
+        SELECT id, year, book_type, target FROM goals
+        WHERE ? = 0 OR year = ?
+        ORDER BY year, book_type;`, year, year)
+	if err != nil {
+		logger.Error("SQL Error: Executing GetGoals query failed", "error", err)
+		return nil, fmt.Errorf("failed to query goals: %w", err)
+	}
+	defer rows.Close()
+
+	goals := []model.Goal{}
+	for rows.Next() {
+		var goal model.Goal
+		if err := rows.Scan(&goal.ID, &goal.Year, &goal.Type, &goal.Target); err != nil {
+			logger.Error("SQL Error: Scanning goal row failed", "error", err)
+			return nil, fmt.Errorf("failed to scan goal row: %w", err)
+		}
+		goals = append(goals, goal)
+	}
+	if err = rows.Err(); err != nil {
+		logger.Error("SQL Error: Error during goal iteration", "error", err)
+		return nil, fmt.Errorf("error iterating goal rows: %w", err)
+	}
+	return goals, nil
+}
+
+// DeleteGoal removes the goal for a year and type (empty for the all-types goal).
+func (s *SQLiteBookStore) DeleteGoal(ctx context.Context, year int, bookType model.BookType) error {
+	defer metrics.ObserveDBQuery("DeleteGoal", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	logger.Info("SQL: Executing DeleteGoal query", "year", year, "type", bookType)
+	res, err := s.DB.ExecContext(ctx, `DELETE FROM goals WHERE year = ? AND book_type = ?;`, year, bookType)
+	if err != nil {
+		logger.Error("SQL Error: Executing DeleteGoal statement failed", "error", err)
+		return fmt.Errorf("failed to delete goal: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("goal for %d: %w", year, ErrNotFound)
+	}
+	return nil
+}
+
+// CountFinished counts the finishes in year (see finishedCTE), of books of bookType (or of
+// every type if empty). These are the finishes counted per year by GetStats.
+func (s *SQLiteBookStore) CountFinished(ctx context.Context, year int, bookType model.BookType) (int, error) {
+	defer metrics.ObserveDBQuery("CountFinished", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	var count int
+	err := s.DB.QueryRowContext(ctx, finishedCTE+`
+        SELECT COUNT(*) FROM finished
+        WHERE CAST(strftime('%Y', finished_at) AS INTEGER) = ? AND (? = '' OR book_type = ?);`,
+		year, bookType, bookType).Scan(&count)
+	if err != nil {
+		logger.Error("SQL Error: Counting finished books failed", "year", year, "type", bookType, "error", err)
+		return 0, fmt.Errorf("failed to count finished books: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestGoalStore(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	overall := &model.Goal{Year: 2025, Target: 30}
+	if err := store.SetGoal(ctx, overall); err != nil {
+		t.Fatalf("SetGoal failed: %v", err)
+	}
+	audio := &model.Goal{Year: 2025, Type: model.TypeAudiobook, Target: 5}
+	if err := store.SetGoal(ctx, audio); err != nil {
+		t.Fatalf("SetGoal for audiobooks failed: %v", err)
+	}
+	// Setting again replaces the target rather than adding a goal
+	updated := &model.Goal{Year: 2025, Target: 40}
+	if err := store.SetGoal(ctx, updated); err != nil {
+		t.Fatalf("SetGoal update failed: %v", err)
+	}
+	if updated.ID != overall.ID {
+		t.Errorf("Expected update to keep ID %d, got %d", overall.ID, updated.ID)
+	}
+
+	goals, err := store.GetGoals(ctx, 2025)
+	if err != nil {
+		t.Fatalf("GetGoals failed: %v", err)
+	}
+	if len(goals) != 2 || goals[0].Type != "" || goals[0].Target != 40 || goals[1].Type != model.TypeAudiobook {
+		t.Errorf("Unexpected goals: %+v", goals)
+	}
+	if other, _ := store.GetGoals(ctx, 2024); len(other) != 0 {
+		t.Errorf("Expected no goals for 2024, got %+v", other)
+	}
+
+	// Finished books are counted by the year of their moves to Read
+	finish := func(title string, bookType model.BookType, at string) int64 {
+		id, err := store.AddBook(ctx, &model.Book{Title: title, Author: "A", OpenLibraryID: "OL-" + title, Status: model.StatusRead, Type: bookType})
+		if err != nil {
+			t.Fatalf("Failed to add book: %v", err)
+		}
+		if _, err := db.Exec(`UPDATE book_status_history SET changed_at = ? WHERE book_id = ?;`, at, id); err != nil {
+			t.Fatalf("Failed to backdate history: %v", err)
+		}
+		return id
+	}
+	one := finish("one", model.TypeBook, "2025-02-01 10:00:00")
+	finish("two", model.TypeAudiobook, "2025-11-30 23:00:00")
+	three := finish("three", model.TypeBook, "2024-12-31 23:59:59")
+
+	// Moving a book off Read, or reading it again, keeps its earlier finishes
+	for _, move := range []struct {
+		id     int64
+		status model.BookStatus
+	}{{one, model.StatusCurrentlyReading}, {three, model.StatusCurrentlyReading}, {three, model.StatusRead}} {
+		if err := store.UpdateBookStatus(ctx, move.id, move.status); err != nil {
+			t.Fatalf("Failed to update status: %v", err)
+		}
+	}
+	if n, err := store.CountFinished(ctx, 2024, ""); err != nil || n != 1 {
+		t.Errorf("Expected the first reading of three in 2024, got %d (%v)", n, err)
+	}
+	if n, err := store.CountFinished(ctx, time.Now().Year(), model.TypeBook); err != nil || n != 1 {
+		t.Errorf("Expected the second reading of three this year, got %d (%v)", n, err)
+	}
+
+	if n, err := store.CountFinished(ctx, 2025, ""); err != nil || n != 2 {
+		t.Errorf("Expected 2 books finished in 2025, got %d (%v)", n, err)
+	}
+	if n, err := store.CountFinished(ctx, 2025, model.TypeAudiobook); err != nil || n != 1 {
+		t.Errorf("Expected 1 audiobook finished in 2025, got %d (%v)", n, err)
+	}
+	if n, _ := store.CountFinished(ctx, time.Now().Year()+1, ""); n != 0 {
+		t.Errorf("Expected nothing finished next year, got %d", n)
+	}
+
+	// The statistics count the same finishes per year
+	stats, err := store.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	for _, pc := range stats.FinishedPerYear {
+		year, _ := strconv.Atoi(pc.Period)
+		if n, _ := store.CountFinished(ctx, year, ""); n != pc.Count {
+			t.Errorf("Expected goal progress for %s to match the %d finishes in the statistics, got %d", pc.Period, pc.Count, n)
+		}
+	}
+
+	if err := store.DeleteGoal(ctx, 2025, model.TypeAudiobook); err != nil {
+		t.Fatalf("DeleteGoal failed: %v", err)
+	}
+	if err := store.DeleteGoal(ctx, 2025, model.TypeAudiobook); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+	}
+	if err := store.SetGoal(ctx, &model.Goal{Year: 2025, Target: 0}); err == nil {
+		t.Error("Expected validation error for zero target")
+	}
+}
@@ -0,0 +1,322 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/metrics"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// LoanStore defines the interface for books lent to and borrowed from other people. Loans of
+// books in the trash are hidden along with their book, and purged with it.
+type LoanStore interface {
+	GetLoans(ctx context.Context, filter LoanFilter) ([]model.Loan, error)
+	GetLoan(ctx context.Context, id int64) (*model.Loan, error)
+	AddLoan(ctx context.Context, loan *model.Loan) error
+	UpdateLoan(ctx context.Context, loan *model.Loan) error
+	ReturnLoan(ctx context.Context, id int64, returnedAt time.Time) (*model.Loan, error)
+	DeleteLoan(ctx context.Context, id int64) error
+}
+
+// LoanFilter selects loans for GetLoans. Zero fields match every loan.
+type LoanFilter struct {
+	BookID    int64
+	Person    string // Matched ignoring case
+	Direction model.LoanDirection
+	Open      bool // Only loans not returned yet
+	Overdue   bool // Only open loans past their due date, soonest due first
+}
+
+// loanColumns lists the columns read by scanLoan, from loans l joined with books b.
+const loanColumns = `l.id, l.book_id, l.person, l.direction, l.date_out, l.due_date, l.returned_at, b.title, b.author`
+
+// scanLoan reads a loan selected with loanColumns and works out whether it is overdue.
+func scanLoan(row rowScanner, now time.Time) (*model.Loan, error) {
+	var loan model.Loan
+	var dueDate, returnedAt sql.NullTime
+	err := row.Scan(&loan.ID, &loan.BookID, &loan.Person, &loan.Direction, &loan.DateOut, &dueDate, &returnedAt,
+		&loan.BookTitle, &loan.BookAuthor)
+	if err != nil {
+		return nil, err
+	}
+	if dueDate.Valid {
+		loan.DueDate = &dueDate.Time
+	}
+	if returnedAt.Valid {
+		loan.ReturnedAt = &returnedAt.Time
+	}
+	loan.Overdue = loan.IsOverdue(now)
+	return &loan, nil
+}
+
+// readLoan reads a loan through q, returning ErrNotFound if it does not exist or its book is
+// in the trash.
+func readLoan(ctx context.Context, q dbtx, id int64) (*model.Loan, error) {
+	loan, err := scanLoan(q.QueryRowContext(ctx, `
+        SELECT `+loanColumns+` FROM loans l JOIN books b ON b.id = l.book_id
+        WHERE l.id = ? AND b.deleted_at IS NULL;`, id), time.Now())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("loan with ID %d: %w", id, ErrNotFound)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read loan %d: %w", id, err)
+	}
+	return loan, nil
+}
+
+// loadLent sets Lent on each book that is lent out, with a single query.
+func (s *SQLiteBookStore) loadLent(ctx context.Context, books []model.Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+	index := make(map[int64]int, len(books))
+	placeholders := make([]string, len(books))
+	args := make([]interface{}, len(books))
+	for i, book := range books {
+		index[book.ID] = i
+		placeholders[i] = "?"
+		args[i] = book.ID
+	}
+
+	return s.queryRows(ctx, `
+        SELECT book_id FROM loans
+        WHERE returned_at IS NULL AND direction = 'lent' AND book_id IN (`+strings.Join(placeholders, ", ")+`);`,
+		args, func(rows *sql.Rows) error {
+			var bookID int64
+			if err := rows.Scan(&bookID); err != nil {
+				return err
+			}
+			books[index[bookID]].Lent = true
+			return nil
+		})
+}
+
+// GetLoans retrieves the loans matching filter, most recent first (overdue loans soonest due
+// first). Returns ErrNotFound if filter.BookID is set and the book does not exist.
+func (s *SQLiteBookStore) GetLoans(ctx context.Context, filter LoanFilter) ([]model.Loan, error) {
+	defer metrics.ObserveDBQuery("GetLoans", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	now := time.Now()
+	where := []string{"b.deleted_at IS NULL"}
+	var args []interface{}
+	if filter.BookID != 0 {
+		if err := bookExists(ctx, s.DB, filter.BookID); err != nil {
+			return nil, err
+		}
+		where = append(where, "l.book_id = ?")
+		args = append(args, filter.BookID)
+	}
+	if filter.Person != "" {
+		where = append(where, "l.person = ? COLLATE NOCASE")
+		args = append(args, filter.Person)
+	}
+	if filter.Direction != "" {
+		where = append(where, "l.direction = ?")
+		args = append(args, filter.Direction)
+	}
+	if filter.Open || filter.Overdue {
+		where = append(where, "l.returned_at IS NULL")
+	}
+	order := "l.date_out DESC, l.id DESC"
+	if filter.Overdue {
+		where = append(where, "l.due_date < ?")
+		args = append(args, sqlTimestamp(ptrTo(model.Today(now))))
+		order = "l.due_date, l.id"
+	}
+
+	logger.Info("SQL: Executing GetLoans query", "filter", fmt.Sprintf("%+v", filter))
+	loans := []model.Loan{}
+	err := s.queryRows(ctx, `
+        SELECT `+loanColumns+` FROM loans l JOIN books b ON b.id = l.book_id
+        WHERE `+strings.Join(where, " AND ")+`
+        ORDER BY `+order+`;`, args, func(rows *sql.Rows) error {
+		loan, err := scanLoan(rows, now)
+		if err != nil {
+			return err
+		}
+		loans = append(loans, *loan)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query loans: %w", err)
+	}
+	return loans, nil
+}
+
+// GetLoan retrieves a loan by ID. Returns ErrNotFound if it does not exist.
+func (s *SQLiteBookStore) GetLoan(ctx context.Context, id int64) (*model.Loan, error) {
+	defer metrics.ObserveDBQuery("GetLoan", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	logger.Info("SQL: Executing GetLoan query", "id", id)
+	loan, err := readLoan(ctx, s.DB, id)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		logger.Error("SQL Error: Querying loan failed", "error", err)
+	}
+	return loan, err
+}
+
+// AddLoan records a loan of the book loan.BookID, dated today unless DateOut is set, and
+// fills in the loan as stored. Returns ErrNotFound if the book does not exist, or
+// ErrDuplicate if it is already lent or borrowed and not returned. Lending a book changes
+// its Lent flag, so the book gets a new version.
+func (s *SQLiteBookStore) AddLoan(ctx context.Context, loan *model.Loan) error {
+	defer metrics.ObserveDBQuery("AddLoan", time.Now())
+	if loan.DateOut.IsZero() {
+		loan.DateOut = model.Today(time.Now())
+	}
+	if err := loan.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	var stored *model.Loan
+	err := s.changeBook(ctx, "AddLoan", loan.BookID, func(tx *sql.Tx) error {
+		var id int64
+		err := tx.QueryRowContext(ctx, `
+            INSERT INTO loans (book_id, person, direction, date_out, due_date, returned_at) VALUES (?, ?, ?, ?, ?, ?)
+            RETURNING id;`,
+			loan.BookID, loan.Person, loan.Direction, sqlTimestamp(&loan.DateOut), sqlTimestamp(loan.DueDate),
+			sqlTimestamp(loan.ReturnedAt)).Scan(&id)
+		if isUniqueViolation(err) {
+			return fmt.Errorf("an open loan of book %d %w; return it first", loan.BookID, ErrDuplicate)
+		} else if err != nil {
+			return fmt.Errorf("failed to add loan: %w", err)
+		}
+		stored, err = readLoan(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	*loan = *stored
+	return nil
+}
+
+// UpdateLoan replaces the person, direction and dates of loan.ID, keeping its date out if
+// DateOut is zero, and fills in the loan as stored. The book of a loan cannot change.
+// Returns ErrNotFound if the loan does not exist, or ErrDuplicate if reopening it would
+// make two open loans of its book.
+func (s *SQLiteBookStore) UpdateLoan(ctx context.Context, loan *model.Loan) error {
+	defer metrics.ObserveDBQuery("UpdateLoan", time.Now())
+	if err := loan.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+	bookID, err := s.loanBookID(ctx, loan.ID)
+	if err != nil {
+		return err
+	}
+
+	var stored *model.Loan
+	err = s.changeBook(ctx, "UpdateLoan", bookID, func(tx *sql.Tx) error {
+		existing, err := readLoan(ctx, tx, loan.ID)
+		if err != nil {
+			return err
+		}
+		if loan.DateOut.IsZero() {
+			// Now the dates can be checked against the stored date out
+			loan.DateOut = existing.DateOut
+			if err := loan.Validate(); err != nil {
+				return fmt.Errorf("validation failed: %w", err)
+			}
+		}
+		_, err = tx.ExecContext(ctx, `
+            UPDATE loans SET person = ?, direction = ?, date_out = ?, due_date = ?, returned_at = ? WHERE id = ?;`,
+			loan.Person, loan.Direction, sqlTimestamp(&loan.DateOut), sqlTimestamp(loan.DueDate), sqlTimestamp(loan.ReturnedAt), loan.ID)
+		if isUniqueViolation(err) {
+			return fmt.Errorf("an open loan of book %d %w; return it first", existing.BookID, ErrDuplicate)
+		} else if err != nil {
+			return fmt.Errorf("failed to update loan: %w", err)
+		}
+		stored, err = readLoan(ctx, tx, loan.ID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	*loan = *stored
+	return nil
+}
+
+// ReturnLoan records that the book of loan id came back on returnedAt and returns the loan.
+// Returns ErrNotFound if the loan does not exist, or a validation error if it was already
+// returned or returnedAt is before it went out.
+func (s *SQLiteBookStore) ReturnLoan(ctx context.Context, id int64, returnedAt time.Time) (*model.Loan, error) {
+	defer metrics.ObserveDBQuery("ReturnLoan", time.Now())
+	bookID, err := s.loanBookID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var loan *model.Loan
+	err = s.changeBook(ctx, "ReturnLoan", bookID, func(tx *sql.Tx) error {
+		existing, err := readLoan(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if existing.ReturnedAt != nil {
+			return fmt.Errorf("validation failed: %w", &model.ValidationError{Message: fmt.Sprintf("loan %d was already returned", id)})
+		}
+		existing.ReturnedAt = &returnedAt
+		if err := existing.Validate(); err != nil {
+			return fmt.Errorf("validation failed: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE loans SET returned_at = ? WHERE id = ?;`, sqlTimestamp(&returnedAt), id); err != nil {
+			return fmt.Errorf("failed to return loan: %w", err)
+		}
+		loan, err = readLoan(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return loan, nil
+}
+
+// DeleteLoan deletes a loan, e.g. one recorded by mistake. Returns ErrNotFound if it does not
+// exist.
+func (s *SQLiteBookStore) DeleteLoan(ctx context.Context, id int64) error {
+	defer metrics.ObserveDBQuery("DeleteLoan", time.Now())
+	bookID, err := s.loanBookID(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.changeBook(ctx, "DeleteLoan", bookID, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `DELETE FROM loans WHERE id = ?;`, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete loan: %w", err)
+		}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rowsAffected == 0 {
+			return fmt.Errorf("loan with ID %d: %w", id, ErrNotFound)
+		}
+		return nil
+	})
+}
+
+// loanBookID returns the book of loan id, so its change can be made as a new version of the
+// book. Returns ErrNotFound if the loan does not exist or its book is in the trash.
+func (s *SQLiteBookStore) loanBookID(ctx context.Context, id int64) (int64, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	loan, err := readLoan(ctx, s.DB, id)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			logging.FromContext(ctx).Error("SQL Error: Querying loan failed", "id", id, "error", err)
+		}
+		return 0, err
+	}
+	return loan.BookID, nil
+}
@@ -0,0 +1,193 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/events"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestLoanStore(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	add := func(title string) int64 {
+		id, err := store.AddBook(ctx, &model.Book{Title: title, Author: "A", OpenLibraryID: "OL-" + title, Status: model.StatusRead})
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", title, err)
+		}
+		return id
+	}
+	dune, emma := add("Dune"), add("Emma")
+
+	today := model.Today(time.Now())
+	lastMonth, yesterday, nextWeek := today.AddDate(0, -1, 0), today.AddDate(0, 0, -1), today.AddDate(0, 0, 7)
+	lent := &model.Loan{BookID: dune, Person: " Alice ", Direction: model.LoanLent, DateOut: lastMonth, DueDate: &yesterday}
+	if err := store.AddLoan(ctx, lent); err != nil {
+		t.Fatalf("AddLoan failed: %v", err)
+	}
+	if lent.ID == 0 || lent.Person != "Alice" || !lent.Overdue || lent.BookTitle != "Dune" {
+		t.Errorf("Expected the stored loan, overdue, with its book, got %+v", lent)
+	}
+	borrowed := &model.Loan{BookID: emma, Person: "Bob", Direction: model.LoanBorrowed, DueDate: &nextWeek}
+	if err := store.AddLoan(ctx, borrowed); err != nil {
+		t.Fatalf("AddLoan failed: %v", err)
+	}
+	if !borrowed.DateOut.Equal(today) || borrowed.Overdue {
+		t.Errorf("Expected the loan dated today and not overdue, got %+v", borrowed)
+	}
+
+	// One open loan per book
+	if err := store.AddLoan(ctx, &model.Loan{BookID: dune, Person: "Carol", Direction: model.LoanLent}); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Expected ErrDuplicate lending a lent book, got %v", err)
+	}
+	if err := store.AddLoan(ctx, &model.Loan{BookID: 999, Person: "Carol", Direction: model.LoanLent}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a missing book, got %v", err)
+	}
+
+	// Books lent out are flagged; borrowed ones are not
+	books, err := store.ListBooks(ctx, ListOptions{})
+	if err != nil {
+		t.Fatalf("ListBooks failed: %v", err)
+	}
+	for _, book := range books {
+		if book.Lent != (book.ID == dune) {
+			t.Errorf("Expected only Dune lent, got %s lent=%v", book.Title, book.Lent)
+		}
+	}
+
+	overdue, err := store.GetLoans(ctx, LoanFilter{Overdue: true})
+	if err != nil || len(overdue) != 1 || overdue[0].ID != lent.ID {
+		t.Errorf("Expected the loan to Alice overdue, got %+v, %v", overdue, err)
+	}
+	if loans, _ := store.GetLoans(ctx, LoanFilter{Person: "bob"}); len(loans) != 1 || loans[0].ID != borrowed.ID {
+		t.Errorf("Expected the person matched ignoring case, got %+v", loans)
+	}
+	if loans, _ := store.GetLoans(ctx, LoanFilter{Direction: model.LoanBorrowed}); len(loans) != 1 {
+		t.Errorf("Expected 1 borrowed loan, got %+v", loans)
+	}
+
+	// Returning a loan
+	if _, err := store.ReturnLoan(ctx, lent.ID, lastMonth.AddDate(0, 0, -1)); err == nil {
+		t.Error("Expected an error returning a book before it went out")
+	}
+	returned, err := store.ReturnLoan(ctx, lent.ID, today)
+	if err != nil {
+		t.Fatalf("ReturnLoan failed: %v", err)
+	}
+	if returned.ReturnedAt == nil || !returned.ReturnedAt.Equal(today) || returned.Overdue {
+		t.Errorf("Expected the loan returned today and no longer overdue, got %+v", returned)
+	}
+	var validationErr *model.ValidationError
+	if _, err := store.ReturnLoan(ctx, lent.ID, today); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a validation error returning twice, got %v", err)
+	}
+	if book, _ := store.GetBookByID(ctx, dune); book.Lent {
+		t.Error("Expected the returned book no longer lent")
+	}
+	if open, _ := store.GetLoans(ctx, LoanFilter{Open: true}); len(open) != 1 || open[0].ID != borrowed.ID {
+		t.Errorf("Expected only the borrowed book open, got %+v", open)
+	}
+
+	// Updating keeps the date out unless given, and checks dates against it
+	update := &model.Loan{ID: borrowed.ID, Person: "Bob Smith", Direction: model.LoanBorrowed, DueDate: &yesterday}
+	if err := store.UpdateLoan(ctx, update); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a validation error for a due date before the date out, got %v", err)
+	}
+	update = &model.Loan{ID: borrowed.ID, Person: "Bob Smith", Direction: model.LoanBorrowed}
+	if err := store.UpdateLoan(ctx, update); err != nil {
+		t.Fatalf("UpdateLoan failed: %v", err)
+	}
+	if update.Person != "Bob Smith" || !update.DateOut.Equal(today) || update.DueDate != nil || update.BookID != emma {
+		t.Errorf("Expected the loan replaced with its date out kept, got %+v", update)
+	}
+
+	// A returned loan can't be reopened while the book is on loan again
+	if err := store.AddLoan(ctx, &model.Loan{BookID: dune, Person: "Carol", Direction: model.LoanLent}); err != nil {
+		t.Fatalf("AddLoan failed: %v", err)
+	}
+	reopen := &model.Loan{ID: lent.ID, Person: "Alice", Direction: model.LoanLent}
+	if err := store.UpdateLoan(ctx, reopen); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Expected ErrDuplicate reopening a loan, got %v", err)
+	}
+	if history, err := store.GetLoans(ctx, LoanFilter{BookID: dune}); err != nil || len(history) != 2 || history[0].Person != "Carol" {
+		t.Errorf("Expected the book's loans, most recent first, got %+v, %v", history, err)
+	}
+
+	if err := store.DeleteLoan(ctx, lent.ID); err != nil {
+		t.Fatalf("DeleteLoan failed: %v", err)
+	}
+	if _, err := store.GetLoan(ctx, lent.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after deleting, got %v", err)
+	}
+
+	// Loans of books in the trash are hidden with them
+	if err := store.DeleteBook(ctx, emma); err != nil {
+		t.Fatalf("DeleteBook failed: %v", err)
+	}
+	if _, err := store.GetLoans(ctx, LoanFilter{BookID: emma}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a book in the trash, got %v", err)
+	}
+	if err := store.DeleteLoan(ctx, borrowed.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting a loan of a book in the trash, got %v", err)
+	}
+}
+
+func TestLoanEvents(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	id, err := store.AddBook(ctx, createTestBook())
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	store.Events = events.NewBus(10)
+	ch, _, _, cancel := store.Events.Subscribe(0)
+	defer cancel()
+
+	loan := &model.Loan{BookID: id, Person: "Alice", Direction: model.LoanLent}
+	if err := store.AddLoan(ctx, loan); err != nil {
+		t.Fatalf("AddLoan failed: %v", err)
+	}
+	lent := <-ch
+	if lent.Type != events.BookUpdated || lent.Previous == nil || lent.Previous.Lent || lent.Book == nil || !lent.Book.Lent ||
+		lent.Book.Version != lent.Previous.Version+1 {
+		t.Fatalf("Expected the book to be published as lent in a new version, got %+v", lent)
+	}
+	if _, err := store.ReturnLoan(ctx, loan.ID, time.Now()); err != nil {
+		t.Fatalf("ReturnLoan failed: %v", err)
+	}
+	if returned := <-ch; returned.Book == nil || returned.Book.Lent {
+		t.Errorf("Expected the book to be published as returned, got %+v", returned)
+	}
+	// A failed change publishes nothing
+	if _, err := store.ReturnLoan(ctx, loan.ID, time.Now()); err == nil {
+		t.Fatal("Expected an error returning a loan twice")
+	}
+	if err := store.DeleteLoan(ctx, loan.ID); err != nil {
+		t.Fatalf("DeleteLoan failed: %v", err)
+	}
+	if deleted := <-ch; deleted.Type != events.BookUpdated || deleted.BookID != id {
+		t.Errorf("Expected an update of the book for the deleted loan, got %+v", deleted)
+	}
+
+	page, err := store.GetAuditLog(ctx, AuditFilter{BookID: id, Limit: 50})
+	if err != nil {
+		t.Fatalf("GetAuditLog failed: %v", err)
+	}
+	var lentChanges int
+	for _, entry := range page.Entries {
+		if entry.Action == model.AuditUpdate && strings.Contains(string(entry.After), `"lent"`) {
+			lentChanges++
+		}
+	}
+	if lentChanges != 2 {
+		t.Errorf("Expected the lent flag to change twice in the audit log, got %d in %+v", lentChanges, page.Entries)
+	}
+}
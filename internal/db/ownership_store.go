@@ -0,0 +1,177 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/events"
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/metrics"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// OwnershipStore defines the interface for the ownership of books and the inventory of the
+// collection.
+type OwnershipStore interface {
+	SetOwnership(ctx context.Context, id int64, ownership *model.Ownership) error
+	GetInventory(ctx context.Context, filter InventoryFilter) (*model.InventoryReport, error)
+}
+
+// InventoryFilter selects the books counted by GetInventory. Zero values apply no filter.
+type InventoryFilter struct {
+	Ownership model.OwnershipStatus
+	Type      model.BookType
+}
+
+// ownershipArgs returns the values of the ownership columns of books, in the order ownership,
+// purchase_date, price, currency, store, location_room, location_shelf. All are NULL if
+// ownership is nil.
+func ownershipArgs(ownership *model.Ownership) []interface{} {
+	if ownership == nil {
+		return make([]interface{}, 7)
+	}
+	return []interface{}{ownership.Status, sqlTimestamp(ownership.PurchaseDate), ownership.Price, ownership.Currency,
+		ownership.Store, ownership.Room, ownership.Shelf}
+}
+
+// SetOwnership replaces the ownership of a book, or clears it if ownership is nil. It is a new
+// version of the book, recorded in the audit log and published. Returns ErrNotFound if the
+// book does not exist or is in the trash.
+func (s *SQLiteBookStore) SetOwnership(ctx context.Context, id int64, ownership *model.Ownership) error {
+	defer metrics.ObserveDBQuery("SetOwnership", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	if ownership != nil {
+		if err := ownership.Validate(); err != nil {
+			return fmt.Errorf("validation failed: %w", err)
+		}
+	}
+
+	logger.Info("SQL: Executing SetOwnership", "id", id, "ownership", ownership)
+	previous := s.snapshot(ctx, id)
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := readBook(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	args := append(ownershipArgs(ownership), id, expectedVersion(ctx), expectedVersion(ctx))
+	res, err := tx.ExecContext(ctx, `
+        UPDATE books SET ownership = ?, purchase_date = ?, price = ?, currency = ?, store = ?,
+            location_room = ?, location_shelf = ?, version = version + 1
+        WHERE id = ? AND deleted_at IS NULL AND (? IS NULL OR version = ?);`, args...)
+	if err != nil {
+		logger.Error("SQL Error: Executing SetOwnership failed", "error", err)
+		return fmt.Errorf("failed to set ownership: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		logger.Info("SQL: No book found to set ownership", "id", id)
+		return s.notFoundOrConflict(ctx, tx, id)
+	}
+	if err := recordAudit(ctx, tx, model.AuditUpdate, id, before); err != nil {
+		logger.Error("SQL Error: Recording audit entry failed", "error", err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("SQL Error: Committing SetOwnership transaction failed", "error", err)
+		return fmt.Errorf("failed to commit ownership change: %w", err)
+	}
+
+	logger.Info("SQL: Successfully set ownership", "id", id)
+	s.publish(events.BookUpdated, id, previous, s.snapshot(ctx, id))
+	return nil
+}
+
+// GetInventory counts the books matching filter, and totals what was paid for them, for the
+// whole collection and for each room and shelf. Rooms and shelves are grouped ignoring case.
+// Books in the trash are not counted.
+func (s *SQLiteBookStore) GetInventory(ctx context.Context, filter InventoryFilter) (*model.InventoryReport, error) {
+	defer metrics.ObserveDBQuery("GetInventory", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	where := []string{"deleted_at IS NULL", "ownership IS NOT NULL"}
+	var args []interface{}
+	if filter.Ownership != "" {
+		where = append(where, "ownership = ?")
+		args = append(args, filter.Ownership)
+	}
+	if filter.Type != "" {
+		where = append(where, "type = ?")
+		args = append(args, filter.Type)
+	}
+	// Books without a price are counted apart, whatever their currency
+	query := `
+        SELECT COALESCE(location_room, ''), COALESCE(location_shelf, ''),
+               CASE WHEN price IS NULL THEN NULL ELSE currency END AS priced_currency, COUNT(*), SUM(price)
+        FROM books WHERE ` + strings.Join(where, " AND ") + `
+        GROUP BY COALESCE(location_room, '') COLLATE NOCASE, COALESCE(location_shelf, '') COLLATE NOCASE, priced_currency
+        ORDER BY COALESCE(location_room, '') COLLATE NOCASE, COALESCE(location_shelf, '') COLLATE NOCASE, priced_currency;`
+	logger.Info("SQL: Executing GetInventory query", "filter", filter)
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.Error("SQL Error: Executing GetInventory query failed", "error", err)
+		return nil, fmt.Errorf("failed to query inventory: %w", err)
+	}
+	defer rows.Close()
+
+	report := &model.InventoryReport{Value: []model.Amount{}, Locations: []model.InventoryLocation{}}
+	totals := map[string]float64{}
+	for rows.Next() {
+		var room, shelf string
+		var currency sql.NullString
+		var count int
+		var value sql.NullFloat64
+		if err := rows.Scan(&room, &shelf, &currency, &count, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan inventory row: %w", err)
+		}
+		last := len(report.Locations) - 1
+		if last < 0 || !strings.EqualFold(report.Locations[last].Room, room) || !strings.EqualFold(report.Locations[last].Shelf, shelf) {
+			report.Locations = append(report.Locations, model.InventoryLocation{Room: room, Shelf: shelf, Value: []model.Amount{}})
+			last++
+		}
+		location := &report.Locations[last]
+		location.Count += count
+		report.Count += count
+		if currency.Valid {
+			location.Value = append(location.Value, model.Amount{Currency: currency.String, Amount: roundCents(value.Float64)})
+			totals[currency.String] += value.Float64
+		} else {
+			location.Unpriced += count
+			report.Unpriced += count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("SQL Error: Error during inventory iteration", "error", err)
+		return nil, fmt.Errorf("error iterating inventory rows: %w", err)
+	}
+
+	for currency, total := range totals {
+		report.Value = append(report.Value, model.Amount{Currency: currency, Amount: roundCents(total)})
+	}
+	slices.SortFunc(report.Value, func(a, b model.Amount) int { return strings.Compare(a.Currency, b.Currency) })
+	logger.Info("SQL: Retrieved inventory", "count", report.Count, "locations", len(report.Locations))
+	return report, nil
+}
+
+// roundCents rounds a sum of prices to cents, dropping floating point error.
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestSetOwnership(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	id, err := store.AddBook(ctx, &model.Book{Title: "Dune", Author: "Frank Herbert", OpenLibraryID: "OL-Dune", Status: model.StatusRead,
+		Ownership: &model.Ownership{Status: model.OwnershipWishlist}})
+	if err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	book, err := store.GetBookByID(ctx, id)
+	if err != nil || book.Ownership == nil || book.Ownership.Status != model.OwnershipWishlist {
+		t.Fatalf("Expected the book added on the wishlist, got %+v, %v", book, err)
+	}
+
+	bought := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	ownership := &model.Ownership{Status: model.OwnershipOwned, PurchaseDate: &bought, Price: ptrTo(18.99), Currency: ptrTo("usd"),
+		Store: ptrTo("City Books"), Room: ptrTo("Study"), Shelf: ptrTo("B2")}
+	if err := store.SetOwnership(ctx, id, ownership); err != nil {
+		t.Fatalf("SetOwnership failed: %v", err)
+	}
+	book, _ = store.GetBookByID(ctx, id)
+	if o := book.Ownership; o == nil || o.Status != model.OwnershipOwned || !o.PurchaseDate.Equal(bought) || *o.Price != 18.99 ||
+		*o.Currency != "USD" || *o.Store != "City Books" || *o.Room != "Study" || *o.Shelf != "B2" {
+		t.Errorf("Expected the ownership stored, got %+v", book.Ownership)
+	}
+	if book.Version != 2 {
+		t.Errorf("Expected a new version of the book, got %d", book.Version)
+	}
+	page, err := store.GetAuditLog(ctx, AuditFilter{BookID: id, Limit: 50})
+	if err != nil || len(page.Entries) != 2 || page.Entries[0].Action != model.AuditUpdate {
+		t.Errorf("Expected the change audited, got %+v, %v", page, err)
+	}
+
+	var validationErr *model.ValidationError
+	if err := store.SetOwnership(ctx, id, &model.Ownership{Status: model.OwnershipOwned, Price: ptrTo(5.0)}); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a validation error for a price without currency, got %v", err)
+	}
+	if err := store.SetOwnership(ctx, 999, &model.Ownership{Status: model.OwnershipOwned}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a missing book, got %v", err)
+	}
+	if err := store.SetOwnership(WithExpectedVersion(ctx, 1), id, nil); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict for a stale version, got %v", err)
+	}
+
+	if err := store.SetOwnership(ctx, id, nil); err != nil {
+		t.Fatalf("Clearing ownership failed: %v", err)
+	}
+	if book, _ := store.GetBookByID(ctx, id); book.Ownership != nil {
+		t.Errorf("Expected the ownership cleared, got %+v", book.Ownership)
+	}
+}
+
+func TestOwnershipFiltersAndInventory(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	add := func(title string, bookType model.BookType, ownership *model.Ownership) int64 {
+		id, err := store.AddBook(ctx, &model.Book{Title: title, Author: "A", OpenLibraryID: "OL-" + title, Status: model.StatusRead,
+			Type: bookType, Ownership: ownership})
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", title, err)
+		}
+		return id
+	}
+	owned := func(price *float64, currency, room, shelf string) *model.Ownership {
+		o := &model.Ownership{Status: model.OwnershipOwned, Price: price}
+		if currency != "" {
+			o.Currency = &currency
+		}
+		if room != "" {
+			o.Room, o.Shelf = &room, &shelf
+		}
+		return o
+	}
+	add("Dune", model.TypeBook, owned(ptrTo(10.10), "USD", "Study", "A"))
+	add("Emma", model.TypeBook, owned(ptrTo(20.20), "USD", "study", "a"))
+	add("Ulysses", model.TypeBook, owned(ptrTo(15.0), "EUR", "Study", "A"))
+	add("Walden", model.TypeBook, owned(nil, "", "Study", "A"))
+	add("Beloved", model.TypeBook, owned(ptrTo(8.0), "USD", "Hall", "1"))
+	add("Hamlet", model.TypeEbook, owned(ptrTo(2.99), "USD", "", ""))
+	add("Ivanhoe", model.TypeBook, &model.Ownership{Status: model.OwnershipWishlist, Price: ptrTo(30.0), Currency: ptrTo("USD")})
+	add("Kim", model.TypeBook, nil)
+	trashed := add("Lolita", model.TypeBook, owned(ptrTo(100.0), "USD", "Study", "A"))
+	if err := store.DeleteBook(ctx, trashed); err != nil {
+		t.Fatalf("DeleteBook failed: %v", err)
+	}
+
+	titles := func(opts ListOptions) []string {
+		books, err := store.ListBooks(ctx, opts)
+		if err != nil {
+			t.Fatalf("ListBooks failed: %v", err)
+		}
+		var titles []string
+		for _, book := range books {
+			titles = append(titles, book.Title)
+		}
+		return titles
+	}
+	if got := titles(ListOptions{Ownership: model.OwnershipWishlist}); len(got) != 1 || got[0] != "Ivanhoe" {
+		t.Errorf("Expected the wishlist, got %v", got)
+	}
+	if got := titles(ListOptions{Room: "STUDY", Shelf: "a"}); len(got) != 4 {
+		t.Errorf("Expected the 4 books on shelf A of the study, ignoring case, got %v", got)
+	}
+	if got := titles(ListOptions{SortBy: "price", Descending: true, Ownership: model.OwnershipOwned}); len(got) != 6 || got[0] != "Emma" || got[5] != "Walden" {
+		t.Errorf("Expected owned books by price, unpriced last, got %v", got)
+	}
+
+	report, err := store.GetInventory(ctx, InventoryFilter{Ownership: model.OwnershipOwned})
+	if err != nil {
+		t.Fatalf("GetInventory failed: %v", err)
+	}
+	if report.Count != 6 || report.Unpriced != 1 || len(report.Value) != 2 ||
+		report.Value[0] != (model.Amount{Currency: "EUR", Amount: 15}) || report.Value[1] != (model.Amount{Currency: "USD", Amount: 41.29}) {
+		t.Errorf("Unexpected totals: %+v", report)
+	}
+	if len(report.Locations) != 3 {
+		t.Fatalf("Expected 3 locations, got %+v", report.Locations)
+	}
+	unshelved, hall, study := report.Locations[0], report.Locations[1], report.Locations[2]
+	if unshelved.Room != "" || unshelved.Count != 1 || unshelved.Value[0].Amount != 2.99 {
+		t.Errorf("Expected books without location first, got %+v", unshelved)
+	}
+	if hall.Room != "Hall" || hall.Count != 1 {
+		t.Errorf("Expected the hall, got %+v", hall)
+	}
+	if study.Count != 4 || study.Unpriced != 1 || len(study.Value) != 2 || study.Value[1].Amount != 30.3 {
+		t.Errorf("Expected the study shelf grouped ignoring case, got %+v", study)
+	}
+
+	report, err = store.GetInventory(ctx, InventoryFilter{Type: model.TypeEbook})
+	if err != nil || report.Count != 1 || report.Value[0].Amount != 2.99 {
+		t.Errorf("Expected only the ebook counted, got %+v, %v", report, err)
+	}
+}
@@ -0,0 +1,199 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/events"
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/metrics"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// QueueStore defines the interface for the manual order of books within a shelf.
+// The Want to Read shelf in that order is the reading queue.
+type QueueStore interface {
+	MoveBook(ctx context.Context, id, targetID int64, after bool) error
+	NextBook(ctx context.Context) (*model.Book, error)
+}
+
+// MoveBook places book id directly before (or, if after is set, directly after) book targetID.
+// Both books must be on the same shelf; the shelf is renumbered from 1. Only the moved book's
+// version changes, so the expected version from the context applies to it alone.
+func (s *SQLiteBookStore) MoveBook(ctx context.Context, id, targetID int64, after bool) error {
+	defer metrics.ObserveDBQuery("MoveBook", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	if id == targetID {
+		return fmt.Errorf("validation failed: %w", &model.ValidationError{Message: "Cannot move a book relative to itself"})
+	}
+
+	logger.Info("SQL: Executing MoveBook", "id", id, "target", targetID, "after", after)
+	previous := s.snapshot(ctx, id)
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := readBook(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	var targetStatus model.BookStatus
+	err = tx.QueryRowContext(ctx, `SELECT status FROM books WHERE id = ? AND deleted_at IS NULL;`, targetID).Scan(&targetStatus)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("book %d: %w", targetID, ErrNotFound)
+	} else if err != nil {
+		logger.Error("SQL Error: Querying target book failed", "error", err)
+		return fmt.Errorf("failed to query target book: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, `
+        UPDATE books SET version = version + 1
+        WHERE id = ? AND deleted_at IS NULL AND (? IS NULL OR version = ?);`,
+		id, expectedVersion(ctx), expectedVersion(ctx))
+	if err != nil {
+		logger.Error("SQL Error: Updating book version failed", "error", err)
+		return fmt.Errorf("failed to update book: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return s.notFoundOrConflict(ctx, tx, id)
+	}
+
+	var status model.BookStatus
+	if err := tx.QueryRowContext(ctx, `SELECT status FROM books WHERE id = ?;`, id).Scan(&status); err != nil {
+		return fmt.Errorf("failed to query book: %w", err)
+	}
+	if status != targetStatus {
+		return fmt.Errorf("validation failed: %w", &model.ValidationError{Message: "Books can only be reordered within the same shelf"})
+	}
+
+	shelf, err := shelfOrder(ctx, tx, status)
+	if err != nil {
+		logger.Error("SQL Error: Querying shelf order failed", "error", err)
+		return err
+	}
+	// Renumbering moves the other books too, so undo needs them all
+	var shelfBefore []*model.Book
+	if sessionFromContext(ctx) != "" {
+		for _, other := range shelf {
+			book, err := readBook(ctx, tx, other)
+			if err != nil {
+				return err
+			}
+			shelfBefore = append(shelfBefore, book)
+		}
+	}
+	if err := renumberShelf(ctx, tx, moveID(shelf, id, targetID, after)); err != nil {
+		logger.Error("SQL Error: Renumbering shelf failed", "error", err)
+		return err
+	}
+
+	if err := recordAudit(ctx, tx, model.AuditUpdate, id, before); err != nil {
+		logger.Error("SQL Error: Recording audit entry failed", "error", err)
+		return err
+	}
+	if err := recordUndo(ctx, tx, model.UndoMove, shelfBefore...); err != nil {
+		logger.Error("SQL Error: Recording undo step failed", "error", err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("SQL Error: Committing MoveBook transaction failed", "error", err)
+		return fmt.Errorf("failed to commit move: %w", err)
+	}
+
+	logger.Info("SQL: Successfully moved book", "id", id, "shelf", status)
+	s.publish(events.BookUpdated, id, previous, s.snapshot(ctx, id))
+	return nil
+}
+
+// NextBook returns the book at the top of the reading queue: the first Want to Read book by
+// position, unless an earlier book of its series is also waiting, in which case that one.
+func (s *SQLiteBookStore) NextBook(ctx context.Context) (*model.Book, error) {
+	books, err := s.ListBooks(ctx, ListOptions{Status: model.StatusWantToRead, SortBy: "position"})
+	if err != nil {
+		return nil, err
+	}
+	next := nextInQueue(books)
+	if next == nil {
+		return nil, fmt.Errorf("reading queue is empty: %w", ErrNotFound)
+	}
+	return next, nil
+}
+
+// nextInQueue picks the next book to read from a queue already in position order.
+func nextInQueue(queue []model.Book) *model.Book {
+	if len(queue) == 0 {
+		return nil
+	}
+	next := &queue[0]
+	if next.SeriesID == nil || next.SeriesIndex == nil {
+		return next
+	}
+	for i := range queue {
+		book := &queue[i]
+		if book.SeriesID != nil && *book.SeriesID == *next.SeriesID &&
+			book.SeriesIndex != nil && *book.SeriesIndex < *next.SeriesIndex {
+			next = book
+		}
+	}
+	return next
+}
+
+// shelfOrder lists the IDs of the books on a shelf in their current order.
+func shelfOrder(ctx context.Context, q dbtx, status model.BookStatus) ([]int64, error) {
+	rows, err := q.QueryContext(ctx, `SELECT id FROM books WHERE status = ? AND deleted_at IS NULL ORDER BY position, id;`, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shelf: %w", err)
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan shelf book: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// moveID returns order with id removed and reinserted before (or after) targetID.
+func moveID(order []int64, id, targetID int64, after bool) []int64 {
+	moved := make([]int64, 0, len(order))
+	for _, other := range order {
+		if other == id {
+			continue
+		}
+		if other == targetID && !after {
+			moved = append(moved, id)
+		}
+		moved = append(moved, other)
+		if other == targetID && after {
+			moved = append(moved, id)
+		}
+	}
+	return moved
+}
+
+// renumberShelf stores positions 1..n for the given order of book IDs.
+func renumberShelf(ctx context.Context, q dbtx, order []int64) error {
+	for i, id := range order {
+		if _, err := q.ExecContext(ctx, `UPDATE books SET position = ? WHERE id = ?;`, i+1, id); err != nil {
+			return fmt.Errorf("failed to update position: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestQueueStore(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	if _, err := store.NextBook(ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an empty queue, got %v", err)
+	}
+
+	add := func(title string, status model.BookStatus) int64 {
+		id, err := store.AddBook(ctx, &model.Book{Title: title, Author: "A", OpenLibraryID: "OL-" + title, Status: status})
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", title, err)
+		}
+		return id
+	}
+	a := add("A", model.StatusWantToRead)
+	b := add("B", model.StatusWantToRead)
+	c := add("C", model.StatusWantToRead)
+	reading := add("D", model.StatusCurrentlyReading)
+
+	queue := func() []int64 {
+		books, err := store.ListBooks(ctx, ListOptions{Status: model.StatusWantToRead, SortBy: "position"})
+		if err != nil {
+			t.Fatalf("ListBooks failed: %v", err)
+		}
+		ids := make([]int64, len(books))
+		for i, book := range books {
+			if book.Position != i+1 {
+				t.Errorf("Expected %s at position %d, got %d", book.Title, i+1, book.Position)
+			}
+			ids[i] = book.ID
+		}
+		return ids
+	}
+	assertQueue := func(want ...int64) {
+		t.Helper()
+		got := queue()
+		if len(got) != len(want) {
+			t.Fatalf("Expected queue %v, got %v", want, got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("Expected queue %v, got %v", want, got)
+			}
+		}
+	}
+	assertQueue(a, b, c)
+
+	if err := store.MoveBook(ctx, c, a, false); err != nil {
+		t.Fatalf("MoveBook failed: %v", err)
+	}
+	assertQueue(c, a, b)
+	if err := store.MoveBook(ctx, c, b, true); err != nil {
+		t.Fatalf("MoveBook failed: %v", err)
+	}
+	assertQueue(a, b, c)
+
+	if book, _ := store.GetBookByID(ctx, a); book.Version != 1 {
+		t.Errorf("Expected books moved around to keep their version, got %d", book.Version)
+	}
+	if err := store.MoveBook(WithExpectedVersion(ctx, 1), c, a, false); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict for a stale version, got %v", err)
+	}
+
+	var validationErr *model.ValidationError
+	if err := store.MoveBook(ctx, a, reading, false); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a validation error moving across shelves, got %v", err)
+	}
+	if err := store.MoveBook(ctx, a, 999, false); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a missing target, got %v", err)
+	}
+
+	// Changing shelves appends to the end of the new one
+	if err := store.UpdateBookStatus(ctx, reading, model.StatusWantToRead); err != nil {
+		t.Fatalf("UpdateBookStatus failed: %v", err)
+	}
+	assertQueue(a, b, c, reading)
+
+	next, err := store.NextBook(ctx)
+	if err != nil || next.ID != a {
+		t.Errorf("Expected A next, got %v %v", next, err)
+	}
+
+	// An earlier book of the same series comes first, wherever it is in the queue
+	series := "Saga"
+	for id, index := range map[int64]int{a: 2, c: 1} {
+		if err := store.UpdateBookDetails(ctx, id, model.BookDetails{Series: &series, SeriesIndex: &index}); err != nil {
+			t.Fatalf("UpdateBookDetails failed: %v", err)
+		}
+	}
+	if next, err = store.NextBook(ctx); err != nil || next.ID != c {
+		t.Errorf("Expected the first book of the series next, got %v %v", next, err)
+	}
+}
+
+func TestPositionMigrationBackfill(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open in-memory database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1) // Every connection to :memory: is a separate database
+
+	// Build the schema as it was before positions existed
+	const positionMigration = 9
+	for i, m := range migrations[:positionMigration-1] {
+		if err := applyMigration(db, i+1, m); err != nil {
+			t.Fatalf("Migration %d failed: %v", i+1, err)
+		}
+	}
+	_, err = db.Exec(`INSERT INTO books (title, author, open_library_id, status) VALUES
+        ('Zed', 'A', 'OL-1', 'Want to Read'),
+        ('Alpha', 'A', 'OL-2', 'Want to Read'),
+        ('Mid', 'A', 'OL-3', 'Read');`)
+	if err != nil {
+		t.Fatalf("Failed to insert books: %v", err)
+	}
+	if err := CreateSchema(db); err != nil {
+		t.Fatalf("CreateSchema failed: %v", err)
+	}
+
+	books, err := NewSQLiteBookStore(db).GetBooks(context.Background())
+	if err != nil {
+		t.Fatalf("GetBooks failed: %v", err)
+	}
+	want := map[string]int{"Alpha": 1, "Zed": 2, "Mid": 1}
+	for _, book := range books {
+		if book.Position != want[book.Title] {
+			t.Errorf("Expected %s at position %d, got %d", book.Title, want[book.Title], book.Position)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/metrics"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// QuoteStore defines the interface for the quotes highlighted in books. Quotes of books in the
+// trash are hidden along with their book, and purged with it.
+type QuoteStore interface {
+	GetQuotes(ctx context.Context, bookID int64) ([]model.Quote, error)
+	GetQuote(ctx context.Context, bookID, quoteID int64) (*model.Quote, error)
+	AddQuote(ctx context.Context, quote *model.Quote) error
+	UpdateQuote(ctx context.Context, quote *model.Quote) error
+	DeleteQuote(ctx context.Context, bookID, quoteID int64) error
+	SearchQuotes(ctx context.Context, query string, limit int) ([]model.Quote, error)
+	RandomQuote(ctx context.Context) (*model.Quote, error)
+	ImportQuotes(ctx context.Context, bookID int64, quotes []model.Quote) (int, error)
+}
+
+// quoteColumns lists the columns read by scanQuote, from quotes q joined with books b.
+const quoteColumns = `q.id, q.book_id, q.text, q.page, q.location, q.timestamp_seconds, q.note, q.created_at, b.title, b.author`
+
+// scanQuote reads a quote selected with quoteColumns.
+func scanQuote(row rowScanner) (*model.Quote, error) {
+	var quote model.Quote
+	var page, timestamp sql.NullInt64
+	var location, note sql.NullString
+	err := row.Scan(&quote.ID, &quote.BookID, &quote.Text, &page, &location, &timestamp, &note, &quote.CreatedAt,
+		&quote.BookTitle, &quote.BookAuthor)
+	if err != nil {
+		return nil, err
+	}
+	if page.Valid {
+		quote.Page = ptrTo(int(page.Int64))
+	}
+	if timestamp.Valid {
+		quote.Timestamp = ptrTo(int(timestamp.Int64))
+	}
+	if location.Valid {
+		quote.Location = &location.String
+	}
+	if note.Valid {
+		quote.Note = &note.String
+	}
+	return &quote, nil
+}
+
+// listQuotes runs a query selecting quoteColumns.
+func (s *SQLiteBookStore) listQuotes(ctx context.Context, query string, args ...interface{}) ([]model.Quote, error) {
+	quotes := []model.Quote{}
+	err := s.queryRows(ctx, query, args, func(rows *sql.Rows) error {
+		quote, err := scanQuote(rows)
+		if err != nil {
+			return err
+		}
+		quotes = append(quotes, *quote)
+		return nil
+	})
+	return quotes, err
+}
+
+// bookExists returns ErrNotFound unless book id exists and is not in the trash.
+func bookExists(ctx context.Context, q dbtx, id int64) error {
+	var exists bool
+	err := q.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM books WHERE id = ? AND deleted_at IS NULL);`, id).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check book existence: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("book with ID %d: %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// GetQuotes retrieves the quotes of a book in reading order: by page, then audiobook
+// timestamp, then the order they were added. Returns ErrNotFound if the book does not exist.
+func (s *SQLiteBookStore) GetQuotes(ctx context.Context, bookID int64) ([]model.Quote, error) {
+	defer metrics.ObserveDBQuery("GetQuotes", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	logger.Info("SQL: Executing GetQuotes query", "bookID", bookID)
+	if err := bookExists(ctx, s.DB, bookID); err != nil {
+		return nil, err
+	}
+	quotes, err := s.listQuotes(ctx, `
+        SELECT `+quoteColumns+` FROM quotes q JOIN books b ON b.id = q.book_id
+        WHERE q.book_id = ?
+        ORDER BY q.page IS NULL, q.page, q.timestamp_seconds IS NULL, q.timestamp_seconds, q.id;`, bookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quotes: %w", err)
+	}
+	return quotes, nil
+}
+
+// GetQuote retrieves one quote of a book. Returns ErrNotFound if either does not exist.
+func (s *SQLiteBookStore) GetQuote(ctx context.Context, bookID, quoteID int64) (*model.Quote, error) {
+	defer metrics.ObserveDBQuery("GetQuote", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	logger.Info("SQL: Executing GetQuote query", "bookID", bookID, "quoteID", quoteID)
+	quote, err := scanQuote(s.DB.QueryRowContext(ctx, `
+        SELECT `+quoteColumns+` FROM quotes q JOIN books b ON b.id = q.book_id
+        WHERE q.id = ? AND q.book_id = ? AND b.deleted_at IS NULL;`, quoteID, bookID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("quote %d of book %d: %w", quoteID, bookID, ErrNotFound)
+	} else if err != nil {
+		logger.Error("SQL Error: Querying quote failed", "error", err)
+		return nil, fmt.Errorf("failed to query quote: %w", err)
+	}
+	return quote, nil
+}
+
+// AddQuote adds a quote to the book quote.BookID and sets its ID and CreatedAt, as a new
+// version of the book. Returns ErrNotFound if the book does not exist.
+func (s *SQLiteBookStore) AddQuote(ctx context.Context, quote *model.Quote) error {
+	defer metrics.ObserveDBQuery("AddQuote", time.Now())
+	if err := quote.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+	return s.changeBook(ctx, "AddQuote", quote.BookID, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+            INSERT INTO quotes (book_id, text, page, location, timestamp_seconds, note) VALUES (?, ?, ?, ?, ?, ?)
+            RETURNING id, created_at;`,
+			quote.BookID, quote.Text, quote.Page, quote.Location, quote.Timestamp, quote.Note).Scan(&quote.ID, &quote.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to add quote: %w", err)
+		}
+		return nil
+	})
+}
+
+// UpdateQuote replaces the text, place and note of quote.ID, which must belong to the book
+// quote.BookID, as a new version of the book. Returns ErrNotFound otherwise.
+func (s *SQLiteBookStore) UpdateQuote(ctx context.Context, quote *model.Quote) error {
+	defer metrics.ObserveDBQuery("UpdateQuote", time.Now())
+	if err := quote.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+	return s.changeBook(ctx, "UpdateQuote", quote.BookID, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `
+            UPDATE quotes SET text = ?, page = ?, location = ?, timestamp_seconds = ?, note = ?
+            WHERE id = ? AND book_id = ?;`,
+			quote.Text, quote.Page, quote.Location, quote.Timestamp, quote.Note, quote.ID, quote.BookID)
+		return quoteChanged(res, err, quote.BookID, quote.ID)
+	})
+}
+
+// DeleteQuote deletes a quote of a book, as a new version of the book. Returns ErrNotFound if
+// either does not exist.
+func (s *SQLiteBookStore) DeleteQuote(ctx context.Context, bookID, quoteID int64) error {
+	defer metrics.ObserveDBQuery("DeleteQuote", time.Now())
+	return s.changeBook(ctx, "DeleteQuote", bookID, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `DELETE FROM quotes WHERE id = ? AND book_id = ?;`, quoteID, bookID)
+		return quoteChanged(res, err, bookID, quoteID)
+	})
+}
+
+// quoteChanged returns ErrNotFound if an update or delete of a quote matched no row.
+func quoteChanged(res sql.Result, err error, bookID, quoteID int64) error {
+	if err != nil {
+		return fmt.Errorf("failed to change quote: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("quote %d of book %d: %w", quoteID, bookID, ErrNotFound)
+	}
+	return nil
+}
+
+// SearchQuotes finds up to limit quotes whose text or note contains query (ignoring case),
+// newest first. An empty query returns the newest quotes.
+func (s *SQLiteBookStore) SearchQuotes(ctx context.Context, query string, limit int) ([]model.Quote, error) {
+	defer metrics.ObserveDBQuery("SearchQuotes", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	logger.Info("SQL: Executing SearchQuotes query", "query", query, "limit", limit)
+	// Escape LIKE wildcards so that "50%" finds "50%" literally
+	pattern := "%" + strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(query) + "%"
+	quotes, err := s.listQuotes(ctx, `
+        SELECT `+quoteColumns+` FROM quotes q JOIN books b ON b.id = q.book_id
+        WHERE b.deleted_at IS NULL AND (q.text LIKE ? ESCAPE '\' OR q.note LIKE ? ESCAPE '\')
+        ORDER BY q.id DESC LIMIT ?;`, pattern, pattern, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search quotes: %w", err)
+	}
+	return quotes, nil
+}
+
+// RandomQuote picks a quote at random. Returns ErrNotFound if there are none.
+func (s *SQLiteBookStore) RandomQuote(ctx context.Context) (*model.Quote, error) {
+	defer metrics.ObserveDBQuery("RandomQuote", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	logger.Info("SQL: Executing RandomQuote query")
+	quote, err := scanQuote(s.DB.QueryRowContext(ctx, `
+        SELECT `+quoteColumns+` FROM quotes q JOIN books b ON b.id = q.book_id
+        WHERE b.deleted_at IS NULL
+        ORDER BY RANDOM() LIMIT 1;`))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("no quotes yet: %w", ErrNotFound)
+	} else if err != nil {
+		logger.Error("SQL Error: Querying random quote failed", "error", err)
+		return nil, fmt.Errorf("failed to query random quote: %w", err)
+	}
+	return quote, nil
+}
+
+// ImportQuotes adds imported quotes (with an ImportHash) to a book in a single transaction,
+// as a new version of the book, skipping quotes imported before. A note on a quote imported
+// before without one is added to it. It returns the number of quotes added; CreatedAt is kept
+// if set. An import that changes nothing leaves the book's version alone. Returns ErrNotFound
+// if the book does not exist.
+func (s *SQLiteBookStore) ImportQuotes(ctx context.Context, bookID int64, quotes []model.Quote) (int, error) {
+	defer metrics.ObserveDBQuery("ImportQuotes", time.Now())
+	for i := range quotes {
+		if err := quotes[i].Validate(); err != nil {
+			return 0, fmt.Errorf("validation failed: %w", err)
+		}
+		if quotes[i].ImportHash == "" {
+			return 0, fmt.Errorf("validation failed: %w", &model.ValidationError{Message: "Imported quotes need an import hash"})
+		}
+	}
+
+	added := 0
+	err := s.changeBook(ctx, "ImportQuotes", bookID, func(tx *sql.Tx) error {
+		changed := false
+		for _, quote := range quotes {
+			var existingID int64
+			var existingNote sql.NullString
+			err := tx.QueryRowContext(ctx, `SELECT id, note FROM quotes WHERE book_id = ? AND import_hash = ?;`,
+				bookID, quote.ImportHash).Scan(&existingID, &existingNote)
+			switch {
+			case err == nil:
+				if !existingNote.Valid && quote.Note != nil {
+					if _, err := tx.ExecContext(ctx, `UPDATE quotes SET note = ? WHERE id = ?;`, quote.Note, existingID); err != nil {
+						return fmt.Errorf("failed to update quote: %w", err)
+					}
+					changed = true
+				}
+				continue
+			case !errors.Is(err, sql.ErrNoRows):
+				return fmt.Errorf("failed to look up quote: %w", err)
+			}
+
+			var createdAt interface{} = sqlTimestamp(ptrTo(time.Now()))
+			if !quote.CreatedAt.IsZero() {
+				createdAt = sqlTimestamp(&quote.CreatedAt)
+			}
+			_, err = tx.ExecContext(ctx, `
+                INSERT INTO quotes (book_id, text, page, location, timestamp_seconds, note, created_at, import_hash)
+                VALUES (?, ?, ?, ?, ?, ?, ?, ?);`,
+				bookID, quote.Text, quote.Page, quote.Location, quote.Timestamp, quote.Note, createdAt, quote.ImportHash)
+			if err != nil {
+				return fmt.Errorf("failed to add quote: %w", err)
+			}
+			added++
+			changed = true
+		}
+		if !changed {
+			return errUnchanged
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return added, nil
+}
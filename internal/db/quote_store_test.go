@@ -0,0 +1,238 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/events"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestQuoteStore(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	bookID, err := store.AddBook(ctx, &model.Book{Title: "Nineteen Eighty-Four", Author: "George Orwell", OpenLibraryID: "OL-1984", Status: model.StatusRead})
+	if err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+	otherID, err := store.AddBook(ctx, &model.Book{Title: "Dune", Author: "Frank Herbert", OpenLibraryID: "OL-Dune", Status: model.StatusRead})
+	if err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+
+	page, later := 3, 200
+	note := "Opening line"
+	location := "12-14"
+	first := &model.Quote{BookID: bookID, Text: "It was a bright cold day in April, and the clocks were striking thirteen.", Page: &page, Note: &note}
+	second := &model.Quote{BookID: bookID, Text: "Who controls the past controls the future.", Page: &later}
+	third := &model.Quote{BookID: otherID, Text: "Fear is the mind-killer.", Location: &location}
+	for _, quote := range []*model.Quote{second, first, third} {
+		if err := store.AddQuote(ctx, quote); err != nil {
+			t.Fatalf("AddQuote failed: %v", err)
+		}
+	}
+	if first.ID == 0 || first.CreatedAt.IsZero() {
+		t.Errorf("Expected the ID and creation time set, got %+v", first)
+	}
+	if err := store.AddQuote(ctx, &model.Quote{BookID: 999, Text: "x"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound quoting a missing book, got %v", err)
+	}
+	var validationErr *model.ValidationError
+	if err := store.AddQuote(ctx, &model.Quote{BookID: bookID, Text: " "}); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a validation error for an empty quote, got %v", err)
+	}
+
+	quotes, err := store.GetQuotes(ctx, bookID)
+	if err != nil {
+		t.Fatalf("GetQuotes failed: %v", err)
+	}
+	if len(quotes) != 2 || quotes[0].ID != first.ID || quotes[1].ID != second.ID {
+		t.Fatalf("Expected the book's quotes in page order, got %+v", quotes)
+	}
+	if quotes[0].Note == nil || *quotes[0].Note != note || quotes[0].BookTitle != "Nineteen Eighty-Four" {
+		t.Errorf("Expected the note and book title, got %+v", quotes[0])
+	}
+
+	second.Text = "Who controls the past controls the future: who controls the present controls the past."
+	second.Note = &note
+	if err := store.UpdateQuote(ctx, second); err != nil {
+		t.Fatalf("UpdateQuote failed: %v", err)
+	}
+	updated, err := store.GetQuote(ctx, bookID, second.ID)
+	if err != nil || updated.Text != second.Text || updated.Note == nil {
+		t.Errorf("Expected the updated quote, got %+v (%v)", updated, err)
+	}
+	if _, err := store.GetQuote(ctx, otherID, second.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a quote of another book, got %v", err)
+	}
+	if err := store.UpdateQuote(ctx, &model.Quote{ID: second.ID, BookID: otherID, Text: "x"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound updating through another book, got %v", err)
+	}
+
+	results, err := store.SearchQuotes(ctx, "CONTROLS", 10)
+	if err != nil || len(results) != 1 || results[0].ID != second.ID {
+		t.Errorf("Expected a case-insensitive match on the text, got %+v (%v)", results, err)
+	}
+	if results, _ := store.SearchQuotes(ctx, "opening", 10); len(results) != 2 {
+		t.Errorf("Expected matches on the note, got %d", len(results))
+	}
+	if results, _ := store.SearchQuotes(ctx, "100%", 10); len(results) != 0 {
+		t.Errorf("Expected %% to match literally, got %d", len(results))
+	}
+	if results, _ := store.SearchQuotes(ctx, "", 2); len(results) != 2 || results[0].ID != third.ID {
+		t.Errorf("Expected the newest quotes without a query, got %+v", results)
+	}
+
+	// Quotes of books in the trash are hidden with their book
+	if err := store.DeleteBook(ctx, otherID); err != nil {
+		t.Fatalf("DeleteBook failed: %v", err)
+	}
+	if _, err := store.GetQuotes(ctx, otherID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a trashed book, got %v", err)
+	}
+	if results, _ := store.SearchQuotes(ctx, "fear", 10); len(results) != 0 {
+		t.Errorf("Expected trashed books' quotes left out of searches, got %d", len(results))
+	}
+	for i := 0; i < 10; i++ {
+		quote, err := store.RandomQuote(ctx)
+		if err != nil {
+			t.Fatalf("RandomQuote failed: %v", err)
+		}
+		if quote.BookID != bookID {
+			t.Fatalf("Expected a quote of a book on the shelves, got %+v", quote)
+		}
+	}
+
+	if err := store.DeleteQuote(ctx, bookID, first.ID); err != nil {
+		t.Fatalf("DeleteQuote failed: %v", err)
+	}
+	if err := store.DeleteQuote(ctx, bookID, first.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+	}
+	if err := store.DeleteQuote(ctx, bookID, second.ID); err != nil {
+		t.Fatalf("DeleteQuote failed: %v", err)
+	}
+	if _, err := store.RandomQuote(ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound without quotes, got %v", err)
+	}
+}
+
+func TestQuoteVersions(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	id, err := store.AddBook(ctx, createTestBook())
+	if err != nil {
+		t.Fatalf("Failed to add test book: %v", err)
+	}
+	store.Events = events.NewBus(10)
+	ch, _, _, cancel := store.Events.Subscribe(0)
+	defer cancel()
+
+	// Each quote change is a new version of the book, audited and published
+	quote := &model.Quote{BookID: id, Text: "It was the best of times."}
+	changes := []func() error{
+		func() error { return store.AddQuote(ctx, quote) },
+		func() error { quote.Text = "It was the worst of times."; return store.UpdateQuote(ctx, quote) },
+		func() error { return store.DeleteQuote(ctx, id, quote.ID) },
+	}
+	for i, change := range changes {
+		if err := change(); err != nil {
+			t.Fatalf("Quote change %d failed: %v", i, err)
+		}
+		event := <-ch
+		if event.Type != events.BookUpdated || event.BookID != id || event.Book == nil || event.Book.Version != int64(i+2) {
+			t.Errorf("Expected version %d of the book published, got %+v", i+2, event)
+		}
+	}
+	// A failed change is no new version
+	if err := store.DeleteQuote(ctx, id, quote.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+	}
+	book, err := store.GetBookByID(ctx, id)
+	if err != nil || book.Version != 4 {
+		t.Errorf("Expected version 4 after three quote changes, got %+v (%v)", book, err)
+	}
+
+	page, err := store.GetAuditLog(ctx, AuditFilter{BookID: id, Limit: 50})
+	if err != nil {
+		t.Fatalf("GetAuditLog failed: %v", err)
+	}
+	updates := 0
+	for _, entry := range page.Entries {
+		if entry.Action == model.AuditUpdate {
+			updates++
+		}
+	}
+	if updates != 3 {
+		t.Errorf("Expected the three quote changes audited, got %+v", page.Entries)
+	}
+}
+
+func TestImportQuotes(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	bookID, err := store.AddBook(ctx, &model.Book{Title: "Dune", Author: "Frank Herbert", OpenLibraryID: "OL-Dune", Status: model.StatusRead})
+	if err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+
+	added := time.Date(2023, 3, 4, 21, 5, 0, 0, time.UTC)
+	quotes := []model.Quote{
+		{Text: "Fear is the mind-killer.", CreatedAt: added, ImportHash: "a"},
+		{Text: "The spice must flow.", ImportHash: "b"},
+	}
+	if n, err := store.ImportQuotes(ctx, bookID, quotes); err != nil || n != 2 {
+		t.Fatalf("Expected 2 quotes imported, got %d, %v", n, err)
+	}
+
+	// Importing again adds only new quotes, and notes to quotes that had none
+	note := "Litany against fear"
+	quotes[0].Note = &note
+	quotes = append(quotes, model.Quote{Text: "He who controls the spice controls the universe.", ImportHash: "c"})
+	if n, err := store.ImportQuotes(ctx, bookID, quotes); err != nil || n != 1 {
+		t.Fatalf("Expected 1 new quote imported, got %d, %v", n, err)
+	}
+	saved, err := store.GetQuotes(ctx, bookID)
+	if err != nil || len(saved) != 3 {
+		t.Fatalf("Expected 3 quotes, got %d, %v", len(saved), err)
+	}
+	for _, quote := range saved {
+		if quote.Text != quotes[0].Text {
+			continue
+		}
+		if quote.Note == nil || *quote.Note != note {
+			t.Errorf("Expected the note added on re-import, got %+v", quote)
+		}
+		if !quote.CreatedAt.Equal(added) {
+			t.Errorf("Expected the highlight's date kept, got %v", quote.CreatedAt)
+		}
+	}
+
+	// Each import that changes the quotes is a new version of the book, audited
+	if n, err := store.ImportQuotes(ctx, bookID, quotes); err != nil || n != 0 {
+		t.Fatalf("Expected nothing imported a third time, got %d, %v", n, err)
+	}
+	book, err := store.GetBookByID(ctx, bookID)
+	if err != nil || book.Version != 3 {
+		t.Errorf("Expected version 3 after two imports that changed quotes, got %+v (%v)", book, err)
+	}
+	if page, err := store.GetAuditLog(ctx, AuditFilter{BookID: bookID, Limit: 50}); err != nil || len(page.Entries) != 3 {
+		t.Errorf("Expected the book's creation and two imports audited, got %+v (%v)", page, err)
+	}
+
+	var validationErr *model.ValidationError
+	if _, err := store.ImportQuotes(ctx, bookID, []model.Quote{{Text: "No hash"}}); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a validation error without an import hash, got %v", err)
+	}
+	if _, err := store.ImportQuotes(ctx, 999, quotes); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound importing into a missing book, got %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/metrics"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/recommend"
+)
+
+// RecommendationStore defines the interface for the metadata cache and the recommendations
+// made from it.
+type RecommendationStore interface {
+	GetRecommendations(ctx context.Context, filter RecommendationFilter) ([]model.Recommendation, error)
+	ListWorkMetadata(ctx context.Context) ([]model.WorkMetadata, error)
+	SaveWorkMetadata(ctx context.Context, works []model.WorkMetadata) error
+}
+
+// RecommendationFilter selects the recommendations returned by GetRecommendations. Zero
+// values apply no filter.
+type RecommendationFilter struct {
+	Source model.RecommendationSource
+	Limit  int
+}
+
+// GetRecommendations ranks the Want to Read books and the cached works not on the shelf by
+// their similarity to the books rated highly (see recommend.Rank). It only reads the cache,
+// so it works offline.
+func (s *SQLiteBookStore) GetRecommendations(ctx context.Context, filter RecommendationFilter) ([]model.Recommendation, error) {
+	defer metrics.ObserveDBQuery("GetRecommendations", time.Now())
+	books, err := s.ListBooks(ctx, ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	works, err := s.ListWorkMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	recommendations := recommend.Rank(books, works)
+	if filter.Source != "" {
+		filtered := []model.Recommendation{}
+		for _, rec := range recommendations {
+			if rec.Source == filter.Source {
+				filtered = append(filtered, rec)
+			}
+		}
+		recommendations = filtered
+	}
+	if filter.Limit > 0 && len(recommendations) > filter.Limit {
+		recommendations = recommendations[:filter.Limit]
+	}
+	return recommendations, nil
+}
+
+// ListWorkMetadata returns every cached work, ordered by Open Library ID.
+func (s *SQLiteBookStore) ListWorkMetadata(ctx context.Context) ([]model.WorkMetadata, error) {
+	defer metrics.ObserveDBQuery("ListWorkMetadata", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	logger.Info("SQL: Executing ListWorkMetadata query")
+	rows, err := s.DB.QueryContext(ctx, `
+        SELECT open_library_id, title, author, subjects, isbn, cover_url, fetched_at
+        FROM work_metadata ORDER BY open_library_id;`)
+	if err != nil {
+		logger.Error("SQL Error: Executing ListWorkMetadata query failed", "error", err)
+		return nil, fmt.Errorf("failed to query work metadata: %w", err)
+	}
+	defer rows.Close()
+
+	works := []model.WorkMetadata{}
+	for rows.Next() {
+		var work model.WorkMetadata
+		var subjects string
+		var isbn, coverURL sql.NullString
+		if err := rows.Scan(&work.OpenLibraryID, &work.Title, &work.Author, &subjects, &isbn, &coverURL, &work.FetchedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan work metadata row: %w", err)
+		}
+		if err := json.Unmarshal([]byte(subjects), &work.Subjects); err != nil {
+			return nil, fmt.Errorf("failed to decode subjects of %s: %w", work.OpenLibraryID, err)
+		}
+		if isbn.Valid {
+			work.ISBN = &isbn.String
+		}
+		if coverURL.Valid {
+			work.CoverURL = &coverURL.String
+		}
+		works = append(works, work)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("SQL Error: Error during work metadata iteration", "error", err)
+		return nil, fmt.Errorf("error iterating work metadata rows: %w", err)
+	}
+	return works, nil
+}
+
+// SaveWorkMetadata caches works fetched from the metadata provider, replacing what was cached
+// of them before. Works without a fetch time are stamped with the current time.
+func (s *SQLiteBookStore) SaveWorkMetadata(ctx context.Context, works []model.WorkMetadata) error {
+	defer metrics.ObserveDBQuery("SaveWorkMetadata", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	for _, work := range works {
+		if work.OpenLibraryID == "" || work.Title == "" {
+			return &model.ValidationError{Message: "work metadata needs an Open Library ID and a title"}
+		}
+	}
+
+	logger.Info("SQL: Executing SaveWorkMetadata", "count", len(works))
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	for _, work := range works {
+		subjects := work.Subjects
+		if subjects == nil {
+			subjects = []string{}
+		}
+		encoded, err := json.Marshal(subjects)
+		if err != nil {
+			return fmt.Errorf("failed to encode subjects of %s: %w", work.OpenLibraryID, err)
+		}
+		fetchedAt := work.FetchedAt
+		if fetchedAt.IsZero() {
+			fetchedAt = now
+		}
+		_, err = tx.ExecContext(ctx, `
+            INSERT INTO work_metadata (open_library_id, title, author, subjects, isbn, cover_url, fetched_at)
+            VALUES (?, ?, ?, ?, ?, ?, ?)
+            ON CONFLICT(open_library_id) DO UPDATE SET title = excluded.title, author = excluded.author,
+                subjects = excluded.subjects, isbn = excluded.isbn, cover_url = excluded.cover_url,
+                fetched_at = excluded.fetched_at;`,
+			work.OpenLibraryID, work.Title, work.Author, string(encoded), work.ISBN, work.CoverURL, fetchedAt)
+		if err != nil {
+			logger.Error("SQL Error: Saving work metadata failed", "id", work.OpenLibraryID, "error", err)
+			return fmt.Errorf("failed to save metadata of %s: %w", work.OpenLibraryID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("SQL Error: Committing SaveWorkMetadata transaction failed", "error", err)
+		return fmt.Errorf("failed to commit work metadata: %w", err)
+	}
+	logger.Info("SQL: Successfully saved work metadata", "count", len(works))
+	return nil
+}
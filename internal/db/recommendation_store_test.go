@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestSaveWorkMetadata(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	fetched := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	err := store.SaveWorkMetadata(ctx, []model.WorkMetadata{
+		{OpenLibraryID: "OL2W", Title: "The Dispossessed", Author: "Ursula K. Le Guin", Subjects: []string{"Science fiction"}, FetchedAt: fetched},
+		{OpenLibraryID: "OL1W", Title: "Dune", ISBN: ptrTo("9780441172719")},
+	})
+	if err != nil {
+		t.Fatalf("SaveWorkMetadata failed: %v", err)
+	}
+	// Saving again replaces what was cached
+	if err := store.SaveWorkMetadata(ctx, []model.WorkMetadata{{OpenLibraryID: "OL1W", Title: "Dune", Author: "Frank Herbert"}}); err != nil {
+		t.Fatalf("SaveWorkMetadata failed: %v", err)
+	}
+
+	works, err := store.ListWorkMetadata(ctx)
+	if err != nil {
+		t.Fatalf("ListWorkMetadata failed: %v", err)
+	}
+	if len(works) != 2 || works[0].OpenLibraryID != "OL1W" || works[1].OpenLibraryID != "OL2W" {
+		t.Fatalf("Expected both works ordered by ID, got %+v", works)
+	}
+	if works[0].Author != "Frank Herbert" || works[0].ISBN != nil || works[0].Subjects == nil || works[0].FetchedAt.IsZero() {
+		t.Errorf("Expected the replaced work, stamped with the fetch time, got %+v", works[0])
+	}
+	if len(works[1].Subjects) != 1 || works[1].Subjects[0] != "Science fiction" || !works[1].FetchedAt.Equal(fetched) {
+		t.Errorf("Unexpected work %+v", works[1])
+	}
+
+	var validationErr *model.ValidationError
+	if err := store.SaveWorkMetadata(ctx, []model.WorkMetadata{{OpenLibraryID: "OL3W"}}); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a validation error for a work without a title, got %v", err)
+	}
+}
+
+func TestGetRecommendations(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	for _, book := range []model.Book{
+		{Title: "The Left Hand of Darkness", Author: "Ursula K. Le Guin", OpenLibraryID: "OL59801W", Status: model.StatusRead, Rating: ptrTo(10)},
+		{Title: "The Lathe of Heaven", Author: "Ursula K. Le Guin", OpenLibraryID: "OL59803W", Status: model.StatusWantToRead},
+		{Title: "Emma", Author: "Jane Austen", OpenLibraryID: "OL66554W", Status: model.StatusWantToRead},
+	} {
+		if _, err := store.AddBook(ctx, &book); err != nil {
+			t.Fatalf("AddBook failed: %v", err)
+		}
+	}
+	err := store.SaveWorkMetadata(ctx, []model.WorkMetadata{
+		{OpenLibraryID: "OL59801W", Title: "The Left Hand of Darkness", Subjects: []string{"Science fiction"}},
+		{OpenLibraryID: "OL2W", Title: "Hyperion", Author: "Dan Simmons", Subjects: []string{"Science fiction"}},
+	})
+	if err != nil {
+		t.Fatalf("SaveWorkMetadata failed: %v", err)
+	}
+
+	recs, err := store.GetRecommendations(ctx, RecommendationFilter{})
+	if err != nil {
+		t.Fatalf("GetRecommendations failed: %v", err)
+	}
+	if len(recs) != 2 || recs[0].Title != "The Lathe of Heaven" || recs[1].Title != "Hyperion" {
+		t.Fatalf("Expected the Le Guin book then Hyperion, got %+v", recs)
+	}
+
+	recs, err = store.GetRecommendations(ctx, RecommendationFilter{Source: model.SourceOpenLibrary, Limit: 5})
+	if err != nil {
+		t.Fatalf("GetRecommendations failed: %v", err)
+	}
+	if len(recs) != 1 || recs[0].OpenLibraryID != "OL2W" {
+		t.Errorf("Expected only Hyperion, got %+v", recs)
+	}
+	if recs, _ := store.GetRecommendations(ctx, RecommendationFilter{Limit: 1}); len(recs) != 1 {
+		t.Errorf("Expected the limit to apply, got %d", len(recs))
+	}
+}
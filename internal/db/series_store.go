@@ -0,0 +1,377 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/events"
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/metrics"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// SeriesStore defines the interface for database operations on series.
+// Books join a series through UpdateBookDetails, by series name.
+type SeriesStore interface {
+	GetSeries(ctx context.Context) ([]model.Series, error)
+	GetSeriesByID(ctx context.Context, id int64) (*model.Series, error)
+	UpdateSeries(ctx context.Context, series *model.Series) error
+	MergeSeries(ctx context.Context, sourceID, targetID int64) error
+	GetNextInSeries(ctx context.Context, bookID int64) (*model.NextInSeries, error)
+	ListNextInSeries(ctx context.Context) ([]model.NextInSeries, error)
+}
+
+// resolveSeries finds the series with the given name (case-insensitively), creating it if
+// needed, and returns its canonical name and ID. A nil or blank name resolves to no series.
+func resolveSeries(ctx context.Context, q dbtx, name *string) (*string, *int64, error) {
+	if name == nil || strings.TrimSpace(*name) == "" {
+		return nil, nil, nil
+	}
+	trimmed := strings.TrimSpace(*name)
+
+	var id int64
+	var canonical string
+	err := q.QueryRowContext(ctx, `SELECT id, name FROM series WHERE name = ?;`, trimmed).Scan(&id, &canonical)
+	if errors.Is(err, sql.ErrNoRows) {
+		res, err := q.ExecContext(ctx, `INSERT INTO series (name) VALUES (?);`, trimmed)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create series: %w", err)
+		}
+		if id, err = res.LastInsertId(); err != nil {
+			return nil, nil, fmt.Errorf("failed to retrieve series ID: %w", err)
+		}
+		canonical = trimmed
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up series: %w", err)
+	}
+	return &canonical, &id, nil
+}
+
+// scanSeries scans the id, name and total_books columns of a series row.
+func scanSeries(row rowScanner) (*model.Series, error) {
+	var series model.Series
+	var total sql.NullInt64
+	if err := row.Scan(&series.ID, &series.Name, &total); err != nil {
+		return nil, err
+	}
+	if total.Valid {
+		n := int(total.Int64)
+		series.TotalBooks = &n
+	}
+	return &series, nil
+}
+
+// seriesBookIDs lists the books in a series, to publish their changes after a rename or merge.
+func seriesBookIDs(ctx context.Context, q dbtx, seriesID int64) ([]int64, error) {
+	rows, err := q.QueryContext(ctx, `SELECT id FROM books WHERE series_id = ? ORDER BY id;`, seriesID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query series books: %w", err)
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan series book: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetSeries retrieves every series with at least one book, ordered by name, with their
+// books in series order and a summary of progress through each.
+func (s *SQLiteBookStore) GetSeries(ctx context.Context) ([]model.Series, error) {
+	defer metrics.ObserveDBQuery("GetSeries", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	logger.Info("SQL: Executing GetSeries query")
+	seriesList := []model.Series{}
+	err := s.queryRows(ctx, `
+        SELECT id, name, total_books FROM series
+        WHERE EXISTS (SELECT 1 FROM books WHERE books.series_id = series.id AND books.deleted_at IS NULL)
+        ORDER BY name, id;`, nil, func(rows *sql.Rows) error {
+		series, err := scanSeries(rows)
+		if err != nil {
+			return err
+		}
+		seriesList = append(seriesList, *series)
+		return nil
+	})
+	if err != nil {
+		logger.Error("SQL Error: Executing GetSeries query failed", "error", err)
+		return nil, fmt.Errorf("failed to query series: %w", err)
+	}
+
+	books, err := s.ListBooks(ctx, ListOptions{SortBy: "series_index"})
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[int64]int, len(seriesList))
+	for i := range seriesList {
+		index[seriesList[i].ID] = i
+	}
+	for _, book := range books {
+		if book.SeriesID == nil {
+			continue
+		}
+		if i, ok := index[*book.SeriesID]; ok {
+			seriesList[i].Books = append(seriesList[i].Books, book)
+		}
+	}
+	for i := range seriesList {
+		seriesList[i].Summarize()
+	}
+	return seriesList, nil
+}
+
+// GetSeriesByID retrieves a single series with its books and progress summary.
+func (s *SQLiteBookStore) GetSeriesByID(ctx context.Context, id int64) (*model.Series, error) {
+	defer metrics.ObserveDBQuery("GetSeriesByID", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	logger.Info("SQL: Executing GetSeriesByID query", "id", id)
+	series, err := scanSeries(s.DB.QueryRowContext(ctx, `SELECT id, name, total_books FROM series WHERE id = ?;`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("series with ID %d: %w", id, ErrNotFound)
+		}
+		logger.Error("SQL Error: Scanning series row failed", "id", id, "error", err)
+		return nil, fmt.Errorf("failed to scan series row for ID %d: %w", id, err)
+	}
+
+	if series.Books, err = s.ListBooks(ctx, ListOptions{SeriesID: id, SortBy: "series_index"}); err != nil {
+		return nil, err
+	}
+	series.Summarize()
+	return series, nil
+}
+
+// UpdateSeries renames a series and sets its length. Renaming to the name of another series
+// fails with ErrDuplicate; use MergeSeries to combine them.
+func (s *SQLiteBookStore) UpdateSeries(ctx context.Context, series *model.Series) error {
+	defer metrics.ObserveDBQuery("UpdateSeries", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	if err := series.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	logger.Info("SQL: Executing UpdateSeries", "id", series.ID, "name", series.Name, "totalBooks", series.TotalBooks)
+	previous, err := s.seriesSnapshots(ctx, series.ID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE series SET name = ?, total_books = ? WHERE id = ?;`,
+		series.Name, series.TotalBooks, series.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("series named %q %w", series.Name, ErrDuplicate)
+		}
+		logger.Error("SQL Error: Executing UpdateSeries statement failed", "error", err)
+		return fmt.Errorf("failed to update series: %w", err)
+	}
+	if rowsAffected, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	} else if rowsAffected == 0 {
+		return fmt.Errorf("series with ID %d: %w", series.ID, ErrNotFound)
+	}
+
+	changed, err := relabelSeriesBooks(ctx, tx, series.ID, series.ID, series.Name)
+	if err != nil {
+		logger.Error("SQL Error: Renaming series books failed", "error", err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("SQL Error: Committing UpdateSeries transaction failed", "error", err)
+		return fmt.Errorf("failed to commit series update: %w", err)
+	}
+	s.publishChanged(ctx, changed, previous)
+	return nil
+}
+
+// MergeSeries moves every book of the source series into the target series and deletes the
+// source. The target keeps its name; it takes the source's length if it has none.
+func (s *SQLiteBookStore) MergeSeries(ctx context.Context, sourceID, targetID int64) error {
+	defer metrics.ObserveDBQuery("MergeSeries", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	if sourceID == targetID {
+		return fmt.Errorf("cannot merge series %d into itself", sourceID)
+	}
+
+	logger.Info("SQL: Executing MergeSeries", "sourceID", sourceID, "targetID", targetID)
+	previous, err := s.seriesSnapshots(ctx, sourceID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var sourceTotal sql.NullInt64
+	if err := tx.QueryRowContext(ctx, `SELECT total_books FROM series WHERE id = ?;`, sourceID).Scan(&sourceTotal); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("series with ID %d: %w", sourceID, ErrNotFound)
+		}
+		return fmt.Errorf("failed to look up series %d: %w", sourceID, err)
+	}
+	var targetName string
+	if err := tx.QueryRowContext(ctx, `SELECT name FROM series WHERE id = ?;`, targetID).Scan(&targetName); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("series with ID %d: %w", targetID, ErrNotFound)
+		}
+		return fmt.Errorf("failed to look up series %d: %w", targetID, err)
+	}
+
+	changed, err := relabelSeriesBooks(ctx, tx, sourceID, targetID, targetName)
+	if err != nil {
+		logger.Error("SQL Error: Moving series books failed", "error", err)
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE series SET total_books = COALESCE(total_books, ?) WHERE id = ?;`, sourceTotal, targetID); err != nil {
+		return fmt.Errorf("failed to update merged series: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM series WHERE id = ?;`, sourceID); err != nil {
+		return fmt.Errorf("failed to delete merged series: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("SQL Error: Committing MergeSeries transaction failed", "error", err)
+		return fmt.Errorf("failed to commit series merge: %w", err)
+	}
+	s.publishChanged(ctx, changed, previous)
+	return nil
+}
+
+// seriesSnapshots captures the books of a series before a rename or merge, for the
+// previous state of their update events. It returns nil when there is no event bus.
+func (s *SQLiteBookStore) seriesSnapshots(ctx context.Context, seriesID int64) (map[int64]*model.Book, error) {
+	if s.Events == nil {
+		return nil, nil
+	}
+	ids, err := seriesBookIDs(ctx, s.DB, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	snapshots := make(map[int64]*model.Book, len(ids))
+	for _, id := range ids {
+		snapshots[id] = s.snapshot(ctx, id)
+	}
+	return snapshots, nil
+}
+
+// relabelSeriesBooks moves the books of series fromID to series toID under the given name,
+// bumping the version of each book whose series changes and recording it in the audit log.
+// It returns the changed book IDs.
+func relabelSeriesBooks(ctx context.Context, tx *sql.Tx, fromID, toID int64, name string) ([]int64, error) {
+	ids, err := seriesBookIDs(ctx, tx, fromID)
+	if err != nil {
+		return nil, err
+	}
+	var changed []int64
+	for _, id := range ids {
+		before, err := readBook(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		res, err := tx.ExecContext(ctx, `
+            UPDATE books SET series_id = ?, series = ?, version = version + 1
+            WHERE id = ? AND (series_id IS NOT ? OR series IS NOT ?);`, toID, name, id, toID, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update series of book %d: %w", id, err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return nil, fmt.Errorf("failed to get rows affected: %w", err)
+		} else if n > 0 {
+			if err := recordAudit(ctx, tx, model.AuditUpdate, id, before); err != nil {
+				return nil, err
+			}
+			changed = append(changed, id)
+		}
+	}
+	return changed, nil
+}
+
+// publishChanged publishes an update event for each changed book, after the commit.
+func (s *SQLiteBookStore) publishChanged(ctx context.Context, changed []int64, previous map[int64]*model.Book) {
+	for _, id := range changed {
+		s.publish(events.BookUpdated, id, previous[id], s.snapshot(ctx, id))
+	}
+}
+
+// GetNextInSeries finds what to read after a book numbered in a series: the first later
+// position not read yet. Returns nil if the series is known to end before it, ErrNotFound if
+// the book does not exist or is in the trash, or a validation error if it has no series index.
+func (s *SQLiteBookStore) GetNextInSeries(ctx context.Context, bookID int64) (*model.NextInSeries, error) {
+	defer metrics.ObserveDBQuery("GetNextInSeries", time.Now())
+	book, err := s.GetBookByID(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	if book.SeriesID == nil || book.SeriesIndex == nil {
+		return nil, &model.ValidationError{Message: "book is not numbered in a series"}
+	}
+	series, err := s.GetSeriesByID(ctx, *book.SeriesID)
+	if err != nil {
+		return nil, err
+	}
+	return nextInSeries(series, *book), nil
+}
+
+// ListNextInSeries finds what to read next in every series, after the highest position read.
+// Series with nothing read, or read to their known end, are left out.
+func (s *SQLiteBookStore) ListNextInSeries(ctx context.Context) ([]model.NextInSeries, error) {
+	defer metrics.ObserveDBQuery("ListNextInSeries", time.Now())
+	seriesList, err := s.GetSeries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := []model.NextInSeries{}
+	for i := range seriesList {
+		var last *model.Book
+		for j, book := range seriesList[i].Books {
+			if book.Status == model.StatusRead && book.SeriesIndex != nil && (last == nil || *book.SeriesIndex > *last.SeriesIndex) {
+				last = &seriesList[i].Books[j]
+			}
+		}
+		if last == nil {
+			continue
+		}
+		if next := nextInSeries(&seriesList[i], *last); next != nil {
+			result = append(result, *next)
+		}
+	}
+	return result, nil
+}
+
+// nextInSeries suggests the book after one of series, or returns nil if the series ends first.
+func nextInSeries(series *model.Series, after model.Book) *model.NextInSeries {
+	index, book, ok := series.NextAfter(*after.SeriesIndex)
+	if !ok {
+		return nil
+	}
+	return &model.NextInSeries{SeriesID: series.ID, Series: series.Name, AfterBookID: after.ID, AfterTitle: after.Title,
+		SeriesIndex: index, Book: book}
+}
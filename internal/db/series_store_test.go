@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestSeriesStore(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	// addToSeries adds a book and puts it in a series through its details, like the API does
+	addToSeries := func(title, series string, index int, status model.BookStatus) int64 {
+		id, err := store.AddBook(ctx, &model.Book{Title: title, Author: "A", OpenLibraryID: "OL-" + title, Status: status})
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", title, err)
+		}
+		if err := store.UpdateBookDetails(ctx, id, model.BookDetails{Series: &series, SeriesIndex: &index}); err != nil {
+			t.Fatalf("Failed to set series of %s: %v", title, err)
+		}
+		return id
+	}
+	addToSeries("One", "Discworld", 1, model.StatusRead)
+	addToSeries("Two", "discworld ", 2, model.StatusRead)
+	addToSeries("Four", "Discworld", 4, model.StatusRead)
+	typo := addToSeries("Three", "Diskworld", 3, model.StatusWantToRead)
+
+	seriesList, err := store.GetSeries(ctx)
+	if err != nil {
+		t.Fatalf("GetSeries failed: %v", err)
+	}
+	if len(seriesList) != 2 || seriesList[0].Name != "Discworld" || len(seriesList[0].Books) != 3 {
+		t.Fatalf("Expected Discworld with 3 books and Diskworld, got %+v", seriesList)
+	}
+	discworld, diskworld := seriesList[0], seriesList[1]
+	if discworld.Books[2].Title != "Four" || len(discworld.Gaps) != 1 || discworld.Gaps[0] != 3 {
+		t.Errorf("Expected books in order with a gap at 3, got %+v", discworld)
+	}
+
+	// Renaming onto an existing name is refused
+	if err := store.UpdateSeries(ctx, &model.Series{ID: diskworld.ID, Name: "DISCWORLD"}); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Expected ErrDuplicate renaming onto an existing series, got %v", err)
+	}
+
+	if err := store.MergeSeries(ctx, diskworld.ID, discworld.ID); err != nil {
+		t.Fatalf("MergeSeries failed: %v", err)
+	}
+	merged, err := store.GetSeriesByID(ctx, discworld.ID)
+	if err != nil {
+		t.Fatalf("GetSeriesByID failed: %v", err)
+	}
+	if len(merged.Books) != 4 || merged.Books[2].ID != typo || len(merged.Missing) != 0 {
+		t.Errorf("Expected the typo'd book at index 3 after merging, got %+v", merged)
+	}
+	book, _ := store.GetBookByID(ctx, typo)
+	if book.Series == nil || *book.Series != "Discworld" || *book.SeriesID != discworld.ID {
+		t.Errorf("Expected the book to carry the merged series name, got %v", book.Series)
+	}
+	if _, err := store.GetSeriesByID(ctx, diskworld.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the merged series to be deleted, got %v", err)
+	}
+
+	total := 5
+	if err := store.UpdateSeries(ctx, &model.Series{ID: discworld.ID, Name: "The Discworld", TotalBooks: &total}); err != nil {
+		t.Fatalf("UpdateSeries failed: %v", err)
+	}
+	renamed, _ := store.GetSeriesByID(ctx, discworld.ID)
+	if *renamed.Books[0].Series != "The Discworld" || len(renamed.Missing) != 1 || renamed.Missing[0] != 5 {
+		t.Errorf("Expected renamed series missing book 5, got %+v", renamed)
+	}
+
+	if err := store.MergeSeries(ctx, 999, discworld.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound merging a missing series, got %v", err)
+	}
+}
+
+func TestNextInSeries(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	add := func(title, series string, index int, status model.BookStatus) int64 {
+		id, err := store.AddBook(ctx, &model.Book{Title: title, Author: "Ursula K. Le Guin", OpenLibraryID: "OL-" + title,
+			Status: status, Series: &series, SeriesIndex: &index})
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", title, err)
+		}
+		return id
+	}
+	first := add("A Wizard of Earthsea", "Earthsea", 1, model.StatusRead)
+	// Added with a series, a book joins it like through its details
+	tombs := add("The Tombs of Atuan", "earthsea", 2, model.StatusWantToRead)
+	add("Dune", "Dune", 1, model.StatusWantToRead)
+	unnumbered, err := store.AddBook(ctx, &model.Book{Title: "Tales", Author: "A", OpenLibraryID: "OL-tales", Status: model.StatusRead})
+	if err != nil {
+		t.Fatalf("AddBook failed: %v", err)
+	}
+
+	next, err := store.GetNextInSeries(ctx, first)
+	if err != nil {
+		t.Fatalf("GetNextInSeries failed: %v", err)
+	}
+	if next == nil || next.Series != "Earthsea" || next.SeriesIndex != 2 || next.Book == nil || next.Book.ID != tombs || next.AfterBookID != first {
+		t.Fatalf("Expected The Tombs of Atuan on the shelf, got %+v", next)
+	}
+
+	if err := store.UpdateBookStatus(ctx, tombs, model.StatusRead); err != nil {
+		t.Fatalf("UpdateBookStatus failed: %v", err)
+	}
+	list, err := store.ListNextInSeries(ctx)
+	if err != nil {
+		t.Fatalf("ListNextInSeries failed: %v", err)
+	}
+	// Dune is not started, so only Earthsea has a next book, after the highest one read
+	if len(list) != 1 || list[0].AfterBookID != tombs || list[0].SeriesIndex != 3 || list[0].Book != nil {
+		t.Fatalf("Expected book 3 of Earthsea, not on the shelf, got %+v", list)
+	}
+
+	length := 2
+	if err := store.UpdateSeries(ctx, &model.Series{ID: list[0].SeriesID, Name: "Earthsea", TotalBooks: &length}); err != nil {
+		t.Fatalf("UpdateSeries failed: %v", err)
+	}
+	if next, err := store.GetNextInSeries(ctx, first); err != nil || next != nil {
+		t.Errorf("Expected no next book of a series read to its end, got %+v %v", next, err)
+	}
+
+	var validationErr *model.ValidationError
+	if _, err := store.GetNextInSeries(ctx, unnumbered); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a validation error for a book outside any series, got %v", err)
+	}
+	if _, err := store.GetNextInSeries(ctx, 999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/metrics"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// StatsStore computes aggregate reading statistics.
+type StatsStore interface {
+	GetStats(ctx context.Context) (*model.Stats, error)
+}
+
+// topListSize is the number of entries returned in the top authors/series lists.
+const topListSize = 10
+
+// finishedCTE selects every move to Read recorded in the status history of books not in the
+// trash, with the book's type. A finish keeps counting after the book is moved off Read, and
+// a book read twice is finished twice; undoing a move to Read removes its history row.
+// Goal progress (CountFinished) counts the same finishes, so both agree.
+const finishedCTE = `
+    WITH finished AS (
+        SELECT h.book_id, h.changed_at AS finished_at, b.type AS book_type
+        FROM book_status_history h
+        JOIN books b ON b.id = h.book_id
+        WHERE h.to_status = 'Read' AND b.deleted_at IS NULL
+    )`
+
+// GetStats computes reading statistics from the books table and the status history.
+func (s *SQLiteBookStore) GetStats(ctx context.Context) (*model.Stats, error) {
+	defer metrics.ObserveDBQuery("GetStats", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+	logger.Info("SQL: Executing GetStats queries")
+
+	stats := &model.Stats{
+		FinishedPerMonth:   []model.PeriodCount{},
+		FinishedPerYear:    []model.PeriodCount{},
+		RatingDistribution: []model.RatingCount{},
+		TopAuthors:         []model.AuthorStat{},
+		TopSeries:          []model.SeriesStat{},
+		ByType:             []model.TypeStat{},
+	}
+
+	err := s.DB.QueryRowContext(ctx, `
+        SELECT COUNT(*), COUNT(CASE WHEN status = 'Read' THEN 1 END), AVG(rating)
+        FROM books WHERE deleted_at IS NULL;`).Scan(&stats.TotalBooks, &stats.FinishedBooks, &stats.AverageRating)
+	if err != nil {
+		logger.Error("SQL Error: Querying book totals failed", "error", err)
+		return nil, fmt.Errorf("failed to query book totals: %w", err)
+	}
+
+	for _, period := range []struct {
+		format string
+		dest   *[]model.PeriodCount
+	}{
+		{"%Y-%m", &stats.FinishedPerMonth},
+		{"%Y", &stats.FinishedPerYear},
+	} {
+		err := s.queryRows(ctx, finishedCTE+`
+            SELECT strftime(?, finished_at) AS period, COUNT(*)
+            FROM finished GROUP BY period ORDER BY period;`,
+			[]interface{}{period.format}, func(rows *sql.Rows) error {
+				var pc model.PeriodCount
+				if err := rows.Scan(&pc.Period, &pc.Count); err != nil {
+					return err
+				}
+				*period.dest = append(*period.dest, pc)
+				return nil
+			})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query books finished per period: %w", err)
+		}
+	}
+
+	err = s.queryRows(ctx, `
+        SELECT rating, COUNT(*) FROM books WHERE rating IS NOT NULL AND deleted_at IS NULL
+        GROUP BY rating ORDER BY rating;`, nil, func(rows *sql.Rows) error {
+		var rc model.RatingCount
+		if err := rows.Scan(&rc.Rating, &rc.Count); err != nil {
+			return err
+		}
+		stats.RatingDistribution = append(stats.RatingDistribution, rc)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rating distribution: %w", err)
+	}
+
+	// Co-written books count for each of their authors; narrators and translators are left out
+	err = s.queryRows(ctx, `
+        SELECT a.id, a.name, COUNT(*) AS finished, AVG(b.rating)
+        FROM book_authors ba
+        JOIN authors a ON a.id = ba.author_id
+        JOIN books b ON b.id = ba.book_id
+        WHERE ba.role = 'author' AND b.status = 'Read' AND b.deleted_at IS NULL
+        GROUP BY a.id ORDER BY finished DESC, a.name LIMIT ?;`, []interface{}{topListSize}, func(rows *sql.Rows) error {
+		var as model.AuthorStat
+		if err := rows.Scan(&as.AuthorID, &as.Author, &as.Count, &as.AverageRating); err != nil {
+			return err
+		}
+		stats.TopAuthors = append(stats.TopAuthors, as)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top authors: %w", err)
+	}
+
+	err = s.queryRows(ctx, `
+        SELECT series, COUNT(*) AS finished, AVG(rating)
+        FROM books WHERE status = 'Read' AND series IS NOT NULL AND series != '' AND deleted_at IS NULL
+        GROUP BY series ORDER BY finished DESC, series LIMIT ?;`, []interface{}{topListSize}, func(rows *sql.Rows) error {
+		var ss model.SeriesStat
+		if err := rows.Scan(&ss.Series, &ss.Count, &ss.AverageRating); err != nil {
+			return err
+		}
+		stats.TopSeries = append(stats.TopSeries, ss)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top series: %w", err)
+	}
+
+	err = s.queryRows(ctx, `
+        SELECT type, COUNT(*), COUNT(CASE WHEN status = 'Read' THEN 1 END)
+        FROM books WHERE deleted_at IS NULL GROUP BY type ORDER BY type;`, nil, func(rows *sql.Rows) error {
+		var ts model.TypeStat
+		if err := rows.Scan(&ts.Type, &ts.Total, &ts.Finished); err != nil {
+			return err
+		}
+		stats.ByType = append(stats.ByType, ts)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query type split: %w", err)
+	}
+
+	// Start is the last move to Currently Reading before the book was finished
+	err = s.DB.QueryRowContext(ctx, finishedCTE+`
+        SELECT AVG(julianday(f.finished_at) - julianday((
+            SELECT MAX(h.changed_at) FROM book_status_history h
+            WHERE h.book_id = f.book_id AND h.to_status = 'Currently Reading' AND h.changed_at <= f.finished_at
+        )))
+        FROM finished f;`).Scan(&stats.AverageDaysToFinish)
+	if err != nil {
+		logger.Error("SQL Error: Querying average time to finish failed", "error", err)
+		return nil, fmt.Errorf("failed to query average time to finish: %w", err)
+	}
+
+	// Streaks: number the months with at least one finished book; consecutive months share
+	// the same (month_index - row_number), which identifies each run ("gaps and islands").
+	now := time.Now().UTC()
+	currentMonth := now.Year()*12 + int(now.Month()) - 1
+	err = s.DB.QueryRowContext(ctx, finishedCTE+`,
+    months AS (
+        SELECT DISTINCT CAST(strftime('%Y', finished_at) AS INTEGER) * 12
+            + CAST(strftime('%m', finished_at) AS INTEGER) - 1 AS month_index
+        FROM finished
+    ),
+    islands AS (
+        SELECT month_index, month_index - ROW_NUMBER() OVER (ORDER BY month_index) AS island
+        FROM months
+    ),
+    streaks AS (
+        SELECT COUNT(*) AS length, MAX(month_index) AS last_month FROM islands GROUP BY island
+    )
+    SELECT
+        COALESCE((SELECT length FROM streaks WHERE last_month >= ? - 1 ORDER BY last_month DESC LIMIT 1), 0),
+        COALESCE((SELECT MAX(length) FROM streaks), 0);`, currentMonth).Scan(&stats.CurrentStreakMonths, &stats.LongestStreakMonths)
+	if err != nil {
+		logger.Error("SQL Error: Querying reading streaks failed", "error", err)
+		return nil, fmt.Errorf("failed to query reading streaks: %w", err)
+	}
+
+	return stats, nil
+}
+
+// queryRows runs query and calls scan for every row.
+func (s *SQLiteBookStore) queryRows(ctx context.Context, query string, args []interface{}, scan func(*sql.Rows) error) error {
+	logger := logging.FromContext(ctx)
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.Error("SQL Error: Executing query failed", "error", err)
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			logger.Error("SQL Error: Scanning row failed", "error", err)
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("SQL Error: Error during row iteration", "error", err)
+		return err
+	}
+	return nil
+}
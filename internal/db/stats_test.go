@@ -0,0 +1,219 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// historyEntry is a backdated status transition for setHistory.
+type historyEntry struct {
+	status model.BookStatus
+	at     time.Time
+}
+
+// setHistory replaces the recorded status history of a book.
+func setHistory(t *testing.T, db *sql.DB, bookID int64, entries ...historyEntry) {
+	t.Helper()
+	if _, err := db.Exec(`DELETE FROM book_status_history WHERE book_id = ?;`, bookID); err != nil {
+		t.Fatalf("Failed to clear history: %v", err)
+	}
+	for _, e := range entries {
+		_, err := db.Exec(`INSERT INTO book_status_history (book_id, to_status, changed_at) VALUES (?, ?, ?);`,
+			bookID, e.status, e.at.UTC().Format("2006-01-02 15:04:05"))
+		if err != nil {
+			t.Fatalf("Failed to insert history: %v", err)
+		}
+	}
+}
+
+func TestStatusHistoryRecorded(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	id, err := store.AddBook(ctx, createTestBook())
+	if err != nil {
+		t.Fatalf("Failed to add book: %v", err)
+	}
+	for _, status := range []model.BookStatus{model.StatusCurrentlyReading, model.StatusCurrentlyReading, model.StatusRead} {
+		if err := store.UpdateBookStatus(ctx, id, status); err != nil {
+			t.Fatalf("Failed to update status: %v", err)
+		}
+	}
+
+	rows, err := db.Query(`SELECT COALESCE(from_status, ''), to_status FROM book_status_history WHERE book_id = ? ORDER BY id;`, id)
+	if err != nil {
+		t.Fatalf("Failed to query history: %v", err)
+	}
+	defer rows.Close()
+	var got [][2]string
+	for rows.Next() {
+		var from, to string
+		if err := rows.Scan(&from, &to); err != nil {
+			t.Fatalf("Failed to scan history: %v", err)
+		}
+		got = append(got, [2]string{from, to})
+	}
+	// Setting the same status twice is not a transition
+	want := [][2]string{
+		{"", "Want to Read"},
+		{"Want to Read", "Currently Reading"},
+		{"Currently Reading", "Read"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected history %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("History entry %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	add := func(title, author string, status model.BookStatus, bookType model.BookType, rating int, series string) int64 {
+		book := &model.Book{Title: title, Author: author, OpenLibraryID: "OL-" + title, Status: model.StatusWantToRead, Type: bookType}
+		id, err := store.AddBook(ctx, book)
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", title, err)
+		}
+		if status != model.StatusWantToRead {
+			if err := store.UpdateBookStatus(ctx, id, status); err != nil {
+				t.Fatalf("Failed to set status of %s: %v", title, err)
+			}
+		}
+		var r *int
+		if rating > 0 {
+			r = &rating
+		}
+		var sr *string
+		if series != "" {
+			sr = &series
+		}
+		if err := store.UpdateBookDetails(ctx, id, model.BookDetails{Rating: r, Series: sr}); err != nil {
+			t.Fatalf("Failed to set details of %s: %v", title, err)
+		}
+		return id
+	}
+
+	now := time.Now().UTC()
+	month := func(monthsAgo, day int) time.Time {
+		return time.Date(now.Year(), now.Month()-time.Month(monthsAgo), day, 12, 0, 0, 0, time.UTC)
+	}
+
+	a := add("A", "Xavier", model.StatusRead, model.TypeBook, 8, "Saga")
+	setHistory(t, db, a, historyEntry{model.StatusCurrentlyReading, month(2, 1)}, historyEntry{model.StatusRead, month(2, 11)})
+	b := add("B", "Xavier", model.StatusRead, model.TypeAudiobook, 6, "")
+	setHistory(t, db, b, historyEntry{model.StatusRead, month(1, 5)})
+	c := add("C", "Yvonne", model.StatusRead, model.TypeBook, 8, "")
+	setHistory(t, db, c, historyEntry{model.StatusCurrentlyReading, month(0, 1)}, historyEntry{model.StatusRead, month(0, 3)})
+	f := add("F", "Zed", model.StatusRead, model.TypeBook, 0, "")
+	setHistory(t, db, f, historyEntry{model.StatusRead, month(4, 20)})
+	add("D", "Yvonne", model.StatusWantToRead, model.TypeBook, 0, "")
+	// Moved back from Read: on no shelf count, but still finished in its month
+	e := add("E", "Zed", model.StatusCurrentlyReading, model.TypeBook, 0, "")
+	setHistory(t, db, e, historyEntry{model.StatusRead, month(3, 1)}, historyEntry{model.StatusCurrentlyReading, month(3, 2)})
+
+	stats, err := store.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+
+	if stats.TotalBooks != 6 || stats.FinishedBooks != 4 {
+		t.Errorf("Expected 6 books, 4 finished; got %d, %d", stats.TotalBooks, stats.FinishedBooks)
+	}
+	if len(stats.FinishedPerMonth) != 5 {
+		t.Errorf("Expected 5 months with finished books, got %+v", stats.FinishedPerMonth)
+	} else if stats.FinishedPerMonth[4].Period != now.Format("2006-01") {
+		t.Errorf("Expected last month to be %s, got %s", now.Format("2006-01"), stats.FinishedPerMonth[4].Period)
+	}
+	perYear := 0
+	for _, pc := range stats.FinishedPerYear {
+		perYear += pc.Count
+	}
+	if perYear != 5 {
+		t.Errorf("Expected 5 finishes across years, got %+v", stats.FinishedPerYear)
+	}
+
+	if stats.AverageRating == nil || math.Abs(*stats.AverageRating-22.0/3) > 1e-9 {
+		t.Errorf("Expected average rating 7.33, got %v", stats.AverageRating)
+	}
+	wantDist := []model.RatingCount{{Rating: 6, Count: 1}, {Rating: 8, Count: 2}}
+	if len(stats.RatingDistribution) != 2 || stats.RatingDistribution[0] != wantDist[0] || stats.RatingDistribution[1] != wantDist[1] {
+		t.Errorf("Expected rating distribution %v, got %v", wantDist, stats.RatingDistribution)
+	}
+
+	if len(stats.TopAuthors) == 0 || stats.TopAuthors[0].Author != "Xavier" || stats.TopAuthors[0].Count != 2 {
+		t.Errorf("Expected Xavier as top author with 2 books, got %+v", stats.TopAuthors)
+	}
+	if len(stats.TopSeries) != 1 || stats.TopSeries[0].Series != "Saga" {
+		t.Errorf("Expected Saga as only series, got %+v", stats.TopSeries)
+	}
+
+	wantTypes := []model.TypeStat{
+		{Type: model.TypeAudiobook, Total: 1, Finished: 1},
+		{Type: model.TypeBook, Total: 5, Finished: 3},
+	}
+	if len(stats.ByType) != 2 || stats.ByType[0] != wantTypes[0] || stats.ByType[1] != wantTypes[1] {
+		t.Errorf("Expected type split %v, got %v", wantTypes, stats.ByType)
+	}
+
+	if stats.AverageDaysToFinish == nil || math.Abs(*stats.AverageDaysToFinish-6) > 1e-6 {
+		t.Errorf("Expected 6 average days to finish, got %v", stats.AverageDaysToFinish)
+	}
+
+	if stats.CurrentStreakMonths != 5 || stats.LongestStreakMonths != 5 {
+		t.Errorf("Expected current and longest streak of 5 months, got %d and %d", stats.CurrentStreakMonths, stats.LongestStreakMonths)
+	}
+}
+
+func TestGetStatsTopAuthorsCoWritten(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	for _, book := range []model.Book{
+		{Title: "Good Omens", Author: "Terry Pratchett, Neil Gaiman", OpenLibraryID: "OL1W", Status: model.StatusRead},
+		{Title: "Mort", Author: "Terry Pratchett", OpenLibraryID: "OL2W", Status: model.StatusRead},
+		{Title: "Coraline", Author: "Neil Gaiman", OpenLibraryID: "OL3W", Status: model.StatusWantToRead},
+	} {
+		if _, err := store.AddBook(ctx, &book); err != nil {
+			t.Fatalf("Failed to add %s: %v", book.Title, err)
+		}
+	}
+
+	stats, err := store.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	top := stats.TopAuthors
+	if len(top) != 2 || top[0].Author != "Terry Pratchett" || top[0].Count != 2 || top[0].AuthorID == 0 ||
+		top[1].Author != "Neil Gaiman" || top[1].Count != 1 {
+		t.Errorf("Expected the co-written book to count for both authors, got %+v", top)
+	}
+}
+
+func TestGetStatsEmpty(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+
+	stats, err := store.GetStats(context.Background())
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	if stats.TotalBooks != 0 || stats.AverageRating != nil || stats.AverageDaysToFinish != nil || stats.CurrentStreakMonths != 0 {
+		t.Errorf("Expected empty stats, got %+v", stats)
+	}
+	if stats.FinishedPerMonth == nil || stats.TopAuthors == nil {
+		t.Error("Expected empty lists rather than nil, so they encode as []")
+	}
+}
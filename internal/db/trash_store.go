@@ -0,0 +1,157 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/events"
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/metrics"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// TrashStore defines the interface for books deleted with DeleteBook.
+type TrashStore interface {
+	GetTrash(ctx context.Context) ([]model.Book, error)
+	RestoreBook(ctx context.Context, id int64) error
+	PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error)
+}
+
+// GetTrash retrieves the books in the trash, most recently deleted first.
+func (s *SQLiteBookStore) GetTrash(ctx context.Context) ([]model.Book, error) {
+	return s.ListBooks(ctx, ListOptions{Trashed: true, SortBy: "deleted_at", Descending: true})
+}
+
+// RestoreBook takes a book out of the trash and puts it back at the end of its shelf.
+// Returns ErrNotFound if the book is not in the trash.
+func (s *SQLiteBookStore) RestoreBook(ctx context.Context, id int64) error {
+	defer metrics.ObserveDBQuery("RestoreBook", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	logger.Info("SQL: Executing RestoreBook query", "id", id)
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := readBook(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `
+        UPDATE books SET deleted_at = NULL, version = version + 1,
+            position = (SELECT COALESCE(MAX(b2.position), 0) + 1 FROM books b2 WHERE b2.status = books.status AND b2.deleted_at IS NULL)
+        WHERE id = ? AND deleted_at IS NOT NULL AND (? IS NULL OR version = ?);`,
+		id, expectedVersion(ctx), expectedVersion(ctx))
+	if err != nil {
+		logger.Error("SQL Error: Executing RestoreBook statement failed", "error", err)
+		return fmt.Errorf("failed to restore book: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		var trashed bool
+		err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM books WHERE id = ? AND deleted_at IS NOT NULL);`, id).Scan(&trashed)
+		if err != nil {
+			return fmt.Errorf("failed to check book existence: %w", err)
+		}
+		if trashed {
+			return fmt.Errorf("book with ID %d: %w", id, ErrConflict)
+		}
+		return fmt.Errorf("book with ID %d in the trash: %w", id, ErrNotFound)
+	}
+	if err := recordAudit(ctx, tx, model.AuditRestore, id, before); err != nil {
+		logger.Error("SQL Error: Recording audit entry failed", "error", err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("SQL Error: Committing RestoreBook transaction failed", "error", err)
+		return fmt.Errorf("failed to commit restore: %w", err)
+	}
+
+	logger.Info("SQL: Successfully restored book", "id", id)
+	// The book reappears on its shelf, so to listeners it is as if it had just been added
+	s.publish(events.BookCreated, id, nil, s.snapshot(ctx, id))
+	return nil
+}
+
+// PurgeTrash permanently deletes the books that went to the trash before deletedBefore,
+// along with their credits, tags, editions, quotes, loans and status history. It returns the number of books purged.
+func (s *SQLiteBookStore) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	defer metrics.ObserveDBQuery("PurgeTrash", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	logger.Info("SQL: Executing PurgeTrash query", "deletedBefore", deletedBefore)
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var ids []int64
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM books WHERE deleted_at IS NOT NULL AND deleted_at < ? ORDER BY id;`,
+		sqlTimestamp(&deletedBefore))
+	if err != nil {
+		logger.Error("SQL Error: Querying books to purge failed", "error", err)
+		return 0, fmt.Errorf("failed to query trash: %w", err)
+	}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan trashed book: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to query trash: %w", err)
+	}
+
+	for _, id := range ids {
+		before, err := readBook(ctx, tx, id)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM books WHERE id = ?;`, id); err != nil {
+			logger.Error("SQL Error: Purging book failed", "id", id, "error", err)
+			return 0, fmt.Errorf("failed to purge book %d: %w", id, err)
+		}
+		if err := recordAudit(ctx, tx, model.AuditPurge, id, before); err != nil {
+			logger.Error("SQL Error: Recording audit entry failed", "error", err)
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("SQL Error: Committing PurgeTrash transaction failed", "error", err)
+		return 0, fmt.Errorf("failed to commit purge: %w", err)
+	}
+	logger.Info("SQL: Purged trash", "count", len(ids))
+	return int64(len(ids)), nil
+}
+
+// RunTrashPurge purges books that have been in the trash for longer than retention, once at
+// start and then every interval, until ctx is cancelled.
+func RunTrashPurge(ctx context.Context, store TrashStore, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := store.PurgeTrash(ctx, time.Now().Add(-retention)); err != nil {
+			logging.FromContext(ctx).Error("Purging the trash failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestTrashStore(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	rating := 9
+	comments := "Loved it"
+	var ids []int64
+	for _, title := range []string{"A", "B", "C"} {
+		id, err := store.AddBook(ctx, &model.Book{Title: title, Author: "X", OpenLibraryID: "OL-" + title, Status: model.StatusRead})
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", title, err)
+		}
+		ids = append(ids, id)
+	}
+	if err := store.UpdateBookDetails(ctx, ids[0], model.BookDetails{Rating: &rating, Comments: &comments}); err != nil {
+		t.Fatalf("UpdateBookDetails failed: %v", err)
+	}
+
+	if err := store.DeleteBook(ctx, ids[0]); err != nil {
+		t.Fatalf("DeleteBook failed: %v", err)
+	}
+	if _, err := store.GetBookByID(ctx, ids[0]); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a trashed book to be hidden, got %v", err)
+	}
+	if books, _ := store.GetBooks(ctx); len(books) != 2 {
+		t.Errorf("Expected 2 books on the shelves, got %d", len(books))
+	}
+	if stats, _ := store.GetStats(ctx); stats.TotalBooks != 2 || stats.FinishedBooks != 2 {
+		t.Errorf("Expected trashed books left out of the stats, got %+v", stats)
+	}
+	if err := store.UpdateBookStatus(ctx, ids[0], model.StatusWantToRead); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound changing a trashed book, got %v", err)
+	}
+	if err := store.DeleteBook(ctx, ids[0]); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting a trashed book again, got %v", err)
+	}
+	_, err := store.AddBook(ctx, &model.Book{Title: "A", Author: "X", OpenLibraryID: "OL-A", Status: model.StatusRead})
+	if !errors.Is(err, ErrDuplicate) || !strings.Contains(err.Error(), "trash") {
+		t.Errorf("Expected ErrDuplicate pointing at the trash, got %v", err)
+	}
+
+	trash, err := store.GetTrash(ctx)
+	if err != nil {
+		t.Fatalf("GetTrash failed: %v", err)
+	}
+	if len(trash) != 1 || trash[0].DeletedAt == nil || *trash[0].Rating != 9 {
+		t.Fatalf("Expected the trashed book with its rating, got %+v", trash)
+	}
+
+	if err := store.RestoreBook(ctx, ids[0]); err != nil {
+		t.Fatalf("RestoreBook failed: %v", err)
+	}
+	book, err := store.GetBookByID(ctx, ids[0])
+	last, _ := store.GetBookByID(ctx, ids[2])
+	if err != nil || *book.Comments != comments || book.Position <= last.Position || book.DeletedAt != nil {
+		t.Errorf("Expected the book restored to the end of its shelf, got %+v (%v)", book, err)
+	}
+	if err := store.RestoreBook(ctx, ids[0]); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound restoring a book not in the trash, got %v", err)
+	}
+
+	// Only books deleted before the cutoff are purged
+	store.DeleteBook(ctx, ids[1])
+	if purged, err := store.PurgeTrash(ctx, time.Now().Add(-time.Hour)); err != nil || purged != 0 {
+		t.Errorf("Expected nothing old enough to purge, got %d (%v)", purged, err)
+	}
+	if purged, err := store.PurgeTrash(ctx, time.Now().Add(time.Hour)); err != nil || purged != 1 {
+		t.Errorf("Expected one book purged, got %d (%v)", purged, err)
+	}
+	if err := store.RestoreBook(ctx, ids[1]); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a purged book to be gone, got %v", err)
+	}
+}
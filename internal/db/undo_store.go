@@ -0,0 +1,378 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/events"
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/metrics"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// UndoStore defines the interface for undoing and redoing a session's recent changes.
+// Status changes, moves, detail and type edits, deletions, merges and bulk operations made
+// with a context from WithSession can be undone, most recent first, and redone until the
+// session makes another change.
+type UndoStore interface {
+	Undo(ctx context.Context) (*model.UndoStep, error)
+	Redo(ctx context.Context) (*model.UndoStep, error)
+}
+
+// MaxUndoSteps is how many actions each session can undo.
+const MaxUndoSteps = 50
+
+// undoRetention is how long actions can be undone, so that abandoned sessions don't pile up.
+const undoRetention = 24 * time.Hour
+
+type sessionKey struct{}
+
+// WithSession returns a copy of ctx carrying the session (typically a browser tab) making
+// changes. Undoable mutations made with this context can be undone by the same session.
+func WithSession(ctx context.Context, session string) context.Context {
+	return context.WithValue(ctx, sessionKey{}, session)
+}
+
+// sessionFromContext returns the session set by WithSession, or "" if there is none.
+func sessionFromContext(ctx context.Context) string {
+	session, _ := ctx.Value(sessionKey{}).(string)
+	return session
+}
+
+// recordUndo records an undoable action of the context's session, given the books it changes
+// as they were before. The books after the change are read through q, which must be the
+// transaction making the change; books that did not change are left out. Nothing is recorded
+// without a session, or if nothing changed. A new action discards the actions that could be
+// redone.
+func recordUndo(ctx context.Context, q dbtx, action model.UndoAction, before ...*model.Book) error {
+	session := sessionFromContext(ctx)
+	if session == "" {
+		return nil
+	}
+
+	type change struct {
+		bookID        int64
+		before, after []byte
+	}
+	var changes []change
+	seen := make(map[int64]bool, len(before))
+	for _, book := range before {
+		if book == nil || seen[book.ID] {
+			continue
+		}
+		seen[book.ID] = true
+		after, err := readBook(ctx, q, book.ID)
+		if err != nil {
+			return err
+		}
+		if after == nil || sameUndoState(book, after) {
+			continue
+		}
+		b, err := json.Marshal(book)
+		if err != nil {
+			return fmt.Errorf("failed to encode book %d: %w", book.ID, err)
+		}
+		a, err := json.Marshal(after)
+		if err != nil {
+			return fmt.Errorf("failed to encode book %d: %w", book.ID, err)
+		}
+		changes = append(changes, change{bookID: book.ID, before: b, after: a})
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	if err := deleteUndoSteps(ctx, q, `session_id = ? AND undone = 1`, session); err != nil {
+		return err
+	}
+	res, err := q.ExecContext(ctx, `INSERT INTO undo_steps (session_id, action) VALUES (?, ?);`, session, action)
+	if err != nil {
+		return fmt.Errorf("failed to record undo step: %w", err)
+	}
+	stepID, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get undo step ID: %w", err)
+	}
+	for i, c := range changes {
+		_, err := q.ExecContext(ctx, `INSERT INTO undo_changes (step_id, seq, book_id, before, after) VALUES (?, ?, ?, ?, ?);`,
+			stepID, i, c.bookID, string(c.before), string(c.after))
+		if err != nil {
+			return fmt.Errorf("failed to record undo change: %w", err)
+		}
+	}
+
+	// Only the most recent actions of a session, and none from long ago, can be undone
+	err = deleteUndoSteps(ctx, q, `session_id = ? AND id <= (SELECT id FROM undo_steps WHERE session_id = ? ORDER BY id DESC LIMIT 1 OFFSET ?)`,
+		session, session, MaxUndoSteps)
+	if err != nil {
+		return err
+	}
+	return deleteUndoSteps(ctx, q, `created_at < ?`, sqlTimestamp(ptrTo(time.Now().Add(-undoRetention))))
+}
+
+// deleteUndoSteps deletes the undo steps matching where, and their changes.
+func deleteUndoSteps(ctx context.Context, q dbtx, where string, args ...interface{}) error {
+	if _, err := q.ExecContext(ctx, `DELETE FROM undo_changes WHERE step_id IN (SELECT id FROM undo_steps WHERE `+where+`);`, args...); err != nil {
+		return fmt.Errorf("failed to delete undo changes: %w", err)
+	}
+	if _, err := q.ExecContext(ctx, `DELETE FROM undo_steps WHERE `+where+`;`, args...); err != nil {
+		return fmt.Errorf("failed to delete undo steps: %w", err)
+	}
+	return nil
+}
+
+// undoState is the part of a book that undo restores.
+type undoState struct {
+	Status       model.BookStatus `json:"status"`
+	Type         model.BookType   `json:"type"`
+	Rating       *int             `json:"rating"`
+	Comments     *string          `json:"comments"`
+	Series       *string          `json:"series"`
+	SeriesIndex  *int             `json:"series_index"`
+	Position     int              `json:"position"`
+	DateAdded    *time.Time       `json:"date_added"`
+	DateStarted  *time.Time       `json:"date_started"`
+	DateFinished *time.Time       `json:"date_finished"`
+	Tags         []string         `json:"tags"`
+	DeletedAt    *time.Time       `json:"deleted_at"`
+}
+
+// sameUndoState reports whether two versions of a book agree on everything undo restores.
+func sameUndoState(a, b *model.Book) bool {
+	state := func(book *model.Book) []byte {
+		data, _ := json.Marshal(undoState{
+			Status: book.Status, Type: book.Type, Rating: book.Rating, Comments: book.Comments,
+			Series: book.Series, SeriesIndex: book.SeriesIndex, Position: book.Position,
+			DateAdded: book.DateAdded, DateStarted: book.DateStarted, DateFinished: book.DateFinished,
+			Tags: book.Tags, DeletedAt: book.DeletedAt,
+		})
+		return data
+	}
+	return bytes.Equal(state(a), state(b))
+}
+
+// Undo reverses the session's most recent action that has not been undone yet.
+// Returns ErrNotFound if there is nothing to undo, and ErrConflict (undoing nothing) if one
+// of the books has been changed since.
+func (s *SQLiteBookStore) Undo(ctx context.Context) (*model.UndoStep, error) {
+	defer metrics.ObserveDBQuery("Undo", time.Now())
+	return s.replayUndoStep(ctx, false)
+}
+
+// Redo makes the session's most recently undone action again.
+// Returns ErrNotFound if there is nothing to redo, and ErrConflict (redoing nothing) if one
+// of the books has been changed since.
+func (s *SQLiteBookStore) Redo(ctx context.Context) (*model.UndoStep, error) {
+	defer metrics.ObserveDBQuery("Redo", time.Now())
+	return s.replayUndoStep(ctx, true)
+}
+
+// undoChange is a stored change to one book.
+type undoChange struct {
+	before, after *model.Book
+}
+
+// replayUndoStep undoes (or, for redo, redoes) the next step of the context's session in a
+// single transaction.
+func (s *SQLiteBookStore) replayUndoStep(ctx context.Context, redo bool) (*model.UndoStep, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	verb := "undo"
+	if redo {
+		verb = "redo"
+	}
+	session := sessionFromContext(ctx)
+	if session == "" {
+		return nil, fmt.Errorf("validation failed: %w", &model.ValidationError{Message: "A session is required to " + verb})
+	}
+
+	// Undo takes the newest step not undone; redo the oldest undone step, i.e. the one
+	// undone last, since undone steps are always the newest
+	query := `SELECT id, action, created_at FROM undo_steps WHERE session_id = ? AND undone = 0 ORDER BY id DESC LIMIT 1;`
+	if redo {
+		query = `SELECT id, action, created_at FROM undo_steps WHERE session_id = ? AND undone = 1 ORDER BY id LIMIT 1;`
+	}
+	logger.Info("SQL: Executing "+verb, "session", session)
+	step := &model.UndoStep{}
+	err := s.DB.QueryRowContext(ctx, query, session).Scan(&step.ID, &step.Action, &step.Time)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("nothing to %s: %w", verb, ErrNotFound)
+	} else if err != nil {
+		logger.Error("SQL Error: Querying undo step failed", "error", err)
+		return nil, fmt.Errorf("failed to query undo step: %w", err)
+	}
+
+	var changes []undoChange
+	err = s.queryRows(ctx, `SELECT before, after FROM undo_changes WHERE step_id = ? ORDER BY seq;`, []interface{}{step.ID},
+		func(rows *sql.Rows) error {
+			var before, after string
+			if err := rows.Scan(&before, &after); err != nil {
+				return err
+			}
+			c := undoChange{before: &model.Book{}, after: &model.Book{}}
+			if err := json.Unmarshal([]byte(before), c.before); err != nil {
+				return err
+			}
+			if err := json.Unmarshal([]byte(after), c.after); err != nil {
+				return err
+			}
+			changes = append(changes, c)
+			return nil
+		})
+	if err != nil {
+		logger.Error("SQL Error: Querying undo changes failed", "error", err)
+		return nil, fmt.Errorf("failed to query undo changes: %w", err)
+	}
+	if !redo {
+		slices.Reverse(changes)
+	}
+
+	previous := make(map[int64]*model.Book, len(changes))
+	for _, c := range changes {
+		step.BookIDs = append(step.BookIDs, c.before.ID)
+		previous[c.before.ID] = s.snapshot(ctx, c.before.ID)
+	}
+	slices.Sort(step.BookIDs)
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Claim the step, so that two concurrent requests can't both replay it
+	res, err := tx.ExecContext(ctx, `UPDATE undo_steps SET undone = ? WHERE id = ? AND undone = ?;`, !redo, step.ID, redo)
+	if err != nil {
+		logger.Error("SQL Error: Updating undo step failed", "error", err)
+		return nil, fmt.Errorf("failed to update undo step: %w", err)
+	}
+	if rowsAffected, err := res.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	} else if rowsAffected == 0 {
+		return nil, fmt.Errorf("%s of %s: %w", verb, step.Action, ErrConflict)
+	}
+
+	for _, c := range changes {
+		from, to := c.after, c.before
+		if redo {
+			from, to = c.before, c.after
+		}
+		current, err := readBook(ctx, tx, from.ID)
+		if err != nil {
+			return nil, err
+		}
+		if current == nil || !sameUndoState(current, from) {
+			return nil, fmt.Errorf("cannot %s %s, book with ID %d has changed since: %w", verb, step.Action, from.ID, ErrConflict)
+		}
+		if err := restoreBookState(ctx, tx, current, to, !redo); err != nil {
+			logger.Error("SQL Error: Restoring book failed", "id", from.ID, "error", err)
+			return nil, err
+		}
+		action := model.AuditUpdate
+		switch {
+		case current.DeletedAt == nil && to.DeletedAt != nil:
+			action = model.AuditDelete
+		case current.DeletedAt != nil && to.DeletedAt == nil:
+			action = model.AuditRestore
+		}
+		if err := recordAudit(ctx, tx, action, from.ID, current); err != nil {
+			logger.Error("SQL Error: Recording audit entry failed", "error", err)
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error("SQL Error: Committing "+verb+" transaction failed", "error", err)
+		return nil, fmt.Errorf("failed to commit %s: %w", verb, err)
+	}
+
+	logger.Info("SQL: Successfully replayed undo step", "verb", verb, "step", step.ID, "action", step.Action)
+	for _, c := range changes {
+		id := c.before.ID
+		book := s.snapshot(ctx, id)
+		switch {
+		case book == nil:
+			s.publish(events.BookDeleted, id, previous[id], nil)
+		case previous[id] == nil:
+			s.publish(events.BookCreated, id, nil, book)
+		default:
+			s.publish(events.BookUpdated, id, previous[id], book)
+		}
+	}
+	return step, nil
+}
+
+// restoreBookState writes the undoable state of book to (see undoState) over current. If the
+// book moves shelf, undo deletes the status change it reverses (see revertStatusChange) and
+// redo records the change again.
+func restoreBookState(ctx context.Context, q dbtx, current, to *model.Book, undo bool) error {
+	// Look the series up by name: the series the book was in may have been merged away since
+	series, seriesID, err := resolveSeries(ctx, q, to.Series)
+	if err != nil {
+		return err
+	}
+	_, err = q.ExecContext(ctx, `
+        UPDATE books SET status = ?, type = ?, rating = ?, comments = ?, series = ?, series_id = ?, series_index = ?,
+            position = ?, date_added = ?, date_started = ?, date_finished = ?, deleted_at = ?, version = version + 1
+        WHERE id = ?;`,
+		to.Status, to.Type, to.Rating, to.Comments, series, seriesID, to.SeriesIndex, to.Position,
+		sqlTimestamp(to.DateAdded), sqlTimestamp(to.DateStarted), sqlTimestamp(to.DateFinished), sqlTimestamp(to.DeletedAt),
+		current.ID)
+	if err != nil {
+		return fmt.Errorf("failed to restore book %d: %w", current.ID, err)
+	}
+	if current.Status != to.Status {
+		record := recordStatusChange
+		if undo {
+			record = revertStatusChange
+		}
+		if err := record(ctx, q, current.ID, current.Status, to.Status); err != nil {
+			return err
+		}
+	}
+
+	if slices.Equal(current.Tags, to.Tags) {
+		return nil
+	}
+	if _, err := q.ExecContext(ctx, `DELETE FROM book_tags WHERE book_id = ?;`, current.ID); err != nil {
+		return fmt.Errorf("failed to restore tags of book %d: %w", current.ID, err)
+	}
+	for _, tag := range to.Tags {
+		tagID, err := findOrCreateTag(ctx, q, tag)
+		if err != nil {
+			return err
+		}
+		if _, err := q.ExecContext(ctx, `INSERT INTO book_tags (book_id, tag_id) VALUES (?, ?);`, current.ID, tagID); err != nil {
+			return fmt.Errorf("failed to restore tags of book %d: %w", current.ID, err)
+		}
+	}
+	return nil
+}
+
+// revertStatusChange moves a book back from status from to status to in the history. If the
+// book's latest status change is the move from to to from, it is deleted as if it had never
+// happened, so that e.g. an undone move to Read does not count as a finish; otherwise the
+// move back is recorded.
+func revertStatusChange(ctx context.Context, q dbtx, bookID int64, from, to model.BookStatus) error {
+	res, err := q.ExecContext(ctx, `
+        DELETE FROM book_status_history
+        WHERE id = (SELECT id FROM book_status_history WHERE book_id = ? ORDER BY id DESC LIMIT 1)
+            AND from_status = ? AND to_status = ?;`, bookID, to, from)
+	if err != nil {
+		return fmt.Errorf("failed to revert status change: %w", err)
+	}
+	if rowsAffected, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	} else if rowsAffected == 0 {
+		return recordStatusChange(ctx, q, bookID, from, to)
+	}
+	return nil
+}
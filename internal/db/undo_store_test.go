@@ -0,0 +1,211 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestUndoStore(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := WithSession(context.Background(), "tab-1")
+
+	add := func(title string) int64 {
+		id, err := store.AddBook(ctx, &model.Book{Title: title, Author: "A", OpenLibraryID: "OL-" + title, Status: model.StatusWantToRead})
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", title, err)
+		}
+		return id
+	}
+	get := func(id int64) *model.Book {
+		t.Helper()
+		book, err := store.GetBookByID(context.Background(), id)
+		if err != nil {
+			t.Fatalf("GetBookByID(%d) failed: %v", id, err)
+		}
+		return book
+	}
+	a, b, c := add("A"), add("B"), add("C")
+
+	// Adding books is not undoable
+	if _, err := store.Undo(ctx); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected nothing to undo, got %v", err)
+	}
+
+	if err := store.UpdateBookStatus(ctx, a, model.StatusCurrentlyReading); err != nil {
+		t.Fatalf("UpdateBookStatus failed: %v", err)
+	}
+	step, err := store.Undo(ctx)
+	if err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if step.Action != model.UndoSetStatus || !slices.Equal(step.BookIDs, []int64{a}) {
+		t.Errorf("Expected the status change of book %d undone, got %+v", a, step)
+	}
+	if book := get(a); book.Status != model.StatusWantToRead || book.Position != 1 || book.DateStarted != nil {
+		t.Errorf("Expected the book back first in Want to Read, not started, got %+v", book)
+	}
+	if _, err := store.Redo(ctx); err != nil {
+		t.Fatalf("Redo failed: %v", err)
+	}
+	if book := get(a); book.Status != model.StatusCurrentlyReading || book.DateStarted == nil {
+		t.Errorf("Expected the book started again, got %+v", book)
+	}
+	if _, err := store.Redo(ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected nothing to redo, got %v", err)
+	}
+
+	// Another session can't undo this one's changes, and changes it made since block the undo
+	other := WithSession(context.Background(), "tab-2")
+	if _, err := store.Undo(other); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected nothing to undo in another session, got %v", err)
+	}
+	if err := store.UpdateBookStatus(other, a, model.StatusRead); err != nil {
+		t.Fatalf("UpdateBookStatus failed: %v", err)
+	}
+	if _, err := store.Undo(ctx); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict undoing a book changed since, got %v", err)
+	}
+	if book := get(a); book.Status != model.StatusRead {
+		t.Errorf("Expected a failed undo to change nothing, got %q", book.Status)
+	}
+	if _, err := store.Undo(other); err != nil {
+		t.Fatalf("Undo in the other session failed: %v", err)
+	}
+
+	// Moves restore the whole shelf order
+	if err := store.MoveBook(ctx, c, b, false); err != nil {
+		t.Fatalf("MoveBook failed: %v", err)
+	}
+	if _, err := store.Undo(ctx); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if get(b).Position >= get(c).Position {
+		t.Errorf("Expected B before C again, got %d and %d", get(b).Position, get(c).Position)
+	}
+
+	// Deletions and bulk operations
+	if err := store.DeleteBook(ctx, b); err != nil {
+		t.Fatalf("DeleteBook failed: %v", err)
+	}
+	if _, err := store.ApplyBulk(ctx, model.BulkOperation{Operation: model.BulkAddTag, IDs: []int64{a, c}, Tag: "favorites"}); err != nil {
+		t.Fatalf("ApplyBulk failed: %v", err)
+	}
+	step, err = store.Undo(ctx)
+	if err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if step.Action != model.UndoAddTag || !slices.Equal(step.BookIDs, []int64{a, c}) {
+		t.Errorf("Expected the bulk tag undone for both books, got %+v", step)
+	}
+	if len(get(a).Tags) != 0 || len(get(c).Tags) != 0 {
+		t.Errorf("Expected the tags removed, got %v and %v", get(a).Tags, get(c).Tags)
+	}
+	if _, err := store.Undo(ctx); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if book := get(b); book.DeletedAt != nil {
+		t.Errorf("Expected the book out of the trash, got %+v", book)
+	}
+
+	// A new change discards what could be redone
+	if err := store.UpdateBookType(ctx, c, model.TypeAudiobook); err != nil {
+		t.Fatalf("UpdateBookType failed: %v", err)
+	}
+	if _, err := store.Redo(ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected nothing to redo after a new change, got %v", err)
+	}
+
+	// Only the most recent changes can be undone
+	for i := 0; i < MaxUndoSteps+1; i++ {
+		bookType := model.TypeBook
+		if i%2 == 1 {
+			bookType = model.TypeAudiobook
+		}
+		if err := store.UpdateBookType(ctx, c, bookType); err != nil {
+			t.Fatalf("UpdateBookType failed: %v", err)
+		}
+	}
+	for i := 0; i < MaxUndoSteps; i++ {
+		if _, err := store.Undo(ctx); err != nil {
+			t.Fatalf("Undo %d failed: %v", i+1, err)
+		}
+	}
+	if _, err := store.Undo(ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected only %d changes undoable, got %v", MaxUndoSteps, err)
+	}
+
+	var validationErr *model.ValidationError
+	if _, err := store.Undo(context.Background()); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a validation error without a session, got %v", err)
+	}
+}
+
+func TestUndoStatusChangeHistory(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := WithSession(context.Background(), "tab-1")
+
+	id, err := store.AddBook(ctx, &model.Book{Title: "A", Author: "A", OpenLibraryID: "OL-A", Status: model.StatusCurrentlyReading})
+	if err != nil {
+		t.Fatalf("Failed to add book: %v", err)
+	}
+	year := time.Now().Year()
+	finished := func() int {
+		t.Helper()
+		n, err := store.CountFinished(ctx, year, "")
+		if err != nil {
+			t.Fatalf("CountFinished failed: %v", err)
+		}
+		return n
+	}
+	history := func() int {
+		t.Helper()
+		var n int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM book_status_history WHERE book_id = ?;`, id).Scan(&n); err != nil {
+			t.Fatalf("Failed to count history: %v", err)
+		}
+		return n
+	}
+
+	// An accidental drag onto Read, undone, was never finished
+	if err := store.UpdateBookStatus(ctx, id, model.StatusRead); err != nil {
+		t.Fatalf("UpdateBookStatus failed: %v", err)
+	}
+	if n := finished(); n != 1 {
+		t.Fatalf("Expected the book finished, got %d", n)
+	}
+	if _, err := store.Undo(ctx); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if n := finished(); n != 0 {
+		t.Errorf("Expected the undone finish not to count towards goals, got %d", n)
+	}
+	if n := history(); n != 1 {
+		t.Errorf("Expected only the initial status in the history, got %d entries", n)
+	}
+
+	// Redo finishes it again
+	if _, err := store.Redo(ctx); err != nil {
+		t.Fatalf("Redo failed: %v", err)
+	}
+	if n := finished(); n != 1 {
+		t.Errorf("Expected the redone finish to count, got %d", n)
+	}
+
+	// Undoing a move off Read keeps the finish before it
+	if err := store.UpdateBookStatus(ctx, id, model.StatusWantToRead); err != nil {
+		t.Fatalf("UpdateBookStatus failed: %v", err)
+	}
+	if _, err := store.Undo(ctx); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if n := finished(); n != 1 || history() != 2 {
+		t.Errorf("Expected the earlier finish kept and the move off Read gone, got %d finished, %d entries", n, history())
+	}
+}
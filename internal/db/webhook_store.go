@@ -0,0 +1,257 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/logging"
+	"github.com/ericdahl/bookshelf/internal/metrics"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// WebhookStore defines the interface for database operations on webhook subscriptions
+// and their delivery log.
+type WebhookStore interface {
+	AddWebhook(ctx context.Context, webhook *model.Webhook) (int64, error)
+	GetWebhooks(ctx context.Context) ([]model.Webhook, error)
+	GetWebhookByID(ctx context.Context, id int64) (*model.Webhook, error)
+	DeleteWebhook(ctx context.Context, id int64) error
+	AddDelivery(ctx context.Context, delivery *model.WebhookDelivery) error
+	GetDeliveries(ctx context.Context, webhookID int64, limit int) ([]model.WebhookDelivery, error)
+}
+
+// webhookColumns lists the webhooks columns read by scanWebhook, in order.
+const webhookColumns = `id, url, events, secret, created_at`
+
+// scanWebhook reads a row selected with webhookColumns into a Webhook.
+func scanWebhook(row rowScanner) (*model.Webhook, error) {
+	var webhook model.Webhook
+	var events string
+	if err := row.Scan(&webhook.ID, &webhook.URL, &events, &webhook.Secret, &webhook.CreatedAt); err != nil {
+		return nil, err
+	}
+	webhook.Events = splitWebhookEvents(events)
+	return &webhook, nil
+}
+
+// joinWebhookEvents encodes an event filter as the comma-separated events column.
+func joinWebhookEvents(events []model.WebhookEvent) string {
+	names := make([]string, len(events))
+	for i, event := range events {
+		names[i] = string(event)
+	}
+	return strings.Join(names, ",")
+}
+
+// splitWebhookEvents decodes the comma-separated events column.
+func splitWebhookEvents(events string) []model.WebhookEvent {
+	result := []model.WebhookEvent{}
+	for _, name := range strings.Split(events, ",") {
+		if name != "" {
+			result = append(result, model.WebhookEvent(name))
+		}
+	}
+	return result
+}
+
+// AddWebhook inserts a new webhook subscription and sets its ID and CreatedAt.
+func (s *SQLiteBookStore) AddWebhook(ctx context.Context, webhook *model.Webhook) (int64, error) {
+	defer metrics.ObserveDBQuery("AddWebhook", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	if err := webhook.Validate(); err != nil {
+		return 0, fmt.Errorf("validation failed: %w", err)
+	}
+	if webhook.Secret == "" {
+		return 0, fmt.Errorf("webhook secret is required")
+	}
+
+	webhook.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	logger.Info("SQL: Executing AddWebhook query", "url", webhook.URL, "events", webhook.Events)
+	res, err := s.DB.ExecContext(ctx, `INSERT INTO webhooks (url, events, secret, created_at) VALUES (?, ?, ?, ?);`,
+		webhook.URL, joinWebhookEvents(webhook.Events), webhook.Secret, webhook.CreatedAt)
+	if err != nil {
+		logger.Error("SQL Error: Executing AddWebhook statement failed", "error", err)
+		return 0, fmt.Errorf("failed to insert webhook: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		logger.Error("SQL Error: Failed to get last insert ID", "error", err)
+		return 0, fmt.Errorf("failed to retrieve last insert ID: %w", err)
+	}
+	webhook.ID = id
+	logger.Info("SQL: Successfully added webhook", "id", id)
+	return id, nil
+}
+
+// GetWebhooks retrieves all webhook subscriptions, oldest first.
+func (s *SQLiteBookStore) GetWebhooks(ctx context.Context) ([]model.Webhook, error) {
+	defer metrics.ObserveDBQuery("GetWebhooks", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	logger.Debug("SQL: Executing GetWebhooks query")
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+webhookColumns+` FROM webhooks ORDER BY id;`)
+	if err != nil {
+		logger.Error("SQL Error: Executing GetWebhooks query failed", "error", err)
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []model.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			logger.Error("SQL Error: Scanning webhook row failed", "error", err)
+			return nil, fmt.Errorf("failed to scan webhook row: %w", err)
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+	if err = rows.Err(); err != nil {
+		logger.Error("SQL Error: Error during webhook iteration", "error", err)
+		return nil, fmt.Errorf("error iterating webhook rows: %w", err)
+	}
+	return webhooks, nil
+}
+
+// GetWebhookByID retrieves a single webhook subscription by its ID.
+func (s *SQLiteBookStore) GetWebhookByID(ctx context.Context, id int64) (*model.Webhook, error) {
+	defer metrics.ObserveDBQuery("GetWebhookByID", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	logger.Info("SQL: Executing GetWebhookByID query", "id", id)
+	webhook, err := scanWebhook(s.DB.QueryRowContext(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id = ?;`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("webhook with ID %d: %w", id, ErrNotFound)
+		}
+		logger.Error("SQL Error: Scanning webhook row failed", "id", id, "error", err)
+		return nil, fmt.Errorf("failed to scan webhook row for ID %d: %w", id, err)
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook removes a webhook subscription and its delivery log.
+func (s *SQLiteBookStore) DeleteWebhook(ctx context.Context, id int64) error {
+	defer metrics.ObserveDBQuery("DeleteWebhook", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	logger.Info("SQL: Executing DeleteWebhook query", "id", id)
+	// Deleted explicitly rather than relying on ON DELETE CASCADE, which needs foreign keys enabled
+	if _, err := tx.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE webhook_id = ?;`, id); err != nil {
+		logger.Error("SQL Error: Deleting webhook deliveries failed", "error", err)
+		return fmt.Errorf("failed to delete webhook deliveries: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM webhooks WHERE id = ?;`, id)
+	if err != nil {
+		logger.Error("SQL Error: Executing DeleteWebhook statement failed", "error", err)
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("webhook with ID %d: %w", id, ErrNotFound)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit webhook deletion: %w", err)
+	}
+	logger.Info("SQL: Successfully deleted webhook", "id", id)
+	return nil
+}
+
+// AddDelivery records a delivery attempt in the delivery log and sets its ID.
+func (s *SQLiteBookStore) AddDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	defer metrics.ObserveDBQuery("AddDelivery", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	logger.Debug("SQL: Executing AddDelivery query", "webhookID", delivery.WebhookID, "deliveryID", delivery.DeliveryID, "attempt", delivery.Attempt)
+	res, err := s.DB.ExecContext(ctx, `
+        INSERT INTO webhook_deliveries (webhook_id, delivery_id, event, attempt, status_code, error, success, duration_ms, delivered_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);`,
+		delivery.WebhookID, delivery.DeliveryID, delivery.Event, delivery.Attempt, delivery.StatusCode,
+		delivery.Error, delivery.Success, delivery.DurationMS, delivery.DeliveredAt)
+	if err != nil {
+		logger.Error("SQL Error: Executing AddDelivery statement failed", "error", err)
+		return fmt.Errorf("failed to insert webhook delivery: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve last insert ID: %w", err)
+	}
+	delivery.ID = id
+	return nil
+}
+
+// GetDeliveries retrieves the most recent delivery attempts for a webhook, newest first.
+// Returns ErrNotFound if the webhook does not exist.
+func (s *SQLiteBookStore) GetDeliveries(ctx context.Context, webhookID int64, limit int) ([]model.WebhookDelivery, error) {
+	defer metrics.ObserveDBQuery("GetDeliveries", time.Now())
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	var exists bool
+	if err := s.DB.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM webhooks WHERE id = ?);`, webhookID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check webhook existence: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("webhook with ID %d: %w", webhookID, ErrNotFound)
+	}
+
+	logger.Info("SQL: Executing GetDeliveries query", "webhookID", webhookID, "limit", limit)
+	rows, err := s.DB.QueryContext(ctx, `
+        SELECT id, webhook_id, delivery_id, event, attempt, status_code, error, success, duration_ms, delivered_at
+        FROM webhook_deliveries WHERE webhook_id = ? ORDER BY id DESC LIMIT ?;`, webhookID, limit)
+	if err != nil {
+		logger.Error("SQL Error: Executing GetDeliveries query failed", "error", err)
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []model.WebhookDelivery{}
+	for rows.Next() {
+		var delivery model.WebhookDelivery
+		var statusCode sql.NullInt64
+		var deliveryErr sql.NullString
+		if err := rows.Scan(&delivery.ID, &delivery.WebhookID, &delivery.DeliveryID, &delivery.Event, &delivery.Attempt,
+			&statusCode, &deliveryErr, &delivery.Success, &delivery.DurationMS, &delivery.DeliveredAt); err != nil {
+			logger.Error("SQL Error: Scanning webhook delivery row failed", "error", err)
+			return nil, fmt.Errorf("failed to scan webhook delivery row: %w", err)
+		}
+		if statusCode.Valid {
+			code := int(statusCode.Int64)
+			delivery.StatusCode = &code
+		}
+		if deliveryErr.Valid {
+			delivery.Error = &deliveryErr.String
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err = rows.Err(); err != nil {
+		logger.Error("SQL Error: Error during webhook delivery iteration", "error", err)
+		return nil, fmt.Errorf("error iterating webhook delivery rows: %w", err)
+	}
+	return deliveries, nil
+}
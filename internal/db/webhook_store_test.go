@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestWebhookStore(t *testing.T) {
+	db, store := setupTestDB(t)
+	defer teardownTestDB(db)
+	ctx := context.Background()
+
+	hook := &model.Webhook{
+		URL:    "https://example.com/hook",
+		Events: []model.WebhookEvent{model.WebhookBookAdded, model.WebhookBookRated},
+		Secret: "secret",
+	}
+	id, err := store.AddWebhook(ctx, hook)
+	if err != nil {
+		t.Fatalf("AddWebhook failed: %v", err)
+	}
+
+	got, err := store.GetWebhookByID(ctx, id)
+	if err != nil {
+		t.Fatalf("GetWebhookByID failed: %v", err)
+	}
+	if got.URL != hook.URL || got.Secret != "secret" || len(got.Events) != 2 || got.Events[1] != model.WebhookBookRated {
+		t.Errorf("Unexpected webhook: %+v", got)
+	}
+	if got.CreatedAt.IsZero() {
+		t.Error("Expected CreatedAt to be set")
+	}
+
+	if _, err := store.AddWebhook(ctx, &model.Webhook{URL: "ftp://example.com", Secret: "x"}); err == nil {
+		t.Error("Expected validation error for non-http URL")
+	}
+
+	status := 500
+	msg := "server error"
+	for attempt, delivery := range []model.WebhookDelivery{
+		{WebhookID: id, DeliveryID: "d1", Event: model.WebhookBookAdded, Attempt: 1, StatusCode: &status, Error: &msg},
+		{WebhookID: id, DeliveryID: "d1", Event: model.WebhookBookAdded, Attempt: 2, Success: true},
+	} {
+		delivery.DeliveredAt = time.Now().UTC()
+		if err := store.AddDelivery(ctx, &delivery); err != nil {
+			t.Fatalf("AddDelivery %d failed: %v", attempt, err)
+		}
+	}
+
+	deliveries, err := store.GetDeliveries(ctx, id, 10)
+	if err != nil {
+		t.Fatalf("GetDeliveries failed: %v", err)
+	}
+	if len(deliveries) != 2 || deliveries[0].Attempt != 2 || !deliveries[0].Success {
+		t.Fatalf("Expected newest delivery first, got %+v", deliveries)
+	}
+	if deliveries[1].StatusCode == nil || *deliveries[1].StatusCode != 500 || deliveries[1].Error == nil {
+		t.Errorf("Expected status code and error on failed attempt, got %+v", deliveries[1])
+	}
+	if limited, _ := store.GetDeliveries(ctx, id, 1); len(limited) != 1 {
+		t.Errorf("Expected limit to apply, got %d deliveries", len(limited))
+	}
+
+	if err := store.DeleteWebhook(ctx, id); err != nil {
+		t.Fatalf("DeleteWebhook failed: %v", err)
+	}
+	if _, err := store.GetWebhookByID(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+	if _, err := store.GetDeliveries(ctx, id, 10); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for deliveries of deleted webhook, got %v", err)
+	}
+	if err := store.DeleteWebhook(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+// Package dedupe finds books on the shelf that are the same work entered more than once,
+// for example as two editions or once by hand and once from Open Library.
+package dedupe
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/ericdahl/bookshelf/internal/isbn"
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/textmatch"
+)
+
+// minTitleSimilarity is how similar (0-1) normalized titles must be for books to be
+// duplicates. It is stricter than matching Kindle titles, since both sides are on the shelf.
+const minTitleSimilarity = 0.9
+
+// Find groups the books that share an ISBN (of the book or any of its editions, in either
+// form), or whose titles are nearly the same and whose authors share a name. Titles with
+// different numbers ("Volume 1" and "Volume 2") and books at different positions of a series
+// are never duplicates by title. Groups are ordered by their oldest book.
+func Find(books []model.Book) []model.DuplicateGroup {
+	books = slices.Clone(books)
+	slices.SortFunc(books, func(a, b model.Book) int { return cmp.Compare(a.ID, b.ID) })
+
+	parent := make([]int, len(books))
+	for i := range parent {
+		parent[i] = i
+	}
+	var root func(i int) int
+	root = func(i int) int {
+		if parent[i] != i {
+			parent[i] = root(parent[i])
+		}
+		return parent[i]
+	}
+	byISBN := make([]bool, len(books))
+	union := func(i, j int, sameISBN bool) {
+		ri, rj := root(i), root(j)
+		if ri != rj {
+			// The oldest book stays the root
+			ri, rj = min(ri, rj), max(ri, rj)
+			parent[rj] = ri
+		}
+		byISBN[ri] = byISBN[ri] || byISBN[rj] || sameISBN
+	}
+
+	firstWithISBN := map[string]int{}
+	for i, book := range books {
+		for _, key := range isbnKeys(book) {
+			if j, ok := firstWithISBN[key]; ok {
+				union(j, i, true)
+			} else {
+				firstWithISBN[key] = i
+			}
+		}
+	}
+
+	titles := make([]string, len(books))
+	for i, book := range books {
+		titles[i] = textmatch.NormalizeTitle(book.Title)
+	}
+	for i := range books {
+		for j := i + 1; j < len(books); j++ {
+			if sameTitle(books[i], books[j], titles[i], titles[j]) {
+				union(i, j, false)
+			}
+		}
+	}
+
+	members := map[int][]model.Book{}
+	var roots []int
+	for i, book := range books {
+		r := root(i)
+		if members[r] == nil {
+			roots = append(roots, r)
+		}
+		members[r] = append(members[r], book)
+	}
+	groups := []model.DuplicateGroup{}
+	for _, r := range roots {
+		if len(members[r]) < 2 {
+			continue
+		}
+		reason := model.DuplicateTitle
+		if byISBN[r] {
+			reason = model.DuplicateISBN
+		}
+		groups = append(groups, model.DuplicateGroup{Reason: reason, Books: members[r]})
+	}
+	return groups
+}
+
+// isbnKeys returns the ISBN-13 form of the valid ISBNs of a book and its editions.
+func isbnKeys(book model.Book) []string {
+	var keys []string
+	if key := isbn.To13(book.ISBN); key != "" {
+		keys = append(keys, key)
+	}
+	for _, edition := range book.Editions {
+		if edition.ISBN == nil {
+			continue
+		}
+		if key := isbn.To13(*edition.ISBN); key != "" && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// sameTitle reports whether two books are duplicates by title, given their normalized titles.
+func sameTitle(a, b model.Book, titleA, titleB string) bool {
+	if titleA == "" || titleB == "" || !textmatch.SameAuthor(a.Author, b.Author) {
+		return false
+	}
+	if a.SeriesIndex != nil && b.SeriesIndex != nil && *a.SeriesIndex != *b.SeriesIndex {
+		return false
+	}
+	if numbers(titleA) != numbers(titleB) {
+		return false
+	}
+	// Titles whose lengths differ too much cannot be similar enough, and are not compared
+	la, lb := len([]rune(titleA)), len([]rune(titleB))
+	if float64(max(la, lb)-min(la, lb)) > (1-minTitleSimilarity)*float64(max(la, lb)) {
+		return false
+	}
+	return textmatch.Similarity(titleA, titleB) >= minTitleSimilarity
+}
+
+// numbers returns the digits of a title, separated by spaces where they were apart.
+func numbers(title string) string {
+	return strings.Join(strings.FieldsFunc(title, func(r rune) bool { return !unicode.IsDigit(r) }), " ")
+}
@@ -0,0 +1,52 @@
+package dedupe
+
+import (
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestFind(t *testing.T) {
+	isbn := func(s string) *string { return &s }
+	index := func(i int) *int { return &i }
+	books := []model.Book{
+		{ID: 7, Title: "Dune (Deluxe Edition)", Author: "Herbert, Frank"},
+		{ID: 1, Title: "Dune", Author: "Frank Herbert", ISBN: "0441172717"},
+		{ID: 3, Title: "Arrakis Chronicles", Author: "F. Herbert",
+			Editions: []model.Edition{{Format: model.TypeAudiobook, ISBN: isbn("978-0-441-17271-9")}}},
+		{ID: 2, Title: "Dune Messiah", Author: "Frank Herbert"},
+		{ID: 4, Title: "Emma", Author: "Jane Austen"},
+		{ID: 5, Title: "Emma", Author: "Someone Else"},
+		{ID: 8, Title: "The Pride and Prejudice", Author: "Jane Austen"},
+		{ID: 6, Title: "Pride & Prejudice", Author: "Austen"},
+		{ID: 9, Title: "Foundation Volume 1", Author: "Isaac Asimov"},
+		{ID: 10, Title: "Foundation Volume 2", Author: "Isaac Asimov"},
+		{ID: 11, Title: "Wheel of Time", Author: "Robert Jordan", SeriesIndex: index(1)},
+		{ID: 12, Title: "Wheel of Time", Author: "Robert Jordan", SeriesIndex: index(2)},
+	}
+	groups := Find(books)
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 groups, got %+v", groups)
+	}
+
+	dune := groups[0]
+	if dune.Reason != model.DuplicateISBN || len(dune.Books) != 3 {
+		t.Fatalf("Expected the Dunes grouped by ISBN, got %+v", dune)
+	}
+	for i, want := range []int64{1, 3, 7} {
+		if dune.Books[i].ID != want {
+			t.Errorf("Expected book %d at %d, oldest first, got %d", want, i, dune.Books[i].ID)
+		}
+	}
+
+	pride := groups[1]
+	if pride.Reason != model.DuplicateTitle || len(pride.Books) != 2 || pride.Books[0].ID != 6 || pride.Books[1].ID != 8 {
+		t.Errorf("Expected the two Pride and Prejudice grouped by title, got %+v", pride)
+	}
+}
+
+func TestFindNothing(t *testing.T) {
+	if groups := Find(nil); groups == nil || len(groups) != 0 {
+		t.Errorf("Expected an empty list, got %#v", groups)
+	}
+}
@@ -0,0 +1,149 @@
+// Package events provides an in-process publish/subscribe bus for shelf changes.
+// The BookStore publishes an Event after every successful mutation; consumers such as
+// the /api/events Server-Sent Events stream subscribe to it.
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// Type identifies the kind of change an Event describes.
+type Type string
+
+// Event types published by the BookStore.
+const (
+	BookCreated Type = "book.created"
+	BookUpdated Type = "book.updated"
+	BookDeleted Type = "book.deleted"
+)
+
+// SeriesNext suggests the next book of a series after one moved to Read. It is published
+// by the API once the book was found on the shelf or looked up with the metadata provider.
+const SeriesNext Type = "series.next"
+
+// Event describes a single change to the shelf.
+type Event struct {
+	// ID increases monotonically for the lifetime of the Bus; it is used as the SSE event id.
+	ID     uint64    `json:"id"`
+	Type   Type      `json:"type"`
+	BookID int64     `json:"book_id"`
+	Time   time.Time `json:"time"`
+	// Book is the book after the change; nil for deletions.
+	Book *model.Book `json:"book,omitempty"`
+	// Previous is the book before the change; nil for creations.
+	Previous *model.Book `json:"previous,omitempty"`
+	// Next is the suggestion of a series.next event, whose BookID is the book read.
+	Next *model.NextInSeries `json:"next,omitempty"`
+}
+
+// DefaultHistorySize is the number of recent events kept for Last-Event-ID replay.
+const DefaultHistorySize = 256
+
+// subscriberBuffer is the channel capacity given to each subscriber. A subscriber that
+// falls this far behind is dropped rather than blocking publishers.
+const subscriberBuffer = 64
+
+// Bus fans out published events to subscribers and keeps a bounded history so that
+// reconnecting clients can resume where they left off. A nil *Bus is valid and discards
+// everything published to it.
+type Bus struct {
+	mu          sync.Mutex
+	lastID      uint64
+	history     []Event // ring buffer, oldest first once full
+	historySize int
+	subscribers map[chan Event]struct{}
+}
+
+// NewBus creates a Bus remembering the last historySize events.
+func NewBus(historySize int) *Bus {
+	if historySize <= 0 {
+		historySize = DefaultHistorySize
+	}
+	return &Bus{
+		historySize: historySize,
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Publish assigns the event an ID and timestamp, records it in the history and delivers
+// it to every subscriber. It never blocks: subscribers whose buffer is full are
+// unsubscribed and their channel closed. Returns the event as delivered.
+func (b *Bus) Publish(e Event) Event {
+	if b == nil {
+		return e
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	e.ID = b.lastID
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	if len(b.history) < b.historySize {
+		b.history = append(b.history, e)
+	} else {
+		copy(b.history, b.history[1:])
+		b.history[len(b.history)-1] = e
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+	return e
+}
+
+// Subscribe registers a new subscriber. If lastEventID is non-zero, the events published
+// after it are returned as replay so the caller can send them before reading from the
+// channel; complete is false if some of those events are no longer in the history (or
+// lastEventID is from a previous process), in which case the caller should tell the client
+// to reload its full state. The returned cancel function must be called to unsubscribe.
+// The channel is closed if the subscriber falls too far behind.
+func (b *Bus) Subscribe(lastEventID uint64) (ch <-chan Event, replay []Event, complete bool, cancel func()) {
+	c := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	complete = true
+	if lastEventID > 0 {
+		switch {
+		case lastEventID > b.lastID:
+			complete = false
+		case len(b.history) > 0 && lastEventID < b.history[0].ID-1:
+			complete = false
+		}
+		for _, e := range b.history {
+			if e.ID > lastEventID {
+				replay = append(replay, e)
+			}
+		}
+	}
+
+	b.subscribers[c] = struct{}{}
+	cancel = func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[c]; ok {
+			delete(b.subscribers, c)
+			close(c)
+		}
+	}
+	return c, replay, complete, cancel
+}
+
+// LastID returns the ID of the most recently published event.
+func (b *Bus) LastID() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastID
+}
@@ -0,0 +1,89 @@
+package events
+
+import (
+	"testing"
+)
+
+func TestPublishSubscribe(t *testing.T) {
+	bus := NewBus(10)
+	ch, replay, complete, cancel := bus.Subscribe(0)
+	defer cancel()
+	if len(replay) != 0 || !complete {
+		t.Fatalf("Expected empty complete replay for new subscriber, got %d events (complete=%v)", len(replay), complete)
+	}
+
+	published := bus.Publish(Event{Type: BookCreated, BookID: 7})
+	if published.ID != 1 {
+		t.Errorf("Expected first event ID 1, got %d", published.ID)
+	}
+	if published.Time.IsZero() {
+		t.Error("Expected Publish to set the event time")
+	}
+
+	got := <-ch
+	if got.ID != 1 || got.Type != BookCreated || got.BookID != 7 {
+		t.Errorf("Unexpected event received: %+v", got)
+	}
+}
+
+func TestSubscribeReplay(t *testing.T) {
+	bus := NewBus(3)
+	for i := 0; i < 5; i++ {
+		bus.Publish(Event{Type: BookUpdated, BookID: int64(i)})
+	}
+
+	// History holds events 3, 4 and 5
+	_, replay, complete, cancel := bus.Subscribe(3)
+	cancel()
+	if !complete {
+		t.Error("Expected complete replay when resuming from a retained event")
+	}
+	if len(replay) != 2 || replay[0].ID != 4 || replay[1].ID != 5 {
+		t.Errorf("Expected replay of events 4 and 5, got %+v", replay)
+	}
+
+	// Resuming right before the oldest retained event is still complete
+	_, replay, complete, cancel = bus.Subscribe(2)
+	cancel()
+	if !complete || len(replay) != 3 {
+		t.Errorf("Expected complete replay of 3 events, got %d (complete=%v)", len(replay), complete)
+	}
+
+	// Event 2 has been evicted, so resuming from 1 misses it
+	_, _, complete, cancel = bus.Subscribe(1)
+	cancel()
+	if complete {
+		t.Error("Expected incomplete replay when events were evicted")
+	}
+
+	// An ID from the future (e.g. before a server restart) cannot be resumed
+	_, replay, complete, cancel = bus.Subscribe(42)
+	cancel()
+	if complete || len(replay) != 0 {
+		t.Errorf("Expected incomplete empty replay for unknown ID, got %d (complete=%v)", len(replay), complete)
+	}
+}
+
+func TestSlowSubscriberDropped(t *testing.T) {
+	bus := NewBus(1)
+	ch, _, _, cancel := bus.Subscribe(0)
+	defer cancel()
+
+	for i := 0; i < subscriberBuffer+1; i++ {
+		bus.Publish(Event{Type: BookUpdated})
+	}
+
+	count := 0
+	for range ch {
+		count++
+	}
+	if count != subscriberBuffer {
+		t.Errorf("Expected %d buffered events before the channel closed, got %d", subscriberBuffer, count)
+	}
+}
+
+func TestNilBusPublish(t *testing.T) {
+	var bus *Bus
+	// Must not panic
+	bus.Publish(Event{Type: BookDeleted})
+}
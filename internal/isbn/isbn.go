@@ -0,0 +1,91 @@
+// Package isbn validates International Standard Book Numbers and converts between their
+// 10 and 13 digit forms.
+package isbn
+
+import "strings"
+
+// Normalize removes hyphens and spaces from an ISBN-10 or ISBN-13 and upper-cases a final X,
+// reporting whether its check digit is correct.
+func Normalize(s string) (string, bool) {
+	s = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(s))
+	switch len(s) {
+	case 10:
+		sum := 0
+		for i, r := range s {
+			digit := int(r - '0')
+			if r == 'X' && i == 9 {
+				digit = 10
+			} else if r < '0' || r > '9' {
+				return "", false
+			}
+			sum += (10 - i) * digit
+		}
+		return s, sum%11 == 0
+	case 13:
+		for _, r := range s {
+			if r < '0' || r > '9' {
+				return "", false
+			}
+		}
+		return s, checkDigit13(s[:12]) == s[12]
+	default:
+		return "", false
+	}
+}
+
+// checkDigit13 computes the check digit of the first 12 digits of an ISBN-13.
+func checkDigit13(digits string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += weight * int(digits[i]-'0')
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// To13 returns the ISBN-13 form of a valid ISBN: an ISBN-10 gets the 978 prefix and a new
+// check digit, and an ISBN-13 is returned as is. It returns "" if s is not a valid ISBN.
+func To13(s string) string {
+	s, ok := Normalize(s)
+	if !ok {
+		return ""
+	}
+	if len(s) == 13 {
+		return s
+	}
+	digits := "978" + s[:9]
+	return digits + string(checkDigit13(digits))
+}
+
+// To10 returns the ISBN-10 form of a valid ISBN. ISBN-13s starting with 979 have none, and
+// neither do invalid ISBNs, for which it returns "".
+func To10(s string) string {
+	s, ok := Normalize(s)
+	if !ok {
+		return ""
+	}
+	if len(s) == 10 {
+		return s
+	}
+	if !strings.HasPrefix(s, "978") {
+		return ""
+	}
+	sum := 0
+	for i, r := range s[3:12] {
+		sum += (10 - i) * int(r-'0')
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return s[3:12] + "X"
+	}
+	return s[3:12] + string(rune('0'+check))
+}
+
+// Equivalent reports whether a and b are valid ISBNs of the same book, in either form.
+func Equivalent(a, b string) bool {
+	a13 := To13(a)
+	return a13 != "" && a13 == To13(b)
+}
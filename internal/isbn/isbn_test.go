@@ -0,0 +1,52 @@
+package isbn
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in, want string
+		ok       bool
+	}{
+		{"978-0-593-09932-2", "9780593099322", true},
+		{"0-8044-2957-x", "080442957X", true},
+		{"0 441 17271 7", "0441172717", true},
+		{"9780593099323", "", false},
+		{"0441172718", "", false},
+		{"04411727X7", "", false},
+		{"12345", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := Normalize(tt.in)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("Normalize(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		isbn10, isbn13 string
+	}{
+		{"0441172717", "9780441172719"},
+		{"080442957X", "9780804429573"},
+		{"0306406152", "9780306406157"},
+	}
+	for _, tt := range tests {
+		if got := To13(tt.isbn10); got != tt.isbn13 {
+			t.Errorf("To13(%q) = %q; want %q", tt.isbn10, got, tt.isbn13)
+		}
+		if got := To10(tt.isbn13); got != tt.isbn10 {
+			t.Errorf("To10(%q) = %q; want %q", tt.isbn13, got, tt.isbn10)
+		}
+		if !Equivalent("978-"+tt.isbn13[3:], tt.isbn10) {
+			t.Errorf("Expected %s and %s equivalent", tt.isbn13, tt.isbn10)
+		}
+	}
+	if got := To10("9791032305690"); got != "" {
+		t.Errorf("Expected no ISBN-10 for a 979 ISBN, got %q", got)
+	}
+	if To13("not an isbn") != "" || Equivalent("", "") || Equivalent("0441172717", "9780306406157") {
+		t.Error("Expected invalid and different ISBNs not to be equivalent")
+	}
+}
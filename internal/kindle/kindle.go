@@ -0,0 +1,279 @@
+// Package kindle reads the "My Clippings.txt" file in which Kindle e-readers collect
+// highlights, notes and bookmarks, and matches its books to the books on the shelf.
+package kindle
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/textmatch"
+)
+
+// Kind is the kind of a clipping.
+type Kind string
+
+const (
+	KindHighlight Kind = "highlight"
+	KindNote      Kind = "note"
+	KindBookmark  Kind = "bookmark"
+)
+
+// Clipping is one entry of a clippings file.
+type Clipping struct {
+	Title    string
+	Author   string // "First Last"; several authors are separated by ", "
+	Kind     Kind
+	Page     *int       // Nil if the book has no page numbers
+	Location string     // e.g. "170-172"; empty for books without locations
+	AddedAt  *time.Time // In the device's local time, taken as UTC; nil if unrecognized
+	Text     string     // Empty for bookmarks
+	Note     string     // For highlights, the note attached to it by Group
+}
+
+// separator ends each entry of a clippings file.
+const separator = "=========="
+
+var (
+	metaPattern     = regexp.MustCompile(`(?i)^-\s*(?:your\s+)?(highlight|note|bookmark)\b`)
+	pagePattern     = regexp.MustCompile(`(?i)\bpage\s+(\d+)`)
+	locationPattern = regexp.MustCompile(`(?i)\b(?:location|loc\.)\s+(\d+(?:-\d+)?)`)
+	addedPattern    = regexp.MustCompile(`(?i)\badded on\s+(.+)$`)
+	authorPattern   = regexp.MustCompile(`^(.*)\(([^()]*)\)\s*$`)
+)
+
+// dateLayouts are the date formats of US and UK English Kindles.
+var dateLayouts = []string{
+	"Monday, January 2, 2006 3:04:05 PM",
+	"Monday, 2 January 2006 15:04:05",
+	"Monday, January 2, 2006 15:04:05",
+	"Monday, 2 January 2006 3:04:05 PM",
+}
+
+// Parse reads a clippings file. Entries that are not in the expected format (including those
+// of Kindles set to a language other than English) are skipped and counted.
+func Parse(r io.Reader) (clippings []Clipping, skipped int, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var entry []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) != separator {
+			entry = append(entry, line)
+			continue
+		}
+		if clipping, ok := parseEntry(entry); ok {
+			clippings = append(clippings, clipping)
+		} else {
+			skipped++
+		}
+		entry = entry[:0]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+	// A truncated last entry without a separator still counts
+	if len(strings.TrimSpace(strings.Join(entry, ""))) > 0 {
+		if clipping, ok := parseEntry(entry); ok {
+			clippings = append(clippings, clipping)
+		} else {
+			skipped++
+		}
+	}
+	return clippings, skipped, nil
+}
+
+// parseEntry parses the lines of one entry: the title and author, the metadata line, a blank
+// line and the text.
+func parseEntry(lines []string) (Clipping, bool) {
+	// Kindles start each entry (not just the file) with a byte order mark
+	for len(lines) > 0 && strings.TrimSpace(strings.TrimPrefix(lines[0], "\ufeff")) == "" {
+		lines = lines[1:]
+	}
+	if len(lines) < 2 {
+		return Clipping{}, false
+	}
+	var clipping Clipping
+	clipping.Title, clipping.Author = parseTitle(strings.TrimPrefix(lines[0], "\ufeff"))
+	if clipping.Title == "" {
+		return Clipping{}, false
+	}
+
+	meta := metaPattern.FindStringSubmatch(lines[1])
+	if meta == nil {
+		return Clipping{}, false
+	}
+	clipping.Kind = Kind(strings.ToLower(meta[1]))
+	for _, part := range strings.Split(lines[1], "|") {
+		if m := pagePattern.FindStringSubmatch(part); m != nil {
+			if page, err := strconv.Atoi(m[1]); err == nil && page > 0 {
+				clipping.Page = &page
+			}
+		}
+		if m := locationPattern.FindStringSubmatch(part); m != nil {
+			clipping.Location = m[1]
+		}
+		if m := addedPattern.FindStringSubmatch(strings.TrimSpace(part)); m != nil {
+			clipping.AddedAt = parseDate(m[1])
+		}
+	}
+
+	clipping.Text = strings.TrimSpace(strings.Join(lines[2:], "\n"))
+	if clipping.Text == "" && clipping.Kind != KindBookmark {
+		return Clipping{}, false
+	}
+	return clipping, true
+}
+
+// parseTitle splits "Title (Author)" and turns "Last, First" authors around. Several
+// authors are separated by semicolons.
+func parseTitle(line string) (title, author string) {
+	line = strings.TrimSpace(line)
+	m := authorPattern.FindStringSubmatch(line)
+	if m == nil || strings.TrimSpace(m[1]) == "" {
+		return line, ""
+	}
+	var authors []string
+	for _, name := range strings.Split(m[2], ";") {
+		name = strings.TrimSpace(name)
+		if last, first, ok := strings.Cut(name, ","); ok && !strings.Contains(first, ",") {
+			name = strings.TrimSpace(first) + " " + strings.TrimSpace(last)
+		}
+		if name != "" {
+			authors = append(authors, name)
+		}
+	}
+	return strings.TrimSpace(m[1]), strings.Join(authors, ", ")
+}
+
+// parseDate parses a Kindle "Added on" date, returning nil if the format is not recognized.
+func parseDate(value string) *time.Time {
+	value = strings.Join(strings.Fields(value), " ")
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+// Hash identifies a clipping across imports: the same highlight in a later copy of the file
+// has the same hash.
+func (c *Clipping) Hash() string {
+	sum := sha256.Sum256([]byte(string(c.Kind) + "\x00" + c.Location + "\x00" + c.Text))
+	return hex.EncodeToString(sum[:16])
+}
+
+// SourceID returns a stable stand-in for the Open Library ID of a book known only from its
+// clippings, so that importing the book again finds the book created the first time.
+func SourceID(title, author string) string {
+	sum := sha256.Sum256([]byte(textmatch.NormalizeTitle(title) + "\x00" + strings.ToLower(author)))
+	return "kindle:" + hex.EncodeToString(sum[:8])
+}
+
+// Book holds the clippings of one book worth keeping.
+type Book struct {
+	Title  string
+	Author string
+	// Highlights with their notes attached, then notes that annotate no highlight
+	Clippings []Clipping
+	Bookmarks int // Bookmarks have no text, so they are only counted
+}
+
+// Group collects clippings by book, in the order the books first appear. Notes are attached
+// to the highlight whose location range they fall in; the last highlight wins if several do.
+// Highlights that appear more than once with the same location and text (e.g. from a file
+// copied together from two Kindles) are kept once. An extended highlight is not merged: the
+// Kindle appends it as a new entry with a new location and text, so both versions are kept.
+func Group(clippings []Clipping) []Book {
+	var books []Book
+	index := map[string]int{}
+	for _, c := range clippings {
+		key := c.Title + "\x00" + c.Author
+		i, ok := index[key]
+		if !ok {
+			i = len(books)
+			index[key] = i
+			books = append(books, Book{Title: c.Title, Author: c.Author})
+		}
+		if c.Kind == KindBookmark {
+			books[i].Bookmarks++
+			continue
+		}
+		books[i].Clippings = append(books[i].Clippings, c)
+	}
+
+	for i := range books {
+		var highlights, notes []Clipping
+		seen := map[string]bool{}
+		for _, c := range books[i].Clippings {
+			if c.Kind == KindNote {
+				notes = append(notes, c)
+			} else if hash := c.Hash(); !seen[hash] {
+				seen[hash] = true
+				highlights = append(highlights, c)
+			}
+		}
+		for _, note := range notes {
+			if h := annotated(highlights, note.Location); h >= 0 {
+				if highlights[h].Note != "" {
+					highlights[h].Note += "\n"
+				}
+				highlights[h].Note += note.Text
+			} else {
+				highlights = append(highlights, note)
+			}
+		}
+		books[i].Clippings = highlights
+	}
+	return books
+}
+
+// annotated returns the index of the last highlight whose location range contains location,
+// or -1.
+func annotated(highlights []Clipping, location string) int {
+	at, _, ok := locationRange(location)
+	if !ok {
+		return -1
+	}
+	found := -1
+	for i, h := range highlights {
+		if h.Kind != KindHighlight {
+			continue
+		}
+		if start, end, ok := locationRange(h.Location); ok && start <= at && at <= end {
+			found = i
+		}
+	}
+	return found
+}
+
+// locationRange parses "170-172" or "172". Kindles abbreviate ranges such as "1170-72".
+func locationRange(location string) (start, end int, ok bool) {
+	from, to, isRange := strings.Cut(location, "-")
+	start, err := strconv.Atoi(from)
+	if err != nil {
+		return 0, 0, false
+	}
+	if !isRange {
+		return start, start, true
+	}
+	end, err = strconv.Atoi(to)
+	if err != nil {
+		return 0, 0, false
+	}
+	if len(to) < len(from) {
+		// Take the missing leading digits from the start: 1170-72 is 1170-1172
+		prefix := from[:len(from)-len(to)]
+		end, _ = strconv.Atoi(prefix + to)
+		if end < start {
+			end = start
+		}
+	}
+	return start, end, true
+}
@@ -0,0 +1,167 @@
+package kindle
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const clippingsFile = "\ufeffDune (Herbert, Frank)\r\n" +
+	"- Your Highlight on page 8 | Location 120-122 | Added on Saturday, March 4, 2023 9:05:00 PM\r\n" +
+	"\r\n" +
+	"Fear is the mind-killer.\r\n" +
+	"==========\r\n" +
+	"\ufeffDune (Herbert, Frank)\r\n" +
+	"- Your Note on page 8 | Location 122 | Added on Saturday, March 4, 2023 9:06:00 PM\r\n" +
+	"\r\n" +
+	"Litany against fear\r\n" +
+	"==========\r\n" +
+	"\ufeffDune (Herbert, Frank)\r\n" +
+	"- Your Bookmark on page 9 | Location 130 | Added on Saturday, March 4, 2023 9:10:00 PM\r\n" +
+	"\r\n" +
+	"\r\n" +
+	"==========\r\n" +
+	"\ufeffThe Hobbit (J. R. R. Tolkien)\r\n" +
+	"- Your Highlight at location 1170-72 | Added on Sunday, 5 March 2023 10:15:30\r\n" +
+	"\r\n" +
+	"In a hole in the ground there lived a hobbit.\r\n" +
+	"==========\r\n" +
+	"\ufeffThe Hobbit (J. R. R. Tolkien)\r\n" +
+	"- Your Highlight at location 1170-72 | Added on Sunday, 5 March 2023 10:15:30\r\n" +
+	"\r\n" +
+	"In a hole in the ground there lived a hobbit.\r\n" +
+	"==========\r\n" +
+	"\ufeffThe Hobbit (J. R. R. Tolkien)\r\n" +
+	"- Your Note at location 1500 | Added on Sunday, 5 March 2023 10:20:00\r\n" +
+	"\r\n" +
+	"Compare with the film\r\n" +
+	"==========\r\n" +
+	"\ufeffDer Hobbit (J. R. R. Tolkien)\r\n" +
+	"- Ihre Markierung bei Position 10-12 | Hinzugefügt am Sonntag, 5. März 2023 10:15:30\r\n" +
+	"\r\n" +
+	"In einer Höhle in der Erde, da lebte ein Hobbit.\r\n" +
+	"==========\r\n"
+
+func TestParse(t *testing.T) {
+	clippings, skipped, err := Parse(strings.NewReader(clippingsFile))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(clippings) != 6 || skipped != 1 {
+		t.Fatalf("Expected 6 clippings and 1 skipped, got %d and %d", len(clippings), skipped)
+	}
+
+	first := clippings[0]
+	if first.Title != "Dune" || first.Author != "Frank Herbert" || first.Kind != KindHighlight {
+		t.Errorf("Expected a highlight of Dune by Frank Herbert, got %+v", first)
+	}
+	if first.Page == nil || *first.Page != 8 || first.Location != "120-122" || first.Text != "Fear is the mind-killer." {
+		t.Errorf("Unexpected page, location or text: %+v", first)
+	}
+	if want := time.Date(2023, 3, 4, 21, 5, 0, 0, time.UTC); first.AddedAt == nil || !first.AddedAt.Equal(want) {
+		t.Errorf("Expected added at %v, got %v", want, first.AddedAt)
+	}
+
+	if bookmark := clippings[2]; bookmark.Kind != KindBookmark || bookmark.Text != "" {
+		t.Errorf("Expected a bookmark without text, got %+v", bookmark)
+	}
+	hobbit := clippings[3]
+	if hobbit.Title != "The Hobbit" || hobbit.Author != "J. R. R. Tolkien" || hobbit.Page != nil || hobbit.Location != "1170-72" {
+		t.Errorf("Unexpected Hobbit highlight: %+v", hobbit)
+	}
+	if want := time.Date(2023, 3, 5, 10, 15, 30, 0, time.UTC); hobbit.AddedAt == nil || !hobbit.AddedAt.Equal(want) {
+		t.Errorf("Expected the UK date parsed as %v, got %v", want, hobbit.AddedAt)
+	}
+}
+
+func TestParseTitle(t *testing.T) {
+	tests := []struct {
+		line, title, author string
+	}{
+		{"Dune (Frank Herbert)", "Dune", "Frank Herbert"},
+		{"Dune (Herbert, Frank)", "Dune", "Frank Herbert"},
+		{"Good Omens (Pratchett, Terry;Gaiman, Neil)", "Good Omens", "Terry Pratchett, Neil Gaiman"},
+		{"Leviathan Wakes (The Expanse Book 1) (James S. A. Corey)", "Leviathan Wakes (The Expanse Book 1)", "James S. A. Corey"},
+		{"My Notes", "My Notes", ""},
+	}
+	for _, tt := range tests {
+		if title, author := parseTitle(tt.line); title != tt.title || author != tt.author {
+			t.Errorf("parseTitle(%q) = %q, %q; want %q, %q", tt.line, title, author, tt.title, tt.author)
+		}
+	}
+}
+
+func TestGroup(t *testing.T) {
+	clippings, _, err := Parse(strings.NewReader(clippingsFile))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	books := Group(clippings)
+	if len(books) != 2 {
+		t.Fatalf("Expected 2 books, got %d", len(books))
+	}
+
+	dune := books[0]
+	if dune.Bookmarks != 1 || len(dune.Clippings) != 1 {
+		t.Fatalf("Expected Dune with 1 bookmark and 1 highlight, got %+v", dune)
+	}
+	if dune.Clippings[0].Note != "Litany against fear" {
+		t.Errorf("Expected the note attached to the highlight, got %q", dune.Clippings[0].Note)
+	}
+
+	hobbit := books[1]
+	if len(hobbit.Clippings) != 2 {
+		t.Fatalf("Expected the duplicate highlight dropped and the stray note kept, got %+v", hobbit.Clippings)
+	}
+	if hobbit.Clippings[0].Note != "" || hobbit.Clippings[1].Kind != KindNote {
+		t.Errorf("Expected the note outside the highlight kept on its own, got %+v", hobbit.Clippings)
+	}
+
+	// An extended highlight is a new entry, kept along with the highlight it extends
+	extended := append(clippings[:1:1], Clipping{Title: "Dune", Author: "Frank Herbert", Kind: KindHighlight,
+		Location: "120-124", Text: "Fear is the mind-killer. Fear is the little-death."})
+	if books := Group(extended); len(books) != 1 || len(books[0].Clippings) != 2 {
+		t.Errorf("Expected both versions of the extended highlight kept, got %+v", books)
+	}
+}
+
+func TestLocationRange(t *testing.T) {
+	tests := []struct {
+		location   string
+		start, end int
+		ok         bool
+	}{
+		{"172", 172, 172, true},
+		{"170-172", 170, 172, true},
+		{"1170-72", 1170, 1172, true},
+		{"998-2", 998, 998, true},
+		{"", 0, 0, false},
+		{"a-b", 0, 0, false},
+	}
+	for _, tt := range tests {
+		start, end, ok := locationRange(tt.location)
+		if start != tt.start || end != tt.end || ok != tt.ok {
+			t.Errorf("locationRange(%q) = %d, %d, %v; want %d, %d, %v", tt.location, start, end, ok, tt.start, tt.end, tt.ok)
+		}
+	}
+}
+
+func TestHashAndSourceID(t *testing.T) {
+	a := Clipping{Kind: KindHighlight, Location: "10-12", Text: "Text"}
+	b := a
+	b.Note, b.AddedAt = "A note", &time.Time{}
+	if a.Hash() != b.Hash() {
+		t.Error("Expected notes and dates not to change the hash")
+	}
+	b.Text = "Text, extended"
+	if a.Hash() == b.Hash() {
+		t.Error("Expected a different text to change the hash")
+	}
+
+	if SourceID("The Hobbit", "J. R. R. Tolkien") != SourceID("Hobbit", "j. r. r. tolkien") {
+		t.Error("Expected the source ID to ignore articles and case")
+	}
+	if !strings.HasPrefix(SourceID("Dune", "Frank Herbert"), "kindle:") {
+		t.Error("Expected the source ID to be prefixed")
+	}
+}
@@ -0,0 +1,30 @@
+package kindle
+
+import (
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/textmatch"
+)
+
+// minTitleSimilarity is how similar (0-1) normalized titles must be for a book to match.
+const minTitleSimilarity = 0.85
+
+// Match finds the book on the shelf that a Kindle title and author refer to, or returns nil.
+// Titles are compared without case, punctuation, leading articles, subtitles and parenthesized
+// series names, allowing for small differences; the author must share a name with the book's
+// author, unless either is unknown.
+func Match(title, author string, books []model.Book) *model.Book {
+	wanted := textmatch.NormalizeTitle(title)
+	if wanted == "" {
+		return nil
+	}
+	var best *model.Book
+	bestScore := 0.0
+	for i := range books {
+		score := textmatch.Similarity(wanted, textmatch.NormalizeTitle(books[i].Title))
+		if score < minTitleSimilarity || score <= bestScore || !textmatch.SameAuthor(author, books[i].Author) {
+			continue
+		}
+		best, bestScore = &books[i], score
+	}
+	return best
+}
@@ -0,0 +1,40 @@
+package kindle
+
+import (
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func TestMatch(t *testing.T) {
+	shelf := []model.Book{
+		{ID: 1, Title: "The Fellowship of the Ring", Author: "J.R.R. Tolkien"},
+		{ID: 2, Title: "Ender's Game", Author: "Orson Scott Card"},
+		{ID: 3, Title: "Dune", Author: "Frank Herbert"},
+		{ID: 4, Title: "Dune Messiah", Author: "Frank Herbert"},
+	}
+	tests := []struct {
+		title, author string
+		want          int64
+	}{
+		{"The Fellowship of the Ring (The Lord of the Rings, Book 1)", "J. R. R. Tolkien", 1},
+		{"Fellowship of the Ring: Being the First Part", "Tolkien", 1},
+		{"Enders Game", "Card, Orson Scott", 2},
+		{"Ender’s Game", "", 2},
+		{"Dune", "Frank Herbert", 3},
+		{"Dune Messiah", "Frank Herbert", 4},
+		{"Dune", "Brian Herbert", 3},
+		{"Dune", "Someone Else", 0},
+		{"Children of Dune", "Frank Herbert", 0},
+		{"", "Frank Herbert", 0},
+	}
+	for _, tt := range tests {
+		var got int64
+		if book := Match(tt.title, tt.author, shelf); book != nil {
+			got = book.ID
+		}
+		if got != tt.want {
+			t.Errorf("Match(%q, %q) = book %d; want %d", tt.title, tt.author, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestValidRequestID(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want bool
+	}{
+		{name: "hex id", id: NewRequestID(), want: true},
+		{name: "uuid", id: "3f2504e0-4f89-11d3-9a0c-0305e82c3301", want: true},
+		{name: "empty", id: "", want: false},
+		{name: "contains space", id: "abc def", want: false},
+		{name: "contains newline", id: "abc\ninjected", want: false},
+		{name: "too long", id: strings.Repeat("a", maxRequestIDLength+1), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidRequestID(tt.id); got != tt.want {
+				t.Errorf("ValidRequestID(%q) = %v, want %v", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContextHelpers(t *testing.T) {
+	ctx := context.Background()
+	if FromContext(ctx) != slog.Default() {
+		t.Error("Expected default logger for a context without one")
+	}
+	if RequestID(ctx) != "" {
+		t.Error("Expected empty request ID for a context without one")
+	}
+
+	logger := slog.Default().With("requestID", "abc")
+	ctx = WithLogger(WithRequestID(ctx, "abc"), logger)
+	if FromContext(ctx) != logger {
+		t.Error("Expected logger stored in context")
+	}
+	if got := RequestID(ctx); got != "abc" {
+		t.Errorf("RequestID() = %q, want %q", got, "abc")
+	}
+}
@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "bookshelf"
+
+// Registry holds all application metrics. A dedicated registry (rather than the
+// global default one) keeps tests isolated and the /metrics output predictable.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequestsTotal counts handled HTTP requests by route template, method and status code.
+	HTTPRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Total number of HTTP requests handled, by route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	// HTTPRequestDuration observes HTTP request latency by route template, method and status code.
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency in seconds, by route, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	// OpenLibraryRequestDuration observes latency of outgoing Open Library API calls.
+	OpenLibraryRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "openlibrary_request_duration_seconds",
+		Help:      "Open Library API call latency in seconds, by operation.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"operation"})
+
+	// OpenLibraryErrorsTotal counts failed Open Library API calls by operation and reason.
+	OpenLibraryErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "openlibrary_errors_total",
+		Help:      "Total number of failed Open Library API calls, by operation and reason.",
+	}, []string{"operation", "reason"})
+
+	// DBQueryDuration observes database query latency per BookStore method.
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Database query latency in seconds, by store method.",
+		Buckets:   []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 1},
+	}, []string{"method"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestsTotal,
+		HTTPRequestDuration,
+		OpenLibraryRequestDuration,
+		OpenLibraryErrorsTotal,
+		DBQueryDuration,
+	)
+}
+
+// Handler returns the HTTP handler that serves the metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveDBQuery records the duration of a store method call started at start.
+// Intended to be used as: defer metrics.ObserveDBQuery("GetBooks", time.Now())
+func ObserveDBQuery(method string, start time.Time) {
+	DBQueryDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+// collectTimeout bounds the queries run while serving a scrape.
+const collectTimeout = 5 * time.Second
+
+// BookCountFunc returns the number of books grouped by status and type.
+type BookCountFunc func(ctx context.Context) (map[model.BookStatus]map[model.BookType]int, error)
+
+// bookCountCollector exposes the number of books per status and type as gauges.
+// The counts are queried at scrape time so they are always current.
+type bookCountCollector struct {
+	count BookCountFunc
+	desc  *prometheus.Desc
+}
+
+// NewBookCountCollector creates a collector reporting books per status/type using count.
+func NewBookCountCollector(count BookCountFunc) prometheus.Collector {
+	return &bookCountCollector{
+		count: count,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "books"),
+			"Number of books on the shelf, by status and type.",
+			[]string{"status", "type"}, nil,
+		),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *bookCountCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect implements prometheus.Collector.
+func (c *bookCountCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	counts, err := c.count(ctx)
+	if err != nil {
+		slog.Error("Failed to collect book counts for metrics", "error", err)
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+	// Always report every status/type combination so dashboards see zeros rather than gaps
+	for _, status := range []model.BookStatus{model.StatusWantToRead, model.StatusCurrentlyReading, model.StatusRead} {
+		for _, bookType := range model.BookTypes {
+			ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue,
+				float64(counts[status][bookType]), string(status), string(bookType))
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestBookCountCollector(t *testing.T) {
+	collector := NewBookCountCollector(func(ctx context.Context) (map[model.BookStatus]map[model.BookType]int, error) {
+		return map[model.BookStatus]map[model.BookType]int{
+			model.StatusRead:       {model.TypeBook: 3, model.TypeEbook: 4, model.TypeAudiobook: 1},
+			model.StatusWantToRead: {model.TypeBook: 2},
+		}, nil
+	})
+
+	expected := `
+# HELP bookshelf_books Number of books on the shelf, by status and type.
+# TYPE bookshelf_books gauge
+bookshelf_books{status="Currently Reading",type="audiobook"} 0
+bookshelf_books{status="Currently Reading",type="book"} 0
+bookshelf_books{status="Currently Reading",type="ebook"} 0
+bookshelf_books{status="Read",type="audiobook"} 1
+bookshelf_books{status="Read",type="book"} 3
+bookshelf_books{status="Read",type="ebook"} 4
+bookshelf_books{status="Want to Read",type="audiobook"} 0
+bookshelf_books{status="Want to Read",type="book"} 2
+bookshelf_books{status="Want to Read",type="ebook"} 0
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected)); err != nil {
+		t.Errorf("Unexpected collector output: %v", err)
+	}
+}
+
+func TestBookCountCollectorError(t *testing.T) {
+	collector := NewBookCountCollector(func(ctx context.Context) (map[model.BookStatus]map[model.BookType]int, error) {
+		return nil, errors.New("database is locked")
+	})
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collector)
+	if _, err := reg.Gather(); err == nil {
+		t.Error("Expected gather error when counting books fails")
+	}
+}
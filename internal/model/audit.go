@@ -0,0 +1,113 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// AuditAction is the kind of change recorded in an audit entry.
+type AuditAction string
+
+const (
+	AuditCreate  AuditAction = "create"
+	AuditUpdate  AuditAction = "update"
+	AuditDelete  AuditAction = "delete"  // Moved to the trash
+	AuditRestore AuditAction = "restore" // Taken out of the trash
+	AuditPurge   AuditAction = "purge"   // Permanently deleted from the trash, or merged into another book
+)
+
+// IsValid checks if the audit action is one of the allowed values.
+func (a AuditAction) IsValid() bool {
+	switch a {
+	case AuditCreate, AuditUpdate, AuditDelete, AuditRestore, AuditPurge:
+		return true
+	default:
+		return false
+	}
+}
+
+// AuditEntry records one change to one book: who made it, in which request, and the fields
+// that changed.
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	Time      time.Time       `json:"time"`
+	Actor     string          `json:"actor,omitempty"`      // Empty for anonymous requests and background jobs
+	RequestID string          `json:"request_id,omitempty"` // Ties entries to request logs; empty for background jobs
+	Action    AuditAction     `json:"action"`
+	BookID    int64           `json:"book_id"`
+	Before    json.RawMessage `json:"before"` // Changed fields before the change, or the whole book; null for create
+	After     json.RawMessage `json:"after"`  // Changed fields after the change, or the whole book; null for purge
+}
+
+// AuditPage is a page of audit entries, newest first. NextBeforeID is set if there are more.
+type AuditPage struct {
+	Entries      []AuditEntry `json:"entries"`
+	NextBeforeID *int64       `json:"next_before_id,omitempty"`
+}
+
+// DiffBooks returns the JSON fields that differ between two versions of a book, as an object
+// for each side. If either side is nil (created or purged) the other is returned whole and the
+// nil side is JSON null. Both are nil if nothing changed.
+func DiffBooks(before, after *Book) (json.RawMessage, json.RawMessage, error) {
+	if before == nil || after == nil {
+		if before == nil && after == nil {
+			return nil, nil, nil
+		}
+		b, err := json.Marshal(before)
+		if err != nil {
+			return nil, nil, err
+		}
+		a, err := json.Marshal(after)
+		if err != nil {
+			return nil, nil, err
+		}
+		return b, a, nil
+	}
+
+	beforeFields, err := jsonFields(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	afterFields, err := jsonFields(after)
+	if err != nil {
+		return nil, nil, err
+	}
+	changedBefore := map[string]json.RawMessage{}
+	changedAfter := map[string]json.RawMessage{}
+	for key, value := range beforeFields {
+		if !bytes.Equal(value, afterFields[key]) {
+			changedBefore[key] = value
+		}
+	}
+	for key, value := range afterFields {
+		if !bytes.Equal(value, beforeFields[key]) {
+			changedAfter[key] = value
+		}
+	}
+	if len(changedBefore) == 0 && len(changedAfter) == 0 {
+		return nil, nil, nil
+	}
+	b, err := json.Marshal(changedBefore)
+	if err != nil {
+		return nil, nil, err
+	}
+	a, err := json.Marshal(changedAfter)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b, a, nil
+}
+
+// jsonFields marshals v and splits the resulting object into its fields.
+func jsonFields(v interface{}) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
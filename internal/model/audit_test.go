@@ -0,0 +1,57 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDiffBooks(t *testing.T) {
+	rating := 8
+	before := &Book{ID: 1, Title: "Dune", Author: "Frank Herbert", Status: StatusWantToRead, Version: 1}
+	after := *before
+	after.Status = StatusRead
+	after.Rating = &rating
+	after.Version = 2
+
+	b, a, err := DiffBooks(before, &after)
+	if err != nil {
+		t.Fatalf("DiffBooks failed: %v", err)
+	}
+	var beforeFields, afterFields map[string]interface{}
+	if err := json.Unmarshal(b, &beforeFields); err != nil {
+		t.Fatalf("Invalid before JSON %s: %v", b, err)
+	}
+	if err := json.Unmarshal(a, &afterFields); err != nil {
+		t.Fatalf("Invalid after JSON %s: %v", a, err)
+	}
+	if beforeFields["status"] != string(StatusWantToRead) || afterFields["status"] != string(StatusRead) {
+		t.Errorf("Expected the status change, got %s -> %s", b, a)
+	}
+	if afterFields["rating"] != float64(8) {
+		t.Errorf("Expected the new rating, got %s", a)
+	}
+	if _, ok := beforeFields["title"]; ok {
+		t.Errorf("Expected unchanged fields to be left out, got %s", b)
+	}
+
+	if b, a, err := DiffBooks(before, before); err != nil || b != nil || a != nil {
+		t.Errorf("Expected no diff for an unchanged book, got %s %s %v", b, a, err)
+	}
+	if b, a, _ := DiffBooks(nil, before); string(b) != "null" || len(a) == 0 {
+		t.Errorf("Expected the whole book for a create, got %s %s", b, a)
+	}
+	if b, a, _ := DiffBooks(before, nil); string(a) != "null" || len(b) == 0 {
+		t.Errorf("Expected the whole book for a purge, got %s %s", b, a)
+	}
+}
+
+func TestAuditActionIsValid(t *testing.T) {
+	for _, action := range []AuditAction{AuditCreate, AuditUpdate, AuditDelete, AuditRestore, AuditPurge} {
+		if !action.IsValid() {
+			t.Errorf("Expected %q to be valid", action)
+		}
+	}
+	if AuditAction("edit").IsValid() {
+		t.Error("Expected \"edit\" to be invalid")
+	}
+}
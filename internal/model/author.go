@@ -0,0 +1,94 @@
+package model
+
+import "strings"
+
+// AuthorRole is how a person is credited on a book.
+type AuthorRole string
+
+const (
+	RoleAuthor     AuthorRole = "author"
+	RoleNarrator   AuthorRole = "narrator"
+	RoleTranslator AuthorRole = "translator"
+)
+
+// IsValid checks if the role is one of the predefined roles.
+func (r AuthorRole) IsValid() bool {
+	switch r {
+	case RoleAuthor, RoleNarrator, RoleTranslator:
+		return true
+	default:
+		return false
+	}
+}
+
+// Author is a person credited on one or more books.
+type Author struct {
+	ID             int64   `json:"id"`
+	Name           string  `json:"name"`
+	OpenLibraryKey *string `json:"open_library_key,omitempty"` // e.g., OL23919A
+	BookCount      int     `json:"book_count"`                 // Books crediting the author in any role
+}
+
+// Contributor credits an author on a specific book.
+type Contributor struct {
+	AuthorID       int64      `json:"author_id,omitempty"` // Assigned by the store
+	Name           string     `json:"name"`
+	OpenLibraryKey *string    `json:"open_library_key,omitempty"`
+	Role           AuthorRole `json:"role"` // Defaults to "author"
+}
+
+// Validate checks the contributor, defaulting an empty role to author.
+func (c *Contributor) Validate() error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return &ValidationError{"contributor name is required"}
+	}
+	if c.Role == "" {
+		c.Role = RoleAuthor
+	} else if !c.Role.IsValid() {
+		return &ValidationError{"invalid role, must be 'author', 'narrator' or 'translator'"}
+	}
+	return nil
+}
+
+// ContributorsFromNames splits a comma-joined author string into authors without Open Library
+// keys. Names that contain a comma themselves ("Le Guin, Ursula K." or "Martin Luther King,
+// Jr.") are split too, so callers that have the names separately should not join them.
+func ContributorsFromNames(names string) []Contributor {
+	var contributors []Contributor
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			contributors = append(contributors, Contributor{Name: name, Role: RoleAuthor})
+		}
+	}
+	return contributors
+}
+
+// OpenLibraryContributors credits the authors of an Open Library search result, pairing each
+// name with the author key at the same position.
+func OpenLibraryContributors(names, keys []string) []Contributor {
+	var contributors []Contributor
+	for i, name := range names {
+		c := Contributor{Name: strings.TrimSpace(name), Role: RoleAuthor}
+		if c.Name == "" {
+			continue
+		}
+		if i < len(keys) && keys[i] != "" {
+			key := keys[i]
+			c.OpenLibraryKey = &key
+		}
+		contributors = append(contributors, c)
+	}
+	return contributors
+}
+
+// AuthorNames joins the names of the contributors credited as authors, in order.
+func AuthorNames(contributors []Contributor) string {
+	var names []string
+	for _, c := range contributors {
+		if c.Role == RoleAuthor {
+			names = append(names, c.Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
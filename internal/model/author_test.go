@@ -0,0 +1,46 @@
+package model
+
+import "testing"
+
+func TestContributorsFromNames(t *testing.T) {
+	got := ContributorsFromNames("Terry Pratchett, Neil Gaiman")
+	if len(got) != 2 || got[1].Name != "Neil Gaiman" || got[1].OpenLibraryKey != nil {
+		t.Fatalf("Unexpected contributors: %+v", got)
+	}
+	if got[0].Role != RoleAuthor {
+		t.Errorf("Expected role author, got %q", got[0].Role)
+	}
+	solo := ContributorsFromNames(" , Solo ")
+	if len(solo) != 1 || solo[0].Name != "Solo" || solo[0].OpenLibraryKey != nil {
+		t.Fatalf("Expected a single unkeyed author, got %+v", solo)
+	}
+
+	all := append(solo, Contributor{Name: "Voice", Role: RoleNarrator})
+	if names := AuthorNames(all); names != "Solo" {
+		t.Errorf("Expected narrators to be left out of the author names, got %q", names)
+	}
+}
+
+func TestOpenLibraryContributors(t *testing.T) {
+	// A name with a comma stays one author, and the keys after it stay with their names
+	got := OpenLibraryContributors([]string{"Martin Luther King, Jr.", "", "Coretta Scott King"}, []string{"OL1A", "OL2A", "OL3A"})
+	if len(got) != 2 || got[0].Name != "Martin Luther King, Jr." || *got[0].OpenLibraryKey != "OL1A" ||
+		got[1].Name != "Coretta Scott King" || *got[1].OpenLibraryKey != "OL3A" || got[1].Role != RoleAuthor {
+		t.Fatalf("Unexpected contributors: %+v", got)
+	}
+	if short := OpenLibraryContributors([]string{"A", "B"}, []string{"OL1A"}); len(short) != 2 || short[1].OpenLibraryKey != nil {
+		t.Errorf("Expected names without a key to be unkeyed, got %+v", short)
+	}
+}
+
+func TestContributorValidate(t *testing.T) {
+	c := Contributor{Name: "  Jane  "}
+	if err := c.Validate(); err != nil || c.Role != RoleAuthor || c.Name != "Jane" {
+		t.Errorf("Expected trimmed author, got %+v (%v)", c, err)
+	}
+	for _, invalid := range []Contributor{{Name: " "}, {Name: "Jane", Role: "editor"}} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", invalid)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 // BookStatus represents the reading status of a book.
 type BookStatus string
 
@@ -19,18 +21,23 @@ func (s BookStatus) IsValid() bool {
 	}
 }
 
-// BookType represents the type of book (paper book or audiobook).
+// BookType represents the type of book (printed book, ebook or audiobook). It is also the
+// format of an Edition.
 type BookType string
 
 const (
 	TypeBook      BookType = "book"
+	TypeEbook     BookType = "ebook"
 	TypeAudiobook BookType = "audiobook"
 )
 
+// BookTypes lists every valid BookType.
+var BookTypes = []BookType{TypeBook, TypeEbook, TypeAudiobook}
+
 // IsValid checks if the type is one of the predefined valid types.
 func (t BookType) IsValid() bool {
 	switch t {
-	case TypeBook, TypeAudiobook:
+	case TypeBook, TypeEbook, TypeAudiobook:
 		return true
 	default:
 		return false
@@ -45,16 +52,63 @@ type Book struct {
 	OpenLibraryID string     `json:"open_library_id"` // e.g., OL7353617M
 	ISBN          string     `json:"isbn,omitempty"`  // Optional, but useful
 	Status        BookStatus `json:"status"`
-	Type          BookType   `json:"type"`            // "book" or "audiobook"
+	Type          BookType   `json:"type"`            // "book", "ebook" or "audiobook": the format being read
 	Rating        *int       `json:"rating,omitempty"`   // Pointer to allow null, 1-10
 	Comments      *string    `json:"comments,omitempty"` // Pointer to allow null
 	CoverURL      *string    `json:"cover_url,omitempty"` // URL for the book cover image
 	Series        *string    `json:"series,omitempty"`    // Name of the series (optional)
 	SeriesIndex   *int       `json:"series_index,omitempty"` // Position in the series (optional)
+	SeriesID      *int64     `json:"series_id,omitempty"`    // The series entity named by Series
+	Version       int64      `json:"version"`                // Incremented on every change; send as If-Match to avoid lost updates
+	Position      int        `json:"position"`               // Order within the book's shelf, from 1
+	DateAdded     *time.Time `json:"date_added,omitempty"`    // When the book was added to the shelf
+	DateStarted   *time.Time `json:"date_started,omitempty"`  // Set when the book moves to Currently Reading
+	DateFinished  *time.Time `json:"date_finished,omitempty"` // Set when the book moves to Read
+	Authors       []Contributor `json:"authors,omitempty"`    // Authors, narrators and translators, in credit order
+	Tags          []string      `json:"tags,omitempty"`       // Free-form labels, ordered by name
+	DeletedAt     *time.Time    `json:"deleted_at,omitempty"` // Set while the book is in the trash
+	Editions      []Edition     `json:"editions,omitempty"`   // The editions owned of this work, e.g. a hardcover and an audiobook
+	Lent          bool          `json:"lent"`                 // Lent to someone and not returned yet
+	Ownership     *Ownership    `json:"ownership,omitempty"`  // Whether we own a copy, what it cost and where it is kept
+}
+
+// BookDetails holds the user-editable details of a book, as written by UpdateBookDetails.
+// Nil clears a field, except Type and the dates where nil keeps the current value. The dates
+// started and finished are only cleared when asked to with ClearDateStarted/ClearDateFinished.
+type BookDetails struct {
+	Rating       *int
+	Comments     *string
+	Series       *string
+	SeriesIndex  *int
+	Type         *BookType
+	DateAdded    *time.Time
+	DateStarted  *time.Time
+	DateFinished *time.Time
+
+	ClearDateStarted  bool
+	ClearDateFinished bool
+}
+
+// Validate checks the rating range, series index, type and that the book was not finished
+// before it was started.
+func (d *BookDetails) Validate() error {
+	if d.Rating != nil && (*d.Rating < 1 || *d.Rating > 10) {
+		return &ValidationError{"rating must be between 1 and 10"}
+	}
+	if d.SeriesIndex != nil && *d.SeriesIndex <= 0 {
+		return &ValidationError{"series index must be greater than 0"}
+	}
+	if d.Type != nil && !d.Type.IsValid() {
+		return &ValidationError{"invalid type provided, must be 'book', 'ebook' or 'audiobook'"}
+	}
+	if d.DateStarted != nil && d.DateFinished != nil && d.DateFinished.Before(*d.DateStarted) {
+		return &ValidationError{"date_finished must not be before date_started"}
+	}
+	return nil
 }
 
 // Validate checks the book data for validity.
-// Checks Rating range, Status and Type values.
+// Checks Rating range, Status and Type values, series index, credits and ownership.
 func (b *Book) Validate() error {
 	if b.Rating != nil && (*b.Rating < 1 || *b.Rating > 10) {
 		// Consider using a custom error type or fmt.Errorf
@@ -63,11 +117,24 @@ func (b *Book) Validate() error {
 	if !b.Status.IsValid() {
 		return &ValidationError{"invalid status provided"}
 	}
+	if b.SeriesIndex != nil && *b.SeriesIndex <= 0 {
+		return &ValidationError{"series index must be greater than 0"}
+	}
 	if b.Type == "" {
 		// Default to "book" if not specified
 		b.Type = TypeBook
 	} else if !b.Type.IsValid() {
-		return &ValidationError{"invalid type provided, must be 'book' or 'audiobook'"}
+		return &ValidationError{"invalid type provided, must be 'book', 'ebook' or 'audiobook'"}
+	}
+	for i := range b.Authors {
+		if err := b.Authors[i].Validate(); err != nil {
+			return err
+		}
+	}
+	if b.Ownership != nil {
+		if err := b.Ownership.Validate(); err != nil {
+			return err
+		}
 	}
 	// Add other validations as needed (e.g., Title required)
 	return nil
@@ -0,0 +1,110 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxBulkItems is the largest number of books a single bulk operation may touch.
+const MaxBulkItems = 500
+
+// MaxTagLength is the longest tag name accepted, in characters.
+const MaxTagLength = 50
+
+// BulkAction is the change a bulk operation applies to each of its books.
+type BulkAction string
+
+const (
+	BulkSetStatus BulkAction = "set_status"
+	BulkSetType   BulkAction = "set_type"
+	BulkAddTag    BulkAction = "add_tag"
+	BulkRemoveTag BulkAction = "remove_tag"
+	BulkDelete    BulkAction = "delete"
+)
+
+// IsValid checks if the bulk action is one of the allowed values.
+func (a BulkAction) IsValid() bool {
+	switch a {
+	case BulkSetStatus, BulkSetType, BulkAddTag, BulkRemoveTag, BulkDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// BulkOperation applies one action to a list of books. Status, Type or Tag is the argument
+// of the action, depending on which it is.
+type BulkOperation struct {
+	IDs       []int64    `json:"ids"`
+	Operation BulkAction `json:"operation"`
+	Status    BookStatus `json:"status,omitempty"` // For set_status
+	Type      BookType   `json:"type,omitempty"`   // For set_type
+	Tag       string     `json:"tag,omitempty"`    // For add_tag and remove_tag
+}
+
+// Validate checks the operation and its argument, and trims the tag.
+func (op *BulkOperation) Validate() error {
+	if len(op.IDs) == 0 {
+		return &ValidationError{"ids must list at least one book"}
+	}
+	if len(op.IDs) > MaxBulkItems {
+		return &ValidationError{fmt.Sprintf("at most %d books can be changed at once", MaxBulkItems)}
+	}
+	seen := make(map[int64]bool, len(op.IDs))
+	for _, id := range op.IDs {
+		if id <= 0 {
+			return &ValidationError{fmt.Sprintf("invalid book ID %d", id)}
+		}
+		if seen[id] {
+			return &ValidationError{fmt.Sprintf("book %d is listed more than once", id)}
+		}
+		seen[id] = true
+	}
+
+	switch op.Operation {
+	case BulkSetStatus:
+		if !op.Status.IsValid() {
+			return &ValidationError{"status must be one of 'Want to Read', 'Currently Reading', 'Read'"}
+		}
+	case BulkSetType:
+		if !op.Type.IsValid() {
+			return &ValidationError{"type must be one of 'book', 'ebook', 'audiobook'"}
+		}
+	case BulkAddTag, BulkRemoveTag:
+		tag, err := NormalizeTag(op.Tag)
+		if err != nil {
+			return err
+		}
+		op.Tag = tag
+	case BulkDelete:
+	default:
+		return &ValidationError{"operation must be one of 'set_status', 'set_type', 'add_tag', 'remove_tag', 'delete'"}
+	}
+	return nil
+}
+
+// NormalizeTag trims a tag name and checks it is neither empty nor too long.
+func NormalizeTag(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", &ValidationError{"tag is required"}
+	}
+	if len([]rune(name)) > MaxTagLength {
+		return "", &ValidationError{fmt.Sprintf("tag must be at most %d characters", MaxTagLength)}
+	}
+	return name, nil
+}
+
+// BulkResult is the outcome of a bulk operation for one book.
+type BulkResult struct {
+	ID    int64  `json:"id"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// BulkResponse reports a bulk operation. Applied is false if any book failed, in which case
+// nothing was changed and the failing results explain why.
+type BulkResponse struct {
+	Applied bool         `json:"applied"`
+	Results []BulkResult `json:"results"`
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBulkOperationValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		op      BulkOperation
+		wantErr bool
+	}{
+		{"set status", BulkOperation{IDs: []int64{1, 2}, Operation: BulkSetStatus, Status: StatusRead}, false},
+		{"set type", BulkOperation{IDs: []int64{1}, Operation: BulkSetType, Type: TypeAudiobook}, false},
+		{"add tag", BulkOperation{IDs: []int64{1}, Operation: BulkAddTag, Tag: " imported "}, false},
+		{"delete", BulkOperation{IDs: []int64{1}, Operation: BulkDelete}, false},
+		{"no ids", BulkOperation{Operation: BulkDelete}, true},
+		{"duplicate id", BulkOperation{IDs: []int64{1, 1}, Operation: BulkDelete}, true},
+		{"invalid id", BulkOperation{IDs: []int64{0}, Operation: BulkDelete}, true},
+		{"too many ids", BulkOperation{IDs: make([]int64, MaxBulkItems+1), Operation: BulkDelete}, true},
+		{"unknown operation", BulkOperation{IDs: []int64{1}, Operation: "archive"}, true},
+		{"missing status", BulkOperation{IDs: []int64{1}, Operation: BulkSetStatus}, true},
+		{"invalid type", BulkOperation{IDs: []int64{1}, Operation: BulkSetType, Type: "scroll"}, true},
+		{"blank tag", BulkOperation{IDs: []int64{1}, Operation: BulkRemoveTag, Tag: "  "}, true},
+		{"long tag", BulkOperation{IDs: []int64{1}, Operation: BulkAddTag, Tag: strings.Repeat("x", MaxTagLength+1)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.op.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	op := BulkOperation{IDs: []int64{1}, Operation: BulkAddTag, Tag: " imported "}
+	if err := op.Validate(); err != nil || op.Tag != "imported" {
+		t.Errorf("Expected the tag to be trimmed, got %q (%v)", op.Tag, err)
+	}
+}
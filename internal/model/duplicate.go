@@ -0,0 +1,15 @@
+package model
+
+// DuplicateReason says why books were grouped as duplicates.
+type DuplicateReason string
+
+const (
+	DuplicateISBN  DuplicateReason = "isbn"  // Two of the books share an ISBN, in either its 10 or 13 digit form
+	DuplicateTitle DuplicateReason = "title" // The books have the same or nearly the same title and author
+)
+
+// DuplicateGroup is a set of books that look like the same work.
+type DuplicateGroup struct {
+	Reason DuplicateReason `json:"reason"` // "isbn" if any of the books were matched by ISBN
+	Books  []Book          `json:"books"`  // Oldest first, the suggested book to keep
+}
@@ -0,0 +1,73 @@
+package model
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ericdahl/bookshelf/internal/isbn"
+)
+
+// MaxPublisherLength is the maximum length of an edition's publisher, in characters.
+const MaxPublisherLength = 200
+
+// Edition is one edition of a book: the book is the work, which has the status and rating,
+// and its editions are the copies owned of it, such as a hardcover, an ebook and an audiobook.
+type Edition struct {
+	ID        int64    `json:"id"`
+	BookID    int64    `json:"book_id"`
+	Format    BookType `json:"format"`
+	ISBN      *string  `json:"isbn,omitempty"` // ISBN-10 or ISBN-13, without hyphens
+	Publisher *string  `json:"publisher,omitempty"`
+	Pages     *int     `json:"pages,omitempty"`            // Printed books and ebooks
+	Duration  *int     `json:"duration_minutes,omitempty"` // Audiobooks
+	CoverURL  *string  `json:"cover_url,omitempty"`
+}
+
+// Validate checks the format, ISBN and length of the edition, trimming its text fields and
+// clearing empty ones. Hyphens and spaces are removed from the ISBN.
+func (e *Edition) Validate() error {
+	if !e.Format.IsValid() {
+		return &ValidationError{"format must be 'book', 'ebook' or 'audiobook'"}
+	}
+	e.ISBN = trimOptional(e.ISBN)
+	if e.ISBN != nil {
+		normalized, ok := isbn.Normalize(*e.ISBN)
+		if !ok {
+			return &ValidationError{"isbn must be a valid ISBN-10 or ISBN-13"}
+		}
+		e.ISBN = &normalized
+	}
+	if e.Publisher = trimOptional(e.Publisher); e.Publisher != nil && utf8.RuneCountInString(*e.Publisher) > MaxPublisherLength {
+		return &ValidationError{"publisher must be at most 200 characters"}
+	}
+	e.CoverURL = trimOptional(e.CoverURL)
+	if e.Pages != nil {
+		if e.Format == TypeAudiobook {
+			return &ValidationError{"audiobooks have a duration rather than pages"}
+		}
+		if *e.Pages < 1 {
+			return &ValidationError{"pages must be at least 1"}
+		}
+	}
+	if e.Duration != nil {
+		if e.Format != TypeAudiobook {
+			return &ValidationError{"only audiobooks have a duration"}
+		}
+		if *e.Duration < 1 {
+			return &ValidationError{"duration_minutes must be at least 1"}
+		}
+	}
+	return nil
+}
+
+// trimOptional trims an optional string, returning nil if it is empty.
+func trimOptional(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*s)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
@@ -0,0 +1,37 @@
+package model
+
+import "testing"
+
+func TestEditionValidate(t *testing.T) {
+	strPtr := func(s string) *string { return &s }
+	tests := []struct {
+		name    string
+		edition Edition
+		wantErr bool
+	}{
+		{name: "format only", edition: Edition{Format: TypeEbook}},
+		{name: "hardcover", edition: Edition{Format: TypeBook, ISBN: strPtr("978-0-441-17271-9"), Publisher: strPtr("Ace"), Pages: intPtr(604)}},
+		{name: "isbn-10 with X", edition: Edition{Format: TypeBook, ISBN: strPtr("0-8044-2957-X")}},
+		{name: "audiobook", edition: Edition{Format: TypeAudiobook, Duration: intPtr(1263)}},
+		{name: "missing format", edition: Edition{}, wantErr: true},
+		{name: "unknown format", edition: Edition{Format: "scroll"}, wantErr: true},
+		{name: "bad check digit", edition: Edition{Format: TypeBook, ISBN: strPtr("9780441172710")}, wantErr: true},
+		{name: "wrong length", edition: Edition{Format: TypeBook, ISBN: strPtr("12345")}, wantErr: true},
+		{name: "audiobook pages", edition: Edition{Format: TypeAudiobook, Pages: intPtr(300)}, wantErr: true},
+		{name: "ebook duration", edition: Edition{Format: TypeEbook, Duration: intPtr(60)}, wantErr: true},
+		{name: "zero pages", edition: Edition{Format: TypeBook, Pages: intPtr(0)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.edition.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	edition := Edition{Format: TypeBook, ISBN: strPtr(" 978 0 441 17271 9 "), Publisher: strPtr("  ")}
+	if err := edition.Validate(); err != nil || *edition.ISBN != "9780441172719" || edition.Publisher != nil {
+		t.Errorf("Expected the ISBN normalized and the blank publisher cleared, got %+v (%v)", edition, err)
+	}
+}
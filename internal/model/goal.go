@@ -0,0 +1,102 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+// Goal is a target number of books to finish in a year, optionally for a single BookType.
+type Goal struct {
+	ID     int64    `json:"id"`
+	Year   int      `json:"year"`
+	Type   BookType `json:"type,omitempty"` // Empty means books of every type count
+	Target int      `json:"target"`
+}
+
+// Validate checks the goal has a sensible year, a positive target and a valid type (if any).
+func (g *Goal) Validate() error {
+	if g.Year < 1900 || g.Year > 9999 {
+		return &ValidationError{"year must be between 1900 and 9999"}
+	}
+	if g.Target < 1 {
+		return &ValidationError{"target must be at least 1"}
+	}
+	if g.Type != "" && !g.Type.IsValid() {
+		return &ValidationError{"invalid type provided, must be 'book', 'ebook' or 'audiobook'"}
+	}
+	return nil
+}
+
+// GoalStatus summarises how a goal is going.
+type GoalStatus string
+
+const (
+	GoalAchieved   GoalStatus = "achieved"    // Target reached
+	GoalAhead      GoalStatus = "ahead"       // More books finished than the even pace requires
+	GoalOnTrack    GoalStatus = "on_track"    // Within half a book of the even pace
+	GoalBehind     GoalStatus = "behind"      // Fewer books finished than the even pace requires
+	GoalNotStarted GoalStatus = "not_started" // The year has not begun yet
+	GoalMissed     GoalStatus = "missed"      // The year is over and the target was not reached
+)
+
+// GoalProgress reports progress towards a Goal.
+type GoalProgress struct {
+	Goal
+	Finished        int     `json:"finished"`
+	Remaining       int     `json:"remaining"`
+	PercentComplete float64 `json:"percent_complete"`
+	// ExpectedByNow is how many books an even pace over the year would have finished by now.
+	ExpectedByNow float64 `json:"expected_by_now"`
+	// AheadBy is Finished - ExpectedByNow; negative when behind.
+	AheadBy float64 `json:"ahead_by"`
+	// ProjectedTotal extrapolates the pace so far to the whole year.
+	ProjectedTotal float64 `json:"projected_total"`
+	// RequiredPerMonth is the pace needed over the rest of the year to reach the target.
+	RequiredPerMonth float64    `json:"required_per_month"`
+	Status           GoalStatus `json:"status"`
+}
+
+// Progress computes progress towards the goal given the number of books finished in the
+// goal's year so far, as of now.
+func (g Goal) Progress(finished int, now time.Time) GoalProgress {
+	start := time.Date(g.Year, time.January, 1, 0, 0, 0, 0, now.Location())
+	end := start.AddDate(1, 0, 0)
+	elapsed := float64(now.Sub(start)) / float64(end.Sub(start))
+	elapsed = math.Max(0, math.Min(1, elapsed))
+
+	p := GoalProgress{
+		Goal:            g,
+		Finished:        finished,
+		Remaining:       max(0, g.Target-finished),
+		PercentComplete: round2(100 * float64(finished) / float64(g.Target)),
+		ExpectedByNow:   round2(elapsed * float64(g.Target)),
+	}
+	p.AheadBy = round2(float64(finished) - p.ExpectedByNow)
+	if elapsed > 0 {
+		p.ProjectedTotal = round2(float64(finished) / elapsed)
+	}
+	if monthsLeft := 12 * (1 - elapsed); monthsLeft > 0 {
+		p.RequiredPerMonth = round2(float64(p.Remaining) / monthsLeft)
+	}
+
+	switch {
+	case finished >= g.Target:
+		p.Status = GoalAchieved
+	case elapsed == 0:
+		p.Status = GoalNotStarted
+	case elapsed == 1:
+		p.Status = GoalMissed
+	case math.Abs(p.AheadBy) < 0.5:
+		p.Status = GoalOnTrack
+	case p.AheadBy > 0:
+		p.Status = GoalAhead
+	default:
+		p.Status = GoalBehind
+	}
+	return p
+}
+
+// round2 rounds to two decimal places, to keep API responses readable.
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
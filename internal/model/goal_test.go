@@ -0,0 +1,59 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGoalProgress(t *testing.T) {
+	goal := Goal{Year: 2026, Target: 24}
+	midYear := time.Date(2026, time.July, 2, 12, 0, 0, 0, time.UTC) // Exactly half of 2026
+
+	tests := []struct {
+		name     string
+		finished int
+		now      time.Time
+		want     GoalStatus
+	}{
+		{"ahead", 15, midYear, GoalAhead},
+		{"on track", 12, midYear, GoalOnTrack},
+		{"behind", 8, midYear, GoalBehind},
+		{"achieved", 24, midYear, GoalAchieved},
+		{"not started", 0, time.Date(2025, time.December, 1, 0, 0, 0, 0, time.UTC), GoalNotStarted},
+		{"missed", 20, time.Date(2027, time.January, 5, 0, 0, 0, 0, time.UTC), GoalMissed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := goal.Progress(tt.finished, tt.now)
+			if p.Status != tt.want {
+				t.Errorf("Expected status %s, got %s (%+v)", tt.want, p.Status, p)
+			}
+		})
+	}
+
+	p := goal.Progress(8, midYear)
+	if p.ExpectedByNow != 12 || p.AheadBy != -4 || p.ProjectedTotal != 16 || p.Remaining != 16 {
+		t.Errorf("Unexpected pace figures: %+v", p)
+	}
+	if p.RequiredPerMonth != 2.67 {
+		t.Errorf("Expected 16 books over 6 months to need 2.67/month, got %v", p.RequiredPerMonth)
+	}
+	if p.PercentComplete != 33.33 {
+		t.Errorf("Expected 33.33%% complete, got %v", p.PercentComplete)
+	}
+}
+
+func TestGoalValidate(t *testing.T) {
+	valid := []Goal{{Year: 2026, Target: 1}, {Year: 2026, Target: 5, Type: TypeAudiobook}}
+	for _, g := range valid {
+		if err := g.Validate(); err != nil {
+			t.Errorf("Expected %+v to be valid, got %v", g, err)
+		}
+	}
+	invalid := []Goal{{Year: 2026, Target: 0}, {Year: 26, Target: 5}, {Year: 2026, Target: 5, Type: "scroll"}}
+	for _, g := range invalid {
+		if err := g.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", g)
+		}
+	}
+}
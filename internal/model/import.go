@@ -0,0 +1,22 @@
+package model
+
+// ImportReport summarises an import of highlights into quotes.
+type ImportReport struct {
+	Clippings  int            `json:"clippings"`  // Entries read from the file
+	Unreadable int            `json:"unreadable"` // Entries that could not be parsed or are not valid quotes
+	Bookmarks  int            `json:"bookmarks"`  // Bookmarks, which have no text to import
+	Added      int            `json:"added"`      // New quotes
+	Duplicates int            `json:"duplicates"` // Quotes imported before
+	Books      []ImportedBook `json:"books"`
+}
+
+// ImportedBook reports what an import did for one book of the file.
+type ImportedBook struct {
+	Title      string `json:"title"`
+	Author     string `json:"author,omitempty"`
+	BookID     int64  `json:"book_id,omitempty"`
+	Created    bool   `json:"created"` // Added to the shelf by the import rather than matched
+	Added      int    `json:"added"`
+	Duplicates int    `json:"duplicates"`
+	Error      string `json:"error,omitempty"` // Why the book's quotes were not imported
+}
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxPersonLength is the maximum length of the person a book is lent to or borrowed from.
+const MaxPersonLength = 200
+
+// LoanDirection says whether a book was lent to someone or borrowed from them.
+type LoanDirection string
+
+const (
+	LoanLent     LoanDirection = "lent"     // Our book, which someone else has
+	LoanBorrowed LoanDirection = "borrowed" // Someone else's book, which we have
+)
+
+// IsValid checks if the direction is one of the allowed values.
+func (d LoanDirection) IsValid() bool {
+	switch d {
+	case LoanLent, LoanBorrowed:
+		return true
+	default:
+		return false
+	}
+}
+
+// Loan records a book lent to or borrowed from someone. Dates are calendar dates, stored as
+// midnight UTC.
+type Loan struct {
+	ID         int64         `json:"id"`
+	BookID     int64         `json:"book_id"`
+	Person     string        `json:"person"` // Who has the book, or whom it was borrowed from
+	Direction  LoanDirection `json:"direction"`
+	DateOut    time.Time     `json:"date_out"`
+	DueDate    *time.Time    `json:"due_date,omitempty"`
+	ReturnedAt *time.Time    `json:"returned_at,omitempty"` // Nil while the loan is open
+	Overdue    bool          `json:"overdue"`               // Open and past its due date; set by the store
+
+	// The book lent, filled in when listing loans
+	BookTitle  string `json:"book_title,omitempty"`
+	BookAuthor string `json:"book_author,omitempty"`
+}
+
+// Validate trims the person and checks the direction and that the due and return dates are
+// not before the date out. A zero DateOut is not checked; the store fills it in.
+func (l *Loan) Validate() error {
+	l.Person = strings.TrimSpace(l.Person)
+	if l.Person == "" {
+		return &ValidationError{"person is required"}
+	}
+	if utf8.RuneCountInString(l.Person) > MaxPersonLength {
+		return &ValidationError{"person must be at most 200 characters"}
+	}
+	if !l.Direction.IsValid() {
+		return &ValidationError{"direction must be 'lent' or 'borrowed'"}
+	}
+	if l.DateOut.IsZero() {
+		return nil
+	}
+	if l.DueDate != nil && l.DueDate.Before(l.DateOut) {
+		return &ValidationError{"due_date must not be before date_out"}
+	}
+	if l.ReturnedAt != nil && l.ReturnedAt.Before(l.DateOut) {
+		return &ValidationError{"returned_at must not be before date_out"}
+	}
+	return nil
+}
+
+// IsOverdue reports whether the loan is open and its due date was before the day of now (UTC).
+func (l *Loan) IsOverdue(now time.Time) bool {
+	return l.ReturnedAt == nil && l.DueDate != nil && l.DueDate.Before(Today(now))
+}
+
+// Today returns midnight UTC of the day of t, the form in which calendar dates are stored.
+func Today(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoanValidate(t *testing.T) {
+	out := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	before, after := out.AddDate(0, 0, -1), out.AddDate(0, 1, 0)
+	tests := []struct {
+		name    string
+		loan    Loan
+		wantErr bool
+	}{
+		{name: "lent", loan: Loan{Person: "Alice", Direction: LoanLent, DateOut: out, DueDate: &after}},
+		{name: "borrowed and returned", loan: Loan{Person: "Bob", Direction: LoanBorrowed, DateOut: out, ReturnedAt: &after}},
+		{name: "returned the same day", loan: Loan{Person: "Bob", Direction: LoanBorrowed, DateOut: out, ReturnedAt: &out}},
+		{name: "no date out yet", loan: Loan{Person: "Alice", Direction: LoanLent, DueDate: &before}},
+		{name: "missing person", loan: Loan{Person: "  ", Direction: LoanLent}, wantErr: true},
+		{name: "long person", loan: Loan{Person: strings.Repeat("a", MaxPersonLength+1), Direction: LoanLent}, wantErr: true},
+		{name: "bad direction", loan: Loan{Person: "Alice", Direction: "given"}, wantErr: true},
+		{name: "due before out", loan: Loan{Person: "Alice", Direction: LoanLent, DateOut: out, DueDate: &before}, wantErr: true},
+		{name: "returned before out", loan: Loan{Person: "Alice", Direction: LoanLent, DateOut: out, ReturnedAt: &before}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.loan.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoanIsOverdue(t *testing.T) {
+	due := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	loan := Loan{DueDate: &due}
+	if loan.IsOverdue(time.Date(2024, 6, 1, 23, 0, 0, 0, time.UTC)) {
+		t.Error("Expected a loan not overdue on its due date")
+	}
+	if !loan.IsOverdue(time.Date(2024, 6, 2, 0, 30, 0, 0, time.UTC)) {
+		t.Error("Expected a loan overdue the day after its due date")
+	}
+	loan.ReturnedAt = &due
+	if loan.IsOverdue(time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Error("Expected a returned loan never overdue")
+	}
+	if (&Loan{}).IsOverdue(time.Now()) {
+		t.Error("Expected a loan without due date never overdue")
+	}
+}
@@ -0,0 +1,110 @@
+package model
+
+import (
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Limits on the length of ownership fields, in characters.
+const (
+	MaxStoreLength = 200
+	MaxPlaceLength = 100 // Room and shelf
+)
+
+// OwnershipStatus says whether and how we have a copy of a book. Reading status is separate:
+// a library copy can be Currently Reading, a sold book Read.
+type OwnershipStatus string
+
+const (
+	OwnershipOwned    OwnershipStatus = "owned"
+	OwnershipWishlist OwnershipStatus = "wishlist" // Not owned yet, wanted
+	OwnershipLibrary  OwnershipStatus = "library"  // Borrowed from a library
+	OwnershipSold     OwnershipStatus = "sold"
+	OwnershipDonated  OwnershipStatus = "donated"
+)
+
+// OwnershipStatuses lists every valid OwnershipStatus.
+var OwnershipStatuses = []OwnershipStatus{OwnershipOwned, OwnershipWishlist, OwnershipLibrary, OwnershipSold, OwnershipDonated}
+
+// IsValid checks if the ownership status is one of the allowed values.
+func (s OwnershipStatus) IsValid() bool {
+	switch s {
+	case OwnershipOwned, OwnershipWishlist, OwnershipLibrary, OwnershipSold, OwnershipDonated:
+		return true
+	default:
+		return false
+	}
+}
+
+// Ownership records whether we own a book, how it was bought and where the copy is kept.
+type Ownership struct {
+	Status       OwnershipStatus `json:"status"`
+	PurchaseDate *time.Time      `json:"purchase_date,omitempty"`
+	Price        *float64        `json:"price,omitempty"`    // In Currency, rounded to cents
+	Currency     *string         `json:"currency,omitempty"` // ISO 4217 code, e.g. "USD"; required with a price
+	Store        *string         `json:"store,omitempty"`    // Where it was bought
+	Room         *string         `json:"room,omitempty"`     // Where the physical copy is kept
+	Shelf        *string         `json:"shelf,omitempty"`
+}
+
+// Validate checks the status, price and currency, trimming the text fields and clearing empty
+// ones. The currency is upper-cased and the price rounded to cents.
+func (o *Ownership) Validate() error {
+	if !o.Status.IsValid() {
+		return &ValidationError{"ownership status must be 'owned', 'wishlist', 'library', 'sold' or 'donated'"}
+	}
+	if o.Currency = trimOptional(o.Currency); o.Currency != nil {
+		currency := strings.ToUpper(*o.Currency)
+		if len(currency) != 3 || strings.Trim(currency, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+			return &ValidationError{"currency must be a three-letter ISO 4217 code"}
+		}
+		o.Currency = &currency
+	}
+	if o.Price != nil {
+		if *o.Price < 0 || math.IsNaN(*o.Price) || math.IsInf(*o.Price, 0) {
+			return &ValidationError{"price must not be negative"}
+		}
+		if o.Currency == nil {
+			return &ValidationError{"currency is required with a price"}
+		}
+		price := math.Round(*o.Price*100) / 100
+		o.Price = &price
+	}
+	if o.Store = trimOptional(o.Store); o.Store != nil && utf8.RuneCountInString(*o.Store) > MaxStoreLength {
+		return &ValidationError{"store must be at most 200 characters"}
+	}
+	if o.Room = trimOptional(o.Room); o.Room != nil && utf8.RuneCountInString(*o.Room) > MaxPlaceLength {
+		return &ValidationError{"room must be at most 100 characters"}
+	}
+	if o.Shelf = trimOptional(o.Shelf); o.Shelf != nil && utf8.RuneCountInString(*o.Shelf) > MaxPlaceLength {
+		return &ValidationError{"shelf must be at most 100 characters"}
+	}
+	return nil
+}
+
+// Amount is a sum of money in one currency.
+type Amount struct {
+	Currency string  `json:"currency"`
+	Amount   float64 `json:"amount"`
+}
+
+// InventoryLocation counts the books kept in one room and shelf, and what they cost. Room and
+// Shelf are empty for books whose location is not recorded.
+type InventoryLocation struct {
+	Room     string   `json:"room"`
+	Shelf    string   `json:"shelf"`
+	Count    int      `json:"count"`
+	Value    []Amount `json:"value"`    // Total price paid, per currency
+	Unpriced int      `json:"unpriced"` // Books without a price, not included in Value
+}
+
+// InventoryReport is the response of GET /api/inventory: the collection as a whole and per
+// location, ordered by room and shelf.
+type InventoryReport struct {
+	Count     int                 `json:"count"`
+	Value     []Amount            `json:"value"`
+	Unpriced  int                 `json:"unpriced"`
+	Locations []InventoryLocation `json:"locations"`
+}
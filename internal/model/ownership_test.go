@@ -0,0 +1,46 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOwnershipValidate(t *testing.T) {
+	price := func(p float64) *float64 { return &p }
+	str := func(s string) *string { return &s }
+	tests := []struct {
+		name      string
+		ownership Ownership
+		wantErr   bool
+	}{
+		{name: "status only", ownership: Ownership{Status: OwnershipWishlist}},
+		{name: "bought", ownership: Ownership{Status: OwnershipOwned, Price: price(18.99), Currency: str("usd"), Store: str("City Books"), Room: str("Study"), Shelf: str("B2")}},
+		{name: "free", ownership: Ownership{Status: OwnershipOwned, Price: price(0), Currency: str("EUR")}},
+		{name: "missing status", ownership: Ownership{Room: str("Study")}, wantErr: true},
+		{name: "bad status", ownership: Ownership{Status: "borrowed"}, wantErr: true},
+		{name: "negative price", ownership: Ownership{Status: OwnershipOwned, Price: price(-1), Currency: str("USD")}, wantErr: true},
+		{name: "price without currency", ownership: Ownership{Status: OwnershipOwned, Price: price(10)}, wantErr: true},
+		{name: "bad currency", ownership: Ownership{Status: OwnershipOwned, Currency: str("dollars")}, wantErr: true},
+		{name: "long store", ownership: Ownership{Status: OwnershipOwned, Store: str(strings.Repeat("a", MaxStoreLength+1))}, wantErr: true},
+		{name: "long shelf", ownership: Ownership{Status: OwnershipOwned, Shelf: str(strings.Repeat("a", MaxPlaceLength+1))}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.ownership.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOwnershipValidateNormalizes(t *testing.T) {
+	price, currency, store := 12.3456, " gbp ", "  "
+	ownership := Ownership{Status: OwnershipOwned, Price: &price, Currency: &currency, Store: &store}
+	if err := ownership.Validate(); err != nil {
+		t.Fatalf("Validate() failed: %v", err)
+	}
+	if *ownership.Price != 12.35 || *ownership.Currency != "GBP" || ownership.Store != nil {
+		t.Errorf("Expected the price rounded, currency upper-cased and empty store cleared, got %+v", ownership)
+	}
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Limits on the length of quote fields, in characters.
+const (
+	MaxQuoteLength    = 5000
+	MaxLocationLength = 100
+)
+
+// Quote is a highlighted passage of a book, with where it is in the book and a personal note.
+type Quote struct {
+	ID        int64     `json:"id"`
+	BookID    int64     `json:"book_id"`
+	Text      string    `json:"text"`
+	Page      *int      `json:"page,omitempty"`      // Page in a printed book
+	Location  *string   `json:"location,omitempty"`  // E-reader location or chapter, e.g. "1234-1240"
+	Timestamp *int      `json:"timestamp,omitempty"` // Seconds into an audiobook
+	Note      *string   `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// ImportHash identifies a quote imported from elsewhere, so that importing it again
+	// does not add it twice
+	ImportHash string `json:"-"`
+
+	// The book quoted, filled in when listing quotes across books
+	BookTitle  string `json:"book_title,omitempty"`
+	BookAuthor string `json:"book_author,omitempty"`
+}
+
+// Validate trims the quote and checks it has text and a sensible page, location and timestamp.
+// Empty locations and notes are cleared.
+func (q *Quote) Validate() error {
+	q.Text = strings.TrimSpace(q.Text)
+	if q.Text == "" {
+		return &ValidationError{"quote text is required"}
+	}
+	if utf8.RuneCountInString(q.Text) > MaxQuoteLength {
+		return &ValidationError{"quote text must be at most 5000 characters"}
+	}
+	if q.Page != nil && *q.Page < 1 {
+		return &ValidationError{"page must be at least 1"}
+	}
+	if q.Timestamp != nil && *q.Timestamp < 0 {
+		return &ValidationError{"timestamp must not be negative"}
+	}
+	if q.Location != nil {
+		if *q.Location = strings.TrimSpace(*q.Location); *q.Location == "" {
+			q.Location = nil
+		} else if utf8.RuneCountInString(*q.Location) > MaxLocationLength {
+			return &ValidationError{"location must be at most 100 characters"}
+		}
+	}
+	if q.Note != nil {
+		if *q.Note = strings.TrimSpace(*q.Note); *q.Note == "" {
+			q.Note = nil
+		} else if utf8.RuneCountInString(*q.Note) > MaxQuoteLength {
+			return &ValidationError{"note must be at most 5000 characters"}
+		}
+	}
+	return nil
+}
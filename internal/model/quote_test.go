@@ -0,0 +1,39 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQuoteValidate(t *testing.T) {
+	blank := "  "
+	longLocation := strings.Repeat("1", MaxLocationLength+1)
+	tests := []struct {
+		name    string
+		quote   Quote
+		wantErr bool
+	}{
+		{name: "text only", quote: Quote{Text: "It was a bright cold day in April"}},
+		{name: "with page", quote: Quote{Text: "x", Page: intPtr(12)}},
+		{name: "start of audiobook", quote: Quote{Text: "x", Timestamp: intPtr(0)}},
+		{name: "missing text", quote: Quote{Text: "   "}, wantErr: true},
+		{name: "too long", quote: Quote{Text: strings.Repeat("a", MaxQuoteLength+1)}, wantErr: true},
+		{name: "page zero", quote: Quote{Text: "x", Page: intPtr(0)}, wantErr: true},
+		{name: "negative timestamp", quote: Quote{Text: "x", Timestamp: intPtr(-1)}, wantErr: true},
+		{name: "long location", quote: Quote{Text: "x", Location: &longLocation}, wantErr: true},
+		{name: "blank note", quote: Quote{Text: "x", Note: &blank}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.quote.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	quote := Quote{Text: "  trimmed  ", Note: &blank}
+	if err := quote.Validate(); err != nil || quote.Text != "trimmed" || quote.Note != nil {
+		t.Errorf("Expected the text trimmed and the blank note cleared, got %+v (%v)", quote, err)
+	}
+}
@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// WorkMetadata is what the metadata provider (Open Library) told us about a work. It is cached
+// so that recommendations can be made offline.
+type WorkMetadata struct {
+	OpenLibraryID string    `json:"open_library_id"` // Work ID, e.g. OL27448W
+	Title         string    `json:"title"`
+	Author        string    `json:"author"`              // Combined author names
+	Subjects      []string  `json:"subjects"`            // As given by the provider, e.g. "Fantasy fiction"
+	ISBN          *string   `json:"isbn,omitempty"`      // First available ISBN-13 or ISBN-10
+	CoverURL      *string   `json:"cover_url,omitempty"` // URL for medium cover
+	FetchedAt     time.Time `json:"fetched_at"`          // When it was last fetched from the provider
+}
+
+// RecommendationSource says where a recommended book comes from.
+type RecommendationSource string
+
+const (
+	SourceShelf       RecommendationSource = "shelf"        // A Want to Read book
+	SourceOpenLibrary RecommendationSource = "open_library" // A book not on the shelf yet
+)
+
+// IsValid checks if the source is one of the predefined values.
+func (s RecommendationSource) IsValid() bool {
+	return s == SourceShelf || s == SourceOpenLibrary
+}
+
+// Recommendation is a book suggested to read next, with why.
+type Recommendation struct {
+	Source        RecommendationSource `json:"source"`
+	BookID        *int64               `json:"book_id,omitempty"` // The Want to Read book, for shelf recommendations
+	OpenLibraryID string               `json:"open_library_id,omitempty"`
+	Title         string               `json:"title"`
+	Author        string               `json:"author"`
+	ISBN          *string              `json:"isbn,omitempty"`
+	CoverURL      *string              `json:"cover_url,omitempty"`
+	Score         float64              `json:"score"`   // Higher is better; only comparable within one response
+	Reasons       []string             `json:"reasons"` // Why it is recommended, strongest first
+}
@@ -0,0 +1,149 @@
+package model
+
+import (
+	"sort"
+	"strings"
+)
+
+// SeriesStatus summarises how far through a series the reader is.
+type SeriesStatus string
+
+const (
+	SeriesNotStarted SeriesStatus = "not_started" // No book of the series has been read
+	SeriesInProgress SeriesStatus = "in_progress"
+	SeriesComplete   SeriesStatus = "complete" // Every book of the series has been read
+)
+
+// Series groups books that share a series name. Books are linked by Book.SeriesID.
+type Series struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	TotalBooks *int   `json:"total_books,omitempty"` // Number of books in the series, if known
+
+	// Computed by Summarize from Books.
+	Books           []Book       `json:"books"`   // Ordered by series index, unnumbered books last
+	Read            []int        `json:"read"`    // Series indexes that have been read
+	Gaps            []int        `json:"gaps"`    // Unread indexes before the highest one read ("read 1, 2 and 4" gives [3])
+	Missing         []int        `json:"missing"` // Indexes up to the series length that are not on the shelf
+	PercentComplete float64      `json:"percent_complete"`
+	Status          SeriesStatus `json:"status"`
+}
+
+// Validate checks the series name and length.
+func (s *Series) Validate() error {
+	s.Name = strings.TrimSpace(s.Name)
+	if s.Name == "" {
+		return &ValidationError{"series name is required"}
+	}
+	if s.TotalBooks != nil && *s.TotalBooks <= 0 {
+		return &ValidationError{"total_books must be greater than 0"}
+	}
+	return nil
+}
+
+// Length is the number of books the series is known to have: TotalBooks if set, otherwise
+// the highest series index on the shelf.
+func (s *Series) Length() int {
+	length := 0
+	if s.TotalBooks != nil {
+		length = *s.TotalBooks
+	}
+	for _, book := range s.Books {
+		if book.SeriesIndex != nil {
+			length = max(length, *book.SeriesIndex)
+		}
+	}
+	return length
+}
+
+// Summarize computes the read indexes, gaps, missing books and completion from Books.
+// Books without a series index only count towards completion if the series has none numbered.
+func (s *Series) Summarize() {
+	owned := map[int]bool{}
+	read := map[int]bool{}
+	unnumbered, unnumberedRead := 0, 0
+	for _, book := range s.Books {
+		if book.SeriesIndex == nil {
+			unnumbered++
+			if book.Status == StatusRead {
+				unnumberedRead++
+			}
+			continue
+		}
+		owned[*book.SeriesIndex] = true
+		if book.Status == StatusRead {
+			read[*book.SeriesIndex] = true
+		}
+	}
+
+	s.Read, s.Gaps, s.Missing = []int{}, []int{}, []int{}
+	for index := range read {
+		s.Read = append(s.Read, index)
+	}
+	sort.Ints(s.Read)
+	if len(s.Read) > 0 {
+		for index := 1; index < s.Read[len(s.Read)-1]; index++ {
+			if !read[index] {
+				s.Gaps = append(s.Gaps, index)
+			}
+		}
+	}
+
+	length := s.Length()
+	for index := 1; index <= length; index++ {
+		if !owned[index] {
+			s.Missing = append(s.Missing, index)
+		}
+	}
+
+	readCount, total := len(s.Read), length
+	if length == 0 {
+		readCount, total = unnumberedRead, unnumbered
+	}
+	switch {
+	case total > 0 && readCount >= total:
+		s.Status = SeriesComplete
+		s.PercentComplete = 100
+	case readCount > 0:
+		s.Status = SeriesInProgress
+		s.PercentComplete = round2(float64(readCount) / float64(total) * 100)
+	default:
+		s.Status = SeriesNotStarted
+		s.PercentComplete = 0
+	}
+}
+
+// NextAfter finds the first position after index that has not been read: the book there if
+// it is on the shelf, or nil if it is not. ok is false if the series is known to end before
+// that position.
+func (s *Series) NextAfter(index int) (next int, book *Book, ok bool) {
+	for next = index + 1; s.TotalBooks == nil || next <= *s.TotalBooks; next++ {
+		var found *Book
+		for i := range s.Books {
+			if s.Books[i].SeriesIndex != nil && *s.Books[i].SeriesIndex == next {
+				found = &s.Books[i]
+				if found.Status != StatusRead {
+					break
+				}
+			}
+		}
+		if found == nil || found.Status != StatusRead {
+			return next, found, true
+		}
+	}
+	return 0, nil, false
+}
+
+// NextInSeries suggests what to read after a book of a series: the next book, if it is on
+// the shelf, or else a Candidate for it found with the metadata provider.
+type NextInSeries struct {
+	SeriesID    int64  `json:"series_id"`
+	Series      string `json:"series"`
+	AfterBookID int64  `json:"after_book_id"` // The book read
+	AfterTitle  string `json:"after_title"`
+	SeriesIndex int    `json:"series_index"`   // Position of the next book
+	Book        *Book  `json:"book,omitempty"` // The next book, if it is on the shelf
+	// Candidate is the provider's best match for the next book when it is not on the shelf,
+	// with its series set and status Want to Read: POST it to /api/books to add it.
+	Candidate *Book `json:"candidate,omitempty"`
+}
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSeriesSummarize(t *testing.T) {
+	book := func(index int, status BookStatus) Book {
+		return Book{SeriesIndex: &index, Status: status}
+	}
+	five := 5
+
+	tests := []struct {
+		name        string
+		series      Series
+		wantGaps    []int
+		wantMissing []int
+		wantPercent float64
+		wantStatus  SeriesStatus
+	}{
+		{
+			name: "gap in reading",
+			series: Series{Books: []Book{
+				book(1, StatusRead), book(2, StatusRead), book(3, StatusWantToRead), book(4, StatusRead),
+			}},
+			wantGaps: []int{3}, wantMissing: []int{}, wantPercent: 75, wantStatus: SeriesInProgress,
+		},
+		{
+			name:        "known length with missing books",
+			series:      Series{TotalBooks: &five, Books: []Book{book(1, StatusRead), book(4, StatusRead)}},
+			wantGaps:    []int{2, 3},
+			wantMissing: []int{2, 3, 5},
+			wantPercent: 40,
+			wantStatus:  SeriesInProgress,
+		},
+		{
+			name:     "complete",
+			series:   Series{Books: []Book{book(1, StatusRead), book(2, StatusRead)}},
+			wantGaps: []int{}, wantMissing: []int{}, wantPercent: 100, wantStatus: SeriesComplete,
+		},
+		{
+			name:     "not started, unnumbered",
+			series:   Series{Books: []Book{{Status: StatusWantToRead}}},
+			wantGaps: []int{}, wantMissing: []int{}, wantPercent: 0, wantStatus: SeriesNotStarted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.series
+			s.Summarize()
+			if !reflect.DeepEqual(s.Gaps, tt.wantGaps) || !reflect.DeepEqual(s.Missing, tt.wantMissing) {
+				t.Errorf("Expected gaps %v and missing %v, got %v and %v", tt.wantGaps, tt.wantMissing, s.Gaps, s.Missing)
+			}
+			if s.PercentComplete != tt.wantPercent || s.Status != tt.wantStatus {
+				t.Errorf("Expected %v%% %s, got %v%% %s", tt.wantPercent, tt.wantStatus, s.PercentComplete, s.Status)
+			}
+		})
+	}
+}
+
+func TestSeriesNextAfter(t *testing.T) {
+	book := func(index int, status BookStatus) Book {
+		return Book{Title: "Book " + string(rune('0'+index)), SeriesIndex: &index, Status: status}
+	}
+	three := 3
+	series := Series{Books: []Book{book(1, StatusRead), book(2, StatusRead), book(3, StatusWantToRead)}}
+
+	if next, found, ok := series.NextAfter(1); !ok || next != 3 || found == nil || found.Title != "Book 3" {
+		t.Errorf("Expected the unread book 3 after 1, got %d %+v %v", next, found, ok)
+	}
+	series.Books[2].Status = StatusRead
+	if next, found, ok := series.NextAfter(3); !ok || next != 4 || found != nil {
+		t.Errorf("Expected book 4, not on the shelf, got %d %+v %v", next, found, ok)
+	}
+	series.TotalBooks = &three
+	if _, _, ok := series.NextAfter(2); ok {
+		t.Error("Expected no next book in a series read to its end")
+	}
+}
@@ -0,0 +1,64 @@
+package model
+
+// Stats is an aggregate view of the shelf and of reading activity.
+// FinishedBooks, the top lists and the type split cover the books currently on the Read
+// shelf. The per-period counts, streaks and time to finish cover every move to Read in the
+// status history, the finishes that also count towards reading goals.
+type Stats struct {
+	TotalBooks    int `json:"total_books"`
+	FinishedBooks int `json:"finished_books"`
+
+	// A book moved off Read still counts in the period it was finished, and a re-read counts again.
+	FinishedPerMonth []PeriodCount `json:"finished_per_month"` // Period is "YYYY-MM", oldest first
+	FinishedPerYear  []PeriodCount `json:"finished_per_year"`  // Period is "YYYY", oldest first
+
+	AverageRating      *float64      `json:"average_rating"` // nil if no book is rated
+	RatingDistribution []RatingCount `json:"rating_distribution"`
+
+	TopAuthors []AuthorStat `json:"top_authors"`
+	TopSeries  []SeriesStat `json:"top_series"`
+	ByType     []TypeStat   `json:"by_type"`
+
+	// AverageDaysToFinish is the mean time between moving to Currently Reading and
+	// moving to Read; nil if no finish has both transitions recorded.
+	AverageDaysToFinish *float64 `json:"average_days_to_finish"`
+
+	// CurrentStreakMonths counts consecutive months, up to this month, in which at least one
+	// book was finished. A streak is still current if the last finish was last month.
+	CurrentStreakMonths int `json:"current_streak_months"`
+	LongestStreakMonths int `json:"longest_streak_months"`
+}
+
+// PeriodCount is the number of books finished in a month or year.
+type PeriodCount struct {
+	Period string `json:"period"`
+	Count  int    `json:"count"`
+}
+
+// RatingCount is the number of books with a given rating.
+type RatingCount struct {
+	Rating int `json:"rating"`
+	Count  int `json:"count"`
+}
+
+// AuthorStat summarises the finished books of one author.
+type AuthorStat struct {
+	AuthorID      int64    `json:"author_id"`
+	Author        string   `json:"author"`
+	Count         int      `json:"count"`
+	AverageRating *float64 `json:"average_rating"`
+}
+
+// SeriesStat summarises the finished books of one series.
+type SeriesStat struct {
+	Series        string   `json:"series"`
+	Count         int      `json:"count"`
+	AverageRating *float64 `json:"average_rating"`
+}
+
+// TypeStat counts books of one BookType.
+type TypeStat struct {
+	Type     BookType `json:"type"`
+	Total    int      `json:"total"`
+	Finished int      `json:"finished"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// UndoAction names the kind of action an undo step reverses. Bulk operations use the name of
+// their operation.
+type UndoAction string
+
+const (
+	UndoSetStatus   UndoAction = "set_status"
+	UndoSetType     UndoAction = "set_type"
+	UndoEditDetails UndoAction = "edit_details"
+	UndoMove        UndoAction = "move"
+	UndoDelete      UndoAction = "delete"
+	UndoAddTag      UndoAction = "add_tag"
+	UndoRemoveTag   UndoAction = "remove_tag"
+	UndoMerge       UndoAction = "merge"
+)
+
+// UndoStep describes an action that was undone or redone.
+type UndoStep struct {
+	ID      int64      `json:"id"`
+	Action  UndoAction `json:"action"`
+	Time    time.Time  `json:"time"`     // When the action was first made
+	BookIDs []int64    `json:"book_ids"` // The books it changed
+}
@@ -0,0 +1,76 @@
+package model
+
+import (
+	"net/url"
+	"time"
+)
+
+// WebhookEvent is the name of an event delivered to webhook subscribers.
+type WebhookEvent string
+
+const (
+	WebhookBookAdded         WebhookEvent = "book.added"
+	WebhookBookStatusChanged WebhookEvent = "book.status_changed"
+	WebhookBookRated         WebhookEvent = "book.rated"
+	WebhookBookDeleted       WebhookEvent = "book.deleted"
+)
+
+// IsValid checks if the event is one of the predefined webhook events.
+func (e WebhookEvent) IsValid() bool {
+	switch e {
+	case WebhookBookAdded, WebhookBookStatusChanged, WebhookBookRated, WebhookBookDeleted:
+		return true
+	default:
+		return false
+	}
+}
+
+// Webhook is a subscription that receives shelf events as signed HTTP POST requests.
+type Webhook struct {
+	ID        int64          `json:"id"`
+	URL       string         `json:"url"`
+	Events    []WebhookEvent `json:"events"`           // Event filter; empty means all events
+	Secret    string         `json:"secret,omitempty"` // HMAC-SHA256 signing key; only returned on creation
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// Validate checks the webhook has an absolute http(s) URL and only known events.
+func (w *Webhook) Validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return &ValidationError{"url must be an absolute http or https URL"}
+	}
+	for _, event := range w.Events {
+		if !event.IsValid() {
+			return &ValidationError{"invalid event: " + string(event)}
+		}
+	}
+	return nil
+}
+
+// Wants reports whether the webhook subscribes to event.
+func (w *Webhook) Wants(event WebhookEvent) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery records a single attempt to deliver an event to a webhook.
+type WebhookDelivery struct {
+	ID          int64        `json:"id"`
+	WebhookID   int64        `json:"webhook_id"`
+	DeliveryID  string       `json:"delivery_id"` // Shared by all attempts of the same delivery
+	Event       WebhookEvent `json:"event"`
+	Attempt     int          `json:"attempt"`
+	StatusCode  *int         `json:"status_code,omitempty"` // nil if no response was received
+	Error       *string      `json:"error,omitempty"`
+	Success     bool         `json:"success"`
+	DurationMS  int64        `json:"duration_ms"`
+	DeliveredAt time.Time    `json:"delivered_at"`
+}
@@ -0,0 +1,403 @@
+// Package recommend ranks books to read next, from the Want to Read shelf and from the
+// metadata provider, by how much they resemble the books rated highly on the shelf.
+package recommend
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+	"github.com/ericdahl/bookshelf/internal/textmatch"
+)
+
+// HighRating is the lowest rating (1-10) of a book that was liked. The subjects of liked
+// books make the profile that candidates are compared with.
+const HighRating = 7
+
+// Weights of the signals in a score, and caps on the signals that add up over many books.
+const (
+	authorWeight      = 2.0
+	maxAuthorAffinity = 3.0
+	subjectWeight     = 1.0
+	maxSubjectScore   = 3.0
+	seriesWeight      = 2.0
+)
+
+// workIDPattern matches the Open Library IDs of works, which have subjects to fetch. Books
+// added by hand or imported have other IDs.
+var workIDPattern = regexp.MustCompile(`^OL[0-9]+W$`)
+
+// IsWorkID reports whether id is an Open Library work ID, e.g. OL27448W.
+func IsWorkID(id string) bool {
+	return workIDPattern.MatchString(id)
+}
+
+// liking scales a rating from -1 (rated 1) to 1 (rated 10), 0 being indifferent.
+func liking(rating int) float64 {
+	return (float64(rating) - 5.5) / 4.5
+}
+
+// ignoredSubjects are subjects the provider gives to too many books to say anything about
+// them, or that describe the copy rather than the work.
+var ignoredSubjects = map[string]bool{
+	"fiction": true, "general": true, "literature": true, "novel": true, "novels": true,
+	"accessible book": true, "protected daisy": true, "in library": true, "lending library": true,
+	"large type books": true, "overdrive": true, "open library staff picks": true,
+	"english literature": true, "american literature": true, "juvenile fiction": true,
+}
+
+// subjectKeys splits provider subjects such as "Fiction, fantasy, general" into their parts,
+// lowercased, without ignored or machine-readable ones ("nyt:...", "series:...").
+func subjectKeys(subjects []string) map[string]string {
+	keys := map[string]string{}
+	for _, subject := range subjects {
+		for _, part := range strings.Split(subject, ",") {
+			part = strings.Join(strings.Fields(part), " ")
+			key := strings.ToLower(part)
+			if key == "" || ignoredSubjects[key] || strings.ContainsAny(key, ":=") {
+				continue
+			}
+			if _, ok := keys[key]; !ok {
+				keys[key] = part
+			}
+		}
+	}
+	return keys
+}
+
+// authorNames returns the names of the authors of a book, from its credits if it has any.
+func authorNames(book model.Book) []string {
+	if names := model.AuthorNames(book.Authors); names != "" {
+		return splitAuthors(names)
+	}
+	return splitAuthors(book.Author)
+}
+
+// splitAuthors splits a comma-joined author string into names.
+func splitAuthors(author string) []string {
+	var names []string
+	for _, name := range strings.Split(author, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// seriesKey identifies a series by its name, ignoring case.
+func seriesKey(series string) string {
+	return strings.ToLower(strings.TrimSpace(series))
+}
+
+// authorStats is how the rated books of an author were liked.
+type authorStats struct {
+	name      string
+	affinity  float64 // Sum of the liking of the author's rated books
+	ratingSum int
+	titles    []string
+}
+
+// subjectStats is how much the liked books with a subject were liked.
+type subjectStats struct {
+	name   string
+	weight float64
+	titles []string
+}
+
+// profile is what the shelf says about the reader's taste.
+type profile struct {
+	authors  map[string]*authorStats  // By textmatch.NormalizeName
+	subjects map[string]*subjectStats // By subject key
+	read     map[string]map[int]model.Book
+}
+
+// newProfile computes the author affinities from every rated book, the subjects of liked
+// books with cached metadata, and the series positions read.
+func newProfile(library []model.Book, metadata map[string]model.WorkMetadata) *profile {
+	p := &profile{authors: map[string]*authorStats{}, subjects: map[string]*subjectStats{}, read: map[string]map[int]model.Book{}}
+	for _, book := range library {
+		if book.Status == model.StatusRead && book.Series != nil && book.SeriesIndex != nil {
+			key := seriesKey(*book.Series)
+			if p.read[key] == nil {
+				p.read[key] = map[int]model.Book{}
+			}
+			p.read[key][*book.SeriesIndex] = book
+		}
+		if book.Rating == nil {
+			continue
+		}
+		for _, name := range authorNames(book) {
+			key := textmatch.NormalizeName(name)
+			if key == "" {
+				continue
+			}
+			stats := p.authors[key]
+			if stats == nil {
+				stats = &authorStats{name: name}
+				p.authors[key] = stats
+			}
+			stats.affinity += liking(*book.Rating)
+			stats.ratingSum += *book.Rating
+			stats.titles = append(stats.titles, book.Title)
+		}
+		work, ok := metadata[book.OpenLibraryID]
+		if !ok || *book.Rating < HighRating {
+			continue
+		}
+		for key, name := range subjectKeys(work.Subjects) {
+			stats := p.subjects[key]
+			if stats == nil {
+				stats = &subjectStats{name: name}
+				p.subjects[key] = stats
+			}
+			stats.weight += liking(*book.Rating)
+			stats.titles = append(stats.titles, book.Title)
+		}
+	}
+	return p
+}
+
+// signal is one reason a candidate is recommended, and how much it adds to the score.
+type signal struct {
+	score  float64
+	reason string
+}
+
+// authorSignals scores the candidate's authors by the reader's affinity for them.
+func (p *profile) authorSignals(authors []string) []signal {
+	var signals []signal
+	for _, name := range authors {
+		stats, ok := p.authors[textmatch.NormalizeName(name)]
+		if !ok {
+			continue
+		}
+		affinity := max(-maxAuthorAffinity, min(maxAuthorAffinity, stats.affinity))
+		var reason string
+		if len(stats.titles) == 1 {
+			reason = fmt.Sprintf("By %s, whose %s you rated %d", name, stats.titles[0], stats.ratingSum)
+		} else {
+			reason = fmt.Sprintf("By %s, whose %d books you rated %.1f on average", name, len(stats.titles),
+				float64(stats.ratingSum)/float64(len(stats.titles)))
+		}
+		signals = append(signals, signal{authorWeight * affinity, reason})
+	}
+	return signals
+}
+
+// subjectSignal scores the subjects the candidate shares with liked books.
+func (p *profile) subjectSignal(subjects []string) (signal, bool) {
+	var shared []*subjectStats
+	total := 0.0
+	for key := range subjectKeys(subjects) {
+		if stats, ok := p.subjects[key]; ok && stats.weight > 0 {
+			shared = append(shared, stats)
+			total += stats.weight
+		}
+	}
+	if len(shared) == 0 {
+		return signal{}, false
+	}
+	slices.SortFunc(shared, func(a, b *subjectStats) int {
+		return cmp.Or(cmp.Compare(b.weight, a.weight), strings.Compare(a.name, b.name))
+	})
+	var names, titles []string
+	for _, stats := range shared[:min(3, len(shared))] {
+		names = append(names, stats.name)
+		for _, title := range stats.titles {
+			if len(titles) < 2 && !slices.Contains(titles, title) {
+				titles = append(titles, title)
+			}
+		}
+	}
+	reason := fmt.Sprintf("About %s, like %s", strings.Join(names, ", "), strings.Join(titles, " and "))
+	return signal{subjectWeight * min(total, maxSubjectScore), reason}, true
+}
+
+// seriesSignal scores a book that comes right after one read in its series, more so the
+// better that one was rated.
+func (p *profile) seriesSignal(book model.Book) (signal, bool) {
+	if book.Series == nil || book.SeriesIndex == nil {
+		return signal{}, false
+	}
+	previous, ok := p.read[seriesKey(*book.Series)][*book.SeriesIndex-1]
+	if !ok {
+		return signal{}, false
+	}
+	if previous.Rating == nil {
+		return signal{seriesWeight, fmt.Sprintf("Next in %s after %s, which you have read", *book.Series, previous.Title)}, true
+	}
+	return signal{seriesWeight * (1 + liking(*previous.Rating)),
+		fmt.Sprintf("Next in %s after %s, which you rated %d", *book.Series, previous.Title, *previous.Rating)}, true
+}
+
+// score adds up the signals into the recommendation, with their reasons strongest first.
+// Signals that lower the score are explained too.
+func score(rec model.Recommendation, signals []signal) model.Recommendation {
+	slices.SortStableFunc(signals, func(a, b signal) int { return cmp.Compare(math.Abs(b.score), math.Abs(a.score)) })
+	total := 0.0
+	rec.Reasons = []string{}
+	for _, s := range signals {
+		if s.score == 0 {
+			continue
+		}
+		total += s.score
+		rec.Reasons = append(rec.Reasons, s.reason)
+	}
+	rec.Score = math.Round(total*100) / 100
+	return rec
+}
+
+// Rank scores the Want to Read books of library, and the works in cache that are not on the
+// shelf, by their authors (weighted by the ratings of the authors' other books), the subjects
+// they share with books rated HighRating or more, and whether they continue a series that was
+// read. It returns those that score above zero, best first, each with its reasons. The
+// subjects of books on the shelf are taken from cache; without them only authors and series
+// count.
+func Rank(library []model.Book, cache []model.WorkMetadata) []model.Recommendation {
+	metadata := make(map[string]model.WorkMetadata, len(cache))
+	for _, work := range cache {
+		metadata[work.OpenLibraryID] = work
+	}
+	p := newProfile(library, metadata)
+
+	recommendations := []model.Recommendation{}
+	add := func(rec model.Recommendation, signals []signal) {
+		if rec = score(rec, signals); rec.Score > 0 {
+			recommendations = append(recommendations, rec)
+		}
+	}
+	onShelf := map[string]bool{}
+	for _, book := range library {
+		onShelf[book.OpenLibraryID] = true
+		if book.Status != model.StatusWantToRead {
+			continue
+		}
+		id := book.ID
+		rec := model.Recommendation{Source: model.SourceShelf, BookID: &id, OpenLibraryID: book.OpenLibraryID,
+			Title: book.Title, Author: book.Author, CoverURL: book.CoverURL}
+		if book.ISBN != "" {
+			isbn := book.ISBN
+			rec.ISBN = &isbn
+		}
+		signals := p.authorSignals(authorNames(book))
+		if work, ok := metadata[book.OpenLibraryID]; ok {
+			if s, ok := p.subjectSignal(work.Subjects); ok {
+				signals = append(signals, s)
+			}
+		}
+		if s, ok := p.seriesSignal(book); ok {
+			signals = append(signals, s)
+		}
+		add(rec, signals)
+	}
+
+	// The provider has several works for some books; only the first counts
+	cache = slices.Clone(cache)
+	slices.SortFunc(cache, func(a, b model.WorkMetadata) int { return strings.Compare(a.OpenLibraryID, b.OpenLibraryID) })
+	var seen []model.WorkMetadata
+	for _, work := range cache {
+		if onShelf[work.OpenLibraryID] || sameWork(work, library, seen) {
+			continue
+		}
+		seen = append(seen, work)
+		rec := model.Recommendation{Source: model.SourceOpenLibrary, OpenLibraryID: work.OpenLibraryID,
+			Title: work.Title, Author: work.Author, ISBN: work.ISBN, CoverURL: work.CoverURL}
+		signals := p.authorSignals(splitAuthors(work.Author))
+		if s, ok := p.subjectSignal(work.Subjects); ok {
+			signals = append(signals, s)
+		}
+		add(rec, signals)
+	}
+
+	slices.SortFunc(recommendations, func(a, b model.Recommendation) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(sourceOrder(a.Source), sourceOrder(b.Source)),
+			strings.Compare(a.Title, b.Title))
+	})
+	return recommendations
+}
+
+// sourceOrder puts books already on the shelf before those that score the same on Open
+// Library.
+func sourceOrder(source model.RecommendationSource) int {
+	if source == model.SourceShelf {
+		return 0
+	}
+	return 1
+}
+
+// sameWork reports whether work has the same title and author as a book on the shelf or a
+// work already ranked.
+func sameWork(work model.WorkMetadata, library []model.Book, seen []model.WorkMetadata) bool {
+	title := textmatch.NormalizeTitle(work.Title)
+	for _, book := range library {
+		if textmatch.NormalizeTitle(book.Title) == title && textmatch.SameAuthor(book.Author, work.Author) {
+			return true
+		}
+	}
+	for _, other := range seen {
+		if textmatch.NormalizeTitle(other.Title) == title && textmatch.SameAuthor(other.Author, work.Author) {
+			return true
+		}
+	}
+	return false
+}
+
+// MissingWorks returns the Open Library work IDs of liked and Want to Read books whose
+// metadata is not in cache, at most limit of them, to fetch from the provider.
+func MissingWorks(library []model.Book, cache []model.WorkMetadata, limit int) []string {
+	cached := map[string]bool{}
+	for _, work := range cache {
+		cached[work.OpenLibraryID] = true
+	}
+	var ids []string
+	for _, book := range library {
+		liked := book.Rating != nil && *book.Rating >= HighRating
+		if len(ids) == limit || !IsWorkID(book.OpenLibraryID) || cached[book.OpenLibraryID] ||
+			!(liked || book.Status == model.StatusWantToRead) {
+			continue
+		}
+		cached[book.OpenLibraryID] = true
+		ids = append(ids, book.OpenLibraryID)
+	}
+	return ids
+}
+
+// TopAuthors returns the names of the n authors the reader likes most, to search the provider
+// for more of their books.
+func TopAuthors(library []model.Book, n int) []string {
+	return top(newProfile(library, nil).authors, n, func(s *authorStats) (float64, string) { return s.affinity, s.name })
+}
+
+// TopSubjects returns the n subjects of the books liked most, as given by the provider, to
+// search it for more books about them.
+func TopSubjects(library []model.Book, cache []model.WorkMetadata, n int) []string {
+	metadata := make(map[string]model.WorkMetadata, len(cache))
+	for _, work := range cache {
+		metadata[work.OpenLibraryID] = work
+	}
+	return top(newProfile(library, metadata).subjects, n, func(s *subjectStats) (float64, string) { return s.weight, s.name })
+}
+
+// top returns the names of the n stats with the highest positive weights.
+func top[T any](stats map[string]T, n int, weight func(T) (float64, string)) []string {
+	type entry struct {
+		weight float64
+		name   string
+	}
+	var entries []entry
+	for _, s := range stats {
+		if w, name := weight(s); w > 0 {
+			entries = append(entries, entry{w, name})
+		}
+	}
+	slices.SortFunc(entries, func(a, b entry) int { return cmp.Or(cmp.Compare(b.weight, a.weight), strings.Compare(a.name, b.name)) })
+	names := []string{}
+	for _, e := range entries[:min(n, len(entries))] {
+		names = append(names, e.name)
+	}
+	return names
+}
@@ -0,0 +1,110 @@
+package recommend
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+func ptrTo[T any](v T) *T { return &v }
+
+// testLibrary has two liked Le Guin books, a disliked Dan Brown and three books to read.
+func testLibrary() []model.Book {
+	return []model.Book{
+		{ID: 1, Title: "A Wizard of Earthsea", Author: "Ursula K. Le Guin", OpenLibraryID: "OL59800W",
+			Status: model.StatusRead, Rating: ptrTo(9), Series: ptrTo("Earthsea"), SeriesIndex: ptrTo(1)},
+		{ID: 2, Title: "The Left Hand of Darkness", Author: "Ursula K. Le Guin", OpenLibraryID: "OL59801W",
+			Status: model.StatusRead, Rating: ptrTo(10)},
+		{ID: 3, Title: "The Da Vinci Code", Author: "Dan Brown", OpenLibraryID: "OL76837W",
+			Status: model.StatusRead, Rating: ptrTo(2)},
+		{ID: 4, Title: "The Tombs of Atuan", Author: "Ursula K. Le Guin", OpenLibraryID: "OL59802W",
+			Status: model.StatusWantToRead, Series: ptrTo("earthsea"), SeriesIndex: ptrTo(2)},
+		{ID: 5, Title: "Inferno", Author: "Dan Brown", OpenLibraryID: "OL16439W", Status: model.StatusWantToRead},
+		{ID: 6, Title: "Cookbook", Author: "Someone Else", OpenLibraryID: "hand-added", Status: model.StatusWantToRead},
+	}
+}
+
+func testCache() []model.WorkMetadata {
+	return []model.WorkMetadata{
+		{OpenLibraryID: "OL59800W", Title: "A Wizard of Earthsea", Subjects: []string{"Fiction, fantasy, general", "Wizards", "Magic"}},
+		{OpenLibraryID: "OL59801W", Title: "The Left Hand of Darkness", Subjects: []string{"Fiction", "Science fiction", "Gender"}},
+		{OpenLibraryID: "OL1W", Title: "The Name of the Wind", Author: "Patrick Rothfuss", Subjects: []string{"Wizards", "Magic", "nyt:fantasy=2008"}},
+		{OpenLibraryID: "OL2W", Title: "The Dispossessed", Author: "Ursula K. Le Guin", Subjects: []string{"Science fiction"}},
+		{OpenLibraryID: "OL3W", Title: "Dispossessed, The", Author: "Le Guin, Ursula K."},                                // Another work of the same book
+		{OpenLibraryID: "OL4W", Title: "A Wizard of Earthsea", Author: "Ursula K. Le Guin", Subjects: []string{"Magic"}}, // On the shelf under another ID
+		{OpenLibraryID: "OL5W", Title: "Gardening", Author: "Nobody", Subjects: []string{"Fiction"}},
+	}
+}
+
+func TestRank(t *testing.T) {
+	recs := Rank(testLibrary(), testCache())
+
+	var titles []string
+	for _, rec := range recs {
+		titles = append(titles, rec.Title)
+	}
+	want := []string{"The Tombs of Atuan", "The Dispossessed", "The Name of the Wind"}
+	if !slices.Equal(titles, want) {
+		t.Fatalf("Expected %v, got %v", want, titles)
+	}
+
+	tombs := recs[0]
+	if tombs.Source != model.SourceShelf || tombs.BookID == nil || *tombs.BookID != 4 {
+		t.Errorf("Expected the Want to Read book, got %+v", tombs)
+	}
+	if len(tombs.Reasons) != 2 || !strings.HasPrefix(tombs.Reasons[0], "By Ursula K. Le Guin, whose 2 books you rated 9.5 on average") ||
+		tombs.Reasons[1] != "Next in earthsea after A Wizard of Earthsea, which you rated 9" {
+		t.Errorf("Unexpected reasons %q", tombs.Reasons)
+	}
+
+	wind := recs[2]
+	if wind.Source != model.SourceOpenLibrary || wind.OpenLibraryID != "OL1W" || wind.BookID != nil {
+		t.Errorf("Expected an Open Library book, got %+v", wind)
+	}
+	if len(wind.Reasons) != 1 || wind.Reasons[0] != "About Magic, Wizards, like A Wizard of Earthsea" {
+		t.Errorf("Unexpected reasons %q", wind.Reasons)
+	}
+	if wind.Score <= 0 || wind.Score >= recs[1].Score {
+		t.Errorf("Expected scores to decrease, got %v after %v", wind.Score, recs[1].Score)
+	}
+}
+
+func TestRankDislikedAuthor(t *testing.T) {
+	// Inferno is only by an author rated low, so it is not recommended at all
+	for _, rec := range Rank(testLibrary(), nil) {
+		if rec.Title == "Inferno" {
+			t.Errorf("Expected a disliked author not to be recommended, got %+v", rec)
+		}
+	}
+}
+
+func TestRankEmpty(t *testing.T) {
+	if recs := Rank(nil, nil); recs == nil || len(recs) != 0 {
+		t.Errorf("Expected an empty list, got %v", recs)
+	}
+}
+
+func TestMissingWorks(t *testing.T) {
+	got := MissingWorks(testLibrary(), testCache()[:1], 10)
+	// The disliked book and the hand-added one are not needed
+	want := []string{"OL59801W", "OL59802W", "OL16439W"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got := MissingWorks(testLibrary(), nil, 1); len(got) != 1 {
+		t.Errorf("Expected the limit to apply, got %v", got)
+	}
+}
+
+func TestTopAuthorsAndSubjects(t *testing.T) {
+	if got := TopAuthors(testLibrary(), 3); !slices.Equal(got, []string{"Ursula K. Le Guin"}) {
+		t.Errorf("Expected only the liked author, got %v", got)
+	}
+	got := TopSubjects(testLibrary(), testCache(), 2)
+	// Science fiction and Gender are from the book rated 10, the others from one rated 9
+	if !slices.Equal(got, []string{"Gender", "Science fiction"}) {
+		t.Errorf("Unexpected subjects %v", got)
+	}
+}
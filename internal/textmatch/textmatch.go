@@ -0,0 +1,119 @@
+// Package textmatch compares book titles and author names as people write them, allowing
+// for differences in case, punctuation, articles, subtitles and small typos.
+package textmatch
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// NormalizeTitle reduces a title to lowercase words, without subtitle, parenthesized parts
+// and a leading article, and with "&" spelled out: "The Fellowship of the Ring (The Lord of
+// the Rings, Book 1)" becomes "fellowship of the ring".
+func NormalizeTitle(title string) string {
+	var b strings.Builder
+	depth := 0
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r == '(' || r == '[':
+			depth++
+		case r == ')' || r == ']':
+			if depth > 0 {
+				depth--
+			}
+		case depth > 0:
+		case r == ':':
+			// Drop the subtitle
+			return stripArticle(strings.Join(strings.Fields(b.String()), " "))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		case r == '&':
+			b.WriteString(" and ")
+		case r == '\'' || r == '’':
+			// "Ender's" and "Enders" are the same word
+		default:
+			b.WriteRune(' ')
+		}
+	}
+	return stripArticle(strings.Join(strings.Fields(b.String()), " "))
+}
+
+// stripArticle removes a leading English article.
+func stripArticle(title string) string {
+	for _, article := range []string{"the ", "a ", "an "} {
+		if rest, ok := strings.CutPrefix(title, article); ok && rest != "" {
+			return rest
+		}
+	}
+	return title
+}
+
+// nameWords returns the lowercase words of an author string longer than an initial.
+func nameWords(author string) map[string]bool {
+	words := map[string]bool{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(author), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		if len([]rune(word)) > 1 {
+			words[word] = true
+		}
+	}
+	return words
+}
+
+// NormalizeName reduces an author's name to its lowercase words longer than an initial, in
+// alphabetical order, so that "Le Guin, Ursula K." and "Ursula K. Le Guin" are the same.
+func NormalizeName(name string) string {
+	words := make([]string, 0, 4)
+	for word := range nameWords(name) {
+		words = append(words, word)
+	}
+	slices.Sort(words)
+	return strings.Join(words, " ")
+}
+
+// SameAuthor reports whether two author strings share a name, or either is empty.
+func SameAuthor(a, b string) bool {
+	wordsA, wordsB := nameWords(a), nameWords(b)
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return true
+	}
+	for word := range wordsA {
+		if wordsB[word] {
+			return true
+		}
+	}
+	return false
+}
+
+// Similarity is 1 minus the edit distance between a and b relative to the longer of the two.
+func Similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// levenshtein computes the edit distance between a and b.
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,51 @@
+package textmatch
+
+import "testing"
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := []struct {
+		title, want string
+	}{
+		{"The Fellowship of the Ring (The Lord of the Rings, Book 1)", "fellowship of the ring"},
+		{"Dune: Deluxe Edition", "dune"},
+		{"Ender’s Game", "enders game"},
+		{"A", "a"},
+		{"Pride & Prejudice", "pride and prejudice"},
+		{"  Harry Potter and the Half-Blood Prince [Illustrated]", "harry potter and the half blood prince"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeTitle(tt.title); got != tt.want {
+			t.Errorf("NormalizeTitle(%q) = %q; want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	if got := NormalizeName("Le Guin, Ursula K."); got != "guin le ursula" {
+		t.Errorf("NormalizeName = %q; want %q", got, "guin le ursula")
+	}
+	if NormalizeName("Ursula K. Le Guin") != NormalizeName("LE GUIN, URSULA") {
+		t.Error("Expected the same name written differently to normalize alike")
+	}
+}
+
+func TestSameAuthor(t *testing.T) {
+	if !SameAuthor("J. R. R. Tolkien", "J.R.R. Tolkien") || !SameAuthor("", "Anyone") {
+		t.Error("Expected authors sharing a name, or unknown, to match")
+	}
+	if SameAuthor("Frank Herbert", "Jane Austen") {
+		t.Error("Expected different authors not to match")
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	if got := Similarity("dune", "dune"); got != 1 {
+		t.Errorf("Expected identical strings fully similar, got %v", got)
+	}
+	if got := Similarity("kitten", "sitting"); got < 0.57 || got > 0.58 {
+		t.Errorf("Expected 1 - 3/7, got %v", got)
+	}
+	if got := Similarity("", ""); got != 1 {
+		t.Errorf("Expected empty strings fully similar, got %v", got)
+	}
+}
@@ -0,0 +1,295 @@
+// Package webhook delivers shelf events to webhook subscribers as signed HTTP POST requests.
+//
+// The Dispatcher subscribes to the store's event bus, translates book changes into webhook
+// events (book.added, book.status_changed, book.rated, book.deleted) and delivers each one
+// to every subscribed webhook, retrying failed attempts with exponential backoff. Every
+// attempt is recorded in the delivery log.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/events"
+	"github.com/ericdahl/bookshelf/internal/model"
+)
+
+// Headers sent with every delivery.
+const (
+	EventHeader     = "X-Bookshelf-Event"
+	DeliveryHeader  = "X-Bookshelf-Delivery"
+	SignatureHeader = "X-Bookshelf-Signature" // "sha256=" + hex HMAC-SHA256 of the body
+)
+
+// Delivery defaults used by NewDispatcher.
+const (
+	DefaultMaxAttempts    = 5
+	DefaultInitialBackoff = 2 * time.Second
+	DefaultMaxBackoff     = 5 * time.Minute
+)
+
+// userAgent identifies webhook requests to receivers.
+const userAgent = "BookshelfApp-Webhook/1.0"
+
+// Payload is the JSON body POSTed to webhook URLs.
+type Payload struct {
+	Event     model.WebhookEvent `json:"event"`
+	Timestamp time.Time          `json:"timestamp"`
+	BookID    int64              `json:"book_id"`
+	Book      *model.Book        `json:"book,omitempty"`     // After the change; omitted for book.deleted
+	Previous  *model.Book        `json:"previous,omitempty"` // Before the change; omitted for book.added
+}
+
+// Dispatcher delivers events to the webhooks in Store.
+type Dispatcher struct {
+	Store  db.WebhookStore
+	Client *http.Client
+
+	// MaxAttempts is the total number of attempts per delivery, including the first.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry; it doubles for each further retry, up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	wg sync.WaitGroup
+}
+
+// NewDispatcher creates a Dispatcher with the default retry policy.
+func NewDispatcher(store db.WebhookStore) *Dispatcher {
+	return &Dispatcher{
+		Store:          store,
+		Client:         &http.Client{Timeout: 10 * time.Second},
+		MaxAttempts:    DefaultMaxAttempts,
+		InitialBackoff: DefaultInitialBackoff,
+		MaxBackoff:     DefaultMaxBackoff,
+	}
+}
+
+// Run consumes events from bus and dispatches them until ctx is cancelled. If the
+// dispatcher falls behind and is dropped by the bus, it resubscribes and resumes from the
+// last event it handled.
+func (d *Dispatcher) Run(ctx context.Context, bus *events.Bus) {
+	var lastID uint64
+	for {
+		ch, replay, complete, cancel := bus.Subscribe(lastID)
+		if !complete {
+			slog.Warn("Webhook dispatcher missed events", "lastEventID", lastID)
+		}
+		for _, event := range replay {
+			d.Dispatch(ctx, event)
+			lastID = event.ID
+		}
+
+	consume:
+		for {
+			select {
+			case <-ctx.Done():
+				cancel()
+				return
+			case event, ok := <-ch:
+				if !ok {
+					break consume
+				}
+				d.Dispatch(ctx, event)
+				lastID = event.ID
+			}
+		}
+		cancel()
+		slog.Warn("Webhook dispatcher fell behind, resubscribing", "lastEventID", lastID)
+	}
+}
+
+// Wait blocks until all in-flight deliveries (including pending retries) have finished.
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// Dispatch starts delivering the webhook events derived from a store event to every
+// subscribed webhook. Deliveries run in the background; Dispatch does not wait for them.
+func (d *Dispatcher) Dispatch(ctx context.Context, event events.Event) {
+	webhookEvents := Translate(event)
+	if len(webhookEvents) == 0 {
+		return
+	}
+
+	webhooks, err := d.Store.GetWebhooks(ctx)
+	if err != nil {
+		slog.Error("Failed to load webhooks for dispatch", "eventID", event.ID, "error", err)
+		return
+	}
+
+	for _, webhookEvent := range webhookEvents {
+		payload := Payload{
+			Event:     webhookEvent,
+			Timestamp: event.Time,
+			BookID:    event.BookID,
+			Book:      event.Book,
+			Previous:  event.Previous,
+		}
+		body, err := json.Marshal(payload)
+		if err != nil {
+			slog.Error("Failed to marshal webhook payload", "event", webhookEvent, "error", err)
+			continue
+		}
+		for _, webhook := range webhooks {
+			if !webhook.Wants(webhookEvent) {
+				continue
+			}
+			d.wg.Add(1)
+			go func(webhook model.Webhook, event model.WebhookEvent) {
+				defer d.wg.Done()
+				d.deliver(ctx, webhook, event, body)
+			}(webhook, webhookEvent)
+		}
+	}
+}
+
+// Translate maps a store event to the webhook events it represents. An update can be
+// both a status change and a rating; updates touching neither produce no webhook events.
+func Translate(event events.Event) []model.WebhookEvent {
+	switch event.Type {
+	case events.BookCreated:
+		return []model.WebhookEvent{model.WebhookBookAdded}
+	case events.BookDeleted:
+		return []model.WebhookEvent{model.WebhookBookDeleted}
+	case events.BookUpdated:
+		if event.Book == nil || event.Previous == nil {
+			return nil
+		}
+		var result []model.WebhookEvent
+		if event.Book.Status != event.Previous.Status {
+			result = append(result, model.WebhookBookStatusChanged)
+		}
+		if event.Book.Rating != nil && (event.Previous.Rating == nil || *event.Previous.Rating != *event.Book.Rating) {
+			result = append(result, model.WebhookBookRated)
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+// Sign returns the signature header value for body: "sha256=" followed by the hex-encoded
+// HMAC-SHA256 of body keyed with secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret generates a random signing secret for a new webhook.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// newDeliveryID generates the ID shared by all attempts of one delivery.
+func newDeliveryID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// deliver POSTs body to the webhook, retrying with exponential backoff until it succeeds,
+// fails permanently, runs out of attempts or ctx is cancelled.
+func (d *Dispatcher) deliver(ctx context.Context, webhook model.Webhook, event model.WebhookEvent, body []byte) {
+	deliveryID := newDeliveryID()
+	logger := slog.With("webhookID", webhook.ID, "event", event, "deliveryID", deliveryID)
+	backoff := d.InitialBackoff
+
+	for attempt := 1; attempt <= d.MaxAttempts; attempt++ {
+		delivery := d.attempt(ctx, webhook, event, deliveryID, attempt, body)
+		if err := d.Store.AddDelivery(context.WithoutCancel(ctx), &delivery); err != nil {
+			logger.Error("Failed to record webhook delivery", "error", err)
+		}
+		if delivery.Success {
+			logger.Info("Webhook delivered", "attempt", attempt, "statusCode", *delivery.StatusCode)
+			return
+		}
+		if !retryable(delivery) || attempt == d.MaxAttempts {
+			logger.Warn("Webhook delivery failed", "attempt", attempt, "statusCode", delivery.StatusCode, "error", delivery.Error)
+			return
+		}
+
+		logger.Info("Webhook delivery failed, retrying", "attempt", attempt, "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > d.MaxBackoff {
+			backoff = d.MaxBackoff
+		}
+	}
+}
+
+// attempt makes a single delivery attempt and describes its outcome.
+func (d *Dispatcher) attempt(ctx context.Context, webhook model.Webhook, event model.WebhookEvent, deliveryID string, attempt int, body []byte) model.WebhookDelivery {
+	delivery := model.WebhookDelivery{
+		WebhookID:   webhook.ID,
+		DeliveryID:  deliveryID,
+		Event:       event,
+		Attempt:     attempt,
+		DeliveredAt: time.Now().UTC(),
+	}
+	start := time.Now()
+
+	fail := func(err error) model.WebhookDelivery {
+		msg := err.Error()
+		delivery.Error = &msg
+		delivery.DurationMS = time.Since(start).Milliseconds()
+		return delivery
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return fail(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(EventHeader, string(event))
+	req.Header.Set(DeliveryHeader, deliveryID)
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+	// Drain (a bounded amount of) the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	statusCode := resp.StatusCode
+	delivery.StatusCode = &statusCode
+	delivery.Success = statusCode >= 200 && statusCode < 300
+	delivery.DurationMS = time.Since(start).Milliseconds()
+	return delivery
+}
+
+// retryable reports whether a failed attempt is worth retrying: network errors, rate
+// limiting and server errors are; other client errors (e.g. 404, 410) are not.
+func retryable(delivery model.WebhookDelivery) bool {
+	if delivery.StatusCode == nil {
+		return true
+	}
+	code := *delivery.StatusCode
+	return code == http.StatusTooManyRequests || code == http.StatusRequestTimeout || code >= 500
+}
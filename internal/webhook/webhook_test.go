@@ -0,0 +1,251 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ericdahl/bookshelf/internal/db"
+	"github.com/ericdahl/bookshelf/internal/events"
+	"github.com/ericdahl/bookshelf/internal/model"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// receivedRequest is a webhook request captured by the test receiver.
+type receivedRequest struct {
+	Header http.Header
+	Body   []byte
+}
+
+// testReceiver is a local webhook endpoint that records requests and answers with the
+// queued status codes (200 once they run out).
+type testReceiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []receivedRequest
+}
+
+func (rcv *testReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	rcv.requests = append(rcv.requests, receivedRequest{Header: r.Header.Clone(), Body: body})
+	status := http.StatusOK
+	if len(rcv.statuses) > 0 {
+		status, rcv.statuses = rcv.statuses[0], rcv.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (rcv *testReceiver) received() []receivedRequest {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	return append([]receivedRequest(nil), rcv.requests...)
+}
+
+// setupDispatcher creates a dispatcher backed by an in-memory database, with fast retries.
+func setupDispatcher(t *testing.T) (*Dispatcher, *db.SQLiteBookStore) {
+	t.Helper()
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open in-memory database: %v", err)
+	}
+	database.SetMaxOpenConns(1)
+	t.Cleanup(func() { database.Close() })
+	if err := db.CreateSchema(database); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+
+	store := db.NewSQLiteBookStore(database)
+	dispatcher := NewDispatcher(store)
+	dispatcher.InitialBackoff = time.Millisecond
+	dispatcher.MaxBackoff = 5 * time.Millisecond
+	return dispatcher, store
+}
+
+func addWebhook(t *testing.T, store *db.SQLiteBookStore, url string, events ...model.WebhookEvent) *model.Webhook {
+	t.Helper()
+	hook := &model.Webhook{URL: url, Events: events, Secret: "s3cret"}
+	if _, err := store.AddWebhook(context.Background(), hook); err != nil {
+		t.Fatalf("Failed to add webhook: %v", err)
+	}
+	return hook
+}
+
+func TestDispatchSignsAndRetries(t *testing.T) {
+	dispatcher, store := setupDispatcher(t)
+	receiver := &testReceiver{statuses: []int{http.StatusInternalServerError, http.StatusServiceUnavailable}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	hook := addWebhook(t, store, server.URL)
+
+	book := &model.Book{ID: 3, Title: "Dune", Status: model.StatusWantToRead}
+	dispatcher.Dispatch(context.Background(), events.Event{ID: 1, Type: events.BookCreated, BookID: 3, Book: book})
+	dispatcher.Wait()
+
+	requests := receiver.received()
+	if len(requests) != 3 {
+		t.Fatalf("Expected 3 attempts (2 failures then success), got %d", len(requests))
+	}
+	last := requests[2]
+	if got := last.Header.Get(EventHeader); got != string(model.WebhookBookAdded) {
+		t.Errorf("Expected event header %q, got %q", model.WebhookBookAdded, got)
+	}
+	if got, want := last.Header.Get(SignatureHeader), Sign("s3cret", last.Body); got != want {
+		t.Errorf("Expected signature %q, got %q", want, got)
+	}
+	if requests[0].Header.Get(DeliveryHeader) != last.Header.Get(DeliveryHeader) {
+		t.Error("Expected all attempts to share the delivery ID")
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(last.Body, &payload); err != nil {
+		t.Fatalf("Failed to decode payload: %v", err)
+	}
+	if payload.Event != model.WebhookBookAdded || payload.BookID != 3 || payload.Book == nil || payload.Book.Title != "Dune" {
+		t.Errorf("Unexpected payload: %+v", payload)
+	}
+
+	deliveries, err := store.GetDeliveries(context.Background(), hook.ID, 10)
+	if err != nil {
+		t.Fatalf("Failed to get deliveries: %v", err)
+	}
+	if len(deliveries) != 3 {
+		t.Fatalf("Expected 3 logged attempts, got %d", len(deliveries))
+	}
+	if !deliveries[0].Success || deliveries[0].Attempt != 3 || *deliveries[0].StatusCode != http.StatusOK {
+		t.Errorf("Expected newest entry to be the successful third attempt, got %+v", deliveries[0])
+	}
+	if deliveries[2].Success || *deliveries[2].StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected oldest entry to be the failed first attempt, got %+v", deliveries[2])
+	}
+}
+
+func TestDispatchStopsOnClientError(t *testing.T) {
+	dispatcher, store := setupDispatcher(t)
+	receiver := &testReceiver{statuses: []int{http.StatusGone}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	addWebhook(t, store, server.URL)
+
+	dispatcher.Dispatch(context.Background(), events.Event{ID: 1, Type: events.BookDeleted, BookID: 1})
+	dispatcher.Wait()
+
+	if n := len(receiver.received()); n != 1 {
+		t.Errorf("Expected a single attempt for a 410 response, got %d", n)
+	}
+}
+
+func TestDispatchGivesUpAfterMaxAttempts(t *testing.T) {
+	dispatcher, store := setupDispatcher(t)
+	dispatcher.MaxAttempts = 3
+	receiver := &testReceiver{statuses: []int{500, 500, 500, 500}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	addWebhook(t, store, server.URL)
+
+	dispatcher.Dispatch(context.Background(), events.Event{ID: 1, Type: events.BookDeleted, BookID: 1})
+	dispatcher.Wait()
+
+	if n := len(receiver.received()); n != 3 {
+		t.Errorf("Expected 3 attempts, got %d", n)
+	}
+}
+
+func TestDispatchEventFilter(t *testing.T) {
+	dispatcher, store := setupDispatcher(t)
+	ratedReceiver := &testReceiver{}
+	ratedServer := httptest.NewServer(ratedReceiver)
+	defer ratedServer.Close()
+	allReceiver := &testReceiver{}
+	allServer := httptest.NewServer(allReceiver)
+	defer allServer.Close()
+	addWebhook(t, store, ratedServer.URL, model.WebhookBookRated)
+	addWebhook(t, store, allServer.URL)
+
+	rating := 9
+	previous := &model.Book{ID: 1, Status: model.StatusCurrentlyReading}
+	book := &model.Book{ID: 1, Status: model.StatusRead}
+	dispatcher.Dispatch(context.Background(), events.Event{ID: 1, Type: events.BookUpdated, BookID: 1, Book: book, Previous: previous})
+	rated := *book
+	rated.Rating = &rating
+	dispatcher.Dispatch(context.Background(), events.Event{ID: 2, Type: events.BookUpdated, BookID: 1, Book: &rated, Previous: book})
+	dispatcher.Wait()
+
+	if got := ratedReceiver.received(); len(got) != 1 || got[0].Header.Get(EventHeader) != string(model.WebhookBookRated) {
+		t.Errorf("Expected only book.rated for the filtered webhook, got %d requests", len(got))
+	}
+	if got := allReceiver.received(); len(got) != 2 {
+		t.Errorf("Expected book.status_changed and book.rated for the unfiltered webhook, got %d requests", len(got))
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	rating, newRating := 5, 8
+	base := model.Book{Status: model.StatusWantToRead, Rating: &rating}
+
+	tests := []struct {
+		name  string
+		event events.Event
+		want  []model.WebhookEvent
+	}{
+		{"created", events.Event{Type: events.BookCreated}, []model.WebhookEvent{model.WebhookBookAdded}},
+		{"deleted", events.Event{Type: events.BookDeleted}, []model.WebhookEvent{model.WebhookBookDeleted}},
+		{"status changed", events.Event{Type: events.BookUpdated, Previous: &base,
+			Book: &model.Book{Status: model.StatusRead, Rating: &rating}}, []model.WebhookEvent{model.WebhookBookStatusChanged}},
+		{"rated", events.Event{Type: events.BookUpdated, Previous: &base,
+			Book: &model.Book{Status: model.StatusWantToRead, Rating: &newRating}}, []model.WebhookEvent{model.WebhookBookRated}},
+		{"rating cleared", events.Event{Type: events.BookUpdated, Previous: &base,
+			Book: &model.Book{Status: model.StatusWantToRead}}, nil},
+		{"other change", events.Event{Type: events.BookUpdated, Previous: &base, Book: &base}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Translate(tt.event)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Expected %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRunDispatchesBusEvents(t *testing.T) {
+	dispatcher, store := setupDispatcher(t)
+	receiver := &testReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	addWebhook(t, store, server.URL)
+
+	bus := events.NewBus(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		dispatcher.Run(ctx, bus)
+		close(done)
+	}()
+
+	// Wait for the dispatcher to subscribe before publishing
+	deadline := time.Now().Add(2 * time.Second)
+	for len(receiver.received()) == 0 && time.Now().Before(deadline) {
+		bus.Publish(events.Event{Type: events.BookDeleted, BookID: 1})
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+	dispatcher.Wait()
+
+	if len(receiver.received()) == 0 {
+		t.Error("Expected the dispatcher to deliver events published on the bus")
+	}
+}
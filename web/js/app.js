@@ -5,9 +5,33 @@ document.addEventListener('DOMContentLoaded', function() {
         SEARCH: '/api/books/search',
         BOOK_STATUS: (id) => `/api/books/${id}`,
         BOOK_DETAILS: (id) => `/api/books/${id}/details`,
-        DELETE_BOOK: (id) => `/api/books/${id}`
+        DELETE_BOOK: (id) => `/api/books/${id}`,
+        BOOK_POSITION: (id) => `/api/books/${id}/position`,
+        UNDO: '/api/undo',
+        REDO: '/api/redo',
+        EVENTS: '/api/events'
     };
 
+    // Identifies this tab to the server, so Undo reverts this tab's changes only
+    const SESSION_ID = sessionStorage.getItem('bookshelfSession') || newSessionId();
+    sessionStorage.setItem('bookshelfSession', SESSION_ID);
+
+    function newSessionId() {
+        const bytes = new Uint8Array(16);
+        crypto.getRandomValues(bytes);
+        return Array.from(bytes, b => b.toString(16).padStart(2, '0')).join('');
+    }
+
+    // Icons and labels of the book types shown on cards ("book" is the default and isn't shown)
+    const TYPE_BADGES = {
+        ebook: { icon: 'fa-tablet-alt', label: 'Ebook' },
+        audiobook: { icon: 'fa-headphones', label: 'Audiobook' }
+    };
+
+    function typeIconHtml(type) {
+        return TYPE_BADGES[type] ? `<i class="fas ${TYPE_BADGES[type].icon}"></i> ` : '';
+    }
+
     // DOM Elements
     const searchInput = document.getElementById('search-input');
     const searchButton = document.getElementById('search-button');
@@ -23,6 +47,8 @@ document.addEventListener('DOMContentLoaded', function() {
     const ratingStars = document.querySelectorAll('.stars i');
     const fullViewButton = document.getElementById('full-view');
     const compactViewButton = document.getElementById('compact-view');
+    const undoButton = document.getElementById('undo-button');
+    const redoButton = document.getElementById('redo-button');
     const shelvesContainer = document.querySelector('.shelves-container');
 
     // Current book being viewed/edited
@@ -45,18 +71,118 @@ document.addEventListener('DOMContentLoaded', function() {
         
         // Initialize shelf sorting
         initShelfSorting();
+
+        // Follow changes made in other tabs and by other people
+        subscribeToShelfEvents();
+    }
+
+    // Subscribe to the server's live shelf updates. EventSource reconnects on its own and
+    // resumes from the last event it saw, so nothing is missed across short disconnects.
+    function subscribeToShelfEvents() {
+        if (!window.EventSource) {
+            return;
+        }
+        const source = new EventSource(API.EVENTS);
+        source.addEventListener('book.created', event => applyShelfEvent(JSON.parse(event.data)));
+        source.addEventListener('book.updated', event => applyShelfEvent(JSON.parse(event.data)));
+        source.addEventListener('book.deleted', event => applyShelfEvent(JSON.parse(event.data)));
+        source.addEventListener('series.next', event => suggestNextInSeries(JSON.parse(event.data)));
+        // The server could not replay what we missed; start over from a full load
+        source.addEventListener('reset', () => loadBooks());
+    }
+
+    // Books this tab moved to Read, so only this tab suggests what comes next in their series
+    const finishedInThisTab = new Set();
+
+    // Suggest the next book of a series after one was finished: point to it if it is on the
+    // shelf, or offer to add the book Open Library found for it to Want to Read
+    function suggestNextInSeries(change) {
+        if (!finishedInThisTab.delete(change.book_id) || !change.next) {
+            return;
+        }
+        const next = change.next;
+        if (next.book) {
+            if (next.book.status !== 'Currently Reading') {
+                alert(`Next in ${next.series}: "${next.book.title}" is already on your "${next.book.status}" shelf.`);
+            }
+            return;
+        }
+        if (!next.candidate ||
+            !confirm(`You finished "${next.after_title}". Add #${next.series_index} of ${next.series}, "${next.candidate.title}" by ${next.candidate.author}, to Want to Read?`)) {
+            return;
+        }
+        fetch(API.BOOKS, {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json',
+                'X-Session-ID': SESSION_ID
+            },
+            body: JSON.stringify(next.candidate)
+        })
+        .then(response => {
+            if (!response.ok) {
+                return apiError(response, 'Failed to add book');
+            }
+            // The book.created event puts it on the shelf
+        })
+        .catch(error => {
+            console.error('Error adding next book in series:', error);
+            alert(error.code === 'duplicate' ? 'This book is already on your bookshelf.' : 'Failed to add book. Please try again.');
+        });
+    }
+
+    // Apply a single book change event to the shelves
+    function applyShelfEvent(change) {
+        const existingCard = document.querySelector(`.book-card[data-id="${change.book_id}"]`);
+
+        if (!change.book) {
+            // Deleted
+            if (existingCard) {
+                existingCard.remove();
+            }
+            if (currentBook && currentBook.id === change.book_id) {
+                bookDetails.classList.add('hidden');
+                currentBook = null;
+            }
+            return;
+        }
+
+        const book = change.book;
+        const currentShelf = existingCard ? existingCard.closest('.books-container') : null;
+        if (existingCard && currentShelf && currentShelf.dataset.status === book.status) {
+            // Same shelf: swap the card in place so its click handler sees the new data
+            const card = createBookCard(book);
+            existingCard.replaceWith(card);
+            if (shelvesContainer.classList.contains('compact-mode')) {
+                convertBookCardToTableRow(card);
+            }
+        } else {
+            if (existingCard) {
+                existingCard.remove();
+            }
+            addBookToShelf(book);
+        }
+        if (currentBook && currentBook.id === book.id) {
+            currentBook = book;
+        }
     }
     
     // Initialize shelf sorting
     function initShelfSorting() {
-        // Default sort by title for all shelves when app starts
+        // Default sort by title for all shelves when app starts, except the
+        // Want to Read queue which keeps its manual order
         document.querySelectorAll('.books-container').forEach(container => {
             const status = container.dataset.status;
             if (status) {
-                sortShelfBooks(status, 'title', 'asc');
+                sortShelfBooks(status, defaultSort(status), 'asc');
             }
         });
     }
+
+    // The default sort for a shelf
+    function defaultSort(status) {
+        return status === 'Want to Read' ? 'position' : 'title';
+    }
     
     // Handle column header clicks for sorting
     function handleHeaderClick(event) {
@@ -98,10 +224,10 @@ document.addEventListener('DOMContentLoaded', function() {
                     }
                 });
                 
-                // Sort each shelf's books by title (default) and add to shelf
+                // Sort each shelf's books (see defaultSort) and add to shelf
                 Object.keys(booksByStatus).forEach(status => {
                     const sortSelect = document.querySelector(`.sort-select[data-status="${status}"]`);
-                    const sortBy = sortSelect ? sortSelect.value : 'title';
+                    const sortBy = sortSelect ? sortSelect.value : defaultSort(status);
                     
                     const sortedBooks = sortBooks(booksByStatus[status], sortBy);
                     sortedBooks.forEach(book => {
@@ -126,6 +252,8 @@ document.addEventListener('DOMContentLoaded', function() {
                     return a.title.localeCompare(b.title);
                 case 'author':
                     return a.author.localeCompare(b.author);
+                case 'position':
+                    return a.position - b.position;
                 case 'rating':
                     // Handle null ratings (null ratings go at the end)
                     if (a.rating === null && b.rating === null) return 0;
@@ -171,7 +299,9 @@ document.addEventListener('DOMContentLoaded', function() {
                 }
             }
             
-            return { element: card, id, title, author, series, rating };
+            const position = parseInt(card.dataset.position, 10) || 0;
+            
+            return { element: card, id, title, author, series, rating, position };
         });
         
         // Sort books
@@ -185,6 +315,9 @@ document.addEventListener('DOMContentLoaded', function() {
                 case 'author':
                     result = a.author.localeCompare(b.author);
                     break;
+                case 'position':
+                    result = a.position - b.position;
+                    break;
                 case 'series':
                     // Handle empty series (empty series go at the end)
                     if (a.series === "" && b.series === "") return 0;
@@ -289,9 +422,50 @@ document.addEventListener('DOMContentLoaded', function() {
             setViewMode('compact');
         });
         
+        // Undo and redo this tab's changes
+        undoButton.addEventListener('click', () => undoChange(false));
+        redoButton.addEventListener('click', () => undoChange(true));
+        document.addEventListener('keydown', e => {
+            if (!(e.ctrlKey || e.metaKey) || e.target.closest('input, textarea, select')) {
+                return;
+            }
+            const key = e.key.toLowerCase();
+            if (key === 'z' || key === 'y') {
+                e.preventDefault();
+                undoChange(key === 'y' || e.shiftKey);
+            }
+        });
+        
         // Load saved view preference
         loadViewPreference();
     }
+
+    // Undo (or redo) this tab's most recent change
+    function undoChange(redo) {
+        showLoading();
+        
+        fetch(redo ? API.REDO : API.UNDO, {
+            method: 'POST',
+            headers: {
+                'X-Session-ID': SESSION_ID
+            }
+        })
+        .then(response => {
+            if (!response.ok) {
+                return apiError(response, `Failed to ${redo ? 'redo' : 'undo'}`);
+            }
+            // Reload rather than wait for the live updates, which may be unavailable
+            loadBooks();
+        })
+        .catch(error => {
+            hideLoading();
+            if (error.code === 'not_found') {
+                return; // Nothing to undo
+            }
+            console.error('Error undoing change:', error);
+            alert(error.message);
+        });
+    }
     
     // Set the view mode (full or compact)
     function setViewMode(mode) {
@@ -400,14 +574,13 @@ document.addEventListener('DOMContentLoaded', function() {
         const title = card.querySelector('.book-title').textContent;
         const author = card.querySelector('.book-author').textContent;
         
-        // Check if book is an audiobook
-        const typeElement = card.querySelector('.book-type');
-        const isAudiobook = typeElement && typeElement.textContent.includes('Audiobook');
+        // Keep the type icon of ebooks and audiobooks
+        const typeElement = card.querySelector('.book-type i');
         
         // Create table cells
         const titleCell = document.createElement('div');
         titleCell.className = 'cell-title';
-        const typeIcon = isAudiobook ? '<i class="fas fa-headphones"></i> ' : '';
+        const typeIcon = typeElement ? `${typeElement.outerHTML} ` : '';
         titleCell.innerHTML = `<div class="book-title">${typeIcon}${title}</div>`;
         
         const authorCell = document.createElement('div');
@@ -487,8 +660,19 @@ document.addEventListener('DOMContentLoaded', function() {
                     const bookId = evt.item.dataset.id;
                     const newStatus = evt.to.dataset.status;
                     
-                    // Update the book status on the server
-                    updateBookStatus(bookId, newStatus);
+                    if (evt.from === evt.to) {
+                        if (evt.oldIndex !== evt.newIndex) {
+                            moveBook(evt.item);
+                        }
+                        return;
+                    }
+                    
+                    // Update the book status on the server, then keep the drop position
+                    updateBookStatus(bookId, newStatus).then(updated => {
+                        if (updated) {
+                            moveBook(evt.item);
+                        }
+                    });
                 }
             });
         });
@@ -557,6 +741,7 @@ document.addEventListener('DOMContentLoaded', function() {
         const card = document.createElement('div');
         card.className = 'book-card';
         card.dataset.id = book.id;
+        card.dataset.position = book.position;
         
         const coverUrl = book.cover_url || 'https://via.placeholder.com/150x200?text=No+Cover';
         const ratingHtml = book.rating ? `<p class="book-rating">Rating: ${book.rating}/10</p>` : '';
@@ -569,8 +754,8 @@ document.addEventListener('DOMContentLoaded', function() {
             seriesHtml = `<p class="book-series">${book.series}</p>`;
         }
         
-        // Show book type if it's an audiobook (default type "book" isn't shown to keep UI clean)
-        const typeHtml = book.type === 'audiobook' ? `<p class="book-type"><i class="fas fa-headphones"></i> Audiobook</p>` : '';
+        // Show book type if it's an ebook or audiobook (default type "book" isn't shown to keep UI clean)
+        const typeHtml = TYPE_BADGES[book.type] ? `<p class="book-type">${typeIconHtml(book.type)}${TYPE_BADGES[book.type].label}</p>` : '';
         
         card.innerHTML = `
             <div class="book-cover">
@@ -643,6 +828,7 @@ document.addEventListener('DOMContentLoaded', function() {
         const newBook = {
             title: book.title,
             author: book.author,
+            authors: book.authors || [],
             open_library_id: book.open_library_id,
             isbn: book.isbn || '',
             status: 'Want to Read',
@@ -653,13 +839,14 @@ document.addEventListener('DOMContentLoaded', function() {
         fetch(API.BOOKS, {
             method: 'POST',
             headers: {
-                'Content-Type': 'application/json'
+                'Content-Type': 'application/json',
+                'X-Session-ID': SESSION_ID
             },
             body: JSON.stringify(newBook)
         })
         .then(response => {
             if (!response.ok) {
-                throw new Error('Failed to add book');
+                return apiError(response, 'Failed to add book');
             }
             return response.json();
         })
@@ -680,18 +867,40 @@ document.addEventListener('DOMContentLoaded', function() {
         .catch(error => {
             console.error('Error adding book:', error);
             hideLoading();
-            alert('Failed to add book. Please try again.');
+            if (error.code === 'duplicate') {
+                alert('This book is already on your bookshelf.');
+            } else {
+                alert('Failed to add book. Please try again.');
+            }
         });
     }
 
+    // Turn an RFC 7807 problem+json error response into a rejected promise.
+    // The Error carries the API's machine-readable code (e.g. 'duplicate', 'not_found').
+    function apiError(response, fallbackMessage) {
+        return response.json()
+            .catch(() => ({}))
+            .then(problem => {
+                const error = new Error(problem.detail || fallbackMessage);
+                error.code = problem.code;
+                error.status = response.status;
+                throw error;
+            });
+    }
+
     // Update a book's status
+    // Resolves to true if the server accepted the change
     function updateBookStatus(bookId, newStatus) {
         showLoading();
+        if (newStatus === 'Read') {
+            finishedInThisTab.add(Number(bookId)); // Card IDs are strings, event IDs numbers
+        }
         
-        fetch(API.BOOK_STATUS(bookId), {
+        return fetch(API.BOOK_STATUS(bookId), {
             method: 'PUT',
             headers: {
-                'Content-Type': 'application/json'
+                'Content-Type': 'application/json',
+                'X-Session-ID': SESSION_ID
             },
             body: JSON.stringify({ status: newStatus })
         })
@@ -700,6 +909,7 @@ document.addEventListener('DOMContentLoaded', function() {
                 throw new Error('Failed to update book status');
             }
             hideLoading();
+            return true;
         })
         .catch(error => {
             console.error('Error updating book status:', error);
@@ -707,6 +917,45 @@ document.addEventListener('DOMContentLoaded', function() {
             alert('Failed to update book status. Please try again.');
             // Reload books to reset the UI to the server state
             loadBooks();
+            return false;
+        });
+    }
+
+    // Save where a card was dropped within its shelf: after the card above it,
+    // or before the card below it if it is now first
+    function moveBook(card) {
+        const previous = card.previousElementSibling;
+        const next = card.nextElementSibling;
+        let body;
+        if (previous && previous.classList.contains('book-card')) {
+            body = { after: parseInt(previous.dataset.id, 10) };
+        } else if (next && next.classList.contains('book-card')) {
+            body = { before: parseInt(next.dataset.id, 10) };
+        } else {
+            return; // Alone on the shelf
+        }
+        
+        fetch(API.BOOK_POSITION(card.dataset.id), {
+            method: 'PUT',
+            headers: {
+                'Content-Type': 'application/json',
+                'X-Session-ID': SESSION_ID
+            },
+            body: JSON.stringify(body)
+        })
+        .then(response => {
+            if (!response.ok) {
+                throw new Error('Failed to reorder book');
+            }
+            // The shelf is renumbered in the order shown
+            card.closest('.books-container').querySelectorAll('.book-card').forEach((other, index) => {
+                other.dataset.position = index + 1;
+            });
+        })
+        .catch(error => {
+            console.error('Error reordering book:', error);
+            alert('Failed to save the new order. Please try again.');
+            loadBooks();
         });
     }
 
@@ -717,6 +966,7 @@ document.addEventListener('DOMContentLoaded', function() {
         // Update the UI with book details
         document.getElementById('detail-title').textContent = book.title;
         document.getElementById('detail-author').textContent = book.author;
+        document.getElementById('detail-contributors').textContent = formatContributors(book.authors);
         document.getElementById('detail-cover').src = book.cover_url || 'https://via.placeholder.com/150x200?text=No+Cover';
         
         // Update OpenLibrary link
@@ -755,6 +1005,7 @@ document.addEventListener('DOMContentLoaded', function() {
         
         // Update type radio buttons
         document.getElementById('type-book').checked = book.type === 'book' || !book.type;
+        document.getElementById('type-ebook').checked = book.type === 'ebook';
         document.getElementById('type-audiobook').checked = book.type === 'audiobook';
         
         // Update comments
@@ -764,10 +1015,31 @@ document.addEventListener('DOMContentLoaded', function() {
         document.getElementById('book-series').value = book.series || '';
         document.getElementById('book-series-index').value = book.series_index || '';
         
+        // Update reading dates
+        document.getElementById('book-date-started').value = toDateInput(book.date_started);
+        document.getElementById('book-date-finished').value = toDateInput(book.date_finished);
+        
         // Show the details popup
         bookDetails.classList.remove('hidden');
     }
 
+    // Credit narrators and translators, e.g. "Narrated by Jane Doe · Translated by John Roe"
+    function formatContributors(contributors) {
+        const credits = { narrator: 'Narrated by', translator: 'Translated by' };
+        return Object.entries(credits)
+            .map(([role, label]) => {
+                const names = (contributors || []).filter(c => c.role === role).map(c => c.name);
+                return names.length ? `${label} ${names.join(', ')}` : '';
+            })
+            .filter(Boolean)
+            .join(' · ');
+    }
+
+    // Convert an API timestamp to the YYYY-MM-DD value of a date input
+    function toDateInput(timestamp) {
+        return timestamp ? timestamp.slice(0, 10) : '';
+    }
+
     // Preview rating on hover
     function previewRating(rating) {
         ratingStars.forEach((star, index) => {
@@ -806,7 +1078,7 @@ document.addEventListener('DOMContentLoaded', function() {
         
         const comments = document.getElementById('book-comments').value.trim();
         const series = document.getElementById('book-series').value.trim();
-        const type = document.getElementById('type-audiobook').checked ? 'audiobook' : 'book';
+        const type = document.querySelector('input[name="book-type"]:checked')?.value || 'book';
         let seriesIndex = document.getElementById('book-series-index').value;
         
         // Convert seriesIndex to a number if it's not empty
@@ -829,18 +1101,36 @@ document.addEventListener('DOMContentLoaded', function() {
             return;
         }
         
+        const payload = {
+            rating: currentRating,
+            comments: comments || null,
+            series: series || null,
+            series_index: seriesIndex,
+            type: type
+        };
+        
+        // Only send dates that were changed, so unchanged ones keep their time of day
+        const dateStarted = document.getElementById('book-date-started').value;
+        const dateFinished = document.getElementById('book-date-finished').value;
+        if (dateStarted && dateFinished && dateFinished < dateStarted) {
+            hideLoading();
+            alert('The finish date cannot be before the start date');
+            return;
+        }
+        if (dateStarted !== toDateInput(currentBook.date_started)) {
+            payload.date_started = dateStarted || null;
+        }
+        if (dateFinished !== toDateInput(currentBook.date_finished)) {
+            payload.date_finished = dateFinished || null;
+        }
+        
         fetch(API.BOOK_DETAILS(currentBook.id), {
             method: 'PUT',
             headers: {
-                'Content-Type': 'application/json'
+                'Content-Type': 'application/json',
+                'X-Session-ID': SESSION_ID
             },
-            body: JSON.stringify({
-                rating: currentRating,
-                comments: comments || null,
-                series: series || null,
-                series_index: seriesIndex,
-                type: type
-            })
+            body: JSON.stringify(payload)
         })
         .then(response => {
             if (!response.ok) {
@@ -853,6 +1143,12 @@ document.addEventListener('DOMContentLoaded', function() {
             currentBook.series = series || null;
             currentBook.series_index = seriesIndex;
             currentBook.type = type;
+            if ('date_started' in payload) {
+                currentBook.date_started = payload.date_started;
+            }
+            if ('date_finished' in payload) {
+                currentBook.date_finished = payload.date_finished;
+            }
             
             // Update the book card in the shelf
             updateBookCardInShelf(currentBook);
@@ -870,12 +1166,15 @@ document.addEventListener('DOMContentLoaded', function() {
 
     // Delete a book
     function deleteBook() {
-        if (!currentBook || !confirm('Are you sure you want to delete this book?')) return;
+        if (!currentBook || !confirm('Move this book to the trash? It can be restored until it is purged.')) return;
         
         showLoading();
         
         fetch(API.DELETE_BOOK(currentBook.id), {
-            method: 'DELETE'
+            method: 'DELETE',
+            headers: {
+                'X-Session-ID': SESSION_ID
+            }
         })
         .then(response => {
             if (!response.ok) {
@@ -932,15 +1231,15 @@ document.addEventListener('DOMContentLoaded', function() {
                 
                 // Update type info if needed
                 let typeElement = bookCard.querySelector('.book-info .book-type');
-                if (book.type === 'audiobook') {
+                if (TYPE_BADGES[book.type]) {
                     if (typeElement) {
-                        typeElement.innerHTML = `<i class="fas fa-headphones"></i> Audiobook`;
+                        typeElement.innerHTML = `${typeIconHtml(book.type)}${TYPE_BADGES[book.type].label}`;
                     } else {
                         const authorElement = bookInfo.querySelector('.book-author');
                         
                         const typeP = document.createElement('p');
                         typeP.className = 'book-type';
-                        typeP.innerHTML = `<i class="fas fa-headphones"></i> Audiobook`;
+                        typeP.innerHTML = `${typeIconHtml(book.type)}${TYPE_BADGES[book.type].label}`;
                         
                         // Insert after author element or series element if it exists
                         const seriesElement = bookInfo.querySelector('.book-series');
@@ -1009,11 +1308,11 @@ document.addEventListener('DOMContentLoaded', function() {
                     }
                 }
                 
-                // Update title cell with type icon for audiobooks
+                // Update title cell with type icon for ebooks and audiobooks
                 const titleCell = bookCard.querySelector('.cell-title');
                 if (titleCell) {
                     const titleText = book.title;
-                    const typeIcon = book.type === 'audiobook' ? '<i class="fas fa-headphones"></i> ' : '';
+                    const typeIcon = typeIconHtml(book.type);
                     titleCell.innerHTML = `<div class="book-title">${typeIcon}${titleText}</div>`;
                 }
             }